@@ -32,6 +32,7 @@ const (
 	X509ClientCertificateAuthentication
 	PlainIdentityAuthentication
 	AnonymousAccessAuthentication
+	LdapAuthentication
 
 	// The following constants are used to identify the different methods of metadata fetching.
 	UnknownMetadataMethod MetadataMethod = iota
@@ -45,12 +46,15 @@ const (
 	OpaAuthorization
 	KubernetesSubjectAccessReviewAuthorization
 	SpiceDBAuthorization
+	ScopesAuthorization
 
 	// The following constants are used to identify the different methods of auth response.
 	UnknownAuthResponseMethod AuthResponseMethod = iota
 	PlainAuthResponse
 	JsonAuthResponse
 	WristbandAuthResponse
+	CombinedAuthResponse
+	RateLimitDescriptorsAuthResponse
 
 	// The following constants are used to identify the different methods of callback functions.
 	UnknownCallbackMethod CallbackMethod = iota
@@ -113,21 +117,52 @@ type AuthConfigSpec struct {
 	// Authorino uses the requested host to lookup for the corresponding authentication/authorization configs to enforce.
 	Hosts []string `json:"hosts"`
 
+	// Hosts from the list of hosts temporarily disabled, e.g. during the migration of the service.
+	// Disabled hosts are not linked to the AuthConfig in the index, thus not served by it, while the other hosts continue
+	// to be. Requests to a disabled host are handled as if there was no AuthConfig for the host, unless another one matches it
+	// (e.g. with a wildcard host).
+	// +optional
+	DisabledHosts []string `json:"disabledHosts,omitempty"`
+
 	// Named sets of patterns that can be referred in `when` conditions and in pattern-matching authorization policy rules.
 	// +optional
 	NamedPatterns map[string]PatternExpressions `json:"patterns,omitempty"`
 
+	// Timeout of the auth pipeline for the requests to the hosts of this AuthConfig - in milliseconds.
+	// Overrides the global timeout of the Authorino instance, up to the maximum allowed by the instance.
+	// If omitted, the global timeout applies.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	Timeout int `json:"timeout,omitempty"`
+
 	// Overall conditions for the AuthConfig to be enforced.
 	// If omitted, the AuthConfig will be enforced at all requests.
 	// If present, all conditions must match for the AuthConfig to be enforced; otherwise, Authorino skips the AuthConfig and returns to the auth request with status OK.
 	// +optional
 	Conditions []PatternExpressionOrRef `json:"when,omitempty"`
 
+	// Break-glass bypass of the AuthConfig for requests that carry a valid signed maintenance header.
+	// Requests with a valid header are allowed without going through the auth pipeline; invalid headers are ignored.
+	// +optional
+	MaintenanceBypass *MaintenanceBypassSpec `json:"maintenanceBypass,omitempty"`
+
 	// Authentication configs.
 	// At least one config MUST evaluate to a valid identity object for the auth request to be successful.
 	// +optional
 	Authentication map[string]AuthenticationSpec `json:"authentication,omitempty"`
 
+	// Strict authentication.
+	// If true, all the authentication configs are evaluated and the request is denied as ambiguous when more than one of
+	// them resolves an identity, instead of accepting the first identity resolved.
+	// +optional
+	StrictAuthentication bool `json:"strictAuthentication,omitempty"`
+
+	// Default location of the credentials in the request, for the authentication configs that do not specify one.
+	// If omitted, authentication configs that do not specify the location of the credentials expect them in the HTTP
+	// Authorization header, prefixed with "Bearer".
+	// +optional
+	Credentials *Credentials `json:"credentials,omitempty"`
+
 	// Metadata sources.
 	// Authorino fetches auth metadata as JSON from sources specified in this config.
 	// +optional
@@ -149,6 +184,26 @@ type AuthConfigSpec struct {
 	Callbacks map[string]CallbackSpec `json:"callbacks,omitempty"`
 }
 
+// Settings of the maintenance bypass.
+// The value of the maintenance header must be "<expires-at>.<signature>", where <expires-at> is a Unix timestamp (in seconds)
+// and <signature> is the hex-encoded HMAC-SHA256 of "<host>.<expires-at>" with the shared secret, <host> being the host
+// of the request, in lowercase and without the port number.
+type MaintenanceBypassSpec struct {
+	// Name of the HTTP header that carries the signed maintenance token.
+	// +optional
+	// +kubebuilder:default:=x-authorino-maintenance
+	Header string `json:"header,omitempty"`
+
+	// Reference to a Secret key whose value is the shared secret used to sign the maintenance header.
+	SharedSecretRef SecretKeyReference `json:"sharedSecretRef"`
+
+	// Maximum lifetime of a maintenance header, in seconds.
+	// Headers that expire further in the future than this are rejected.
+	// +optional
+	// +kubebuilder:default:=3600
+	MaxTTL int `json:"maxTTL,omitempty"`
+}
+
 type PatternExpressions []PatternExpression
 
 type PatternExpression struct {
@@ -157,14 +212,15 @@ type PatternExpression struct {
 	// Authorino custom JSON path modifiers are also supported.
 	Selector string `json:"selector,omitempty"`
 	// The binary operator to be applied to the content fetched from the authorization JSON, for comparison with "value".
-	// Possible values are: "eq" (equal to), "neq" (not equal to), "incl" (includes; for arrays), "excl" (excludes; for arrays), "matches" (regex)
+	// Possible values are: "eq" (equal to), "neq" (not equal to), "incl" (includes; for arrays), "excl" (excludes; for arrays), "matches" (regex),
+	// and the case-insensitive variants "ieq", "ineq", "iincl" and "iexcl"
 	Operator PatternExpressionOperator `json:"operator,omitempty"`
 	// The value of reference for the comparison with the content fetched from the authorization JSON.
 	// If used with the "matches" operator, the value must compile to a valid Golang regex.
 	Value string `json:"value,omitempty"`
 }
 
-// +kubebuilder:validation:Enum:=eq;neq;incl;excl;matches
+// +kubebuilder:validation:Enum:=eq;neq;incl;excl;matches;ieq;ineq;iincl;iexcl
 type PatternExpressionOperator string
 
 type PatternExpressionOrRef struct {
@@ -231,9 +287,24 @@ type EvaluatorCaching struct {
 	Key ValueOrSelector `json:"key"`
 
 	// Duration (in seconds) of the external data in the cache before pulled again from the source.
+	// If omitted, the default TTL of the Authorino instance applies (default: 60).
+	// The TTL is capped by the maximum TTL of the Authorino instance, if any.
 	// +optional
-	// +kubebuilder:default:=60
 	TTL int `json:"ttl,omitempty"`
+
+	// Maximum number of entries stored in the cache.
+	// If omitted, the default of the Authorino instance applies (default: unlimited – i.e. up to the size of the cache).
+	// The number is capped by the maximum number of entries of the Authorino instance, if any.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxEntries int `json:"maxEntries,omitempty"`
+
+	// Conditions for the result of the evaluator to be stored in the cache.
+	// The conditions are matched against the Authorization JSON extended with the `result` property, which holds the
+	// result of the evaluator (e.g. `result.active`).
+	// Omit it to cache all successful results.
+	// +optional
+	Conditions []PatternExpressionOrRef `json:"when,omitempty"`
 }
 
 type AuthenticationSpec struct {
@@ -258,9 +329,46 @@ type AuthenticationSpec struct {
 	// +optional
 	Defaults ExtendedProperties `json:"defaults,omitempty"`
 
+	// Projects claims of the resolved identity object into properties of the same object, renamed.
+	// Maps the name of each target property to the selector of the source claim, relative to the resolved identity object
+	// (e.g. "email: email_address", "roles: realm_access.roles").
+	// The mapping is applied after the identity is verified and before the defaults and overrides.
+	// Source claims that are not present are not mapped. Original claims are kept.
+	// It requires the resolved identity object to always be a JSON object.
+	// +optional
+	ClaimMapping map[string]string `json:"claimMapping,omitempty"`
+
+	// Selector of a claim of the resolved identity object that must be present and not empty for the identity to count
+	// as authenticated (e.g. "sub").
+	// The claim is checked in the identity object as resolved by the authentication method, i.e. before the claim mapping,
+	// the defaults and the overrides. Identities without the claim are rejected.
+	// +optional
+	RequiredSubjectClaim string `json:"requiredSubjectClaim,omitempty"`
+
+	// List of revoked tokens, whose identities are rejected, including the ones resolved from the cache.
+	// +optional
+	RevocationList *RevocationListSpec `json:"revocationList,omitempty"`
+
 	AuthenticationMethodSpec `json:""`
 }
 
+// Settings to fetch a list of revoked tokens.
+type RevocationListSpec struct {
+	// Endpoint of the revocation list, that responds to GET requests with a JSON array of the identifiers of the revoked
+	// tokens (e.g. ["8f2e1c6a","b71d0e93"]).
+	Url string `json:"url"`
+
+	// Selector of the identifier of the token in the resolved identity object, looked up in the revocation list.
+	// If omitted, it defaults to "jti".
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
+	// Interval (in seconds) to fetch the revocation list again.
+	// If omitted or set to 0, the revocation list is fetched only once, when the AuthConfig is reconciled.
+	// +optional
+	TTL int `json:"ttl,omitempty"`
+}
+
 func (s *AuthenticationSpec) GetMethod() AuthenticationMethod {
 	if s.ApiKey != nil {
 		return ApiKeyAuthentication
@@ -276,6 +384,8 @@ func (s *AuthenticationSpec) GetMethod() AuthenticationMethod {
 		return PlainIdentityAuthentication
 	} else if s.AnonymousAccess != nil {
 		return AnonymousAccessAuthentication
+	} else if s.Ldap != nil {
+		return LdapAuthentication
 	}
 	return UnknownAuthenticationMethod
 }
@@ -331,6 +441,8 @@ type AuthenticationMethodSpec struct {
 	Plain *PlainIdentitySpec `json:"plain,omitempty"`
 	// Anonymous access.
 	AnonymousAccess *AnonymousAccessSpec `json:"anonymous,omitempty"`
+	// Authentication by binding to an LDAP server (e.g. Active Directory) with the username and password of the client.
+	Ldap *LdapAuthenticationSpec `json:"ldap,omitempty"`
 }
 
 // Settings to select the API key Kubernetes secrets.
@@ -343,6 +455,12 @@ type ApiKeyAuthenticationSpec struct {
 	// +optional
 	// +kubebuilder:default:=false
 	AllNamespaces bool `json:"allNamespaces,omitempty"`
+
+	// List of namespaces where Authorino should look for API key secrets, instead of only the namespace of the AuthConfig.
+	// Takes precedence over allNamespaces.
+	// Setting this option in namespaced Authorino instances has no effect.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
 }
 
 // Settings to fetch the JSON Web Key Set (JWKS) for the JWT authentication.
@@ -356,11 +474,84 @@ type JwtAuthenticationSpec struct {
 	IssuerUrl string `json:"issuerUrl"`
 
 	// Decides how long to wait before refreshing the JWKS (in seconds).
-	// If omitted, Authorino will never refresh the JWKS.
+	// If omitted or set to 0, Authorino will never refresh the JWKS, i.e. the OpenID Connect configuration is fetched
+	// only once, when the AuthConfig is reconciled.
 	// +optional
 	TTL int `json:"ttl,omitempty"`
+
+	// Accepted audiences of the JWT.
+	// If present, the "aud" claim of the JWT must contain at least one of the listed audiences.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+
+	// Template of an audience derived from the host of the request, that the "aud" claim of the JWT must contain.
+	// The placeholder "{host}" is replaced with the host of the request, in lowercase and without the port number (e.g. "https://{host}").
+	// The check applies in addition to the one of the static audiences.
+	// Audiences are compared regardless of case and trailing slashes.
+	// +optional
+	HostAudience string `json:"hostAudience,omitempty"`
+
+	// Grace window after the expiration of the JWT (in seconds), during which the expired token is still accepted.
+	// Tokens accepted within the grace window are flagged with the "expired_in_grace" property of the resolved identity.
+	// If omitted, expired tokens are rejected.
+	// +optional
+	ExpiryGrace int `json:"expiryGrace,omitempty"`
+
+	// Allowance for the drift between the clock of the issuer and the one of Authorino (in seconds).
+	// Tokens expired within the allowance are accepted as not expired, i.e. not flagged as expired in grace.
+	// Use it to verify the Festival Wristband tokens issued by other instances of Authorino, whose clocks may drift.
+	// +optional
+	ClockSkew int `json:"clockSkew,omitempty"`
+
+	// Skips the check that the "iss" claim of the JWT equals the issuer of the discovered OpenID Connect configuration.
+	// Only for legacy issuers whose tokens do not carry the same issuer as their OpenID Connect configuration.
+	// The check does not apply to JWTs verified against a static JSON Web Key Set.
+	// +optional
+	SkipIssuerCheck bool `json:"skipIssuerCheck,omitempty"`
+
+	// Rejects without verification the credentials that are not in JWT format or whose "iss" claim, before being verified,
+	// does not match the issuer URL.
+	// Useful when the same credentials (e.g. the "Authorization" header) are shared with other authentication methods,
+	// such as OAuth2 token introspection, so only the plausible ones attempt to verify the token.
+	// The issuer is not matched if `skipIssuerCheck` is true or the JWT is verified against a static JSON Web Key Set.
+	// +optional
+	PreMatchIssuer bool `json:"preMatchIssuer,omitempty"`
+
+	// Static JSON Web Key Set (JWKS), or PEM-encoded public keys or certificates, to verify the JWT offline.
+	// If set, Authorino sends no request to the issuer (neither for OpenID Connect Discovery nor for the JWKS), and the
+	// `issuerUrl` and `ttl` fields are ignored.
+	// +optional
+	Jwks string `json:"jwks,omitempty"`
+
+	// Reference to a key of a ConfigMap, in the namespace of the AuthConfig, that holds a static JSON Web Key Set (JWKS),
+	// or PEM-encoded public keys or certificates, to verify the JWT offline.
+	// If set, Authorino sends no request to the issuer (neither for OpenID Connect Discovery nor for the JWKS), and the
+	// `issuerUrl` and `ttl` fields are ignored.
+	// +optional
+	JwksConfigMapRef *ConfigMapKeyReference `json:"jwksConfigMapRef,omitempty"`
+
+	// Reference to a key of a Secret, in the namespace of the AuthConfig, that holds a static JSON Web Key Set (JWKS), or
+	// PEM-encoded public keys or certificates, to verify the JWT offline.
+	// If set, Authorino sends no request to the issuer (neither for OpenID Connect Discovery nor for the JWKS), and the
+	// `issuerUrl` and `ttl` fields are ignored.
+	// +optional
+	JwksSecretRef *SecretKeyReference `json:"jwksSecretRef,omitempty"`
+
+	// Response to the requests whose JWT cannot be verified because the OpenID Connect configuration of the issuer is
+	// not available (e.g. the discovery failed and has not recovered yet).
+	// "Unauthenticated" denies the request as any other authentication failure; "ServiceUnavailable" responds with
+	// 503 Service Unavailable, so the clients can retry.
+	// +optional
+	// +kubebuilder:validation:Enum:=Unauthenticated;ServiceUnavailable
+	// +kubebuilder:default:=Unauthenticated
+	OnProviderUnavailable string `json:"onProviderUnavailable,omitempty"`
 }
 
+const (
+	ProviderUnavailableUnauthenticated    = "Unauthenticated"
+	ProviderUnavailableServiceUnavailable = "ServiceUnavailable"
+)
+
 // Settings to perform the OAuth2 token introspection request.
 type OAuth2TokenIntrospectionSpec struct {
 	// The full URL of the token introspection endpoint.
@@ -371,6 +562,12 @@ type OAuth2TokenIntrospectionSpec struct {
 	// +optional
 	TokenTypeHint string `json:"tokenTypeHint,omitempty"`
 
+	// Rejects the tokens in JWT format without sending them to the token introspection endpoint.
+	// Useful when the same credentials (e.g. the "Authorization" header) are shared with JWT authentication methods,
+	// so only opaque tokens are introspected.
+	// +optional
+	OpaqueTokensOnly bool `json:"opaqueTokensOnly,omitempty"`
+
 	// Reference to a Kubernetes secret in the same namespace, that stores client credentials to the OAuth2 server.
 	Credentials *k8score.LocalObjectReference `json:"credentialsRef"`
 }
@@ -406,6 +603,37 @@ type PlainIdentitySpec struct {
 
 type AnonymousAccessSpec struct{}
 
+// Settings to verify username and password credentials by binding to an LDAP server.
+// The credentials are expected in the format of HTTP Basic authentication, i.e. base64-encoded "username:password".
+// If the location of the credentials is omitted, it defaults to the HTTP Authorization header with the "Basic" prefix.
+type LdapAuthenticationSpec struct {
+	// URL of the LDAP server, e.g. "ldaps://ldap.example.com:636".
+	// +kubebuilder:validation:Pattern:=`^ldaps?://`
+	Url string `json:"url"`
+
+	// Template of the DN to bind with, where the placeholder "{username}" is replaced with the username of the
+	// credentials, e.g. "uid={username},ou=people,dc=example,dc=org".
+	// For Active Directory, any other bind name accepted by the server can be used, e.g. "{username}@example.com".
+	BindDn string `json:"bindDn"`
+
+	// Attributes of the entry of the user to retrieve after the bind, and add to the resolved identity object, under
+	// the "attributes" property (e.g. "mail", "memberOf").
+	// Attributes are always arrays of values, even when single-valued.
+	// +optional
+	Attributes []string `json:"attributes,omitempty"`
+
+	// Base DN of the subtree where to look up the entry of the user to retrieve the attributes, by `searchFilter`.
+	// If omitted, the attributes are retrieved from the entry at the bind DN.
+	// +optional
+	SearchBaseDn string `json:"searchBaseDn,omitempty"`
+
+	// Filter to look up the entry of the user under the search base DN, where the placeholder "{username}" is replaced
+	// with the username of the credentials, e.g. "(sAMAccountName={username})".
+	// If omitted, it defaults to "(uid={username})".
+	// +optional
+	SearchFilter string `json:"searchFilter,omitempty"`
+}
+
 type MetadataSpec struct {
 	CommonEvaluatorSpec `json:""`
 	MetadataMethodSpec  `json:""`
@@ -457,6 +685,11 @@ type HttpEndpointSpec struct {
 	// +optional
 	Parameters NamedValuesOrSelectors `json:"bodyParameters,omitempty"`
 
+	// Leaves out of the body of the HTTP request the 'bodyParameters' that resolve to an empty value, i.e. null (e.g. a
+	// selector of a missing property), an empty string, an empty array or an empty object.
+	// +optional
+	OmitEmptyParameters bool `json:"omitEmptyBodyParameters,omitempty"`
+
 	// Content-Type of the request body. Shapes how 'bodyParameters' are encoded.
 	// Use it with method=POST; for GET requests, Content-Type is automatically set to 'text/plain'.
 	// +optional
@@ -467,12 +700,34 @@ type HttpEndpointSpec struct {
 	// +optional
 	Headers NamedValuesOrSelectors `json:"headers,omitempty"`
 
+	// Names of the custom headers whose values are sent as repeated header lines in the HTTP request.
+	// If the value of one of these headers resolves to an array, each item is sent as a separate header line with the same name.
+	// Otherwise, a single value is set, overwriting any previous value of the header.
+	// +optional
+	RepeatedHeaders []string `json:"repeatedHeaders,omitempty"`
+
+	// Custom query string parameters appended to the URL of the HTTP request.
+	// Parameters whose values resolve to an array are encoded as one query string parameter per item (e.g. ?scope=a&scope=b),
+	// unless 'queryArraySeparator' is set.
+	// +optional
+	QueryParameters NamedValuesOrSelectors `json:"queryParameters,omitempty"`
+
+	// Separator used to join the items of the query string parameters whose values resolve to an array into a single
+	// parameter (e.g. "," for ?scope=a,b). If omitted, the parameter is repeated for each item.
+	// +optional
+	QueryArraySeparator string `json:"queryArraySeparator,omitempty"`
+
 	// Reference to a Secret key whose value will be passed by Authorino in the request.
 	// The HTTP service can use the shared secret to authenticate the origin of the request.
 	// Ignored if used together with oauth2.
 	// +optional
 	SharedSecret *SecretKeyReference `json:"sharedSecretRef,omitempty"`
 
+	// Reloads the shared secret when the Secret changes, without reconciling the AuthConfig.
+	// Supported by HTTP metadata and callbacks; ignored otherwise.
+	// +optional
+	HotReloadSharedSecret bool `json:"hotReloadSharedSecret,omitempty"`
+
 	// Authentication with the HTTP service by OAuth2 Client Credentials grant.
 	// +optional
 	OAuth2 *OAuth2ClientAuthentication `json:"oauth2,omitempty"`
@@ -481,6 +736,18 @@ type HttpEndpointSpec struct {
 	// If omitted, it defaults to client credentials passed in the HTTP Authorization header and the "Bearer" prefix expected prepended to the secret value.
 	// +optional
 	Credentials Credentials `json:"credentials,omitempty"`
+
+	// Number of times to retry the request after a failure to connect to the HTTP service or a 5xx response.
+	// Only requests of idempotent methods (e.g. GET) are retried, unless `retryNonIdempotent` is set.
+	// If omitted, the request is not retried.
+	// +optional
+	Retries int `json:"retries,omitempty"`
+
+	// Retries the requests of non-idempotent methods (e.g. POST) as well.
+	// Set it only if the HTTP service is known to handle the requests idempotently, i.e. with no side effects when the
+	// same request is received more than once.
+	// +optional
+	RetryNonIdempotent bool `json:"retryNonIdempotent,omitempty"`
 }
 
 // +kubebuilder:validation:Enum:=GET;POST;PUT;PATCH;DELETE;HEAD;OPTIONS;CONNECT;TRACE
@@ -498,6 +765,14 @@ type SecretKeyReference struct {
 	Key string `json:"key"`
 }
 
+type ConfigMapKeyReference struct {
+	// The name of the ConfigMap in the namespace of the AuthConfig to select from.
+	Name string `json:"name"`
+
+	// The key of the ConfigMap to select from.
+	Key string `json:"key"`
+}
+
 // Settings for OAuth2 client authentication with the external service
 type OAuth2ClientAuthentication struct {
 	// Token endpoint URL of the OAuth2 resource server.
@@ -530,13 +805,45 @@ type UmaMetadataSpec struct {
 
 	// Reference to a Kubernetes secret in the same namespace, that stores client credentials to the resource registration API of the UMA server.
 	Credentials *k8score.LocalObjectReference `json:"credentialsRef"`
+
+	// URI of the resources to query the resource registry of the UMA server for.
+	// If omitted, it defaults to the path of the HTTP request.
+	// +optional
+	Uri *ValueOrSelector `json:"uri,omitempty"`
+
+	// Scopes of the resources to query the resource registry of the UMA server for.
+	// The value can resolve either to an array of scopes or to a string of space-delimited scopes.
+	// If omitted, the resources are queried regardless of their scopes.
+	// +optional
+	Scopes *ValueOrSelector `json:"scopes,omitempty"`
 }
 
 type AuthorizationSpec struct {
 	CommonEvaluatorSpec     `json:""`
 	AuthorizationMethodSpec `json:""`
+
+	// Shadow mode: the authorization config is evaluated and the decision is logged and reported in the metrics, but never enforced.
+	// Use it to roll out new authorization policies without affecting the requests.
+	// +optional
+	// +kubebuilder:default:=false
+	Shadow bool `json:"shadow,omitempty"`
+
+	// Outcome of the authorization config that ends the authorization phase, skipping the authorization configs of lower
+	// priority (i.e. higher number).
+	// "Deny" (default) ends the authorization phase only when access is denied; "Allow" also ends it when access is granted,
+	// in which case the request is authorized without evaluating the authorization configs of lower priority.
+	// Configs of the same priority are always enforced, as they are evaluated concurrently.
+	// +optional
+	// +kubebuilder:validation:Enum:=Deny;Allow
+	// +kubebuilder:default:=Deny
+	ShortCircuit string `json:"shortCircuit,omitempty"`
 }
 
+const (
+	ShortCircuitDeny  = "Deny"
+	ShortCircuitAllow = "Allow"
+)
+
 func (s *AuthorizationSpec) GetMethod() AuthorizationMethod {
 	if s.PatternMatching != nil {
 		return PatternMatchingAuthorization
@@ -546,6 +853,8 @@ func (s *AuthorizationSpec) GetMethod() AuthorizationMethod {
 		return KubernetesSubjectAccessReviewAuthorization
 	} else if s.SpiceDB != nil {
 		return SpiceDBAuthorization
+	} else if s.Scopes != nil {
+		return ScopesAuthorization
 	}
 	return UnknownAuthorizationMethod
 }
@@ -559,10 +868,38 @@ type AuthorizationMethodSpec struct {
 	KubernetesSubjectAccessReview *KubernetesSubjectAccessReviewAuthorizationSpec `json:"kubernetesSubjectAccessReview,omitempty"`
 	// Authorization decision delegated to external Authzed/SpiceDB server.
 	SpiceDB *SpiceDBAuthorizationSpec `json:"spicedb,omitempty"`
+	// Authorization by the scopes granted to the identity, required according to the request.
+	Scopes *ScopesAuthorizationSpec `json:"scopes,omitempty"`
 }
 
 type PatternMatchingAuthorizationSpec struct {
-	Patterns []PatternExpressionOrRef `json:"patterns"`
+	// List of patterns that must all be satisfied for the request to be authorized.
+	// +optional
+	Patterns []PatternExpressionOrRef `json:"patterns,omitempty"`
+
+	// Ordered list of allow/deny rules, evaluated from first to last. The effect of the first matching rule decides the authorization.
+	// Requests matching none of the rules are denied.
+	// If specified along with `patterns`, both the patterns and the rules must authorize the request.
+	// +optional
+	Rules []PatternMatchingRule `json:"rules,omitempty"`
+}
+
+type PatternMatchingRuleEffect string
+
+const (
+	PatternMatchingRuleAllow PatternMatchingRuleEffect = "allow"
+	PatternMatchingRuleDeny  PatternMatchingRuleEffect = "deny"
+)
+
+type PatternMatchingRule struct {
+	// Effect of the rule when it matches the request.
+	// +kubebuilder:validation:Enum:=allow;deny
+	Effect PatternMatchingRuleEffect `json:"effect"`
+
+	// Conditions for the rule to match the request. All conditions must be satisfied.
+	// Omit it to match all requests.
+	// +optional
+	Conditions []PatternExpressionOrRef `json:"when,omitempty"`
 }
 
 // Settings of the Open Policy Agent (OPA) authorization.
@@ -572,17 +909,60 @@ type OpaAuthorizationSpec struct {
 	// The Rego document must NOT include the "package" declaration in line 1.
 	Rego string `json:"rego,omitempty"`
 
+	// Reference to a key of a ConfigMap, in the same namespace as the AuthConfig, whose value is the Rego document.
+	// Use it alternatively to 'rego', e.g. for policies too large to be embedded in the AuthConfig.
+	// Setting both 'rego' and 'regoConfigMapRef' is rejected.
+	// Changes to the ConfigMap are reloaded automatically.
+	// +optional
+	RegoConfigMap *ConfigMapKeyReference `json:"regoConfigMapRef,omitempty"`
+
 	// Settings for fetching the OPA policy from an external registry.
 	// Use it alternatively to 'rego'.
 	// For the configurations of the HTTP request, the following options are not implemented: 'method', 'body', 'bodyParameters',
-	// 'contentType', 'headers', 'oauth2'. Use it only with: 'url', 'sharedSecret', 'credentials'.
+	// 'contentType', 'headers', 'oauth2'. Use it only with: 'url', 'sharedSecret', 'credentials', 'clientCertSecretRef'.
 	External *ExternalOpaPolicy `json:"externalPolicy,omitempty"`
 
+	// Policy precompiled to WebAssembly (e.g. with `opa build -t wasm`), evaluated with the OPA WASM runtime instead of interpreting Rego.
+	// Use it alternatively to 'rego', 'regoConfigMapRef' and 'externalPolicy'.
+	// +optional
+	Wasm *OpaWasmPolicy `json:"wasm,omitempty"`
+
 	// Returns the value of all Rego rules in the virtual document. Values can be read in subsequent evaluators/phases of the Auth Pipeline.
-	// Otherwise, only the default `allow` rule will be exposed.
+	// Otherwise, only the default `allow` rule and the rules listed in 'outputs' will be exposed.
+	// All values include intermediate rules of the policy, which may hold sensitive data (e.g. tokens, secrets) that can end up
+	// injected in the responses or sent to external services by the subsequent phases. Prefer 'outputs' to expose only what is needed.
 	// Returning all Rego rules can affect performance of OPA policies during reconciliation (policy precompile) and at runtime.
 	// +kubebuilder:default:=false
 	AllValues bool `json:"allValues,omitempty"`
+
+	// Names of the Rego rules whose values are exposed, in addition to the `allow` rule, to the subsequent evaluators/phases of the Auth Pipeline.
+	// Each name must refer to a rule defined in the policy. Ignored when 'allValues' is true.
+	// +optional
+	Outputs []string `json:"outputs,omitempty"`
+
+	// Routing of the requests among multiple Rego policies, based on a value fetched from the Authorization JSON.
+	// Only the policy whose key matches the selected value is evaluated.
+	// Requests that match none of the keys are evaluated against the policy defined in 'rego' or 'externalPolicy' (fallback).
+	// If no fallback policy is defined, requests that match none of the keys are denied.
+	// +optional
+	Routing *OpaPolicyRoutingSpec `json:"routing,omitempty"`
+
+	// Maximum time the evaluation of the policy can take for a request - in milliseconds.
+	// Evaluations that exceed it are cancelled and the request is denied.
+	// If omitted, the evaluation of the policy is not time-limited.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	EvaluationTimeout int `json:"evaluationTimeout,omitempty"`
+}
+
+// OpaPolicyRoutingSpec sets the selection of one among multiple OPA policies based on a request attribute.
+type OpaPolicyRoutingSpec struct {
+	// Selector of the value from the Authorization JSON used to choose the policy to evaluate (e.g. 'context.request.http.path.@extract:{"sep":"/","pos":1}').
+	Selector string `json:"selector"`
+
+	// Authorization policies as Rego language documents, by value of the selector.
+	// Same rules as 'rego' apply to each document.
+	Policies map[string]string `json:"policies"`
 }
 
 // ExternalOpaPolicy sets the configs for fetching OPA policies from an external source.
@@ -591,6 +971,12 @@ type ExternalOpaPolicy struct {
 
 	// Duration (in seconds) of the external data in the cache before pulled again from the source.
 	TTL int `json:"ttl,omitempty"`
+
+	// Reference to a Secret, in the same namespace as the AuthConfig, that holds the client certificate ('tls.crt') and
+	// key ('tls.key') to authenticate with the external registry by mutual TLS, e.g. a Secret of type kubernetes.io/tls.
+	// If the Secret includes a 'ca.crt' entry, it is used to verify the certificate of the registry instead of the system roots.
+	// +optional
+	ClientCertSecret *k8score.LocalObjectReference `json:"clientCertSecretRef,omitempty"`
 }
 
 // Parameters of the Kubernetes SubjectAccessReview request.
@@ -606,6 +992,18 @@ type KubernetesSubjectAccessReviewAuthorizationSpec struct {
 	// If omitted, it performs a non-resource SubjectAccessReview, with verb and path inferred from the request.
 	// +optional
 	ResourceAttributes *KubernetesSubjectAccessReviewResourceAttributesSpec `json:"resourceAttributes,omitempty"`
+
+	// Reference to a Kubernetes secret in the same namespace that stores a kubeconfig of a remote cluster to issue the
+	// SubjectAccessReview to.
+	// Omit it to issue the SubjectAccessReview to the cluster where Authorino is running.
+	// +optional
+	KubeconfigSecretRef *SecretKeyReference `json:"kubeconfigSecretRef,omitempty"`
+
+	// Issues a SelfSubjectAccessReview impersonating the user (and groups, if any), instead of a SubjectAccessReview
+	// that states them explicitly, so the review reflects the actual access of the user in the Kubernetes RBAC.
+	// Requires `user` and the ServiceAccount of Authorino to be granted the 'impersonate' verb on the users and groups.
+	// +optional
+	Impersonate bool `json:"impersonate,omitempty"`
 }
 
 type KubernetesSubjectAccessReviewResourceAttributesSpec struct {
@@ -625,6 +1023,13 @@ type KubernetesSubjectAccessReviewResourceAttributesSpec struct {
 	// Verb to check for authorization on the resource.
 	// Use '*' for all verbs.
 	Verb ValueOrSelector `json:"verb,omitempty"`
+
+	// Checks multiple resources in a batch.
+	// Attributes whose selectors resolve to arrays are expanded into one resource per item, zipped by position (all arrays must have the same length).
+	// Attributes that do not resolve to arrays apply to all the resources of the batch.
+	// The request is authorized only if all the resources are allowed.
+	// +optional
+	Batch bool `json:"batch,omitempty"`
 }
 
 // Settings of the check request to the external SpiceDB server.
@@ -646,6 +1051,42 @@ type SpiceDBAuthorizationSpec struct {
 
 	// The name of the permission (or relation) on which to execute the check.
 	Permission ValueOrSelector `json:"permission,omitempty"`
+
+	// Settings of the gRPC connection to the SpiceDB server.
+	// +optional
+	Connection *SpiceDBConnectionSpec `json:"connection,omitempty"`
+}
+
+// ScopesAuthorizationSpec requires the identity to carry the scope computed for the request, in either the `scope`
+// claim (space-delimited string) or the `scp` claim (string or array of strings).
+type ScopesAuthorizationSpec struct {
+	// Scope required for the request to be authorized.
+	// Use a selector with a string template (e.g. "{context.request.http.method}:{context.request.http.path}") to compute it from the request.
+	Required ValueOrSelector `json:"required"`
+
+	// Maps the value resolved from 'required' to the scope actually required.
+	// When specified, requests whose resolved value is missing in the mapping are denied.
+	// +optional
+	Mapping map[string]string `json:"mapping,omitempty"`
+}
+
+// Settings of the gRPC connection to the SpiceDB server.
+type SpiceDBConnectionSpec struct {
+	// Disables reusing the connection to the SpiceDB server across check requests.
+	// If true, a new connection (including a new TLS handshake) is established for every request.
+	// +optional
+	DisableReuse bool `json:"disableReuse,omitempty"`
+
+	// Number of TLS sessions cached for resumption with the SpiceDB server, thus skipping full handshakes when reconnecting.
+	// Set to 0 to disable TLS session resumption.
+	// Default: 32
+	// +optional
+	TLSSessionCacheSize *int `json:"tlsSessionCacheSize,omitempty"`
+
+	// Interval in seconds between HTTP/2 keepalive pings sent to the SpiceDB server to keep the connection alive.
+	// The SpiceDB server must permit pings at this frequency. Omit it or set to 0 to disable keepalive pings.
+	// +optional
+	KeepaliveTime int `json:"keepaliveTime,omitempty"`
 }
 
 type SpiceDBObject struct {
@@ -673,6 +1114,27 @@ type ResponseSpec struct {
 	Success WrappedSuccessResponseSpec `json:"success,omitempty"`
 }
 
+// Settings of an OPA policy precompiled to WebAssembly.
+// Exactly one of 'module', 'moduleConfigMapRef' and 'url' must be set.
+type OpaWasmPolicy struct {
+	// Entrypoint of the WASM module, i.e. the path of the package of the policy, e.g. "authz" for a module built with `opa build -t wasm -e authz`.
+	// The value of the entrypoint is the virtual document of the policy, which must include the "allow" rule.
+	Entrypoint string `json:"entrypoint"`
+
+	// Inline WASM module.
+	// +optional
+	Module []byte `json:"module,omitempty"`
+
+	// Reference to a key of the binary data of a ConfigMap, in the same namespace as the AuthConfig, whose value is the WASM module.
+	// Changes to the ConfigMap are reloaded automatically.
+	// +optional
+	ModuleConfigMap *ConfigMapKeyReference `json:"moduleConfigMapRef,omitempty"`
+
+	// Endpoint of the HTTP service to download the WASM module from, when the AuthConfig is reconciled.
+	// +optional
+	Url string `json:"url,omitempty"`
+}
+
 // +kubebuilder:validation:Minimum:=300
 // +kubebuilder:validation:Maximum:=599
 type DenyWithCode int64
@@ -688,8 +1150,47 @@ type DenyWithSpec struct {
 	// HTTP response headers to override the default denial headers.
 	Headers NamedValuesOrSelectors `json:"headers,omitempty"`
 
+	// Conditions for including the HTTP response headers in the denial, by name of the header.
+	// The conditions are evaluated against the Authorization JSON, where 'auth.denial' holds the reason of the denial and,
+	// for unauthorized requests, the name and the type of the authorization rule that denied the request.
+	// Headers without conditions are always included.
+	// +optional
+	HeaderConditions map[string][]PatternExpressionOrRef `json:"headerConditions,omitempty"`
+
 	// HTTP response body to override the default denial body.
 	Body *ValueOrSelector `json:"body,omitempty"`
+
+	// Properties emitted as Envoy dynamic metadata along with the denial, e.g. to capture the denied subject in the access logs.
+	// The values are not sent to the client. Select only what is needed and avoid selecting credentials or other sensitive data.
+	// +optional
+	DynamicMetadata NamedValuesOrSelectors `json:"dynamicMetadata,omitempty"`
+
+	// Problem details (RFC 7807) to return as the body of the denial, with content type "application/problem+json".
+	// The "status" member is the status code of the denial.
+	// If set, it takes precedence over the body.
+	// +optional
+	Problem *ProblemDetailsSpec `json:"problem,omitempty"`
+}
+
+// Members of a problem details document (RFC 7807).
+type ProblemDetailsSpec struct {
+	// URI reference that identifies the problem type.
+	// If omitted, it defaults to "about:blank".
+	// +optional
+	Type *ValueOrSelector `json:"type,omitempty"`
+
+	// Short, human-readable summary of the problem type.
+	// If omitted, it defaults to the message of the denial.
+	// +optional
+	Title *ValueOrSelector `json:"title,omitempty"`
+
+	// Human-readable explanation specific to this occurrence of the problem.
+	// +optional
+	Detail *ValueOrSelector `json:"detail,omitempty"`
+
+	// URI reference that identifies the specific occurrence of the problem.
+	// +optional
+	Instance *ValueOrSelector `json:"instance,omitempty"`
 }
 
 // Settings of the custom success response.
@@ -706,6 +1207,12 @@ type WrappedSuccessResponseSpec struct {
 
 type HeaderSuccessResponseSpec struct {
 	SuccessResponseSpec `json:",omitempty"`
+
+	// Whether to append the value to the header of same name already set in the request to the upstream, instead of
+	// overwriting it.
+	// If omitted or false, the header of the request is overwritten.
+	// +optional
+	Append bool `json:"append,omitempty"`
 }
 
 // Settings of the success custom response item.
@@ -725,6 +1232,10 @@ func (s *SuccessResponseSpec) GetMethod() AuthResponseMethod {
 		return JsonAuthResponse
 	} else if s.Wristband != nil {
 		return WristbandAuthResponse
+	} else if s.Combined != nil {
+		return CombinedAuthResponse
+	} else if s.RateLimitDescriptors != nil {
+		return RateLimitDescriptorsAuthResponse
 	}
 	return UnknownAuthResponseMethod
 }
@@ -738,6 +1249,10 @@ type AuthResponseMethodSpec struct {
 	Json *JsonAuthResponseSpec `json:"json,omitempty"`
 	// Authorino Festival Wristband token
 	Wristband *WristbandAuthResponseSpec `json:"wristband,omitempty"`
+	// Outputs of other response items joined into a single value
+	Combined *CombinedAuthResponseSpec `json:"combined,omitempty"`
+	// Entries of Envoy rate limit descriptors
+	RateLimitDescriptors *RateLimitDescriptorsAuthResponseSpec `json:"rateLimitDescriptors,omitempty"`
 }
 
 // Static value or selector to set the plain custom response item.
@@ -755,11 +1270,57 @@ type WristbandAuthResponseSpec struct {
 	Issuer string `json:"issuer"`
 	// Any claims to be added to the wristband token apart from the standard JWT claims (iss, iat, exp) added by default.
 	CustomClaims NamedValuesOrSelectors `json:"customClaims,omitempty"`
+	// Conditions for the custom claims to be added to the wristband token, by name of the custom claim.
+	// All conditions must match for the claim to be added; otherwise, the claim is left out of the wristband token.
+	// Custom claims without conditions are always added.
+	// +optional
+	CustomClaimConditions map[string][]PatternExpressionOrRef `json:"customClaimConditions,omitempty"`
 	// Time span of the wristband token, in seconds.
 	TokenDuration *int64 `json:"tokenDuration,omitempty"`
 	// Reference by name to Kubernetes secrets and corresponding signing algorithms.
 	// The secrets must contain a `key.pem` entry whose value is the signing key formatted as PEM.
+	// The first key (active key) signs the wristbands; the others are only published in the JWKS, to verify the
+	// wristbands signed before a key rotation. The public keys of all the keys are published in the JWKS.
 	SigningKeyRefs []*WristbandSigningKeyRef `json:"signingKeyRefs"`
+	// Names of the claims to keep in the wristband token, to shrink it.
+	// If set, all other claims are left out, except the required ones (iss, exp).
+	// +optional
+	IncludeClaims []string `json:"includeClaims,omitempty"`
+	// Names of the claims to leave out of the wristband token, to shrink it.
+	// The required claims (iss, exp) cannot be excluded.
+	// +optional
+	ExcludeClaims []string `json:"excludeClaims,omitempty"`
+}
+
+// Settings of the combined custom response item.
+// Combined response items are built after all the other response items.
+type CombinedAuthResponseSpec struct {
+	// Names of the response items whose outputs are joined, in order.
+	// Response items that are not built (e.g. skipped due to conditions) are omitted.
+	// +kubebuilder:validation:MinItems:=1
+	Responses []string `json:"responses"`
+
+	// Separator between the joined outputs.
+	// +optional
+	// +kubebuilder:default:=","
+	Separator string `json:"separator,omitempty"`
+}
+
+// Settings of the rate limit descriptors custom response item.
+// The output is an object whose properties are the descriptor keys and whose values are the descriptor values, as strings,
+// to be read by the metadata actions of the Envoy rate limit filter when emitted as Dynamic Metadata.
+type RateLimitDescriptorsAuthResponseSpec struct {
+	// Entries of the rate limit descriptors, whose values can be static or selected from the authorization JSON.
+	// Entries whose values resolve to empty are left out.
+	// +kubebuilder:validation:MinItems:=1
+	Entries []RateLimitDescriptorEntry `json:"entries"`
+}
+
+type RateLimitDescriptorEntry struct {
+	// Key of the descriptor entry.
+	Key string `json:"key"`
+
+	ValueOrSelector `json:""`
 }
 
 type WristbandSigningKeyRef struct {
@@ -795,6 +1356,38 @@ type CallbackMethodSpec struct {
 type AuthConfigStatus struct {
 	Conditions []AuthConfigStatusCondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 	Summary    AuthConfigStatusSummary     `json:"summary,omitempty"`
+
+	// Non-fatal issues found in the spec of the resource (e.g. settings that are insecure or likely unintended).
+	// Warnings do not prevent the resource from becoming ready and are cleared once the issues are resolved.
+	// +optional
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Errors found in the spec of the resource that prevent it from being reconciled, located by the path of the field
+	// that caused each error, e.g. for tools to point out the offending fields.
+	// Errors are cleared once the resource is reconciled.
+	// +optional
+	Errors []AuthConfigSpecError `json:"errors,omitempty"`
+}
+
+// EnabledHosts returns the hosts of the AuthConfig that are not disabled
+func (s *AuthConfigSpec) EnabledHosts() []string {
+	hosts := make([]string, 0, len(s.Hosts))
+	for _, host := range s.Hosts {
+		if !s.IsHostDisabled(host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// IsHostDisabled tells whether the host is one of the disabled hosts of the AuthConfig
+func (s *AuthConfigSpec) IsHostDisabled(host string) bool {
+	for _, disabled := range s.DisabledHosts {
+		if disabled == host {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *AuthConfigStatus) Ready() bool {
@@ -830,6 +1423,19 @@ type AuthConfigStatusCondition struct {
 	LastUpdatedTime *metav1.Time `json:"lastUpdatedTime,omitempty"`
 }
 
+// Error found in the spec of an AuthConfig
+type AuthConfigSpecError struct {
+	// Path of the field of the AuthConfig that caused the error (e.g. spec.authentication[keycloak].jwt.jwksSecretRef)
+	Field string `json:"field"`
+
+	// Machine-readable reason of the error, one of: Invalid, NotFound, KeyNotFound, FeatureDisabled, Unavailable.
+	Reason string `json:"reason"`
+
+	// Human readable message of the error
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 type AuthConfigStatusSummary struct {
 	// Whether all hosts from spec.hosts have been linked to the resource in the index
 	Ready bool `json:"ready"`
@@ -837,6 +1443,10 @@ type AuthConfigStatusSummary struct {
 	// Lists the hosts from spec.hosts linked to the resource in the index
 	HostsReady []string `json:"hostsReady"`
 
+	// Lists the hosts from spec.hosts disabled by spec.disabledHosts, i.e. not linked to the resource in the index
+	// +optional
+	HostsDisabled []string `json:"hostsDisabled,omitempty"`
+
 	// Number of hosts from spec.hosts linked to the resource in the index, compared to the total number of hosts in spec.hosts
 	NumHostsReady string `json:"numHostsReady"`
 
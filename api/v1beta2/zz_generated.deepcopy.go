@@ -50,6 +50,11 @@ func (in *ApiKeyAuthenticationSpec) DeepCopyInto(out *ApiKeyAuthenticationSpec)
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApiKeyAuthenticationSpec.
@@ -129,6 +134,11 @@ func (in *AuthConfigSpec) DeepCopyInto(out *AuthConfigSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.DisabledHosts != nil {
+		in, out := &in.DisabledHosts, &out.DisabledHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.NamedPatterns != nil {
 		in, out := &in.NamedPatterns, &out.NamedPatterns
 		*out = make(map[string]PatternExpressions, len(*in))
@@ -151,6 +161,11 @@ func (in *AuthConfigSpec) DeepCopyInto(out *AuthConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MaintenanceBypass != nil {
+		in, out := &in.MaintenanceBypass, &out.MaintenanceBypass
+		*out = new(MaintenanceBypassSpec)
+		**out = **in
+	}
 	if in.Authentication != nil {
 		in, out := &in.Authentication, &out.Authentication
 		*out = make(map[string]AuthenticationSpec, len(*in))
@@ -158,6 +173,11 @@ func (in *AuthConfigSpec) DeepCopyInto(out *AuthConfigSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(Credentials)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Metadata != nil {
 		in, out := &in.Metadata, &out.Metadata
 		*out = make(map[string]MetadataSpec, len(*in))
@@ -196,6 +216,21 @@ func (in *AuthConfigSpec) DeepCopy() *AuthConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthConfigSpecError) DeepCopyInto(out *AuthConfigSpecError) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthConfigSpecError.
+func (in *AuthConfigSpecError) DeepCopy() *AuthConfigSpecError {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthConfigSpecError)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AuthConfigStatus) DeepCopyInto(out *AuthConfigStatus) {
 	*out = *in
@@ -207,6 +242,16 @@ func (in *AuthConfigStatus) DeepCopyInto(out *AuthConfigStatus) {
 		}
 	}
 	in.Summary.DeepCopyInto(&out.Summary)
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]AuthConfigSpecError, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthConfigStatus.
@@ -247,6 +292,11 @@ func (in *AuthConfigStatusSummary) DeepCopyInto(out *AuthConfigStatusSummary) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.HostsDisabled != nil {
+		in, out := &in.HostsDisabled, &out.HostsDisabled
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthConfigStatusSummary.
@@ -298,6 +348,16 @@ func (in *AuthResponseMethodSpec) DeepCopyInto(out *AuthResponseMethodSpec) {
 		*out = new(WristbandAuthResponseSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Combined != nil {
+		in, out := &in.Combined, &out.Combined
+		*out = new(CombinedAuthResponseSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RateLimitDescriptors != nil {
+		in, out := &in.RateLimitDescriptors, &out.RateLimitDescriptors
+		*out = new(RateLimitDescriptorsAuthResponseSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthResponseMethodSpec.
@@ -321,7 +381,7 @@ func (in *AuthenticationMethodSpec) DeepCopyInto(out *AuthenticationMethodSpec)
 	if in.Jwt != nil {
 		in, out := &in.Jwt, &out.Jwt
 		*out = new(JwtAuthenticationSpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.OAuth2TokenIntrospection != nil {
 		in, out := &in.OAuth2TokenIntrospection, &out.OAuth2TokenIntrospection
@@ -348,6 +408,11 @@ func (in *AuthenticationMethodSpec) DeepCopyInto(out *AuthenticationMethodSpec)
 		*out = new(AnonymousAccessSpec)
 		**out = **in
 	}
+	if in.Ldap != nil {
+		in, out := &in.Ldap, &out.Ldap
+		*out = new(LdapAuthenticationSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationMethodSpec.
@@ -379,6 +444,18 @@ func (in *AuthenticationSpec) DeepCopyInto(out *AuthenticationSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.ClaimMapping != nil {
+		in, out := &in.ClaimMapping, &out.ClaimMapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RevocationList != nil {
+		in, out := &in.RevocationList, &out.RevocationList
+		*out = new(RevocationListSpec)
+		**out = **in
+	}
 	in.AuthenticationMethodSpec.DeepCopyInto(&out.AuthenticationMethodSpec)
 }
 
@@ -415,6 +492,11 @@ func (in *AuthorizationMethodSpec) DeepCopyInto(out *AuthorizationMethodSpec) {
 		*out = new(SpiceDBAuthorizationSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = new(ScopesAuthorizationSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthorizationMethodSpec.
@@ -481,6 +563,26 @@ func (in *CallbackSpec) DeepCopy() *CallbackSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CombinedAuthResponseSpec) DeepCopyInto(out *CombinedAuthResponseSpec) {
+	*out = *in
+	if in.Responses != nil {
+		in, out := &in.Responses, &out.Responses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CombinedAuthResponseSpec.
+func (in *CombinedAuthResponseSpec) DeepCopy() *CombinedAuthResponseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CombinedAuthResponseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CommonEvaluatorSpec) DeepCopyInto(out *CommonEvaluatorSpec) {
 	*out = *in
@@ -508,6 +610,21 @@ func (in *CommonEvaluatorSpec) DeepCopy() *CommonEvaluatorSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Credentials) DeepCopyInto(out *Credentials) {
 	*out = *in
@@ -574,11 +691,41 @@ func (in *DenyWithSpec) DeepCopyInto(out *DenyWithSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.HeaderConditions != nil {
+		in, out := &in.HeaderConditions, &out.HeaderConditions
+		*out = make(map[string][]PatternExpressionOrRef, len(*in))
+		for key, val := range *in {
+			var outVal []PatternExpressionOrRef
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]PatternExpressionOrRef, len(*in))
+				for i := range *in {
+					(*in)[i].DeepCopyInto(&(*out)[i])
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
 	if in.Body != nil {
 		in, out := &in.Body, &out.Body
 		*out = new(ValueOrSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DynamicMetadata != nil {
+		in, out := &in.DynamicMetadata, &out.DynamicMetadata
+		*out = make(NamedValuesOrSelectors, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Problem != nil {
+		in, out := &in.Problem, &out.Problem
+		*out = new(ProblemDetailsSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DenyWithSpec.
@@ -595,6 +742,13 @@ func (in *DenyWithSpec) DeepCopy() *DenyWithSpec {
 func (in *EvaluatorCaching) DeepCopyInto(out *EvaluatorCaching) {
 	*out = *in
 	in.Key.DeepCopyInto(&out.Key)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PatternExpressionOrRef, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvaluatorCaching.
@@ -636,6 +790,11 @@ func (in *ExternalOpaPolicy) DeepCopyInto(out *ExternalOpaPolicy) {
 		*out = new(HttpEndpointSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ClientCertSecret != nil {
+		in, out := &in.ClientCertSecret, &out.ClientCertSecret
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalOpaPolicy.
@@ -691,6 +850,11 @@ func (in *HttpEndpointSpec) DeepCopyInto(out *HttpEndpointSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.RepeatedHeaders != nil {
+		in, out := &in.RepeatedHeaders, &out.RepeatedHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.SharedSecret != nil {
 		in, out := &in.SharedSecret, &out.SharedSecret
 		*out = new(SecretKeyReference)
@@ -739,6 +903,21 @@ func (in *JsonAuthResponseSpec) DeepCopy() *JsonAuthResponseSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JwtAuthenticationSpec) DeepCopyInto(out *JwtAuthenticationSpec) {
 	*out = *in
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.JwksConfigMapRef != nil {
+		in, out := &in.JwksConfigMapRef, &out.JwksConfigMapRef
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+	if in.JwksSecretRef != nil {
+		in, out := &in.JwksSecretRef, &out.JwksSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JwtAuthenticationSpec.
@@ -769,6 +948,11 @@ func (in *KubernetesSubjectAccessReviewAuthorizationSpec) DeepCopyInto(out *Kube
 		*out = new(KubernetesSubjectAccessReviewResourceAttributesSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.KubeconfigSecretRef != nil {
+		in, out := &in.KubeconfigSecretRef, &out.KubeconfigSecretRef
+		*out = new(SecretKeyReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesSubjectAccessReviewAuthorizationSpec.
@@ -822,6 +1006,42 @@ func (in *KubernetesTokenReviewSpec) DeepCopy() *KubernetesTokenReviewSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LdapAuthenticationSpec) DeepCopyInto(out *LdapAuthenticationSpec) {
+	*out = *in
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LdapAuthenticationSpec.
+func (in *LdapAuthenticationSpec) DeepCopy() *LdapAuthenticationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LdapAuthenticationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceBypassSpec) DeepCopyInto(out *MaintenanceBypassSpec) {
+	*out = *in
+	out.SharedSecretRef = in.SharedSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceBypassSpec.
+func (in *MaintenanceBypassSpec) DeepCopy() *MaintenanceBypassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceBypassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataMethodSpec) DeepCopyInto(out *MetadataMethodSpec) {
 	*out = *in
@@ -961,11 +1181,31 @@ func (in *OAuth2TokenIntrospectionSpec) DeepCopy() *OAuth2TokenIntrospectionSpec
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpaAuthorizationSpec) DeepCopyInto(out *OpaAuthorizationSpec) {
 	*out = *in
+	if in.RegoConfigMap != nil {
+		in, out := &in.RegoConfigMap, &out.RegoConfigMap
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
 	if in.External != nil {
 		in, out := &in.External, &out.External
 		*out = new(ExternalOpaPolicy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Wasm != nil {
+		in, out := &in.Wasm, &out.Wasm
+		*out = new(OpaWasmPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routing != nil {
+		in, out := &in.Routing, &out.Routing
+		*out = new(OpaPolicyRoutingSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpaAuthorizationSpec.
@@ -978,6 +1218,53 @@ func (in *OpaAuthorizationSpec) DeepCopy() *OpaAuthorizationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpaPolicyRoutingSpec) DeepCopyInto(out *OpaPolicyRoutingSpec) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpaPolicyRoutingSpec.
+func (in *OpaPolicyRoutingSpec) DeepCopy() *OpaPolicyRoutingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OpaPolicyRoutingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpaWasmPolicy) DeepCopyInto(out *OpaWasmPolicy) {
+	*out = *in
+	if in.Module != nil {
+		in, out := &in.Module, &out.Module
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.ModuleConfigMap != nil {
+		in, out := &in.ModuleConfigMap, &out.ModuleConfigMap
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpaWasmPolicy.
+func (in *OpaWasmPolicy) DeepCopy() *OpaWasmPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OpaWasmPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PatternExpression) DeepCopyInto(out *PatternExpression) {
 	*out = *in
@@ -1053,6 +1340,13 @@ func (in *PatternMatchingAuthorizationSpec) DeepCopyInto(out *PatternMatchingAut
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PatternMatchingRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatternMatchingAuthorizationSpec.
@@ -1065,6 +1359,28 @@ func (in *PatternMatchingAuthorizationSpec) DeepCopy() *PatternMatchingAuthoriza
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatternMatchingRule) DeepCopyInto(out *PatternMatchingRule) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]PatternExpressionOrRef, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatternMatchingRule.
+func (in *PatternMatchingRule) DeepCopy() *PatternMatchingRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PatternMatchingRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PatternRef) DeepCopyInto(out *PatternRef) {
 	*out = *in
@@ -1126,6 +1442,79 @@ func (in *Prefixed) DeepCopy() *Prefixed {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProblemDetailsSpec) DeepCopyInto(out *ProblemDetailsSpec) {
+	*out = *in
+	if in.Type != nil {
+		in, out := &in.Type, &out.Type
+		*out = new(ValueOrSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Title != nil {
+		in, out := &in.Title, &out.Title
+		*out = new(ValueOrSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Detail != nil {
+		in, out := &in.Detail, &out.Detail
+		*out = new(ValueOrSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Instance != nil {
+		in, out := &in.Instance, &out.Instance
+		*out = new(ValueOrSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProblemDetailsSpec.
+func (in *ProblemDetailsSpec) DeepCopy() *ProblemDetailsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProblemDetailsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitDescriptorEntry) DeepCopyInto(out *RateLimitDescriptorEntry) {
+	*out = *in
+	in.ValueOrSelector.DeepCopyInto(&out.ValueOrSelector)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitDescriptorEntry.
+func (in *RateLimitDescriptorEntry) DeepCopy() *RateLimitDescriptorEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitDescriptorEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitDescriptorsAuthResponseSpec) DeepCopyInto(out *RateLimitDescriptorsAuthResponseSpec) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]RateLimitDescriptorEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitDescriptorsAuthResponseSpec.
+func (in *RateLimitDescriptorsAuthResponseSpec) DeepCopy() *RateLimitDescriptorsAuthResponseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitDescriptorsAuthResponseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResponseSpec) DeepCopyInto(out *ResponseSpec) {
 	*out = *in
@@ -1152,6 +1541,44 @@ func (in *ResponseSpec) DeepCopy() *ResponseSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevocationListSpec) DeepCopyInto(out *RevocationListSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevocationListSpec.
+func (in *RevocationListSpec) DeepCopy() *RevocationListSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RevocationListSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScopesAuthorizationSpec) DeepCopyInto(out *ScopesAuthorizationSpec) {
+	*out = *in
+	in.Required.DeepCopyInto(&out.Required)
+	if in.Mapping != nil {
+		in, out := &in.Mapping, &out.Mapping
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScopesAuthorizationSpec.
+func (in *ScopesAuthorizationSpec) DeepCopy() *ScopesAuthorizationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScopesAuthorizationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
 	*out = *in
@@ -1186,6 +1613,11 @@ func (in *SpiceDBAuthorizationSpec) DeepCopyInto(out *SpiceDBAuthorizationSpec)
 		(*in).DeepCopyInto(*out)
 	}
 	in.Permission.DeepCopyInto(&out.Permission)
+	if in.Connection != nil {
+		in, out := &in.Connection, &out.Connection
+		*out = new(SpiceDBConnectionSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpiceDBAuthorizationSpec.
@@ -1198,6 +1630,26 @@ func (in *SpiceDBAuthorizationSpec) DeepCopy() *SpiceDBAuthorizationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SpiceDBConnectionSpec) DeepCopyInto(out *SpiceDBConnectionSpec) {
+	*out = *in
+	if in.TLSSessionCacheSize != nil {
+		in, out := &in.TLSSessionCacheSize, &out.TLSSessionCacheSize
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpiceDBConnectionSpec.
+func (in *SpiceDBConnectionSpec) DeepCopy() *SpiceDBConnectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SpiceDBConnectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SpiceDBObject) DeepCopyInto(out *SpiceDBObject) {
 	*out = *in
@@ -1240,6 +1692,16 @@ func (in *UmaMetadataSpec) DeepCopyInto(out *UmaMetadataSpec) {
 		*out = new(corev1.LocalObjectReference)
 		**out = **in
 	}
+	if in.Uri != nil {
+		in, out := &in.Uri, &out.Uri
+		*out = new(ValueOrSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = new(ValueOrSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UmaMetadataSpec.
@@ -1338,6 +1800,24 @@ func (in *WristbandAuthResponseSpec) DeepCopyInto(out *WristbandAuthResponseSpec
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.CustomClaimConditions != nil {
+		in, out := &in.CustomClaimConditions, &out.CustomClaimConditions
+		*out = make(map[string][]PatternExpressionOrRef, len(*in))
+		for key, val := range *in {
+			var outVal []PatternExpressionOrRef
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]PatternExpressionOrRef, len(*in))
+				for i := range *in {
+					(*in)[i].DeepCopyInto(&(*out)[i])
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
 	if in.TokenDuration != nil {
 		in, out := &in.TokenDuration, &out.TokenDuration
 		*out = new(int64)
@@ -1354,6 +1834,16 @@ func (in *WristbandAuthResponseSpec) DeepCopyInto(out *WristbandAuthResponseSpec
 			}
 		}
 	}
+	if in.IncludeClaims != nil {
+		in, out := &in.IncludeClaims, &out.IncludeClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeClaims != nil {
+		in, out := &in.ExcludeClaims, &out.ExcludeClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WristbandAuthResponseSpec.
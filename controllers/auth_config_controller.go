@@ -18,9 +18,16 @@ package controllers
 
 import (
 	"context"
+	gojson "encoding/json"
+	goerrors "errors"
 	"fmt"
+	"net"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	api "github.com/kuadrant/authorino/api/v1beta2"
 	"github.com/kuadrant/authorino/pkg/auth"
@@ -33,6 +40,7 @@ import (
 	"github.com/kuadrant/authorino/pkg/json"
 	"github.com/kuadrant/authorino/pkg/jsonexp"
 	"github.com/kuadrant/authorino/pkg/log"
+	"github.com/kuadrant/authorino/pkg/metrics"
 	"github.com/kuadrant/authorino/pkg/oauth2"
 	"github.com/kuadrant/authorino/pkg/utils"
 
@@ -44,17 +52,34 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 const (
 	failedToCleanConfig = "failed to clean up all asynchronous workers"
 
 	AuthConfigsReadyzSubpath = "authconfigs"
+
+	defaultMaintenanceBypassHeader = "x-authorino-maintenance"
+)
+
+var (
+	servingLastValidConfigMetric = metrics.NewGaugeMetric("authconfig_serving_last_valid_config", "Authconfigs served with their last valid config instead of the current spec, due to failures to reconcile (1 while diverged).", "namespace", "authconfig")
 )
 
+func init() {
+	metrics.Register(
+		servingLastValidConfigMetric,
+	)
+}
+
 // AuthConfigReconciler reconciles an AuthConfig object
 type AuthConfigReconciler struct {
 	client.Client
@@ -65,11 +90,66 @@ type AuthConfigReconciler struct {
 	StatusReport                *StatusReportMap
 	LabelSelector               labels.Selector
 	Namespace                   string
+	// Reconciles of AuthConfigs triggered by changes of the Secrets and ConfigMaps they refer to are delayed by
+	// DependencyReconcileDelay, plus a random jitter up to DependencyReconcileJitter, so a burst of changes results in a
+	// single reconcile of each affected AuthConfig.
+	DependencyReconcileDelay  time.Duration
+	DependencyReconcileJitter time.Duration
+	// Defaults and upper bounds of the caching options of the evaluators
+	EvaluatorCachePolicy EvaluatorCachePolicy
+	// Number of consecutive reconcile failures of an AuthConfig after which its last valid config is evicted from the
+	// index, so the requests to its hosts fail closed. Zero keeps serving the last valid config indefinitely.
+	EvictAfterReconcileFailures int
+	// Upper bound of the timeout of the auth pipeline overridden in the AuthConfigs. Zero means no upper bound.
+	MaxAuthConfigTimeout time.Duration
+	// Maximum number of AuthConfigs reconciled concurrently (default: 1) and rate limiter of the work queue of the
+	// controller (default: the one of controller-runtime)
+	MaxConcurrentReconciles int
+	RateLimiter             workqueue.RateLimiter
+	// Experimental evaluator types enabled or disabled. AuthConfigs using a disabled type fail to reconcile.
+	FeatureGates FeatureGates
+
+	indexBootstrap    sync.Mutex
+	reconcileFailures map[string]int
+	failuresMutex     sync.Mutex
+}
 
-	indexBootstrap sync.Mutex
+// EvaluatorCachePolicy sets the defaults applied to the caching options of the evaluators that omit them, and the upper
+// bounds enforced regardless of the options. Zero means no upper bound and, for the max entries, no default limit.
+type EvaluatorCachePolicy struct {
+	DefaultTTL        int // in seconds; if zero, api.EvaluatorDefaultCacheTTL applies
+	DefaultMaxEntries int
+	MaxTTL            int // in seconds
+	MaxEntries        int
+}
+
+// apply returns the ttl and max entries of the cache after applying the defaults and upper bounds of the policy
+func (p EvaluatorCachePolicy) apply(cache *api.EvaluatorCaching) (ttl, maxEntries int) {
+	ttl = cache.TTL
+	if ttl == 0 {
+		ttl = p.DefaultTTL
+	}
+	if ttl == 0 {
+		ttl = api.EvaluatorDefaultCacheTTL
+	}
+	if p.MaxTTL > 0 && ttl > p.MaxTTL {
+		ttl = p.MaxTTL
+	}
+
+	maxEntries = cache.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = p.DefaultMaxEntries
+	}
+	if p.MaxEntries > 0 && (maxEntries == 0 || maxEntries > p.MaxEntries) {
+		maxEntries = p.MaxEntries
+	}
+
+	return ttl, maxEntries
 }
 
 // +kubebuilder:rbac:groups=authorino.kuadrant.io,resources=authconfigs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
 
 func (r *AuthConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	if err := r.bootstrapIndex(ctx); err != nil {
@@ -100,29 +180,49 @@ func (r *AuthConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		// delete related authconfigs from the index.
 		r.Index.Delete(resourceId)
 		r.StatusReport.Clear(resourceId)
+		r.resetReconcileFailures(resourceId)
+		reportServingLastValidConfig(req.NamespacedName, false)
 		reportReconciled = false
 		logger.Info("resource de-indexed")
 	} else {
 		// resource found and it is to be watched by this controller
 		// we need to either create it or update it in the index
 
-		// clean all async workers of the config, i.e. shuts down channels and goroutines
-		if err := r.cleanConfigs(resourceId, ctx); err != nil {
-			logger.Error(err, failedToCleanConfig)
-		}
+		warnings := append(authConfigWarnings(&authConfig), r.wristbandSigningKeyWarnings(ctx, &authConfig)...)
+		r.StatusReport.SetWarnings(resourceId, warnings)
 
 		translatedAuthConfig, err := r.translateAuthConfig(log.IntoContext(ctx, logger), &authConfig)
 		if err != nil {
-			r.StatusReport.Set(resourceId, api.StatusReasonInvalidResource, err.Error(), []string{})
+			message := err.Error()
+			if isTransientAPIError(err) {
+				// the cluster is (temporarily) unreachable – the resource is not to blame, so keep serving the last
+				// valid config, without counting the failure towards the eviction
+				logger.Info("failed to reconcile due to a transient api error; serving the last valid config", "reason", message)
+			} else if r.evictOnReconcileFailure(ctx, resourceId) {
+				message = fmt.Sprintf("%s (evicted after %d consecutive reconcile failures)", message, r.EvictAfterReconcileFailures)
+				logger.Info("resource evicted after consecutive reconcile failures", "failures", r.EvictAfterReconcileFailures)
+			}
+			reportServingLastValidConfig(req.NamespacedName, r.servesValidConfig(resourceId))
+			r.StatusReport.Set(resourceId, api.StatusReasonInvalidResource, message, []string{})
+			r.StatusReport.SetErrors(resourceId, specErrorStatus(err))
 			return ctrl.Result{}, err
 		}
+		r.resetReconcileFailures(resourceId)
+		reportServingLastValidConfig(req.NamespacedName, false)
+
+		// clean all async workers of the config being replaced, i.e. shuts down channels and goroutines
+		// only now that the new config is valid, for the last valid one to keep working while reconciles fail
+		if err := r.cleanConfigs(resourceId, ctx); err != nil {
+			logger.Error(err, failedToCleanConfig)
+		}
 
-		// delete unused hosts from the index
-		for _, host := range utils.SubtractSlice(r.Index.FindKeys(resourceId), authConfig.Spec.Hosts) {
+		// delete unused and disabled hosts from the index
+		enabledHosts := authConfig.Spec.EnabledHosts()
+		for _, host := range utils.SubtractSlice(r.Index.FindKeys(resourceId), enabledHosts) {
 			r.Index.DeleteKey(resourceId, host)
 		}
 
-		linkedHosts, looseHosts, err = r.addToIndex(log.IntoContext(ctx, logger), req.Namespace, resourceId, translatedAuthConfig, authConfig.Spec.Hosts)
+		linkedHosts, looseHosts, err = r.addToIndex(log.IntoContext(ctx, logger), req.Namespace, resourceId, translatedAuthConfig, enabledHosts)
 
 		if len(looseHosts) > 0 {
 			r.StatusReport.Set(resourceId, api.StatusReasonHostsNotLinked, "one or more hosts are not linked to the resource", linkedHosts)
@@ -146,17 +246,324 @@ func (r *AuthConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
+// evictOnReconcileFailure records a failure to reconcile the resource and, if the number of consecutive failures
+// reaches the eviction threshold, deletes the last valid config of the resource from the index.
+// It tells whether the resource is evicted.
+func (r *AuthConfigReconciler) evictOnReconcileFailure(ctx context.Context, resourceId string) bool {
+	if r.EvictAfterReconcileFailures <= 0 {
+		return false
+	}
+
+	r.failuresMutex.Lock()
+	defer r.failuresMutex.Unlock()
+
+	if r.reconcileFailures == nil {
+		r.reconcileFailures = make(map[string]int)
+	}
+	r.reconcileFailures[resourceId]++
+
+	if r.reconcileFailures[resourceId] < r.EvictAfterReconcileFailures {
+		return false
+	}
+
+	if err := r.cleanConfigs(resourceId, ctx); err != nil {
+		r.Logger.Error(err, failedToCleanConfig, "authconfig", resourceId)
+	}
+
+	// the hosts stay in the index with an evicted config, so the requests fail closed regardless of the decision for
+	// unknown hosts
+	var hosts []string
+	for _, host := range r.Index.FindKeys(resourceId) {
+		if id, found := r.Index.FindId(host); found && id == resourceId {
+			hosts = append(hosts, host)
+		}
+	}
+	r.Index.Delete(resourceId)
+	namespace, name, _ := strings.Cut(resourceId, string(types.Separator))
+	evicted := evaluators.AuthConfig{
+		Labels:  map[string]string{"namespace": namespace, "name": name},
+		Evicted: true,
+	}
+	for _, host := range hosts {
+		if err := r.Index.Set(resourceId, host, evicted, true); err != nil {
+			r.Logger.Error(err, "failed to index the evicted config", "authconfig", resourceId, "host", host)
+		}
+	}
+	return true
+}
+
+// servesValidConfig tells whether a valid config of the resource is served for any of its hosts, i.e. not evicted
+func (r *AuthConfigReconciler) servesValidConfig(resourceId string) bool {
+	for _, host := range r.Index.FindKeys(resourceId) {
+		if authConfig := r.Index.Get(host); authConfig != nil && !authConfig.Evicted {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *AuthConfigReconciler) resetReconcileFailures(resourceId string) {
+	r.failuresMutex.Lock()
+	defer r.failuresMutex.Unlock()
+	delete(r.reconcileFailures, resourceId)
+}
+
+// isTransientAPIError tells whether an error to reconcile a resource is due to the Kubernetes API server being
+// temporarily unavailable or unreachable, rather than to the resource itself.
+// Network errors are only told transient if returned by the Kubernetes API client (see NewKubeAPIClient), so failures to
+// reach external services (e.g. to fetch a revocation list) count as errors of the resource.
+func isTransientAPIError(err error) bool {
+	var networkErr *kubeAPINetworkError
+	return errors.IsServerTimeout(err) ||
+		errors.IsTimeout(err) ||
+		errors.IsServiceUnavailable(err) ||
+		errors.IsTooManyRequests(err) ||
+		errors.IsInternalError(err) ||
+		errors.IsUnexpectedServerError(err) ||
+		goerrors.As(err, &networkErr)
+}
+
+// NewKubeAPIClient wraps a client of the Kubernetes API, so the network errors of its requests (e.g. refused
+// connections and timeouts) can be told apart from the network errors of the requests to other services
+func NewKubeAPIClient(c client.Client) client.Client {
+	return &kubeAPIClient{Client: c}
+}
+
+type kubeAPIClient struct {
+	client.Client
+}
+
+func (c *kubeAPIClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return markKubeAPINetworkError(c.Client.Get(ctx, key, obj, opts...))
+}
+
+func (c *kubeAPIClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return markKubeAPINetworkError(c.Client.List(ctx, list, opts...))
+}
+
+// kubeAPINetworkError is a network error of a request to the Kubernetes API server
+type kubeAPINetworkError struct {
+	err error
+}
+
+func (e *kubeAPINetworkError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kubeAPINetworkError) Unwrap() error {
+	return e.err
+}
+
+func markKubeAPINetworkError(err error) error {
+	if err != nil && (utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) || utilnet.IsTimeout(err)) {
+		return &kubeAPINetworkError{err: err}
+	}
+	return err
+}
+
+// reportServingLastValidConfig reports whether the config served for an AuthConfig diverges from its current spec,
+// i.e. the last valid config is served because the latest version of the resource failed to reconcile
+func reportServingLastValidConfig(authConfig types.NamespacedName, diverged bool) {
+	if !diverged {
+		servingLastValidConfigMetric.DeleteLabelValues(authConfig.Namespace, authConfig.Name)
+		return
+	}
+	servingLastValidConfigMetric.WithLabelValues(authConfig.Namespace, authConfig.Name).Set(1)
+}
+
+// cleanConfigs cleans the configs indexed for all the hosts of the resource.
+// The hosts usually share the same config, but not necessarily (e.g. hosts left from previous versions of the
+// resource), so all the distinct configs are cleaned, each evaluator only once.
 func (r *AuthConfigReconciler) cleanConfigs(resourceId string, ctx context.Context) error {
-	if hosts := r.Index.FindKeys(resourceId); len(hosts) > 0 {
-		// no need to clean for all the hosts as the config should be the same
-		if authConfig := r.Index.Get(hosts[0]); authConfig != nil {
-			return authConfig.Clean(ctx)
+	var configs []*evaluators.AuthConfig
+	for _, host := range r.Index.FindKeys(resourceId) {
+		// skips hosts no longer served for the resource, whose configs may belong to other resources
+		if id, found := r.Index.FindId(host); !found || id != resourceId {
+			continue
+		}
+		if authConfig := r.Index.Get(host); authConfig != nil {
+			configs = append(configs, authConfig)
 		}
 	}
-	return nil
+	return evaluators.CleanAll(ctx, configs...)
+}
+
+// authConfigWarnings lists the non-fatal issues found in the spec of an AuthConfig, sorted.
+func authConfigWarnings(authConfig *api.AuthConfig) []string {
+	var warnings []string
+
+	for _, host := range authConfig.Spec.Hosts {
+		if err := validateHost(host); err != nil {
+			warnings = append(warnings, fmt.Sprintf("host %s: %s", host, err))
+		}
+	}
+
+	if len(authConfig.Spec.Authentication) > 1 {
+		for name, identity := range authConfig.Spec.Authentication {
+			if identity.GetMethod() == api.AnonymousAccessAuthentication {
+				warnings = append(warnings, fmt.Sprintf("authentication %s: anonymous access combined with other authentication methods", name))
+			}
+		}
+	}
+
+	warnings = append(warnings, unknownPatternRefWarnings(authConfig)...)
+
+	for name, authorization := range authConfig.Spec.Authorization {
+		if authorization.GetMethod() == api.SpiceDBAuthorization && authorization.SpiceDB.Insecure {
+			warnings = append(warnings, fmt.Sprintf("authorization %s: insecure connection to the SpiceDB server", name))
+		}
+		if authorization.GetMethod() == api.OpaAuthorization && authorization.Opa.AllValues {
+			warnings = append(warnings, fmt.Sprintf("authorization %s: allValues exposes the values of all rules of the policy", name))
+		}
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+// unknownPatternRefWarnings lists the references to named patterns not declared in the AuthConfig.
+// Unknown references are otherwise translated to the inline expression, if any, or to no expression at all, i.e. as if omitted.
+func unknownPatternRefWarnings(authConfig *api.AuthConfig) []string {
+	var warnings []string
+
+	walkPatterns(authConfig, func(prefix string, _ *field.Path, patterns []api.PatternExpressionOrRef) {
+		for _, name := range unknownPatternRefs(authConfig, patterns) {
+			warnings = append(warnings, fmt.Sprintf("%s: unknown pattern reference %s", prefix, name))
+		}
+	})
+
+	return warnings
+}
+
+// unknownPatternOperatorErrors returns the errors of the pattern expressions of the AuthConfig whose operators are not
+// supported, including the named patterns and the nested `all` and `any` expressions, each located at the field of its
+// list of patterns. These expressions would otherwise never match.
+func unknownPatternOperatorErrors(authConfig *api.AuthConfig) []error {
+	var errs []error
+
+	for name, expressions := range authConfig.Spec.NamedPatterns {
+		for _, expression := range expressions {
+			if jsonexp.OperatorFromString(string(expression.Operator)) == jsonexp.UnknownOperator {
+				errs = append(errs, newSpecError(field.NewPath("spec", "patterns").Key(name), fmt.Errorf("invalid patterns: patterns %s: unknown operator %s", name, expression.Operator)))
+			}
+		}
+	}
+
+	walkPatterns(authConfig, func(prefix string, path *field.Path, patterns []api.PatternExpressionOrRef) {
+		for _, operator := range unknownPatternOperators(patterns) {
+			errs = append(errs, newSpecError(path, fmt.Errorf("invalid patterns: %s: unknown operator %s", prefix, operator)))
+		}
+	})
+
+	return errs
+}
+
+// walkPatterns calls check with each list of patterns of the AuthConfig, prefixed by the name of the part of the
+// AuthConfig it belongs to and located by the path of its field
+func walkPatterns(authConfig *api.AuthConfig, check func(prefix string, path *field.Path, patterns []api.PatternExpressionOrRef)) {
+	checkEvaluator := func(prefix string, path *field.Path, spec api.CommonEvaluatorSpec) {
+		check(prefix, path.Child("when"), spec.Conditions)
+		if spec.Cache != nil {
+			check(prefix, path.Child("cache", "when"), spec.Cache.Conditions)
+		}
+	}
+
+	specPath := field.NewPath("spec")
+
+	check("when", specPath.Child("when"), authConfig.Spec.Conditions)
+	for name, identity := range authConfig.Spec.Authentication {
+		checkEvaluator("authentication "+name, specPath.Child("authentication").Key(name), identity.CommonEvaluatorSpec)
+	}
+	for name, metadata := range authConfig.Spec.Metadata {
+		checkEvaluator("metadata "+name, specPath.Child("metadata").Key(name), metadata.CommonEvaluatorSpec)
+	}
+	for name, authorization := range authConfig.Spec.Authorization {
+		authorizationPath := specPath.Child("authorization").Key(name)
+		checkEvaluator("authorization "+name, authorizationPath, authorization.CommonEvaluatorSpec)
+		if patternMatching := authorization.PatternMatching; patternMatching != nil {
+			check("authorization "+name, authorizationPath.Child("patternMatching", "patterns"), patternMatching.Patterns)
+			for i, rule := range patternMatching.Rules {
+				check("authorization "+name, authorizationPath.Child("patternMatching", "rules").Index(i).Child("when"), rule.Conditions)
+			}
+		}
+	}
+	if response := authConfig.Spec.Response; response != nil {
+		checkSuccessResponse := func(name string, path *field.Path, spec api.SuccessResponseSpec) {
+			checkEvaluator("response "+name, path, spec.CommonEvaluatorSpec)
+			if wristband := spec.Wristband; wristband != nil {
+				for claim, conditions := range wristband.CustomClaimConditions {
+					check("response "+name, path.Child("wristband", "customClaimConditions").Key(claim), conditions)
+				}
+			}
+		}
+		responsePath := specPath.Child("response")
+		for name, header := range response.Success.Headers {
+			checkSuccessResponse(name, responsePath.Child("success", "headers").Key(name), header.SuccessResponseSpec)
+		}
+		for name, dynamicMetadata := range response.Success.DynamicMetadata {
+			checkSuccessResponse(name, responsePath.Child("success", "dynamicMetadata").Key(name), dynamicMetadata)
+		}
+		for denial, denyWith := range map[string]*api.DenyWithSpec{"unauthenticated": response.Unauthenticated, "unauthorized": response.Unauthorized} {
+			if denyWith == nil {
+				continue
+			}
+			for header, conditions := range denyWith.HeaderConditions {
+				check("response "+denial, responsePath.Child(denial, "headerConditions").Key(header), conditions)
+			}
+		}
+	}
+	for name, callback := range authConfig.Spec.Callbacks {
+		checkEvaluator("callback "+name, specPath.Child("callbacks").Key(name), callback.CommonEvaluatorSpec)
+	}
+}
+
+// unknownPatternRefs returns the names referred in the patterns, including nested `all` and `any` expressions,
+// that match none of the named patterns of the AuthConfig
+func unknownPatternRefs(authConfig *api.AuthConfig, patterns ...[]api.PatternExpressionOrRef) []string {
+	var unknown []string
+	for _, list := range patterns {
+		for _, pattern := range list {
+			if name := pattern.PatternRef.Name; name != "" {
+				if _, found := authConfig.Spec.NamedPatterns[name]; !found {
+					unknown = append(unknown, name)
+				}
+			}
+			for _, nested := range pattern.All {
+				unknown = append(unknown, unknownPatternRefs(authConfig, []api.PatternExpressionOrRef{nested.PatternExpressionOrRef})...)
+			}
+			for _, nested := range pattern.Any {
+				unknown = append(unknown, unknownPatternRefs(authConfig, []api.PatternExpressionOrRef{nested.PatternExpressionOrRef})...)
+			}
+		}
+	}
+	return unknown
+}
+
+// unknownPatternOperators returns the operators of the inline expressions of the patterns, including nested `all` and
+// `any` expressions, that are not supported
+func unknownPatternOperators(patterns ...[]api.PatternExpressionOrRef) []string {
+	var unknown []string
+	for _, list := range patterns {
+		for _, pattern := range list {
+			if operator := pattern.PatternExpression.Operator; operator != "" && jsonexp.OperatorFromString(string(operator)) == jsonexp.UnknownOperator {
+				unknown = append(unknown, string(operator))
+			}
+			for _, nested := range pattern.All {
+				unknown = append(unknown, unknownPatternOperators([]api.PatternExpressionOrRef{nested.PatternExpressionOrRef})...)
+			}
+			for _, nested := range pattern.Any {
+				unknown = append(unknown, unknownPatternOperators([]api.PatternExpressionOrRef{nested.PatternExpressionOrRef})...)
+			}
+		}
+	}
+	return unknown
 }
 
 func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConfig *api.AuthConfig) (*evaluators.AuthConfig, error) {
+	if err := joinSpecErrors(append(unknownPatternOperatorErrors(authConfig), r.FeatureGates.check(authConfig))...); err != nil {
+		return nil, err
+	}
+
 	var ctxWithLogger context.Context
 
 	identityConfigs := make([]evaluators.IdentityConfig, 0)
@@ -179,6 +586,7 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 	}
 
 	for identityCfgName, identity := range authConfigIdentityConfigs {
+		identityPath := field.NewPath("spec", "authentication").Key(identityCfgName)
 		extendedProperties := make([]evaluators.IdentityExtension, len(identity.Defaults)+len(identity.Overrides))
 		for propertyName, property := range identity.Defaults {
 			extendedProperties = append(extendedProperties, evaluators.NewIdentityExtension(propertyName, json.JSONValue{
@@ -193,26 +601,49 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 			}, true))
 		}
 
+		claimMappingTargets := make([]string, 0, len(identity.ClaimMapping))
+		for target := range identity.ClaimMapping {
+			claimMappingTargets = append(claimMappingTargets, target)
+		}
+		sort.Strings(claimMappingTargets)
+		claimMappings := make([]json.JSONProperty, 0, len(claimMappingTargets))
+		for _, target := range claimMappingTargets {
+			claimMappings = append(claimMappings, json.JSONProperty{Name: target, Value: json.JSONValue{Pattern: identity.ClaimMapping[target]}})
+		}
+
 		translatedIdentity := &evaluators.IdentityConfig{
-			Name:               identityCfgName,
-			Priority:           identity.Priority,
-			Conditions:         buildJSONExpression(authConfig, identity.Conditions, jsonexp.All),
-			ExtendedProperties: extendedProperties,
-			Metrics:            identity.Metrics,
+			Name:                 identityCfgName,
+			Priority:             identity.Priority,
+			Conditions:           buildJSONExpression(authConfig, identity.Conditions, jsonexp.All),
+			ClaimMappings:        claimMappings,
+			ExtendedProperties:   extendedProperties,
+			Metrics:              identity.Metrics,
+			RequiredSubjectClaim: identity.RequiredSubjectClaim,
 		}
 
 		if identity.Cache != nil {
-			ttl := identity.Cache.TTL
-			if ttl == 0 {
-				ttl = api.EvaluatorDefaultCacheTTL
+			translatedIdentity.Cache = r.buildEvaluatorCache(authConfig, identity.Cache)
+		}
+
+		if revocationList := identity.RevocationList; revocationList != nil {
+			selector := revocationList.Selector
+			if selector == "" {
+				selector = "jti"
+			}
+			var err error
+			if translatedIdentity.RevocationList, err = identity_evaluators.NewRevocationList(revocationList.Url, selector, revocationList.TTL, ctxWithLogger); err != nil {
+				return nil, newSpecError(identityPath.Child("revocationList"), fmt.Errorf("failed to fetch the revocation list of %s: %w", identityCfgName, err))
 			}
-			translatedIdentity.Cache = evaluators.NewEvaluatorCache(
-				*getJsonFromStaticDynamic(&identity.Cache.Key),
-				ttl,
-			)
 		}
 
-		authCred := newAuthCredential(identity.Credentials)
+		credentials := identity.Credentials
+		if credentials.GetType() == api.UnknownCredentialsType && authConfig.Spec.Credentials != nil {
+			credentials = *authConfig.Spec.Credentials
+		}
+		if credentials.GetType() == api.UnknownCredentialsType && identity.GetMethod() == api.LdapAuthentication {
+			credentials = api.Credentials{AuthorizationHeader: &api.Prefixed{Prefix: "Basic"}}
+		}
+		authCred := newAuthCredential(credentials)
 
 		switch identity.GetMethod() {
 		// oauth2
@@ -224,7 +655,7 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 				Namespace: authConfig.Namespace,
 				Name:      oauth2Identity.Credentials.Name},
 				secret); err != nil {
-				return nil, err // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
+				return nil, newSpecError(identityPath.Child("oauth2Introspection", "credentialsRef"), err) // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
 			}
 
 			translatedIdentity.OAuth2 = identity_evaluators.NewOAuth2Identity(
@@ -234,10 +665,29 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 				string(secret.Data["clientSecret"]),
 				authCred,
 			)
+			translatedIdentity.OAuth2.OpaqueTokensOnly = oauth2Identity.OpaqueTokensOnly
 
 		// oidc
 		case api.JwtAuthentication:
-			translatedIdentity.OIDC = identity_evaluators.NewOIDC(identity.Jwt.IssuerUrl, authCred, identity.Jwt.TTL, ctxWithLogger)
+			jwksPath := identityPath.Child("jwt", staticJwksField(identity.Jwt))
+			staticJwks, err := r.readStaticJwks(ctx, authConfig.Namespace, jwksPath, identity.Jwt)
+			if err != nil {
+				return nil, err
+			}
+			if staticJwks != nil {
+				if translatedIdentity.OIDC, err = identity_evaluators.NewOIDCWithStaticJWKS(staticJwks, authCred); err != nil {
+					return nil, newSpecError(jwksPath, err)
+				}
+			} else {
+				translatedIdentity.OIDC = identity_evaluators.NewOIDC(identity.Jwt.IssuerUrl, authCred, identity.Jwt.TTL, ctxWithLogger)
+			}
+			translatedIdentity.OIDC.Audiences = identity.Jwt.Audiences
+			translatedIdentity.OIDC.HostAudience = identity.Jwt.HostAudience
+			translatedIdentity.OIDC.ExpiryGrace = time.Duration(identity.Jwt.ExpiryGrace) * time.Second
+			translatedIdentity.OIDC.ClockSkew = time.Duration(identity.Jwt.ClockSkew) * time.Second
+			translatedIdentity.OIDC.SkipIssuerCheck = identity.Jwt.SkipIssuerCheck
+			translatedIdentity.OIDC.PreMatchIssuer = identity.Jwt.PreMatchIssuer
+			translatedIdentity.OIDC.RetryableWhenProviderUnavailable = identity.Jwt.OnProviderUnavailable == api.ProviderUnavailableServiceUnavailable
 
 		// apiKey
 		case api.ApiKeyAuthentication:
@@ -247,9 +697,16 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 			}
 			selector, err := metav1.LabelSelectorAsSelector(identity.ApiKey.Selector)
 			if err != nil {
-				return nil, err
+				return nil, newSpecError(identityPath.Child("apiKey", "selector"), err)
+			}
+			if namespaces := identity.ApiKey.Namespaces; len(namespaces) > 0 && r.ClusterWide() {
+				translatedIdentity.APIKey, err = identity_evaluators.NewApiKeyIdentityInNamespaces(identityCfgName, selector, namespaces, authCred, r.Client, ctxWithLogger)
+			} else {
+				translatedIdentity.APIKey, err = identity_evaluators.NewApiKeyIdentity(identityCfgName, selector, namespace, authCred, r.Client, ctxWithLogger)
+			}
+			if err != nil {
+				return nil, newSpecError(identityPath.Child("apiKey"), err)
 			}
-			translatedIdentity.APIKey = identity_evaluators.NewApiKeyIdentity(identityCfgName, selector, namespace, authCred, r.Client, ctxWithLogger)
 
 		// MTLS
 		case api.X509ClientCertificateAuthentication:
@@ -259,14 +716,14 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 			}
 			selector, err := metav1.LabelSelectorAsSelector(identity.X509ClientCertificate.Selector)
 			if err != nil {
-				return nil, err
+				return nil, newSpecError(identityPath.Child("x509", "selector"), err)
 			}
 			translatedIdentity.MTLS = identity_evaluators.NewMTLSIdentity(identityCfgName, selector, namespace, r.Client, ctxWithLogger)
 
 		// kubernetes auth
 		case api.KubernetesTokenReviewAuthentication:
 			if k8sAuthConfig, err := identity_evaluators.NewKubernetesAuthIdentity(authCred, identity.KubernetesTokenReview.Audiences); err != nil {
-				return nil, err
+				return nil, newSpecError(identityPath.Child("kubernetesTokenReview"), err)
 			} else {
 				translatedIdentity.KubernetesAuth = k8sAuthConfig
 			}
@@ -277,8 +734,15 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 		case api.AnonymousAccessAuthentication:
 			translatedIdentity.Noop = &identity_evaluators.Noop{AuthCredentials: authCred}
 
+		case api.LdapAuthentication:
+			ldap := identity.Ldap
+			var err error
+			if translatedIdentity.LDAP, err = identity_evaluators.NewLDAPIdentity(ldap.Url, ldap.BindDn, ldap.Attributes, ldap.SearchBaseDn, ldap.SearchFilter, authCred); err != nil {
+				return nil, newSpecError(identityPath.Child("ldap"), err)
+			}
+
 		case api.UnknownAuthenticationMethod:
-			return nil, fmt.Errorf("unknown identity type %v", identity)
+			return nil, newSpecError(identityPath, fmt.Errorf("unknown identity type %v", identity))
 		}
 
 		identityConfigs = append(identityConfigs, *translatedIdentity)
@@ -288,6 +752,7 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 	interfacedMetadataConfigs := make([]auth.AuthConfigEvaluator, 0)
 
 	for name, metadata := range authConfig.Spec.Metadata {
+		metadataPath := field.NewPath("spec", "metadata").Key(name)
 		translatedMetadata := &evaluators.MetadataConfig{
 			Name:       name,
 			Priority:   metadata.Priority,
@@ -296,14 +761,7 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 		}
 
 		if metadata.Cache != nil {
-			ttl := metadata.Cache.TTL
-			if ttl == 0 {
-				ttl = api.EvaluatorDefaultCacheTTL
-			}
-			translatedMetadata.Cache = evaluators.NewEvaluatorCache(
-				*getJsonFromStaticDynamic(&metadata.Cache.Key),
-				ttl,
-			)
+			translatedMetadata.Cache = r.buildEvaluatorCache(authConfig, metadata.Cache)
 		}
 
 		switch metadata.GetMethod() {
@@ -314,7 +772,7 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 				Namespace: authConfig.Namespace,
 				Name:      metadata.Uma.Credentials.Name},
 				secret); err != nil {
-				return nil, err // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
+				return nil, newSpecError(metadataPath.Child("uma", "credentialsRef"), err) // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
 			}
 
 			if uma, err := metadata_evaluators.NewUMAMetadata(
@@ -322,8 +780,10 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 				string(secret.Data["clientID"]),
 				string(secret.Data["clientSecret"]),
 			); err != nil {
-				return nil, err
+				return nil, newSpecError(metadataPath.Child("uma", "endpoint"), err)
 			} else {
+				uma.URI = getJsonFromStaticDynamic(metadata.Uma.Uri)
+				uma.Scopes = getJsonFromStaticDynamic(metadata.Uma.Scopes)
 				translatedMetadata.UMA = uma
 			}
 
@@ -332,21 +792,21 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 			translatedMetadata.UserInfo = &metadata_evaluators.UserInfo{}
 
 			if idConfig, err := findIdentityConfigByName(identityConfigs, metadata.UserInfo.IdentitySource); err != nil {
-				return nil, err
+				return nil, newSpecError(metadataPath.Child("userInfo", "identitySource"), err)
 			} else {
 				translatedMetadata.UserInfo.OIDC = idConfig.OIDC
 			}
 
 		// generic http
 		case api.HttpMetadata:
-			ev, err := r.buildGenericHttpEvaluator(ctx, metadata.Http, authConfig.Namespace)
+			ev, err := r.buildGenericHttpEvaluator(ctx, metadataPath.Child("http"), metadata.Http, authConfig.Namespace)
 			if err != nil {
-				return nil, err
+				return nil, newSpecError(metadataPath.Child("http"), err)
 			}
 			translatedMetadata.GenericHTTP = ev
 
 		case api.UnknownMetadataMethod:
-			return nil, fmt.Errorf("unknown metadata type %v", metadata)
+			return nil, newSpecError(metadataPath, fmt.Errorf("unknown metadata type %v", metadata))
 		}
 
 		interfacedMetadataConfigs = append(interfacedMetadataConfigs, translatedMetadata)
@@ -357,22 +817,19 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 
 	authzIndex := 0
 	for authzName, authorization := range authConfig.Spec.Authorization {
+		authorizationPath := field.NewPath("spec", "authorization").Key(authzName)
 		translatedAuthorization := &evaluators.AuthorizationConfig{
 			Name:       authzName,
 			Priority:   authorization.Priority,
 			Conditions: buildJSONExpression(authConfig, authorization.Conditions, jsonexp.All),
 			Metrics:    authorization.Metrics,
+			Shadow:     authorization.Shadow,
+
+			AllowShortCircuits: authorization.ShortCircuit == api.ShortCircuitAllow,
 		}
 
 		if authorization.Cache != nil {
-			ttl := authorization.Cache.TTL
-			if ttl == 0 {
-				ttl = api.EvaluatorDefaultCacheTTL
-			}
-			translatedAuthorization.Cache = evaluators.NewEvaluatorCache(
-				*getJsonFromStaticDynamic(&authorization.Cache.Key),
-				ttl,
-			)
+			translatedAuthorization.Cache = r.buildEvaluatorCache(authConfig, authorization.Cache)
 		}
 
 		switch authorization.GetMethod() {
@@ -394,7 +851,7 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 						Namespace: authConfig.Namespace,
 						Name:      externalRegistry.SharedSecret.Name},
 						secret); err != nil {
-						return nil, err // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
+						return nil, newSpecError(authorizationPath.Child("opa", "externalPolicy", "sharedSecretRef"), err) // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
 					}
 					sharedSecret = string(secret.Data[externalRegistry.SharedSecret.Key])
 				}
@@ -405,12 +862,87 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 					AuthCredentials: newAuthCredential(externalRegistry.Credentials),
 					TTL:             externalRegistry.TTL,
 				}
+
+				if clientCertRef := externalRegistry.ClientCertSecret; clientCertRef != nil {
+					clientCertSecret := &v1.Secret{}
+					if err := r.Client.Get(ctx, types.NamespacedName{Namespace: authConfig.Namespace, Name: clientCertRef.Name}, clientCertSecret); err != nil {
+						return nil, newSpecError(authorizationPath.Child("opa", "externalPolicy", "clientCertSecretRef"), err)
+					}
+					tlsConfig, err := authorization_evaluators.NewOPAExternalSourceTLSConfig(clientCertSecret.Data[v1.TLSCertKey], clientCertSecret.Data[v1.TLSPrivateKeyKey], clientCertSecret.Data[v1.ServiceAccountRootCAKey])
+					if err != nil {
+						return nil, newSpecError(authorizationPath.Child("opa", "externalPolicy", "clientCertSecretRef"), fmt.Errorf("secret %s/%s: %v", authConfig.Namespace, clientCertRef.Name, err))
+					}
+					externalSource.TLSConfig = tlsConfig
+				}
+			}
+
+			rego := opa.Rego
+			if configMapRef := opa.RegoConfigMap; configMapRef != nil {
+				if rego != "" {
+					return nil, newSpecError(authorizationPath.Child("opa", "regoConfigMapRef"), fmt.Errorf("rego and regoConfigMapRef are mutually exclusive"))
+				}
+				configMap := &v1.ConfigMap{}
+				if err := r.Client.Get(ctx, types.NamespacedName{Namespace: authConfig.Namespace, Name: configMapRef.Name}, configMap); err != nil {
+					return nil, newSpecError(authorizationPath.Child("opa", "regoConfigMapRef"), err)
+				}
+				var exists bool
+				if rego, exists = configMap.Data[configMapRef.Key]; !exists {
+					return nil, newSpecError(authorizationPath.Child("opa", "regoConfigMapRef"), fmt.Errorf("%w %s in configmap %s/%s", errMissingKey, configMapRef.Key, authConfig.Namespace, configMapRef.Name))
+				}
+			}
+
+			newOPAAuthorization := func(policyName, rego string, externalSource *authorization_evaluators.OPAExternalSource) (*authorization_evaluators.OPA, error) {
+				var (
+					policy *authorization_evaluators.OPA
+					err    error
+				)
+				if len(opa.Outputs) > 0 && !opa.AllValues {
+					policy, err = authorization_evaluators.NewOPAAuthorizationWithOutputs(policyName, rego, externalSource, opa.Outputs, authzIndex, ctxWithLogger)
+				} else {
+					policy, err = authorization_evaluators.NewOPAAuthorization(policyName, rego, externalSource, opa.AllValues, authzIndex, ctxWithLogger)
+				}
+				if err != nil {
+					return nil, err
+				}
+				policy.EvaluationTimeout = time.Duration(opa.EvaluationTimeout) * time.Millisecond
+				return policy, nil
 			}
 
 			var err error
-			translatedAuthorization.OPA, err = authorization_evaluators.NewOPAAuthorization(policyName, opa.Rego, externalSource, opa.AllValues, authzIndex, ctxWithLogger)
-			if err != nil {
-				return nil, err
+			if wasm := opa.Wasm; wasm != nil {
+				module := wasm.Module
+				if configMapRef := wasm.ModuleConfigMap; configMapRef != nil {
+					configMap := &v1.ConfigMap{}
+					if err := r.Client.Get(ctx, types.NamespacedName{Namespace: authConfig.Namespace, Name: configMapRef.Name}, configMap); err != nil {
+						return nil, newSpecError(authorizationPath.Child("opa", "wasm", "moduleConfigMapRef"), err)
+					}
+					var exists bool
+					if module, exists = configMap.BinaryData[configMapRef.Key]; !exists {
+						return nil, newSpecError(authorizationPath.Child("opa", "wasm", "moduleConfigMapRef"), fmt.Errorf("%w %s in the binary data of configmap %s/%s", errMissingKey, configMapRef.Key, authConfig.Namespace, configMapRef.Name))
+					}
+				}
+				translatedAuthorization.OPA, err = authorization_evaluators.NewOPAWasmAuthorization(policyName, module, wasm.Url, wasm.Entrypoint, opa.AllValues, opa.Outputs, ctxWithLogger)
+				if err != nil {
+					return nil, newSpecError(authorizationPath.Child("opa", "wasm"), err)
+				}
+				translatedAuthorization.OPA.EvaluationTimeout = time.Duration(opa.EvaluationTimeout) * time.Millisecond
+			} else {
+				translatedAuthorization.OPA, err = newOPAAuthorization(policyName, rego, externalSource)
+				if err != nil {
+					return nil, newSpecError(authorizationPath.Child("opa"), err)
+				}
+			}
+
+			if routing := opa.Routing; routing != nil {
+				translatedAuthorization.OPA.RouteSelector = &json.JSONValue{Pattern: routing.Selector}
+				translatedAuthorization.OPA.Routes = make(map[string]*authorization_evaluators.OPA, len(routing.Policies))
+				for key, rego := range routing.Policies {
+					route, err := newOPAAuthorization(policyName+"/"+key, rego, nil)
+					if err != nil {
+						return nil, newSpecError(authorizationPath.Child("opa", "routing", "policies").Key(key), err)
+					}
+					translatedAuthorization.OPA.Routes[key] = route
+				}
 			}
 
 		// json
@@ -418,6 +950,16 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 			translatedAuthorization.JSON = &authorization_evaluators.JSONPatternMatching{
 				Rules: buildJSONExpression(authConfig, authorization.PatternMatching.Patterns, jsonexp.All),
 			}
+			if rules := authorization.PatternMatching.Rules; len(rules) > 0 {
+				orderedRules := make([]authorization_evaluators.JSONPatternMatchingRule, len(rules))
+				for i, rule := range rules {
+					orderedRules[i] = authorization_evaluators.JSONPatternMatchingRule{
+						Effect:     rule.Effect == api.PatternMatchingRuleAllow,
+						Conditions: buildJSONExpression(authConfig, rule.Conditions, jsonexp.All),
+					}
+				}
+				translatedAuthorization.JSON.OrderedRules = orderedRules
+			}
 
 		case api.KubernetesSubjectAccessReviewAuthorization:
 			user := authorization.KubernetesSubjectAccessReview.User
@@ -433,14 +975,28 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 					Name:        json.JSONValue{Static: resourceAttributes.Name.Value, Pattern: resourceAttributes.Name.Selector},
 					SubResource: json.JSONValue{Static: resourceAttributes.SubResource.Value, Pattern: resourceAttributes.SubResource.Selector},
 					Verb:        json.JSONValue{Static: resourceAttributes.Verb.Value, Pattern: resourceAttributes.Verb.Selector},
+					Batch:       resourceAttributes.Batch,
 				}
 			}
 
 			var err error
-			translatedAuthorization.KubernetesAuthz, err = authorization_evaluators.NewKubernetesAuthz(authorinoUser, authorization.KubernetesSubjectAccessReview.Groups, authorinoResourceAttributes)
+			if kubeconfigRef := authorization.KubernetesSubjectAccessReview.KubeconfigSecretRef; kubeconfigRef != nil {
+				secret := &v1.Secret{}
+				if err := r.Client.Get(ctx, types.NamespacedName{Namespace: authConfig.Namespace, Name: kubeconfigRef.Name}, secret); err != nil {
+					return nil, newSpecError(authorizationPath.Child("kubernetesSubjectAccessReview", "kubeconfigSecretRef"), err)
+				}
+				kubeconfig, exists := secret.Data[kubeconfigRef.Key]
+				if !exists {
+					return nil, newSpecError(authorizationPath.Child("kubernetesSubjectAccessReview", "kubeconfigSecretRef"), fmt.Errorf("%w %s in secret %s/%s", errMissingKey, kubeconfigRef.Key, authConfig.Namespace, kubeconfigRef.Name))
+				}
+				translatedAuthorization.KubernetesAuthz, err = authorization_evaluators.NewKubernetesAuthzWithKubeconfig(kubeconfig, authorinoUser, authorization.KubernetesSubjectAccessReview.Groups, authorinoResourceAttributes)
+			} else {
+				translatedAuthorization.KubernetesAuthz, err = authorization_evaluators.NewKubernetesAuthz(authorinoUser, authorization.KubernetesSubjectAccessReview.Groups, authorinoResourceAttributes)
+			}
 			if err != nil {
-				return nil, err
+				return nil, newSpecError(authorizationPath.Child("kubernetesSubjectAccessReview"), err)
 			}
+			translatedAuthorization.KubernetesAuthz.Impersonate = authorization.KubernetesSubjectAccessReview.Impersonate
 
 		case api.SpiceDBAuthorization:
 			authzed := authorization.SpiceDB
@@ -449,9 +1005,13 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 			var sharedSecret string
 			if secretRef := authzed.SharedSecret; secretRef != nil {
 				if err := r.Client.Get(ctx, types.NamespacedName{Namespace: authConfig.Namespace, Name: secretRef.Name}, secret); err != nil {
-					return nil, err // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
+					return nil, newSpecError(authorizationPath.Child("spicedb", "sharedSecretRef"), err) // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
 				}
-				sharedSecret = string(secret.Data[secretRef.Key])
+				value, exists := secret.Data[secretRef.Key]
+				if !exists {
+					return nil, newSpecError(authorizationPath.Child("spicedb", "sharedSecretRef"), fmt.Errorf("%w %s in secret %s/%s", errMissingKey, secretRef.Key, authConfig.Namespace, secretRef.Name))
+				}
+				sharedSecret = string(value)
 			}
 
 			translatedAuthzed := &authorization_evaluators.Authzed{
@@ -462,11 +1022,25 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 			}
 			translatedAuthzed.Subject, translatedAuthzed.SubjectKind = spiceDBObjectToJsonValues(authzed.Subject)
 			translatedAuthzed.Resource, translatedAuthzed.ResourceKind = spiceDBObjectToJsonValues(authzed.Resource)
+			translatedAuthzed.TLSSessionCacheSize = authorization_evaluators.DefaultAuthzedTLSSessionCacheSize
+			if conn := authzed.Connection; conn != nil {
+				translatedAuthzed.DisableConnectionReuse = conn.DisableReuse
+				translatedAuthzed.KeepaliveTime = time.Duration(conn.KeepaliveTime) * time.Second
+				if conn.TLSSessionCacheSize != nil {
+					translatedAuthzed.TLSSessionCacheSize = *conn.TLSSessionCacheSize
+				}
+			}
 
 			translatedAuthorization.Authzed = translatedAuthzed
 
+		case api.ScopesAuthorization:
+			translatedAuthorization.Scopes = &authorization_evaluators.Scopes{
+				Required: *getJsonFromStaticDynamic(&authorization.Scopes.Required),
+				Mapping:  authorization.Scopes.Mapping,
+			}
+
 		case api.UnknownAuthorizationMethod:
-			return nil, fmt.Errorf("unknown authorization type %v", authorization)
+			return nil, newSpecError(authorizationPath, fmt.Errorf("unknown authorization type %v", authorization))
 		}
 
 		interfacedAuthorizationConfigs = append(interfacedAuthorizationConfigs, translatedAuthorization)
@@ -486,9 +1060,11 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 				headerSuccessResponse.Metrics,
 			)
 
-			injectCache(headerSuccessResponse.Cache, translatedResponse)
-			if err := injectResponseConfig(ctx, authConfig, headerSuccessResponse.SuccessResponseSpec, r, translatedResponse); err != nil {
-				return nil, err
+			translatedResponse.AppendHeader = headerSuccessResponse.Append
+			r.injectCache(authConfig, headerSuccessResponse.Cache, translatedResponse)
+			responsePath := field.NewPath("spec", "response", "success", "headers").Key(responseName)
+			if err := injectResponseConfig(ctx, authConfig, responsePath, headerSuccessResponse.SuccessResponseSpec, r, translatedResponse); err != nil {
+				return nil, newSpecError(responsePath, err)
 			}
 
 			interfacedResponseConfigs = append(interfacedResponseConfigs, translatedResponse)
@@ -504,9 +1080,10 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 				successResponse.Metrics,
 			)
 
-			injectCache(successResponse.Cache, translatedResponse)
-			if err := injectResponseConfig(ctx, authConfig, successResponse, r, translatedResponse); err != nil {
-				return nil, err
+			r.injectCache(authConfig, successResponse.Cache, translatedResponse)
+			responsePath := field.NewPath("spec", "response", "success", "dynamicMetadata").Key(responseName)
+			if err := injectResponseConfig(ctx, authConfig, responsePath, successResponse, r, translatedResponse); err != nil {
+				return nil, newSpecError(responsePath, err)
 			}
 
 			interfacedResponseConfigs = append(interfacedResponseConfigs, translatedResponse)
@@ -516,6 +1093,7 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 	interfacedCallbackConfigs := make([]auth.AuthConfigEvaluator, 0)
 
 	for callbackName, callback := range authConfig.Spec.Callbacks {
+		callbackPath := field.NewPath("spec", "callbacks").Key(callbackName)
 		translatedCallback := &evaluators.CallbackConfig{
 			Name:       callbackName,
 			Priority:   callback.Priority,
@@ -526,19 +1104,23 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 		switch callback.GetMethod() {
 		// http
 		case api.HttpCallback:
-			ev, err := r.buildGenericHttpEvaluator(ctx, callback.Http, authConfig.Namespace)
+			ev, err := r.buildGenericHttpEvaluator(ctx, callbackPath.Child("http"), callback.Http, authConfig.Namespace)
 			if err != nil {
-				return nil, err
+				return nil, newSpecError(callbackPath.Child("http"), err)
 			}
 			translatedCallback.HTTP = ev
 
 		case api.UnknownCallbackMethod:
-			return nil, fmt.Errorf("unknown callback type %v", callback)
+			return nil, newSpecError(callbackPath, fmt.Errorf("unknown callback type %v", callback))
 		}
 
 		interfacedCallbackConfigs = append(interfacedCallbackConfigs, translatedCallback)
 	}
 
+	for _, configs := range [][]auth.AuthConfigEvaluator{interfacedIdentityConfigs, interfacedMetadataConfigs, interfacedAuthorizationConfigs, interfacedResponseConfigs, interfacedCallbackConfigs} {
+		evaluators.SortByPriority(configs)
+	}
+
 	translatedAuthConfig := &evaluators.AuthConfig{
 		Conditions:           buildJSONExpression(authConfig, authConfig.Spec.Conditions, jsonexp.All),
 		IdentityConfigs:      interfacedIdentityConfigs,
@@ -546,44 +1128,133 @@ func (r *AuthConfigReconciler) translateAuthConfig(ctx context.Context, authConf
 		AuthorizationConfigs: interfacedAuthorizationConfigs,
 		ResponseConfigs:      interfacedResponseConfigs,
 		CallbackConfigs:      interfacedCallbackConfigs,
-		Labels:               map[string]string{"namespace": authConfig.Namespace, "name": authConfig.Name},
+		Labels:               map[string]string{"namespace": authConfig.Namespace, "name": authConfig.Name, fingerprintLabel: authConfigFingerprint(authConfig)},
+		OmitRequestBody:      !requestBodyNeeded(authConfig),
+		StrictIdentity:       authConfig.Spec.StrictAuthentication,
+		Timeout:              r.authConfigTimeout(authConfig),
+	}
+
+	// maintenance bypass
+	if bypass := authConfig.Spec.MaintenanceBypass; bypass != nil {
+		secretRef := bypass.SharedSecretRef
+		secret := &v1.Secret{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: authConfig.Namespace, Name: secretRef.Name}, secret); err != nil {
+			return nil, newSpecError(field.NewPath("spec", "maintenanceBypass", "sharedSecretRef"), err)
+		}
+		sharedSecret, exists := secret.Data[secretRef.Key]
+		if !exists {
+			return nil, newSpecError(field.NewPath("spec", "maintenanceBypass", "sharedSecretRef"), fmt.Errorf("%w %s in secret %s/%s", errMissingKey, secretRef.Key, authConfig.Namespace, secretRef.Name))
+		}
+		header := bypass.Header
+		if header == "" {
+			header = defaultMaintenanceBypassHeader
+		}
+		translatedAuthConfig.MaintenanceBypass = &evaluators.MaintenanceBypass{
+			Header: header,
+			Secret: sharedSecret,
+			MaxTTL: time.Duration(bypass.MaxTTL) * time.Second,
+		}
 	}
 
 	// denyWith
 	if responseConfig := authConfig.Spec.Response; responseConfig != nil {
 		if denyWith := responseConfig.Unauthenticated; denyWith != nil {
-			translatedAuthConfig.Unauthenticated = buildAuthorinoDenyWithValues(denyWith)
+			translatedAuthConfig.Unauthenticated = buildAuthorinoDenyWithValues(authConfig, denyWith)
 		}
 		if denyWith := responseConfig.Unauthorized; denyWith != nil {
-			translatedAuthConfig.Unauthorized = buildAuthorinoDenyWithValues(denyWith)
+			translatedAuthConfig.Unauthorized = buildAuthorinoDenyWithValues(authConfig, denyWith)
 		}
 	}
 
 	return translatedAuthConfig, nil
 }
 
-func injectResponseConfig(ctx context.Context, authConfig *api.AuthConfig, successResponse api.SuccessResponseSpec, r *AuthConfigReconciler, translatedResponse *evaluators.ResponseConfig) error {
+// wristbandSigningKey builds a wristband signing key out of the "key.pem" entry of a Kubernetes secret, locating the
+// errors at the path of the reference to the secret
+func wristbandSigningKey(path *field.Path, ref *api.WristbandSigningKeyRef, secret *v1.Secret) (*jose.JSONWebKey, error) {
+	keyPEM, ok := secret.Data["key.pem"]
+	if !ok {
+		return nil, newSpecError(path, fmt.Errorf("%w key.pem in secret %s/%s", errMissingKey, secret.Namespace, secret.Name))
+	}
+	signingKey, err := response_evaluators.NewSigningKey(ref.Name, string(ref.Algorithm), keyPEM)
+	if err != nil {
+		return nil, newSpecError(path, err)
+	}
+	return signingKey, nil
+}
+
+// wristbandSigningKeyWarnings validates the signing keys of the wristband responses of an AuthConfig, returning one
+// warning per invalid key. Secrets that cannot be read are left for the translation of the AuthConfig to report.
+func (r *AuthConfigReconciler) wristbandSigningKeyWarnings(ctx context.Context, authConfig *api.AuthConfig) []string {
+	if authConfig.Spec.Response == nil {
+		return nil
+	}
+
+	type wristbandResponse struct {
+		name string
+		path *field.Path
+		spec *api.WristbandAuthResponseSpec
+	}
+	var wristbands []wristbandResponse
+	successPath := field.NewPath("spec", "response", "success")
+	for name, response := range authConfig.Spec.Response.Success.Headers {
+		if response.GetMethod() == api.WristbandAuthResponse {
+			wristbands = append(wristbands, wristbandResponse{name, successPath.Child("headers").Key(name).Child("wristband"), response.Wristband})
+		}
+	}
+	for name, response := range authConfig.Spec.Response.Success.DynamicMetadata {
+		if response.GetMethod() == api.WristbandAuthResponse {
+			wristbands = append(wristbands, wristbandResponse{name, successPath.Child("dynamicMetadata").Key(name).Child("wristband"), response.Wristband})
+		}
+	}
+
+	var warnings []string
+	for _, wristband := range wristbands {
+		name := wristband.name
+		for i, signingKeyRef := range wristband.spec.SigningKeyRefs {
+			secret := &v1.Secret{}
+			if err := r.Client.Get(ctx, types.NamespacedName{Namespace: authConfig.Namespace, Name: signingKeyRef.Name}, secret); err != nil {
+				continue
+			}
+			if _, err := wristbandSigningKey(wristband.path.Child("signingKeyRefs").Index(i), signingKeyRef, secret); err != nil {
+				if i == 0 {
+					warnings = append(warnings, fmt.Sprintf("response %s: invalid active signing key %s, wristband not issued: %s", name, signingKeyRef.Name, err.Error()))
+				} else {
+					warnings = append(warnings, fmt.Sprintf("response %s: invalid signing key %s: %s", name, signingKeyRef.Name, err.Error()))
+				}
+			}
+		}
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+func injectResponseConfig(ctx context.Context, authConfig *api.AuthConfig, path *field.Path, successResponse api.SuccessResponseSpec, r *AuthConfigReconciler, translatedResponse *evaluators.ResponseConfig) error {
 	switch successResponse.GetMethod() {
 	// wristband
 	case api.WristbandAuthResponse:
 		wristband := successResponse.Wristband
+		wristbandPath := path.Child("wristband")
 		signingKeys := make([]jose.JSONWebKey, 0)
 
-		for _, signingKeyRef := range wristband.SigningKeyRefs {
+		for i, signingKeyRef := range wristband.SigningKeyRefs {
 			secret := &v1.Secret{}
 			secretName := types.NamespacedName{
 				Namespace: authConfig.Namespace,
 				Name:      signingKeyRef.Name,
 			}
 			if err := r.Client.Get(ctx, secretName, secret); err != nil {
-				return err // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
+				return newSpecError(wristbandPath.Child("signingKeyRefs").Index(i), err) // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
 			} else {
-				if signingKey, err := response_evaluators.NewSigningKey(
-					signingKeyRef.Name,
-					string(signingKeyRef.Algorithm),
-					secret.Data["key.pem"],
-				); err != nil {
-					return err
+				if signingKey, err := wristbandSigningKey(wristbandPath.Child("signingKeyRefs").Index(i), signingKeyRef, secret); err != nil {
+					// invalid keys are reported as warnings in the status of the resource. Invalid verification-only keys
+					// are left out of the wristband, whereas an invalid active key disables the wristband response alone,
+					// instead of promoting a verification-only key to sign the wristbands
+					if i == 0 {
+						return nil
+					}
+					continue
 				} else {
 					signingKeys = append(signingKeys, *signingKey)
 				}
@@ -607,8 +1278,19 @@ func injectResponseConfig(ctx context.Context, authConfig *api.AuthConfig, succe
 			wristband.TokenDuration,
 			signingKeys,
 		); err != nil {
-			return err
+			return newSpecError(wristbandPath, err)
 		} else {
+			authorinoWristband.IncludeClaims = wristband.IncludeClaims
+			authorinoWristband.ExcludeClaims = wristband.ExcludeClaims
+			if len(wristband.CustomClaimConditions) > 0 {
+				authorinoWristband.CustomClaimConditions = make(map[string]jsonexp.Expression, len(wristband.CustomClaimConditions))
+				for claimName, conditions := range wristband.CustomClaimConditions {
+					if _, found := wristband.CustomClaims[claimName]; !found {
+						return newSpecError(wristbandPath.Child("customClaimConditions").Key(claimName), fmt.Errorf("conditions for unknown custom claim: %s", claimName))
+					}
+					authorinoWristband.CustomClaimConditions[claimName] = buildJSONExpression(authConfig, conditions, jsonexp.All)
+				}
+			}
 			translatedResponse.Wristband = authorinoWristband
 		}
 
@@ -637,33 +1319,71 @@ func injectResponseConfig(ctx context.Context, authConfig *api.AuthConfig, succe
 			},
 		}
 
+	// combined
+	case api.CombinedAuthResponse:
+		translatedResponse.Combined = &response_evaluators.Combined{
+			Responses: successResponse.Combined.Responses,
+			Separator: successResponse.Combined.Separator,
+		}
+
+	// rate limit descriptors
+	case api.RateLimitDescriptorsAuthResponse:
+		entries := make([]json.JSONProperty, 0, len(successResponse.RateLimitDescriptors.Entries))
+		for _, entry := range successResponse.RateLimitDescriptors.Entries {
+			entries = append(entries, json.JSONProperty{
+				Name: entry.Key,
+				Value: json.JSONValue{
+					Static:  entry.Value,
+					Pattern: entry.Selector,
+				},
+			})
+		}
+		translatedResponse.RateLimitDescriptors = &response_evaluators.RateLimitDescriptors{Entries: entries}
+
 	case api.UnknownAuthResponseMethod:
 		return fmt.Errorf("unknown successResponse type %v", successResponse)
 	}
 	return nil
 }
 
-func injectCache(cache *api.EvaluatorCaching, translatedResponse *evaluators.ResponseConfig) {
+func (r *AuthConfigReconciler) injectCache(authConfig *api.AuthConfig, cache *api.EvaluatorCaching, translatedResponse *evaluators.ResponseConfig) {
 	if cache != nil {
-		ttl := cache.TTL
-		if ttl == 0 {
-			ttl = api.EvaluatorDefaultCacheTTL
-		}
-		translatedResponse.Cache = evaluators.NewEvaluatorCache(
-			*getJsonFromStaticDynamic(&cache.Key),
-			ttl,
-		)
+		translatedResponse.Cache = r.buildEvaluatorCache(authConfig, cache)
 	}
 }
 
+func (r *AuthConfigReconciler) buildEvaluatorCache(authConfig *api.AuthConfig, cache *api.EvaluatorCaching) evaluators.EvaluatorCache {
+	ttl, maxEntries := r.EvaluatorCachePolicy.apply(cache)
+	var conditions jsonexp.Expression
+	if len(cache.Conditions) > 0 {
+		conditions = buildJSONExpression(authConfig, cache.Conditions, jsonexp.All)
+	}
+	return evaluators.NewEvaluatorCache(
+		*getJsonFromStaticDynamic(&cache.Key),
+		ttl,
+		maxEntries,
+		conditions,
+	)
+}
+
 func (r *AuthConfigReconciler) addToIndex(ctx context.Context, resourceNamespace, resourceId string, authConfig *evaluators.AuthConfig, hosts []string) (linkedHosts, looseHosts []string, err error) {
 	logger := log.FromContext(ctx)
 	linkedHosts = []string{}
 	looseHosts = []string{}
 
 	for _, host := range hosts {
+		// skip hosts that can never match a request, removing them from the index in case they were cached before
+		if err := validateHost(host); err != nil {
+			looseHosts = append(looseHosts, host)
+			if id, found := r.Index.FindId(host); found && id == resourceId {
+				r.Index.DeleteKey(resourceId, host)
+			}
+			logger.Info("invalid host", "host", host, "reason", err)
+			continue
+		}
+
 		// check for host name collision between resources
-		if r.hostTaken(host, resourceId) {
+		if takenBy, taken := r.hostTaken(host, resourceId); taken && !r.releaseHostOutOfScope(ctx, host, takenBy) {
 			looseHosts = append(looseHosts, host)
 			logger.Info("host already taken", "host", host)
 			continue
@@ -680,9 +1400,87 @@ func (r *AuthConfigReconciler) addToIndex(ctx context.Context, resourceNamespace
 	return
 }
 
-func (r *AuthConfigReconciler) hostTaken(host, resourceId string) bool {
+var hostLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?$`)
+
+// validateHost checks that the host is a legal hostname or IP address, optionally with a port number, and where the
+// leftmost label of the hostname can be a wildcard (e.g. *.example.com)
+func validateHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("empty host")
+	}
+	if strings.Contains(host, "://") {
+		return fmt.Errorf("invalid hostname: must not include a scheme")
+	}
+	if strings.ContainsAny(host, "/?#") {
+		return fmt.Errorf("invalid hostname: must not include a path")
+	}
+
+	hostname := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid port: %s", port)
+		}
+		hostname = h
+	}
+
+	if net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(hostname, "["), "]")) != nil {
+		return nil
+	}
+
+	if len(hostname) > 253 {
+		return fmt.Errorf("invalid hostname: longer than 253 characters")
+	}
+	for i, label := range strings.Split(hostname, ".") {
+		if i == 0 && label == "*" {
+			continue
+		}
+		if !hostLabelRegex.MatchString(label) {
+			return fmt.Errorf("invalid hostname: illegal label %q", label)
+		}
+	}
+
+	return nil
+}
+
+// hostTaken tells whether the host is linked in the index to another resource, returning the id of the resource
+func (r *AuthConfigReconciler) hostTaken(host, resourceId string) (string, bool) {
 	indexedResourceId, found := r.Index.FindId(host)
-	return found && indexedResourceId != resourceId && !r.supersedeHostSubset(host, indexedResourceId)
+	return indexedResourceId, found && indexedResourceId != resourceId && !r.supersedeHostSubset(host, indexedResourceId)
+}
+
+// releaseHostOutOfScope removes from the index the resource that holds the host, if the resource is out of the scope of
+// this controller (e.g. its labels no longer match the label selector), and tells whether the host was released.
+// Only resources within the scope of this controller can take a host, so independently-scoped instances of Authorino
+// (e.g. a namespaced one and a cluster-wide one) do not collide on hosts of resources that the other one manages.
+func (r *AuthConfigReconciler) releaseHostOutOfScope(ctx context.Context, host, indexedResourceId string) bool {
+	if r.managesResource(ctx, indexedResourceId) {
+		return false
+	}
+	logger := log.FromContext(ctx)
+	logger.Info("releasing host of resource out of scope", "host", host, "resource", indexedResourceId)
+	if err := r.cleanConfigs(indexedResourceId, ctx); err != nil {
+		logger.Error(err, failedToCleanConfig, "resource", indexedResourceId)
+	}
+	r.Index.Delete(indexedResourceId)
+	return true
+}
+
+// managesResource tells whether an AuthConfig is within the scope of this controller, i.e. in the watched namespace and
+// matching the label selector. In case of doubt (e.g. the AuthConfig cannot be read), the AuthConfig is considered
+// managed – AuthConfigs deleted are cleaned up from the index by their own reconciliation.
+func (r *AuthConfigReconciler) managesResource(ctx context.Context, resourceId string) bool {
+	namespace, name, found := strings.Cut(resourceId, string(types.Separator))
+	if !found {
+		return true
+	}
+	if r.Namespace != "" && namespace != r.Namespace {
+		return false
+	}
+	authConfig := api.AuthConfig{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &authConfig); err != nil {
+		return true
+	}
+	return Watched(&authConfig.ObjectMeta, r.LabelSelector)
 }
 
 func (r *AuthConfigReconciler) supersedeHostSubset(host, supersetResourceId string) bool {
@@ -717,6 +1515,7 @@ func (r *AuthConfigReconciler) bootstrapIndex(ctx context.Context) error {
 	sort.Sort(authConfigList.Items)
 
 	ctx = log.IntoContext(ctx, logger)
+
 	denyAll := &evaluators.AuthConfig{
 		AuthorizationConfigs: []auth.AuthConfigEvaluator{evaluators.NewDenyAllAuthorization(ctx, "deny-all", "")},
 		DenyWith:             evaluators.DenyWith{Unauthorized: &evaluators.DenyWithValues{Code: 503, Message: &json.JSONValue{Static: "Busy"}}},
@@ -735,7 +1534,7 @@ func (r *AuthConfigReconciler) bootstrapIndex(ctx context.Context) error {
 			authConfig.Namespace,
 			authConfigName.String(),
 			denyAll,
-			authConfig.Spec.Hosts,
+			authConfig.Spec.EnabledHosts(),
 		)
 
 		if err != nil {
@@ -751,9 +1550,44 @@ func (r *AuthConfigReconciler) ClusterWide() bool {
 }
 
 func (r *AuthConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &api.AuthConfig{}, configMapRefsIndexField, indexConfigMapRefs); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &api.AuthConfig{}, secretRefsIndexField, indexSecretRefs); err != nil {
+		return err
+	}
+
+	if _, err := r.newController(mgr); err != nil {
+		return err
+	}
+
+	sharedSecrets := &sharedSecretReconciler{AuthConfigReconciler: r}
 	return ctrl.NewControllerManagedBy(mgr).
+		Named("sharedsecret").
+		For(&v1.Secret{}, builder.WithPredicates(sharedSecrets.hotReloadedSecretPredicate())).
+		Complete(sharedSecrets)
+}
+
+// newController builds the controller of AuthConfigs, registered in the manager
+func (r *AuthConfigReconciler) newController(mgr ctrl.Manager) (controller.Controller, error) {
+	b := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(r.controllerOptions()).
 		For(&api.AuthConfig{}, builder.WithPredicates(LabelSelectorPredicate(r.LabelSelector))).
-		Complete(r)
+		Watches(&v1.ConfigMap{}, r.dependencyEventHandler(r.authConfigsForConfigMap), builder.OnlyMetadata).
+		Watches(&v1.Secret{}, r.dependencyEventHandler(r.authConfigsForSecret), builder.OnlyMetadata)
+	if src := labelSelectorChangeSource(mgr.GetClient(), r.LabelSelector, r.Logger); src != nil {
+		b = b.WatchesRawSource(src, &handler.EnqueueRequestForObject{})
+	}
+	return b.Build(r)
+}
+
+// controllerOptions returns the options of the controller of AuthConfigs, left to the defaults of controller-runtime
+// where unset
+func (r *AuthConfigReconciler) controllerOptions() controller.Options {
+	return controller.Options{
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		RateLimiter:             r.RateLimiter,
+	}
 }
 
 func (r *AuthConfigReconciler) Ready(includes, _ []string, _ bool) error {
@@ -772,13 +1606,59 @@ func (r *AuthConfigReconciler) Ready(includes, _ []string, _ bool) error {
 	return nil
 }
 
-func (r *AuthConfigReconciler) buildGenericHttpEvaluator(ctx context.Context, http *api.HttpEndpointSpec, namespace string) (*metadata_evaluators.GenericHttp, error) {
+// readStaticJwks reads the static JSON Web Key Set referred in the jwt authentication spec, if any, locating the errors
+// at the path of the field that refers to it
+func (r *AuthConfigReconciler) readStaticJwks(ctx context.Context, namespace string, path *field.Path, jwt *api.JwtAuthenticationSpec) ([]byte, error) {
+	if jwt.Jwks != "" {
+		return []byte(jwt.Jwks), nil
+	}
+
+	if configMapRef := jwt.JwksConfigMapRef; configMapRef != nil {
+		configMap := &v1.ConfigMap{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: configMapRef.Name}, configMap); err != nil {
+			return nil, newSpecError(path, err)
+		}
+		jwks, exists := configMap.Data[configMapRef.Key]
+		if !exists {
+			return nil, newSpecError(path, fmt.Errorf("%w %s in configmap %s/%s", errMissingKey, configMapRef.Key, namespace, configMapRef.Name))
+		}
+		return []byte(jwks), nil
+	}
+
+	if secretRef := jwt.JwksSecretRef; secretRef != nil {
+		secret := &v1.Secret{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretRef.Name}, secret); err != nil {
+			return nil, newSpecError(path, err)
+		}
+		jwks, exists := secret.Data[secretRef.Key]
+		if !exists {
+			return nil, newSpecError(path, fmt.Errorf("%w %s in secret %s/%s", errMissingKey, secretRef.Key, namespace, secretRef.Name))
+		}
+		return jwks, nil
+	}
+
+	return nil, nil
+}
+
+// staticJwksField returns the name of the field of the static JWKS set in the spec
+func staticJwksField(jwt *api.JwtAuthenticationSpec) string {
+	switch {
+	case jwt.Jwks != "":
+		return "jwks"
+	case jwt.JwksConfigMapRef != nil:
+		return "jwksConfigMapRef"
+	default:
+		return "jwksSecretRef"
+	}
+}
+
+func (r *AuthConfigReconciler) buildGenericHttpEvaluator(ctx context.Context, path *field.Path, http *api.HttpEndpointSpec, namespace string) (*metadata_evaluators.GenericHttp, error) {
 	var sharedSecret string
 	if sharedSecretRef := http.SharedSecret; sharedSecretRef != nil {
 		secret := &v1.Secret{}
 		if sharedSecretRef != nil {
 			if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: sharedSecretRef.Name}, secret); err != nil {
-				return nil, err // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
+				return nil, newSpecError(path.Child("sharedSecretRef"), err) // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
 			}
 			sharedSecret = string(secret.Data[sharedSecretRef.Key])
 		}
@@ -789,7 +1669,7 @@ func (r *AuthConfigReconciler) buildGenericHttpEvaluator(ctx context.Context, ht
 	if oauth2Config := http.OAuth2; oauth2Config != nil {
 		secret := &v1.Secret{}
 		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: oauth2Config.ClientSecret.Name}, secret); err != nil {
-			return nil, err // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
+			return nil, newSpecError(path.Child("oauth2", "clientSecretRef"), err) // TODO: Review this error, perhaps we don't need to return an error, just reenqueue.
 		}
 		clientSecret := string(secret.Data[oauth2Config.ClientSecret.Key])
 		oauth2ClientCredentialsConfig = oauth2.NewClientCredentialsConfig(oauth2Config.TokenUrl, oauth2Config.ClientId, clientSecret, oauth2Config.Scopes, oauth2Config.ExtraParams)
@@ -823,6 +1703,17 @@ func (r *AuthConfigReconciler) buildGenericHttpEvaluator(ctx context.Context, ht
 		})
 	}
 
+	queryParams := make([]json.JSONProperty, 0, len(http.QueryParameters))
+	for name, param := range http.QueryParameters {
+		queryParams = append(queryParams, json.JSONProperty{
+			Name: name,
+			Value: json.JSONValue{
+				Static:  param.Value,
+				Pattern: param.Selector,
+			},
+		})
+	}
+
 	method := "GET"
 	if m := http.Method; m != nil {
 		method = string(*m)
@@ -833,11 +1724,22 @@ func (r *AuthConfigReconciler) buildGenericHttpEvaluator(ctx context.Context, ht
 		Method:                method,
 		Body:                  body,
 		Parameters:            params,
+		OmitEmptyParameters:   http.OmitEmptyParameters,
 		Headers:               headers,
+		RepeatedHeaders:       http.RepeatedHeaders,
+		QueryParameters:       queryParams,
+		QueryArraySeparator:   http.QueryArraySeparator,
 		ContentType:           string(http.ContentType),
 		SharedSecret:          sharedSecret,
 		OAuth2:                oauth2ClientCredentialsConfig,
 		OAuth2TokenForceFetch: oauth2TokenForceFetch,
+		Retries:               http.Retries,
+		RetryNonIdempotent:    http.RetryNonIdempotent,
+	}
+
+	if sharedSecretRef := http.SharedSecret; sharedSecretRef != nil && http.HotReloadSharedSecret {
+		ev.SharedSecretRef = &types.NamespacedName{Namespace: namespace, Name: sharedSecretRef.Name}
+		ev.SharedSecretKey = sharedSecretRef.Key
 	}
 
 	if sharedSecret != "" || oauth2ClientCredentialsConfig != nil {
@@ -847,6 +1749,34 @@ func (r *AuthConfigReconciler) buildGenericHttpEvaluator(ctx context.Context, ht
 	return ev, nil
 }
 
+var requestBodyWholeObjectSelectorRegex = regexp.MustCompile(`["{](context|context\.request|context\.request\.http|request|@this)(["}|]|\.@)`)
+
+// authConfigTimeout returns the timeout of the auth pipeline overridden in the AuthConfig, bounded by the maximum
+func (r *AuthConfigReconciler) authConfigTimeout(authConfig *api.AuthConfig) time.Duration {
+	timeout := time.Duration(authConfig.Spec.Timeout) * time.Millisecond
+	if r.MaxAuthConfigTimeout > 0 && timeout > r.MaxAuthConfigTimeout {
+		return r.MaxAuthConfigTimeout
+	}
+	return timeout
+}
+
+// requestBodyNeeded tells whether any evaluator of the AuthConfig may read the body of the request.
+// It errs on the side of needing the body: OPA policies can read the whole input, and any mention of "body" or selector
+// of an object that contains the body counts.
+func requestBodyNeeded(authConfig *api.AuthConfig) bool {
+	for _, authorization := range authConfig.Spec.Authorization {
+		if authorization.Opa != nil {
+			return true
+		}
+	}
+
+	spec, err := gojson.Marshal(authConfig.Spec)
+	if err != nil {
+		return true
+	}
+	return strings.Contains(string(spec), "body") || requestBodyWholeObjectSelectorRegex.Match(spec)
+}
+
 func newAuthCredential(creds api.Credentials) *auth.AuthCredential {
 	var in, key string
 	switch creds.GetType() {
@@ -923,7 +1853,7 @@ func buildJSONExpressionPattern(expression api.PatternExpression) jsonexp.Expres
 	}
 }
 
-func buildAuthorinoDenyWithValues(denyWithSpec *api.DenyWithSpec) *evaluators.DenyWithValues {
+func buildAuthorinoDenyWithValues(authConfig *api.AuthConfig, denyWithSpec *api.DenyWithSpec) *evaluators.DenyWithValues {
 	if denyWithSpec == nil {
 		return nil
 	}
@@ -933,11 +1863,43 @@ func buildAuthorinoDenyWithValues(denyWithSpec *api.DenyWithSpec) *evaluators.De
 		headers = append(headers, json.JSONProperty{Name: name, Value: json.JSONValue{Static: header.Value, Pattern: header.Selector}})
 	}
 
+	var headerConditions map[string]jsonexp.Expression
+	for name, conditions := range denyWithSpec.HeaderConditions {
+		if len(conditions) == 0 {
+			continue
+		}
+		if headerConditions == nil {
+			headerConditions = make(map[string]jsonexp.Expression, len(denyWithSpec.HeaderConditions))
+		}
+		headerConditions[name] = buildJSONExpression(authConfig, conditions, jsonexp.All)
+	}
+
+	var dynamicMetadata []json.JSONProperty
+	for name, property := range denyWithSpec.DynamicMetadata {
+		dynamicMetadata = append(dynamicMetadata, json.JSONProperty{Name: name, Value: json.JSONValue{Static: property.Value, Pattern: property.Selector}})
+	}
+
 	return &evaluators.DenyWithValues{
-		Code:    int32(denyWithSpec.Code),
-		Message: getJsonFromStaticDynamic(denyWithSpec.Message),
-		Headers: headers,
-		Body:    getJsonFromStaticDynamic(denyWithSpec.Body),
+		Code:             int32(denyWithSpec.Code),
+		Message:          getJsonFromStaticDynamic(denyWithSpec.Message),
+		Headers:          headers,
+		HeaderConditions: headerConditions,
+		Body:             getJsonFromStaticDynamic(denyWithSpec.Body),
+		Problem:          buildAuthorinoProblemDetails(denyWithSpec.Problem),
+		DynamicMetadata:  dynamicMetadata,
+	}
+}
+
+func buildAuthorinoProblemDetails(problemSpec *api.ProblemDetailsSpec) *evaluators.ProblemDetails {
+	if problemSpec == nil {
+		return nil
+	}
+
+	return &evaluators.ProblemDetails{
+		Type:     getJsonFromStaticDynamic(problemSpec.Type),
+		Title:    getJsonFromStaticDynamic(problemSpec.Title),
+		Detail:   getJsonFromStaticDynamic(problemSpec.Detail),
+		Instance: getJsonFromStaticDynamic(problemSpec.Instance),
 	}
 }
 
@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -18,6 +19,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 // AuthConfigStatusUpdater updates the status of a newly reconciled auth config
@@ -58,14 +60,18 @@ func (u *AuthConfigStatusUpdater) updateAuthConfigStatus(ctx context.Context, re
 	logger := log.FromContext(ctx)
 
 	var reason, message string
+	var warnings []string
+	var specErrors []api.AuthConfigSpecError
 	linkedHosts := []string{}
 	report, reportAvailable := u.StatusReport.Get(resourceId)
 	if reportAvailable {
 		reason = report.Reason
 		message = report.Message
 		linkedHosts = report.LinkedHosts
+		warnings = report.Warnings
+		specErrors = report.Errors
 	}
-	looseHosts := utils.SubtractSlice(authConfig.Spec.Hosts, linkedHosts)
+	looseHosts := utils.SubtractSlice(authConfig.Spec.EnabledHosts(), linkedHosts)
 
 	// available
 	changed := updateStatusAvailable(authConfig, len(linkedHosts) > 0)
@@ -77,6 +83,12 @@ func (u *AuthConfigStatusUpdater) updateAuthConfigStatus(ctx context.Context, re
 	// summary
 	changed = updateStatusSummary(authConfig, linkedHosts) || changed
 
+	// warnings
+	changed = updateStatusWarnings(authConfig, warnings) || changed
+
+	// errors
+	changed = updateStatusErrors(authConfig, specErrors) || changed
+
 	if !authConfig.Status.Ready() {
 		err = fmt.Errorf("resource not ready")
 	}
@@ -100,9 +112,12 @@ func (u *AuthConfigStatusUpdater) updateAuthConfigStatus(ctx context.Context, re
 }
 
 func (u *AuthConfigStatusUpdater) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&api.AuthConfig{}, builder.WithPredicates(LabelSelectorPredicate(u.LabelSelector))).
-		Complete(u)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&api.AuthConfig{}, builder.WithPredicates(LabelSelectorPredicate(u.LabelSelector)))
+	if src := labelSelectorChangeSource(mgr.GetClient(), u.LabelSelector, u.Logger); src != nil {
+		b = b.WatchesRawSource(src, &handler.EnqueueRequestForObject{})
+	}
+	return b.Complete(u)
 }
 
 func updateStatusConditions(currentConditions []api.AuthConfigStatusCondition, newCondition api.AuthConfigStatusCondition) ([]api.AuthConfigStatusCondition, bool) {
@@ -185,9 +200,17 @@ func updateStatusSummary(authConfig *api.AuthConfig, newLinkedHosts []string) (c
 	if authConfig.Spec.Response != nil {
 		numResponseItems = len(authConfig.Spec.Response.Success.DynamicMetadata) + len(authConfig.Spec.Response.Success.Headers)
 	}
+	var disabledHosts []string
+	for _, host := range authConfig.Spec.Hosts {
+		if authConfig.Spec.IsHostDisabled(host) {
+			disabledHosts = append(disabledHosts, host)
+		}
+	}
+
 	new := api.AuthConfigStatusSummary{
 		Ready:                    authConfig.Status.Ready(),
 		HostsReady:               newLinkedHosts,
+		HostsDisabled:            disabledHosts,
 		NumHostsReady:            fmt.Sprintf("%d/%d", len(newLinkedHosts), len(authConfig.Spec.Hosts)),
 		NumIdentitySources:       int64(len(authConfig.Spec.Authentication)),
 		NumMetadataSources:       int64(len(authConfig.Spec.Metadata)),
@@ -203,6 +226,7 @@ func updateStatusSummary(authConfig *api.AuthConfig, newLinkedHosts []string) (c
 	changed = new.Ready != current.Ready ||
 		new.NumHostsReady != current.NumHostsReady ||
 		strings.Join(currentLinkedHosts, ",") != strings.Join(newLinkedHosts, ",") ||
+		strings.Join(current.HostsDisabled, ",") != strings.Join(disabledHosts, ",") ||
 		new.NumIdentitySources != current.NumIdentitySources ||
 		new.NumMetadataSources != current.NumMetadataSources ||
 		new.NumAuthorizationPolicies != current.NumAuthorizationPolicies ||
@@ -216,6 +240,34 @@ func updateStatusSummary(authConfig *api.AuthConfig, newLinkedHosts []string) (c
 	return
 }
 
+func updateStatusWarnings(authConfig *api.AuthConfig, newWarnings []string) (changed bool) {
+	if len(newWarnings) == 0 {
+		newWarnings = nil
+	}
+
+	changed = strings.Join(authConfig.Status.Warnings, "\n") != strings.Join(newWarnings, "\n")
+
+	if changed {
+		authConfig.Status.Warnings = newWarnings
+	}
+
+	return
+}
+
+func updateStatusErrors(authConfig *api.AuthConfig, newErrors []api.AuthConfigSpecError) (changed bool) {
+	if len(newErrors) == 0 {
+		newErrors = nil
+	}
+
+	changed = !reflect.DeepEqual(authConfig.Status.Errors, newErrors)
+
+	if changed {
+		authConfig.Status.Errors = newErrors
+	}
+
+	return
+}
+
 func issuingWristbands(authConfig *api.AuthConfig) bool {
 	if authConfig.Spec.Response != nil {
 		for _, responseConfig := range authConfig.Spec.Response.Success.Headers {
@@ -37,6 +37,41 @@ func TestAuthConfigStatusUpdater_Reconcile(t *testing.T) {
 	assert.Check(t, authConfigCheck.Status.Ready())
 }
 
+func TestAuthConfigStatusUpdater_Warnings(t *testing.T) {
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	authConfig := mockStatusUpdateAuthConfig()
+	resourceName := types.NamespacedName{Namespace: authConfig.Namespace, Name: authConfig.Name}
+	client := newTestK8sClient(&authConfig)
+	reconciler := mockStatusUpdaterReconciler(client)
+	reconciler.StatusReport.Set(resourceName.String(), api.StatusReasonReconciled, "", []string{"echo-api"})
+	reconciler.StatusReport.SetWarnings(resourceName.String(), []string{"authorization spicedb: insecure connection to the SpiceDB server"})
+
+	_, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: resourceName})
+	assert.NilError(t, err)
+
+	authConfigCheck := api.AuthConfig{}
+	_ = client.Get(context.TODO(), resourceName, &authConfigCheck)
+	assert.Check(t, authConfigCheck.Status.Ready())
+	assert.DeepEqual(t, authConfigCheck.Status.Warnings, []string{"authorization spicedb: insecure connection to the SpiceDB server"})
+
+	// warnings survive new reports of the resource until replaced
+	reconciler.StatusReport.Set(resourceName.String(), api.StatusReasonReconciled, "", []string{"echo-api"})
+	report, _ := reconciler.StatusReport.Get(resourceName.String())
+	assert.Equal(t, len(report.Warnings), 1)
+
+	// resolved
+	reconciler.StatusReport.SetWarnings(resourceName.String(), nil)
+
+	_, err = reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: resourceName})
+	assert.NilError(t, err)
+
+	_ = client.Get(context.TODO(), resourceName, &authConfigCheck)
+	assert.Check(t, authConfigCheck.Status.Ready())
+	assert.Equal(t, len(authConfigCheck.Status.Warnings), 0)
+}
+
 func TestAuthConfigStatusUpdater_MissingWatchedAuthConfigLabels(t *testing.T) {
 	mockctrl := gomock.NewController(t)
 	defer mockctrl.Finish()
@@ -160,6 +195,30 @@ func TestAuthConfigStatusUpdater_HostNotLinked(t *testing.T) {
 	assert.Equal(t, status.Summary.HostsReady[0], "my-api.com")
 }
 
+func TestAuthConfigStatusUpdater_HostDisabled(t *testing.T) {
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	authConfig := mockStatusUpdateAuthConfigWithHosts([]string{"my-api.com", "my-api.local"})
+	authConfig.Spec.DisabledHosts = []string{"my-api.local"}
+	resourceName := types.NamespacedName{Namespace: authConfig.Namespace, Name: authConfig.Name}
+	client := newTestK8sClient(&authConfig)
+	reconciler := mockStatusUpdaterReconciler(client)
+	reconciler.StatusReport.Set(resourceName.String(), api.StatusReasonReconciled, "", []string{"my-api.com"})
+
+	result, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: resourceName})
+
+	assert.Equal(t, result, ctrl.Result{})
+	assert.NilError(t, err)
+
+	authConfigCheck := api.AuthConfig{}
+	_ = client.Get(context.TODO(), resourceName, &authConfigCheck)
+	status := authConfigCheck.Status
+	assert.Check(t, status.Ready()) // disabled hosts are not expected to be linked
+	assert.DeepEqual(t, status.Summary.HostsReady, []string{"my-api.com"})
+	assert.DeepEqual(t, status.Summary.HostsDisabled, []string{"my-api.local"})
+}
+
 func mockStatusUpdateAuthConfig() api.AuthConfig {
 	return mockStatusUpdateAuthConfigWithLabelsAndHosts(map[string]string{"authorino.kuadrant.io/managed-by": "authorino"}, []string{"echo-api"})
 }
@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	api "github.com/kuadrant/authorino/api/v1beta2"
+	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/evaluators"
+	metadata_evaluators "github.com/kuadrant/authorino/pkg/evaluators/metadata"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	DefaultDependencyReconcileDelay  = 1000 // in milliseconds
+	DefaultDependencyReconcileJitter = 500  // in milliseconds
+
+	configMapRefsIndexField = "spec.configMapRefs"
+	secretRefsIndexField    = "spec.secretRefs"
+)
+
+// dependencyEventHandler enqueues the AuthConfigs that depend on a changed object (e.g. a Secret or a ConfigMap referred
+// in the AuthConfig), after a delay plus a random jitter.
+// Until the delay has passed, new events for the same AuthConfig do not enqueue it again, i.e. a burst of changes is
+// coalesced into a single reconcile of each affected AuthConfig, and the jitter spreads the reconciles of AuthConfigs
+// that depend on the same object.
+func (r *AuthConfigReconciler) dependencyEventHandler(mapFunc handler.MapFunc) handler.EventHandler {
+	return &coalescingEventHandler{
+		mapFunc: mapFunc,
+		delay:   r.DependencyReconcileDelay,
+		jitter:  r.DependencyReconcileJitter,
+	}
+}
+
+type coalescingEventHandler struct {
+	mapFunc handler.MapFunc
+	delay   time.Duration
+	jitter  time.Duration
+}
+
+func (h *coalescingEventHandler) Create(ctx context.Context, e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.Object, q)
+}
+
+func (h *coalescingEventHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.ObjectNew, q)
+}
+
+func (h *coalescingEventHandler) Delete(ctx context.Context, e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.Object, q)
+}
+
+func (h *coalescingEventHandler) Generic(ctx context.Context, e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	h.enqueue(ctx, e.Object, q)
+}
+
+func (h *coalescingEventHandler) enqueue(ctx context.Context, obj client.Object, q workqueue.RateLimitingInterface) {
+	if obj == nil {
+		return
+	}
+	for _, req := range h.mapFunc(ctx, obj) {
+		delay := h.delay
+		if h.jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(h.jitter)))
+		}
+		// the delaying queue keeps a single entry per request, at the earliest time it was added for
+		q.AddAfter(req, delay)
+	}
+}
+
+// authConfigsForConfigMap maps a ConfigMap to the AuthConfigs that read Rego policies, WASM policies or static JWKS from
+// it, so these are reloaded when the ConfigMap changes.
+func (r *AuthConfigReconciler) authConfigsForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	return r.authConfigsReferencing(ctx, configMap, configMapRefsIndexField, nil)
+}
+
+// indexConfigMapRefs indexes the AuthConfigs by the names of the ConfigMaps they refer to
+func indexConfigMapRefs(obj client.Object) []string {
+	authConfig, ok := obj.(*api.AuthConfig)
+	if !ok {
+		return nil
+	}
+	return configMapNamesReferencedBy(authConfig)
+}
+
+// configMapNamesReferencedBy returns the names of the ConfigMaps the AuthConfig refers to.
+func configMapNamesReferencedBy(authConfig *api.AuthConfig) []string {
+	var names []string
+
+	for _, authentication := range authConfig.Spec.Authentication {
+		if jwt := authentication.Jwt; jwt != nil && jwt.JwksConfigMapRef != nil {
+			names = append(names, jwt.JwksConfigMapRef.Name)
+		}
+	}
+
+	for _, authorization := range authConfig.Spec.Authorization {
+		if opa := authorization.Opa; opa != nil && opa.RegoConfigMap != nil {
+			names = append(names, opa.RegoConfigMap.Name)
+		}
+		if opa := authorization.Opa; opa != nil && opa.Wasm != nil && opa.Wasm.ModuleConfigMap != nil {
+			names = append(names, opa.Wasm.ModuleConfigMap.Name)
+		}
+	}
+
+	return names
+}
+
+// authConfigsForSecret maps a Secret to the AuthConfigs that refer to it by name, e.g. shared secrets, OAuth2 client
+// credentials, static JWKS and signing keys of Festival Wristbands.
+// Shared secrets of HTTP metadata and callbacks set to be hot-reloaded are reloaded by the sharedSecretReconciler
+// instead, so the served AuthConfigs that refer to the Secret only for these are not reconciled.
+// Secrets that store API keys and trusted root CAs are selected by label instead and handled by the SecretReconciler.
+func (r *AuthConfigReconciler) authConfigsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	return r.authConfigsReferencing(ctx, secret, secretRefsIndexField, func(authConfig *api.AuthConfig) bool {
+		served := len(r.Index.FindKeys(client.ObjectKeyFromObject(authConfig).String())) > 0
+		for _, name := range secretNamesReferencedBy(authConfig, served) {
+			if name == secret.GetName() {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// authConfigsReferencing maps an object to the AuthConfigs in its namespace that refer to it by name, as indexed in
+// indexField, and that match the references filter, if any.
+func (r *AuthConfigReconciler) authConfigsReferencing(ctx context.Context, obj client.Object, indexField string, references func(*api.AuthConfig) bool) []reconcile.Request {
+	authConfigList := api.AuthConfigList{}
+	if err := r.Client.List(ctx, &authConfigList, client.InNamespace(obj.GetNamespace()), client.MatchingFields{indexField: obj.GetName()}); err != nil {
+		r.Logger.Error(err, "failed to list authconfigs referencing the object", "object", client.ObjectKeyFromObject(obj))
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range authConfigList.Items {
+		authConfig := &authConfigList.Items[i]
+		if references == nil || references(authConfig) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(authConfig)})
+		}
+	}
+	return requests
+}
+
+// sharedSecretReconciler reloads the hot-reloaded shared secrets of the HTTP metadata and callbacks of the served
+// AuthConfigs when the Secrets they are read from change, without reconciling the AuthConfigs.
+// Deleted Secrets are not reloaded, i.e. the last value read is kept until the AuthConfig is reconciled.
+type sharedSecretReconciler struct {
+	*AuthConfigReconciler
+}
+
+func (r *sharedSecretReconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	secret := &v1.Secret{}
+	if err := r.Client.Get(ctx, req.NamespacedName, secret); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	r.reloadSharedSecrets(secret)
+	return reconcile.Result{}, nil
+}
+
+// hotReloadedSecretPredicate filters the events of the Secrets read as hot-reloaded shared secrets by any served
+// AuthConfig. Deletions are filtered out.
+func (r *sharedSecretReconciler) hotReloadedSecretPredicate() predicate.Predicate {
+	hotReloaded := func(obj client.Object) bool {
+		found := false
+		r.eachHotReloadedSharedSecret(client.ObjectKeyFromObject(obj), func(_ string, _ *metadata_evaluators.GenericHttp) {
+			found = true
+		})
+		return found
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return hotReloaded(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return hotReloaded(e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return hotReloaded(e.Object) },
+	}
+}
+
+// reloadSharedSecrets updates the hot-reloaded shared secrets of the HTTP metadata and callbacks read from the Secret.
+func (r *AuthConfigReconciler) reloadSharedSecrets(secret *v1.Secret) {
+	secretName := client.ObjectKeyFromObject(secret)
+	r.eachHotReloadedSharedSecret(secretName, func(id string, http *metadata_evaluators.GenericHttp) {
+		value, exists := secret.Data[http.SharedSecretKey]
+		if !exists {
+			r.Logger.Info("missing key in the shared secret, keeping the previous value", "authconfig", id, "secret", secretName, "key", http.SharedSecretKey)
+			return
+		}
+		http.SetSharedSecret(string(value))
+		r.Logger.V(1).Info("shared secret reloaded", "authconfig", id, "secret", secretName)
+	})
+}
+
+// eachHotReloadedSharedSecret calls f for each HTTP metadata and callback of the served AuthConfigs whose shared secret
+// is hot-reloaded from the Secret.
+func (r *AuthConfigReconciler) eachHotReloadedSharedSecret(secretName types.NamespacedName, f func(id string, http *metadata_evaluators.GenericHttp)) {
+	for _, authConfig := range r.Index.List() {
+		var configs []auth.AuthConfigEvaluator
+		configs = append(configs, authConfig.MetadataConfigs...)
+		configs = append(configs, authConfig.CallbackConfigs...)
+
+		for _, config := range configs {
+			var http *metadata_evaluators.GenericHttp
+			switch config := config.(type) {
+			case *evaluators.MetadataConfig:
+				http = config.GenericHTTP
+			case *evaluators.CallbackConfig:
+				http = config.HTTP
+			}
+			if http == nil || http.SharedSecretRef == nil || *http.SharedSecretRef != secretName {
+				continue
+			}
+			f(authConfigName(authConfig), http)
+		}
+	}
+}
+
+// indexSecretRefs indexes the AuthConfigs by the names of the Secrets they refer to
+func indexSecretRefs(obj client.Object) []string {
+	authConfig, ok := obj.(*api.AuthConfig)
+	if !ok {
+		return nil
+	}
+	return secretNamesReferencedBy(authConfig, false)
+}
+
+// secretNamesReferencedBy returns the names of the Secrets the AuthConfig refers to.
+// If skipHotReloaded, the hot-reloaded shared secrets of HTTP metadata and callbacks are left out.
+func secretNamesReferencedBy(authConfig *api.AuthConfig, skipHotReloaded bool) []string {
+	var names []string
+
+	fromHttpEndpoint := func(http *api.HttpEndpointSpec, hotReloadable bool) {
+		if http == nil {
+			return
+		}
+		if http.SharedSecret != nil && !(skipHotReloaded && hotReloadable && http.HotReloadSharedSecret) {
+			names = append(names, http.SharedSecret.Name)
+		}
+		if http.OAuth2 != nil {
+			names = append(names, http.OAuth2.ClientSecret.Name)
+		}
+	}
+
+	fromSuccessResponse := func(response api.SuccessResponseSpec) {
+		if wristband := response.Wristband; wristband != nil {
+			for _, signingKeyRef := range wristband.SigningKeyRefs {
+				if signingKeyRef != nil {
+					names = append(names, signingKeyRef.Name)
+				}
+			}
+		}
+	}
+
+	spec := authConfig.Spec
+
+	if bypass := spec.MaintenanceBypass; bypass != nil {
+		names = append(names, bypass.SharedSecretRef.Name)
+	}
+
+	for _, authentication := range spec.Authentication {
+		if introspection := authentication.OAuth2TokenIntrospection; introspection != nil && introspection.Credentials != nil {
+			names = append(names, introspection.Credentials.Name)
+		}
+		if jwt := authentication.Jwt; jwt != nil && jwt.JwksSecretRef != nil {
+			names = append(names, jwt.JwksSecretRef.Name)
+		}
+	}
+
+	for _, metadata := range spec.Metadata {
+		fromHttpEndpoint(metadata.Http, true)
+		if uma := metadata.Uma; uma != nil && uma.Credentials != nil {
+			names = append(names, uma.Credentials.Name)
+		}
+	}
+
+	for _, authorization := range spec.Authorization {
+		if opa := authorization.Opa; opa != nil && opa.External != nil {
+			fromHttpEndpoint(opa.External.HttpEndpointSpec, false)
+			if opa.External.ClientCertSecret != nil {
+				names = append(names, opa.External.ClientCertSecret.Name)
+			}
+		}
+		if spicedb := authorization.SpiceDB; spicedb != nil && spicedb.SharedSecret != nil {
+			names = append(names, spicedb.SharedSecret.Name)
+		}
+		if sar := authorization.KubernetesSubjectAccessReview; sar != nil && sar.KubeconfigSecretRef != nil {
+			names = append(names, sar.KubeconfigSecretRef.Name)
+		}
+	}
+
+	if response := spec.Response; response != nil {
+		for _, header := range response.Success.Headers {
+			fromSuccessResponse(header.SuccessResponseSpec)
+		}
+		for _, dynamicMetadata := range response.Success.DynamicMetadata {
+			fromSuccessResponse(dynamicMetadata)
+		}
+	}
+
+	for _, callback := range spec.Callbacks {
+		fromHttpEndpoint(callback.Http, true)
+	}
+
+	return names
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "github.com/kuadrant/authorino/api/v1beta2"
+	"github.com/kuadrant/authorino/pkg/evaluators"
+	"github.com/kuadrant/authorino/pkg/index"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDependencyEventHandlerCoalescesEvents(t *testing.T) {
+	authConfig := newTestAuthConfig(map[string]string{})
+	secret := newTestOAuthClientSecret()
+	client := newTestK8sClient(&authConfig, &secret)
+	reconciler := newTestAuthConfigReconciler(client, index.NewIndex())
+	reconciler.DependencyReconcileDelay = 50 * time.Millisecond
+	reconciler.DependencyReconcileJitter = 10 * time.Millisecond
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	eventHandler := reconciler.dependencyEventHandler(reconciler.authConfigsForSecret)
+	for i := 0; i < 5; i++ {
+		eventHandler.Update(context.TODO(), event.UpdateEvent{ObjectOld: &secret, ObjectNew: &secret}, queue)
+	}
+
+	assert.Equal(t, queue.Len(), 0) // delayed
+
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Equal(t, queue.Len(), 1)
+	item, _ := queue.Get()
+	assert.DeepEqual(t, item, reconcile.Request{NamespacedName: types.NamespacedName{Name: authConfig.Name, Namespace: authConfig.Namespace}})
+	queue.Done(item)
+	assert.Equal(t, queue.Len(), 0)
+}
+
+func TestDependencyEventHandlerEnqueuesEachAffectedAuthConfig(t *testing.T) {
+	authConfigA := newTestAuthConfig(map[string]string{})
+	authConfigA.Name = "auth-config-a"
+	authConfigB := newTestAuthConfig(map[string]string{})
+	authConfigB.Name = "auth-config-b"
+	authConfigC := newTestAuthConfig(map[string]string{})
+	authConfigC.Name = "auth-config-c"
+	authConfigC.Spec.Metadata = nil
+	secret := newTestOAuthClientSecret()
+	client := newTestK8sClient(&authConfigA, &authConfigB, &authConfigC, &secret)
+	reconciler := newTestAuthConfigReconciler(client, index.NewIndex())
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	eventHandler := reconciler.dependencyEventHandler(reconciler.authConfigsForSecret)
+	eventHandler.Update(context.TODO(), event.UpdateEvent{ObjectOld: &secret, ObjectNew: &secret}, queue)
+	eventHandler.Delete(context.TODO(), event.DeleteEvent{Object: &secret}, queue)
+
+	assert.Equal(t, queue.Len(), 2)
+}
+
+func TestSecretNamesReferencedBy(t *testing.T) {
+	authConfig := &api.AuthConfig{
+		Spec: api.AuthConfigSpec{
+			MaintenanceBypass: &api.MaintenanceBypassSpec{SharedSecretRef: api.SecretKeyReference{Name: "maintenance", Key: "secret"}},
+			Authentication: map[string]api.AuthenticationSpec{
+				"introspection": {AuthenticationMethodSpec: api.AuthenticationMethodSpec{OAuth2TokenIntrospection: &api.OAuth2TokenIntrospectionSpec{Credentials: &v1.LocalObjectReference{Name: "introspection-creds"}}}},
+				"jwt":           {AuthenticationMethodSpec: api.AuthenticationMethodSpec{Jwt: &api.JwtAuthenticationSpec{JwksSecretRef: &api.SecretKeyReference{Name: "jwks", Key: "jwks.json"}}}},
+			},
+			Metadata: map[string]api.MetadataSpec{
+				"http": {MetadataMethodSpec: api.MetadataMethodSpec{Http: &api.HttpEndpointSpec{
+					SharedSecret: &api.SecretKeyReference{Name: "shared-secret", Key: "secret"},
+					OAuth2:       &api.OAuth2ClientAuthentication{ClientSecret: api.SecretKeyReference{Name: "oauth2-client", Key: "secret"}},
+				}}},
+			},
+			Authorization: map[string]api.AuthorizationSpec{
+				"spicedb": {AuthorizationMethodSpec: api.AuthorizationMethodSpec{SpiceDB: &api.SpiceDBAuthorizationSpec{SharedSecret: &api.SecretKeyReference{Name: "spicedb-token", Key: "token"}}}},
+				"opa": {AuthorizationMethodSpec: api.AuthorizationMethodSpec{Opa: &api.OpaAuthorizationSpec{External: &api.ExternalOpaPolicy{
+					HttpEndpointSpec: &api.HttpEndpointSpec{Url: "https://registry/policy.rego"},
+					ClientCertSecret: &v1.LocalObjectReference{Name: "registry-client-cert"},
+				}}}},
+				"remote-sar": {AuthorizationMethodSpec: api.AuthorizationMethodSpec{KubernetesSubjectAccessReview: &api.KubernetesSubjectAccessReviewAuthorizationSpec{KubeconfigSecretRef: &api.SecretKeyReference{Name: "remote-kubeconfig", Key: "kubeconfig"}}}},
+			},
+			Response: &api.ResponseSpec{
+				Success: api.WrappedSuccessResponseSpec{
+					Headers: map[string]api.HeaderSuccessResponseSpec{
+						"wristband": {SuccessResponseSpec: api.SuccessResponseSpec{AuthResponseMethodSpec: api.AuthResponseMethodSpec{
+							Wristband: &api.WristbandAuthResponseSpec{SigningKeyRefs: []*api.WristbandSigningKeyRef{{Name: "signing-key"}}},
+						}}},
+					},
+				},
+			},
+			Callbacks: map[string]api.CallbackSpec{
+				"webhook": {CallbackMethodSpec: api.CallbackMethodSpec{Http: &api.HttpEndpointSpec{SharedSecret: &api.SecretKeyReference{Name: "webhook-secret", Key: "secret"}}}},
+			},
+		},
+	}
+
+	names := secretNamesReferencedBy(authConfig, false)
+	for _, name := range []string{"introspection-creds", "jwks", "shared-secret", "oauth2-client", "spicedb-token", "remote-kubeconfig", "registry-client-cert", "signing-key", "webhook-secret", "maintenance"} {
+		found := false
+		for _, n := range names {
+			found = found || n == name
+		}
+		assert.Check(t, found, name)
+	}
+	assert.Equal(t, len(names), 10)
+}
+
+func TestAuthConfigsForSecretInOtherNamespace(t *testing.T) {
+	authConfig := newTestAuthConfig(map[string]string{})
+	secret := newTestOAuthClientSecret()
+	secret.Namespace = "other"
+	client := newTestK8sClient(&authConfig)
+	reconciler := newTestAuthConfigReconciler(client, index.NewIndex())
+
+	assert.Equal(t, len(reconciler.authConfigsForSecret(context.TODO(), &secret)), 0)
+	assert.Equal(t, len(reconciler.authConfigsForSecret(context.TODO(), &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: authConfig.Namespace}})), 0)
+}
+
+func TestAuthConfigsForSecretMetadata(t *testing.T) {
+	authConfig := newTestAuthConfig(map[string]string{})
+	secret := newTestOAuthClientSecret()
+	client := newTestK8sClient(&authConfig, &secret)
+	reconciler := newTestAuthConfigReconciler(client, index.NewIndex())
+
+	// the secrets are watched metadata-only
+	requests := reconciler.authConfigsForSecret(context.TODO(), &metav1.PartialObjectMetadata{ObjectMeta: secret.ObjectMeta})
+	assert.DeepEqual(t, requests, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: authConfig.Name, Namespace: authConfig.Namespace}}})
+}
+
+func TestHotReloadSharedSecret(t *testing.T) {
+	authConfig := newTestAuthConfig(map[string]string{})
+	authConfig.Spec.Metadata["http"] = api.MetadataSpec{MetadataMethodSpec: api.MetadataMethodSpec{Http: &api.HttpEndpointSpec{
+		Url:                   "http://127.0.0.1:9005/metadata",
+		SharedSecret:          &api.SecretKeyReference{Name: "shared-secret", Key: "secret"},
+		HotReloadSharedSecret: true,
+	}}}
+	oauthClientSecret := newTestOAuthClientSecret()
+	sharedSecret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-secret", Namespace: authConfig.Namespace},
+		Data:       map[string][]byte{"secret": []byte("s3cr3t")},
+	}
+	client := newTestK8sClient(&authConfig, &oauthClientSecret, &sharedSecret)
+	authConfigIndex := index.NewIndex()
+	reconciler := newTestAuthConfigReconciler(client, authConfigIndex)
+
+	// not served yet => reconciled
+	assert.Equal(t, len(reconciler.authConfigsForSecret(context.TODO(), &sharedSecret)), 1)
+
+	_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: authConfig.Namespace, Name: authConfig.Name}})
+	assert.NilError(t, err)
+
+	var metadataConfig *evaluators.MetadataConfig
+	for _, config := range authConfigIndex.Get("echo-api").MetadataConfigs {
+		if c := config.(*evaluators.MetadataConfig); c.GenericHTTP != nil {
+			metadataConfig = c
+		}
+	}
+	assert.Check(t, metadataConfig != nil)
+	assert.Equal(t, metadataConfig.GenericHTTP.SharedSecret, "s3cr3t")
+
+	// served => not reconciled
+	assert.Equal(t, len(reconciler.authConfigsForSecret(context.TODO(), &sharedSecret)), 0)
+
+	sharedSecrets := &sharedSecretReconciler{AuthConfigReconciler: reconciler}
+	sharedSecretRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: sharedSecret.Namespace, Name: sharedSecret.Name}}
+	predicate := sharedSecrets.hotReloadedSecretPredicate()
+	assert.Check(t, predicate.Update(event.UpdateEvent{ObjectOld: &sharedSecret, ObjectNew: &sharedSecret}))
+	assert.Check(t, !predicate.Update(event.UpdateEvent{ObjectOld: &oauthClientSecret, ObjectNew: &oauthClientSecret}))
+	assert.Check(t, !predicate.Delete(event.DeleteEvent{Object: &sharedSecret}))
+
+	// rotated => reloaded without reconciling the authconfig
+	sharedSecret.Data["secret"] = []byte("r0t4t3d")
+	assert.NilError(t, client.Update(context.TODO(), &sharedSecret))
+	_, err = sharedSecrets.Reconcile(context.TODO(), sharedSecretRequest)
+	assert.NilError(t, err)
+	assert.Equal(t, metadataConfig.GenericHTTP.SharedSecret, "r0t4t3d")
+
+	// missing key => previous value kept
+	delete(sharedSecret.Data, "secret")
+	assert.NilError(t, client.Update(context.TODO(), &sharedSecret))
+	_, err = sharedSecrets.Reconcile(context.TODO(), sharedSecretRequest)
+	assert.NilError(t, err)
+	assert.Equal(t, metadataConfig.GenericHTTP.SharedSecret, "r0t4t3d")
+
+	// deleted => previous value kept
+	assert.NilError(t, client.Delete(context.TODO(), &sharedSecret))
+	_, err = sharedSecrets.Reconcile(context.TODO(), sharedSecretRequest)
+	assert.NilError(t, err)
+	assert.Equal(t, metadataConfig.GenericHTTP.SharedSecret, "r0t4t3d")
+
+	// other secrets the authconfig refers to are still reconciled
+	assert.Equal(t, len(reconciler.authConfigsForSecret(context.TODO(), &oauthClientSecret)), 1)
+}
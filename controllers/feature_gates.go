@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	api "github.com/kuadrant/authorino/api/v1beta2"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Feature gates of the experimental evaluator types
+const (
+	FeatureGateScopesAuthorization          = "ScopesAuthorization"
+	FeatureGateCombinedResponse             = "CombinedResponse"
+	FeatureGateRateLimitDescriptorsResponse = "RateLimitDescriptorsResponse"
+)
+
+var experimentalFeatureGates = []string{
+	FeatureGateScopesAuthorization,
+	FeatureGateCombinedResponse,
+	FeatureGateRateLimitDescriptorsResponse,
+}
+
+// FeatureGates enables or disables the experimental evaluator types, indexed by the name of the gate.
+// Experimental types whose gate is omitted are enabled.
+type FeatureGates map[string]bool
+
+// ParseFeatureGates parses a comma-separated list of `<gate>=<true|false>` pairs, e.g. "ScopesAuthorization=false"
+func ParseFeatureGates(value string) (FeatureGates, error) {
+	gates := FeatureGates{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, enabled, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid feature gate %s: missing value", pair)
+		}
+		if !isExperimentalFeatureGate(name) {
+			return nil, fmt.Errorf("unknown feature gate %s: must be one of: %s", name, strings.Join(experimentalFeatureGates, ", "))
+		}
+		b, err := strconv.ParseBool(enabled)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature gate %s: %s is not a boolean", name, enabled)
+		}
+		gates[name] = b
+	}
+	return gates, nil
+}
+
+// Enabled tells whether the feature gate is enabled
+func (g FeatureGates) Enabled(name string) bool {
+	if enabled, set := g[name]; set {
+		return enabled
+	}
+	return true
+}
+
+// check returns the errors of the evaluators of the AuthConfig whose types are disabled by the feature gates, each
+// located at the field of its evaluator
+func (g FeatureGates) check(authConfig *api.AuthConfig) error {
+	var errs []error
+
+	disabled := func(path *field.Path, evaluator, gate string) {
+		errs = append(errs, newSpecError(path, fmt.Errorf("%w: %s (%s)", errDisabledByFeatureGate, evaluator, gate)))
+	}
+
+	for name, authorization := range authConfig.Spec.Authorization {
+		if authorization.GetMethod() == api.ScopesAuthorization && !g.Enabled(FeatureGateScopesAuthorization) {
+			disabled(field.NewPath("spec", "authorization").Key(name).Child("scopes"), "authorization "+name, FeatureGateScopesAuthorization)
+		}
+	}
+
+	checkResponse := func(name string, path *field.Path, successResponse api.SuccessResponseSpec) {
+		switch method := successResponse.GetMethod(); {
+		case method == api.CombinedAuthResponse && !g.Enabled(FeatureGateCombinedResponse):
+			disabled(path.Child("combined"), "response "+name, FeatureGateCombinedResponse)
+		case method == api.RateLimitDescriptorsAuthResponse && !g.Enabled(FeatureGateRateLimitDescriptorsResponse):
+			disabled(path.Child("rateLimitDescriptors"), "response "+name, FeatureGateRateLimitDescriptorsResponse)
+		}
+	}
+	if response := authConfig.Spec.Response; response != nil {
+		successPath := field.NewPath("spec", "response", "success")
+		for name, header := range response.Success.Headers {
+			checkResponse(name, successPath.Child("headers").Key(name), header.SuccessResponseSpec)
+		}
+		for name, dynamicMetadata := range response.Success.DynamicMetadata {
+			checkResponse(name, successPath.Child("dynamicMetadata").Key(name), dynamicMetadata)
+		}
+	}
+
+	return joinSpecErrors(errs...)
+}
+
+func isExperimentalFeatureGate(name string) bool {
+	for _, gate := range experimentalFeatureGates {
+		if gate == name {
+			return true
+		}
+	}
+	return false
+}
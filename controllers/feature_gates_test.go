@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/kuadrant/authorino/api/v1beta2"
+	"github.com/kuadrant/authorino/pkg/index"
+
+	"gotest.tools/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	gates, err := ParseFeatureGates("")
+	assert.NilError(t, err)
+	assert.Check(t, gates.Enabled(FeatureGateScopesAuthorization))
+
+	gates, err = ParseFeatureGates("ScopesAuthorization=false, CombinedResponse=true")
+	assert.NilError(t, err)
+	assert.Check(t, !gates.Enabled(FeatureGateScopesAuthorization))
+	assert.Check(t, gates.Enabled(FeatureGateCombinedResponse))
+	assert.Check(t, gates.Enabled(FeatureGateRateLimitDescriptorsResponse))
+
+	_, err = ParseFeatureGates("Unknown=false")
+	assert.Error(t, err, "unknown feature gate Unknown: must be one of: ScopesAuthorization, CombinedResponse, RateLimitDescriptorsResponse")
+
+	_, err = ParseFeatureGates("ScopesAuthorization")
+	assert.Error(t, err, "invalid feature gate ScopesAuthorization: missing value")
+
+	_, err = ParseFeatureGates("ScopesAuthorization=no")
+	assert.Error(t, err, "invalid feature gate ScopesAuthorization: no is not a boolean")
+}
+
+func TestTranslateAuthConfigWithFeatureGates(t *testing.T) {
+	authConfig := &api.AuthConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "auth-config-1", Namespace: "authorino"},
+		Spec: api.AuthConfigSpec{
+			Hosts: []string{"echo-api"},
+			Authorization: map[string]api.AuthorizationSpec{
+				"scopes": {
+					AuthorizationMethodSpec: api.AuthorizationMethodSpec{
+						Scopes: &api.ScopesAuthorizationSpec{
+							Required: api.ValueOrSelector{Selector: "context.request.http.method"},
+							Mapping:  map[string]string{"GET": "read"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	reconciler := newTestAuthConfigReconciler(newTestK8sClient(), index.NewIndex())
+
+	// disabled
+	reconciler.FeatureGates = FeatureGates{FeatureGateScopesAuthorization: false}
+	_, err := reconciler.translateAuthConfig(context.TODO(), authConfig)
+	assert.Error(t, err, "evaluator types disabled by feature gate: authorization scopes (ScopesAuthorization)")
+
+	// enabled
+	reconciler.FeatureGates = FeatureGates{FeatureGateScopesAuthorization: true}
+	config, err := reconciler.translateAuthConfig(context.TODO(), authConfig)
+	assert.NilError(t, err)
+	assert.Equal(t, len(config.AuthorizationConfigs), 1)
+
+	// enabled by default
+	reconciler.FeatureGates = nil
+	_, err = reconciler.translateAuthConfig(context.TODO(), authConfig)
+	assert.NilError(t, err)
+}
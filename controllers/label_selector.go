@@ -1,10 +1,22 @@
 package controllers
 
 import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	api "github.com/kuadrant/authorino/api/v1beta2"
+	"github.com/kuadrant/authorino/pkg/workers"
+
+	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type WatchedObject interface {
@@ -43,3 +55,173 @@ func ToLabelSelector(s string) labels.Selector {
 		return selector
 	}
 }
+
+func NewReloadableLabelSelector(selector labels.Selector) *ReloadableLabelSelector {
+	return &ReloadableLabelSelector{selector: selector}
+}
+
+// ReloadableLabelSelector is a label selector that can be replaced at runtime.
+// It delegates to the current selector, so it can be used wherever a labels.Selector is expected.
+type ReloadableLabelSelector struct {
+	selector  labels.Selector
+	listeners []func()
+	mu        sync.RWMutex
+}
+
+// Set replaces the current selector and notifies the listeners in case it changed
+func (s *ReloadableLabelSelector) Set(selector labels.Selector) bool {
+	s.mu.Lock()
+	if s.selector.String() == selector.String() {
+		s.mu.Unlock()
+		return false
+	}
+	s.selector = selector
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+	return true
+}
+
+// OnChange registers a function to be called whenever the selector changes
+func (s *ReloadableLabelSelector) OnChange(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, f)
+}
+
+func (s *ReloadableLabelSelector) current() labels.Selector {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.selector
+}
+
+// impl:labels.Selector
+
+func (s *ReloadableLabelSelector) Matches(l labels.Labels) bool {
+	return s.current().Matches(l)
+}
+
+func (s *ReloadableLabelSelector) Empty() bool {
+	return s.current().Empty()
+}
+
+func (s *ReloadableLabelSelector) String() string {
+	return s.current().String()
+}
+
+func (s *ReloadableLabelSelector) Add(r ...labels.Requirement) labels.Selector {
+	return s.current().Add(r...)
+}
+
+func (s *ReloadableLabelSelector) Requirements() (labels.Requirements, bool) {
+	return s.current().Requirements()
+}
+
+func (s *ReloadableLabelSelector) DeepCopySelector() labels.Selector {
+	return s.current().DeepCopySelector()
+}
+
+func (s *ReloadableLabelSelector) RequiresExactMatch(label string) (string, bool) {
+	return s.current().RequiresExactMatch(label)
+}
+
+var _ labels.Selector = (*ReloadableLabelSelector)(nil)
+
+// WatchLabelSelectorFile reads the label selector from a file (e.g. a mounted ConfigMap) on a given interval (in
+// seconds) and updates the reloadable selector whenever the contents of the file change
+func WatchLabelSelectorFile(ctx context.Context, path string, interval int, selector *ReloadableLabelSelector, logger logr.Logger) (workers.Worker, error) {
+	reload := func() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Error(err, "failed to read label selector file", "path", path)
+			return
+		}
+		newSelector, err := labels.Parse(strings.TrimSpace(string(content)))
+		if err != nil {
+			logger.Error(err, "invalid label selector", "path", path)
+			return
+		}
+		if selector.Set(newSelector) {
+			logger.Info("label selector changed", "selector", newSelector.String())
+		}
+	}
+	reload()
+	return workers.StartWorker(ctx, "label_selector", interval, reload)
+}
+
+// labelSelectorChangeSource returns a source of events that triggers the reconciliation of all AuthConfigs whenever the
+// label selector is reloaded, so newly matching resources are added and no longer matching ones are cleaned up.
+// Returns nil if the selector is not reloadable.
+func labelSelectorChangeSource(c client.Reader, selector labels.Selector, logger logr.Logger) source.Source {
+	reloadable, ok := selector.(*ReloadableLabelSelector)
+	if !ok {
+		return nil
+	}
+	return &labelSelectorChange{client: c, selector: reloadable, logger: logger}
+}
+
+// labelSelectorChange is a source of events of all AuthConfigs, triggered by changes of the label selector.
+// Changes that happen while the AuthConfigs are being listed are coalesced into a single new round of events.
+// Nothing is listed until the source is started, i.e. unless the controller is running (e.g. elected leader).
+type labelSelectorChange struct {
+	client   client.Reader
+	selector *ReloadableLabelSelector
+	logger   logr.Logger
+}
+
+func (s *labelSelectorChange) Start(ctx context.Context, h handler.EventHandler, queue workqueue.RateLimitingInterface, predicates ...predicate.Predicate) error {
+	changed := make(chan struct{}, 1)
+	s.selector.OnChange(func() {
+		select {
+		case changed <- struct{}{}:
+		default: // a change is already pending
+		}
+	})
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			}
+			authConfigs, err := listAllAuthConfigs(ctx, s.client)
+			if err != nil {
+				s.logger.Error(err, "failed to list authconfigs after label selector changed")
+				continue
+			}
+			for i := range authConfigs {
+				e := event.GenericEvent{Object: &authConfigs[i]}
+				if acceptsGeneric(e, predicates) {
+					h.Generic(ctx, e, queue)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *labelSelectorChange) String() string {
+	return "label selector change"
+}
+
+func acceptsGeneric(e event.GenericEvent, predicates []predicate.Predicate) bool {
+	for _, p := range predicates {
+		if !p.Generic(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func listAllAuthConfigs(ctx context.Context, c client.Reader) ([]api.AuthConfig, error) {
+	authConfigList := api.AuthConfigList{}
+	if err := c.List(ctx, &authConfigList); err != nil {
+		return nil, err
+	}
+	return authConfigList.Items, nil
+}
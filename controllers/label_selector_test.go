@@ -1,16 +1,26 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	mock_controllers "github.com/kuadrant/authorino/controllers/mocks"
 	mock_client "github.com/kuadrant/authorino/controllers/mocks/controller-runtime/client"
+	"github.com/kuadrant/authorino/pkg/log"
 
 	"github.com/golang/mock/gomock"
 	"gotest.tools/assert"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 func TestWatched(t *testing.T) {
@@ -117,3 +127,88 @@ func TestToLabelSelector(t *testing.T) {
 	reqs, _ = selector.Requirements()
 	assert.Equal(t, len(reqs), 0)
 }
+
+func TestReloadableLabelSelector(t *testing.T) {
+	selector := NewReloadableLabelSelector(ToLabelSelector("audience=echo-api"))
+
+	var notified int
+	selector.OnChange(func() { notified++ })
+
+	echoAPI := labels.Set{"audience": "echo-api"}
+	otherAPI := labels.Set{"audience": "other-api"}
+
+	assert.Check(t, selector.Matches(echoAPI))
+	assert.Check(t, !selector.Matches(otherAPI))
+	assert.Equal(t, selector.String(), "audience=echo-api")
+
+	assert.Check(t, !selector.Set(ToLabelSelector("audience=echo-api"))) // unchanged
+	assert.Equal(t, notified, 0)
+
+	assert.Check(t, selector.Set(ToLabelSelector("audience=other-api")))
+	assert.Equal(t, notified, 1)
+	assert.Check(t, !selector.Matches(echoAPI))
+	assert.Check(t, selector.Matches(otherAPI))
+	assert.Equal(t, selector.String(), "audience=other-api")
+}
+
+func TestWatchLabelSelectorFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selector")
+	_ = os.WriteFile(path, []byte("audience=echo-api\n"), 0644)
+
+	selector := NewReloadableLabelSelector(ToLabelSelector(""))
+	changed := make(chan struct{}, 1)
+	selector.OnChange(func() { changed <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := WatchLabelSelectorFile(ctx, path, 1, selector, log.WithName("test"))
+	assert.NilError(t, err)
+	<-changed // initial read
+	assert.Equal(t, selector.String(), "audience=echo-api")
+
+	_ = os.WriteFile(path, []byte("audience=other-api"), 0644)
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("label selector not reloaded")
+	}
+	assert.Equal(t, selector.String(), "audience=other-api")
+
+	// invalid selectors are ignored
+	_ = os.WriteFile(path, []byte("audience in ("), 0644)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, selector.String(), "audience=other-api")
+}
+
+func TestLabelSelectorChangeSource(t *testing.T) {
+	authConfig := newTestAuthConfig(map[string]string{})
+	selector := NewReloadableLabelSelector(ToLabelSelector("audience=echo-api"))
+
+	src := labelSelectorChangeSource(newTestK8sClient(&authConfig), selector, log.WithName("test"))
+	assert.Check(t, src != nil)
+	assert.Check(t, labelSelectorChangeSource(nil, ToLabelSelector("audience=echo-api"), log.WithName("test")) == nil)
+
+	// not started (e.g. not the leader) => changes do not block
+	assert.Check(t, selector.Set(ToLabelSelector("audience=other-api")))
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NilError(t, src.Start(ctx, &handler.EnqueueRequestForObject{}, queue))
+
+	assert.Check(t, selector.Set(ToLabelSelector("audience=echo-api")))
+	assert.Check(t, selector.Set(ToLabelSelector("audience=other-api")))
+
+	item, _ := queue.Get()
+	assert.DeepEqual(t, item, reconcile.Request{NamespacedName: types.NamespacedName{Name: authConfig.Name, Namespace: authConfig.Namespace}})
+	queue.Done(item)
+
+	// stopped => changes do not block
+	cancel()
+	for i := 0; i < 3; i++ {
+		selector.Set(ToLabelSelector(fmt.Sprintf("audience=api-%d", i)))
+	}
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	DefaultMaxConcurrentReconciles   = 1
+	DefaultReconcileRetryBaseDelay   = 5       // in milliseconds
+	DefaultReconcileRetryMaxDelay    = 1000000 // in milliseconds
+	DefaultReconcileRateLimiterQPS   = 10
+	DefaultReconcileRateLimiterBurst = 100
+)
+
+// NewReconcileRateLimiter builds the rate limiter of the work queue of a controller, with the same shape as the
+// default one of controller-runtime: a per-item exponential backoff of the retries, from baseDelay up to maxDelay,
+// combined with an overall token bucket of qps and burst
+func NewReconcileRateLimiter(baseDelay, maxDelay time.Duration, qps, burst int) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	gojson "encoding/json"
+	"strings"
+
+	api "github.com/kuadrant/authorino/api/v1beta2"
+	"github.com/kuadrant/authorino/pkg/index"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fingerprintLabel is the label of the translated AuthConfigs that holds the fingerprint of the spec
+const fingerprintLabel = "fingerprint"
+
+var servedHostDesc = prometheus.NewDesc(
+	"authconfig_served_host_info",
+	"Hosts served from the index, with the authconfig and the fingerprint of the spec serving each (always 1).",
+	[]string{"host", "namespace", "authconfig", "fingerprint"},
+	nil,
+)
+
+// authConfigFingerprint is a hash of the spec of an AuthConfig, to tell whether two instances of Authorino serve the
+// same config for a host.
+// The spec refers to the Secrets by name only; the values of the Secrets are not part of the fingerprint.
+func authConfigFingerprint(authConfig *api.AuthConfig) string {
+	spec, err := gojson.Marshal(authConfig.Spec)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(spec)
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// NewServedHostsCollector returns a collector of the hosts served from the index, read from the index at scrape time
+func NewServedHostsCollector(index index.Index) prometheus.Collector {
+	return &servedHostsCollector{index: index}
+}
+
+type servedHostsCollector struct {
+	index index.Index
+}
+
+func (c *servedHostsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- servedHostDesc
+}
+
+// Collect reports the hosts of the index. Hosts indexed at bootstrap and not reconciled yet are reported with an empty
+// fingerprint. Hosts of evicted AuthConfigs are not served, thus not reported.
+func (c *servedHostsCollector) Collect(ch chan<- prometheus.Metric) {
+	for host, id := range c.index.Keys() {
+		namespace, name, _ := strings.Cut(id, string(types.Separator))
+		var fingerprint string
+		if authConfig := c.index.Get(host); authConfig != nil {
+			if authConfig.Evicted {
+				continue
+			}
+			fingerprint = authConfig.Labels[fingerprintLabel]
+		}
+		ch <- prometheus.MustNewConstMetric(servedHostDesc, prometheus.GaugeValue, 1, host, namespace, name, fingerprint)
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	api "github.com/kuadrant/authorino/api/v1beta2"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Machine-readable reasons of the errors in the spec of an AuthConfig
+const (
+	SpecErrorReasonInvalid         = "Invalid"
+	SpecErrorReasonNotFound        = "NotFound"
+	SpecErrorReasonKeyNotFound     = "KeyNotFound"
+	SpecErrorReasonFeatureDisabled = "FeatureDisabled"
+	SpecErrorReasonUnavailable     = "Unavailable"
+)
+
+var (
+	errMissingKey            = errors.New("missing key")
+	errDisabledByFeatureGate = errors.New("evaluator types disabled by feature gate")
+)
+
+// SpecError is an error translating an AuthConfig, located by the path of the field of the spec that caused it
+type SpecError struct {
+	// Path of the field that caused the error, e.g. spec.authentication[keycloak].jwt
+	Field string
+	// Machine-readable reason of the error, e.g. NotFound
+	Reason string
+
+	err error
+}
+
+// newSpecError locates the error at the field of the spec, unless it is already located at a more specific field
+func newSpecError(path *field.Path, err error) error {
+	if err == nil {
+		return nil
+	}
+	var specErr *SpecError
+	if errors.As(err, &specErr) {
+		return err
+	}
+	return &SpecError{Field: path.String(), Reason: specErrorReason(err), err: err}
+}
+
+func (e *SpecError) Error() string {
+	return e.err.Error()
+}
+
+func (e *SpecError) Unwrap() error {
+	return e.err
+}
+
+// specErrors are the errors translating an AuthConfig, each possibly located at a different field of the spec
+type specErrors []error
+
+func (e specErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e specErrors) Unwrap() []error {
+	return e
+}
+
+// joinSpecErrors joins the errors sorted by message, or returns the only one, or nil if there are none
+func joinSpecErrors(errs ...error) error {
+	var joined specErrors
+	for _, err := range errs {
+		switch e := err.(type) {
+		case nil:
+		case specErrors:
+			joined = append(joined, e...)
+		default:
+			joined = append(joined, err)
+		}
+	}
+	switch len(joined) {
+	case 0:
+		return nil
+	case 1:
+		return joined[0]
+	}
+	sort.SliceStable(joined, func(i, j int) bool { return joined[i].Error() < joined[j].Error() })
+	return joined
+}
+
+func specErrorReason(err error) string {
+	switch {
+	case errors.Is(err, errMissingKey):
+		return SpecErrorReasonKeyNotFound
+	case errors.Is(err, errDisabledByFeatureGate):
+		return SpecErrorReasonFeatureDisabled
+	case k8serrors.IsNotFound(err):
+		return SpecErrorReasonNotFound
+	case isTransientAPIError(err):
+		return SpecErrorReasonUnavailable
+	default:
+		return SpecErrorReasonInvalid
+	}
+}
+
+// specErrorStatus returns all the errors located in the spec to report in the status of the resource
+func specErrorStatus(err error) []api.AuthConfigSpecError {
+	var status []api.AuthConfigSpecError
+	switch e := err.(type) {
+	case nil:
+	case *SpecError:
+		status = append(status, api.AuthConfigSpecError{Field: e.Field, Reason: e.Reason, Message: e.Error()})
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			status = append(status, specErrorStatus(err)...)
+		}
+	case interface{ Unwrap() error }:
+		status = specErrorStatus(e.Unwrap())
+	}
+	return status
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	api "github.com/kuadrant/authorino/api/v1beta2"
 	"github.com/kuadrant/authorino/pkg/utils"
 )
 
@@ -34,10 +35,34 @@ func (m *StatusReportMap) Set(id, reason, message string, hosts []string) {
 		Reason:        reason,
 		Message:       message,
 		LinkedHosts:   hosts,
+		Warnings:      m.statuses[id].Warnings,
 		LastUpdatedAt: time.Now(),
 	}
 }
 
+// SetWarnings replaces the warnings reported for the resource, keeping the rest of the report.
+func (m *StatusReportMap) SetWarnings(id string, warnings []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := m.statuses[id]
+	status.Warnings = warnings
+	status.LastUpdatedAt = time.Now()
+	m.statuses[id] = status
+}
+
+// SetErrors sets the errors found in the spec of the resource, keeping the rest of the report.
+// The errors are cleared by the next call to Set.
+func (m *StatusReportMap) SetErrors(id string, specErrors []api.AuthConfigSpecError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := m.statuses[id]
+	status.Errors = specErrors
+	status.LastUpdatedAt = time.Now()
+	m.statuses[id] = status
+}
+
 func (m *StatusReportMap) ReadAll() map[string]StatusReport {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -56,5 +81,7 @@ type StatusReport struct {
 	Reason        string
 	Message       string
 	LinkedHosts   []string
+	Warnings      []string
+	Errors        []api.AuthConfigSpecError
 	LastUpdatedAt time.Time
 }
@@ -22,6 +22,7 @@ import (
 	"crypto/tls"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
 	"os"
@@ -30,14 +31,20 @@ import (
 	v1beta1 "github.com/kuadrant/authorino/api/v1beta1"
 	v1beta2 "github.com/kuadrant/authorino/api/v1beta2"
 	"github.com/kuadrant/authorino/controllers"
+	"github.com/kuadrant/authorino/pkg/audit"
+	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/evaluators"
+	metadata_evaluators "github.com/kuadrant/authorino/pkg/evaluators/metadata"
+	response_evaluators "github.com/kuadrant/authorino/pkg/evaluators/response"
 	"github.com/kuadrant/authorino/pkg/health"
 	"github.com/kuadrant/authorino/pkg/index"
+	"github.com/kuadrant/authorino/pkg/json"
 	"github.com/kuadrant/authorino/pkg/log"
 	"github.com/kuadrant/authorino/pkg/metrics"
 	"github.com/kuadrant/authorino/pkg/service"
 	"github.com/kuadrant/authorino/pkg/trace"
 	"github.com/kuadrant/authorino/pkg/utils"
+	"github.com/kuadrant/authorino/pkg/workers"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -45,6 +52,7 @@ import (
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/go-logr/logr"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -55,7 +63,9 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -66,8 +76,10 @@ import (
 )
 
 const (
-	gRPCMaxConcurrentStreams = 10000
-	leaderElectionIDSuffix   = "authorino.kuadrant.io"
+	gRPCMaxConcurrentStreams           = 10000
+	leaderElectionIDSuffix             = "authorino.kuadrant.io"
+	labelSelectorFileReloadInterval    = 10 // in seconds
+	subjectOverridesFileReloadInterval = 10 // in seconds
 )
 
 var (
@@ -106,23 +118,68 @@ type commonServerOptions struct {
 
 type authServerOptions struct {
 	commonServerOptions
-	watchNamespace                 string
-	watchedAuthConfigLabelSelector string
-	watchedSecretLabelSelector     string
-	allowSupersedingHostSubsets    bool
-	timeout                        int
-	extAuthGRPCPort                int
-	extAuthHTTPPort                int
-	tlsCertPath                    string
-	tlsCertKeyPath                 string
-	oidcHTTPPort                   int
-	oidcTLSCertPath                string
-	oidcTLSCertKeyPath             string
-	evaluatorCacheSize             int
-	deepMetricsEnabled             bool
-	webhookServicePort             int
-	enableLeaderElection           bool
-	maxHttpRequestBodySize         int64
+	watchNamespace                  string
+	watchedAuthConfigLabelSelector  string
+	authConfigLabelSelectorFile     string
+	watchedSecretLabelSelector      string
+	allowSupersedingHostSubsets     bool
+	dependencyReconcileDelay        int
+	dependencyReconcileJitter       int
+	evictAfterReconcileFailures     int
+	maxAuthConfigTimeout            int
+	maxConcurrentReconciles         int
+	reconcileRetryBaseDelay         int
+	reconcileRetryMaxDelay          int
+	reconcileRateLimiterQPS         int
+	reconcileRateLimiterBurst       int
+	timeout                         int
+	extAuthGRPCPort                 int
+	grpcMaxConcurrentStreams        int
+	grpcKeepaliveTime               int
+	grpcKeepaliveTimeout            int
+	grpcKeepaliveMinTime            int
+	grpcKeepaliveWithoutStream      bool
+	grpcMaxConnectionIdle           int
+	grpcMaxConnectionAge            int
+	grpcMaxConnectionAgeGrace       int
+	extAuthHTTPPort                 int
+	tlsCertPath                     string
+	tlsCertKeyPath                  string
+	oidcHTTPPort                    int
+	oidcTLSCertPath                 string
+	oidcTLSCertKeyPath              string
+	diagnosticsHTTPHost             string
+	diagnosticsHTTPPort             int
+	evaluatorCacheSize              int
+	evaluatorCacheDefaultTTL        int
+	evaluatorCacheDefaultMaxEntries int
+	evaluatorCacheMaxTTL            int
+	evaluatorCacheMaxEntries        int
+	deepMetricsEnabled              bool
+	webhookServicePort              int
+	enableLeaderElection            bool
+	maxHttpRequestBodySize          int64
+	maxRequestBodyCaptureSize       int64
+	conditionOutcomesHeader         string
+	distinguishAnonymousIdentity    bool
+	trimCredentials                 bool
+	jsonCodec                       string
+	unknownHostDecision             string
+	subjectOverridesFile            string
+	subjectOverridesSelector        string
+	wristbandMinRSAKeySize          int
+	outboundSigningKeyPath          string
+	outboundSigningKeyAlgorithm     string
+	outboundSigningIssuer           string
+	outboundSigningHeader           string
+	auditWebhookURL                 string
+	auditQueueSize                  int
+	auditBatchSize                  int
+	auditFlushInterval              int
+	auditMaxRetries                 int
+	featureGates                    string
+	workerMaxPending                int
+	workerQueuePolicy               string
 }
 
 type webhookServerOptions struct {
@@ -170,21 +227,66 @@ func authServerCmd(opts *authServerOptions) *cobra.Command {
 
 	cmd.PersistentFlags().StringVar(&opts.watchNamespace, "watch-namespace", utils.EnvVar("WATCH_NAMESPACE", ""), "Kubernetes namespace to watch")
 	cmd.PersistentFlags().StringVar(&opts.watchedAuthConfigLabelSelector, "auth-config-label-selector", utils.EnvVar("AUTH_CONFIG_LABEL_SELECTOR", ""), "Kubernetes label selector to filter AuthConfig resources to watch")
+	cmd.PersistentFlags().StringVar(&opts.authConfigLabelSelectorFile, "auth-config-label-selector-file", utils.EnvVar("AUTH_CONFIG_LABEL_SELECTOR_FILE", ""), "Path to a file in the file system (e.g. mounted from a ConfigMap) with the Kubernetes label selector to filter AuthConfig resources to watch - the file is watched for changes and supersedes --auth-config-label-selector")
 	cmd.PersistentFlags().StringVar(&opts.watchedSecretLabelSelector, "secret-label-selector", utils.EnvVar("SECRET_LABEL_SELECTOR", "authorino.kuadrant.io/managed-by=authorino"), "Kubernetes label selector to filter Secret resources to watch")
 	cmd.PersistentFlags().BoolVar(&opts.allowSupersedingHostSubsets, "allow-superseding-host-subsets", false, "Enable AuthConfigs to supersede strict host subsets of supersets already taken")
+	cmd.PersistentFlags().IntVar(&opts.dependencyReconcileDelay, "dependency-reconcile-delay", utils.EnvVar("DEPENDENCY_RECONCILE_DELAY", controllers.DefaultDependencyReconcileDelay), "Delay of the reconciliation of AuthConfigs triggered by changes of the Secrets and ConfigMaps they refer to, during which further changes are coalesced - in milliseconds")
+	cmd.PersistentFlags().IntVar(&opts.dependencyReconcileJitter, "dependency-reconcile-jitter", utils.EnvVar("DEPENDENCY_RECONCILE_JITTER", controllers.DefaultDependencyReconcileJitter), "Maximum random jitter added to the delay of the reconciliation of AuthConfigs triggered by changes of the Secrets and ConfigMaps they refer to - in milliseconds")
+	cmd.PersistentFlags().IntVar(&opts.evictAfterReconcileFailures, "evict-after-reconcile-failures", utils.EnvVar("EVICT_AFTER_RECONCILE_FAILURES", 0), "Number of consecutive reconcile failures of an AuthConfig after which its last valid config stops being served, failing closed for its hosts - 0 to keep serving the last valid config indefinitely")
+	cmd.PersistentFlags().IntVar(&opts.maxAuthConfigTimeout, "max-authconfig-timeout", utils.EnvVar("MAX_AUTHCONFIG_TIMEOUT", 0), "Maximum timeout of the auth pipeline that an AuthConfig can set to override the global timeout - in milliseconds; 0 for no limit")
+	cmd.PersistentFlags().IntVar(&opts.maxConcurrentReconciles, "max-concurrent-reconciles", utils.EnvVar("MAX_CONCURRENT_RECONCILES", controllers.DefaultMaxConcurrentReconciles), "Maximum number of AuthConfigs reconciled concurrently")
+	cmd.PersistentFlags().IntVar(&opts.reconcileRetryBaseDelay, "reconcile-retry-base-delay", utils.EnvVar("RECONCILE_RETRY_BASE_DELAY", controllers.DefaultReconcileRetryBaseDelay), "Base delay of the exponential backoff of the retries of failed reconciles of AuthConfigs - in milliseconds")
+	cmd.PersistentFlags().IntVar(&opts.reconcileRetryMaxDelay, "reconcile-retry-max-delay", utils.EnvVar("RECONCILE_RETRY_MAX_DELAY", controllers.DefaultReconcileRetryMaxDelay), "Maximum delay of the exponential backoff of the retries of failed reconciles of AuthConfigs - in milliseconds")
+	cmd.PersistentFlags().IntVar(&opts.reconcileRateLimiterQPS, "reconcile-rate-limiter-qps", utils.EnvVar("RECONCILE_RATE_LIMITER_QPS", controllers.DefaultReconcileRateLimiterQPS), "Overall rate of reconciles of AuthConfigs enqueued per second")
+	cmd.PersistentFlags().IntVar(&opts.reconcileRateLimiterBurst, "reconcile-rate-limiter-burst", utils.EnvVar("RECONCILE_RATE_LIMITER_BURST", controllers.DefaultReconcileRateLimiterBurst), "Burst of reconciles of AuthConfigs enqueued above the overall rate")
 	cmd.PersistentFlags().IntVar(&opts.timeout, "timeout", utils.EnvVar("TIMEOUT", 0), "Server timeout - in milliseconds")
 	cmd.PersistentFlags().IntVar(&opts.extAuthGRPCPort, "ext-auth-grpc-port", utils.EnvVar("EXT_AUTH_GRPC_PORT", 50051), "Port number of authorization server - gRPC interface")
+	cmd.PersistentFlags().IntVar(&opts.grpcMaxConcurrentStreams, "grpc-max-concurrent-streams", utils.EnvVar("GRPC_MAX_CONCURRENT_STREAMS", gRPCMaxConcurrentStreams), "Maximum number of concurrent streams per HTTP/2 connection to the authorization server - gRPC interface")
+	cmd.PersistentFlags().IntVar(&opts.grpcKeepaliveTime, "grpc-keepalive-time", utils.EnvVar("GRPC_KEEPALIVE_TIME", 0), "Idle time after which the authorization server pings the client to check if the connection is alive - gRPC interface; in seconds; 0 for the gRPC default (2 hours)")
+	cmd.PersistentFlags().IntVar(&opts.grpcKeepaliveTimeout, "grpc-keepalive-timeout", utils.EnvVar("GRPC_KEEPALIVE_TIMEOUT", 0), "Time the authorization server waits for the response to a keepalive ping before closing the connection - gRPC interface; in seconds; 0 for the gRPC default (20 seconds)")
+	cmd.PersistentFlags().IntVar(&opts.grpcKeepaliveMinTime, "grpc-keepalive-min-time", utils.EnvVar("GRPC_KEEPALIVE_MIN_TIME", 0), "Minimum interval between keepalive pings from the client, under which the authorization server closes the connection - gRPC interface; in seconds; 0 for the gRPC default (5 minutes)")
+	cmd.PersistentFlags().BoolVar(&opts.grpcKeepaliveWithoutStream, "grpc-keepalive-permit-without-stream", utils.EnvVar("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", false), "Allow keepalive pings from the client even when there are no active streams - gRPC interface")
+	cmd.PersistentFlags().IntVar(&opts.grpcMaxConnectionIdle, "grpc-max-connection-idle", utils.EnvVar("GRPC_MAX_CONNECTION_IDLE", 0), "Idle time after which the authorization server closes the connection - gRPC interface; in seconds; 0 for no limit")
+	cmd.PersistentFlags().IntVar(&opts.grpcMaxConnectionAge, "grpc-max-connection-age", utils.EnvVar("GRPC_MAX_CONNECTION_AGE", 0), "Maximum age of a connection to the authorization server before it is gracefully closed - gRPC interface; in seconds; 0 for no limit")
+	cmd.PersistentFlags().IntVar(&opts.grpcMaxConnectionAgeGrace, "grpc-max-connection-age-grace", utils.EnvVar("GRPC_MAX_CONNECTION_AGE_GRACE", 0), "Time the authorization server waits for the pending RPCs to complete after the maximum age of the connection, before forcibly closing it - gRPC interface; in seconds; 0 for no limit")
 	cmd.PersistentFlags().IntVar(&opts.extAuthHTTPPort, "ext-auth-http-port", utils.EnvVar("EXT_AUTH_HTTP_PORT", 5001), "Port number of authorization server - raw HTTP interface")
 	cmd.PersistentFlags().StringVar(&opts.tlsCertPath, "tls-cert", utils.EnvVar("TLS_CERT", ""), "Path to the public TLS server certificate file in the file system - authorization server")
 	cmd.PersistentFlags().StringVar(&opts.tlsCertKeyPath, "tls-cert-key", utils.EnvVar("TLS_CERT_KEY", ""), "Path to the private TLS server certificate key file in the file system - authorization server")
 	cmd.PersistentFlags().IntVar(&opts.oidcHTTPPort, "oidc-http-port", utils.EnvVar("OIDC_HTTP_PORT", 8083), "Port number of OIDC Discovery server for Festival Wristband tokens")
 	cmd.PersistentFlags().StringVar(&opts.oidcTLSCertPath, "oidc-tls-cert", utils.EnvVar("OIDC_TLS_CERT", ""), "Path to the public TLS server certificate file in the file system - Festival Wristband OIDC Discovery server")
 	cmd.PersistentFlags().StringVar(&opts.oidcTLSCertKeyPath, "oidc-tls-cert-key", utils.EnvVar("OIDC_TLS_CERT_KEY", ""), "Path to the private TLS server certificate key file in the file system - Festival Wristband OIDC Discovery server")
+	cmd.PersistentFlags().IntVar(&opts.diagnosticsHTTPPort, "diagnostics-http-port", utils.EnvVar("DIAGNOSTICS_HTTP_PORT", 0), "Port number of the admin server to evaluate synthetic requests against the AuthConfigs in dry-run mode, returning the decision and the trace of the evaluators - 0 to disable")
+	cmd.PersistentFlags().StringVar(&opts.diagnosticsHTTPHost, "diagnostics-http-host", utils.EnvVar("DIAGNOSTICS_HTTP_HOST", "127.0.0.1"), "Network interface the admin server to evaluate synthetic requests binds to - the server has no authentication; empty for all interfaces")
 	cmd.PersistentFlags().IntVar(&opts.evaluatorCacheSize, "evaluator-cache-size", utils.EnvVar("EVALUATOR_CACHE_SIZE", 1), "Cache size of each Authorino evaluator if enabled in the AuthConfig - in megabytes")
+	cmd.PersistentFlags().IntVar(&opts.evaluatorCacheDefaultTTL, "evaluator-cache-default-ttl", utils.EnvVar("EVALUATOR_CACHE_DEFAULT_TTL", v1beta2.EvaluatorDefaultCacheTTL), "Default TTL of the entries of an Authorino evaluator cache whose ttl is omitted in the AuthConfig - in seconds")
+	cmd.PersistentFlags().IntVar(&opts.evaluatorCacheDefaultMaxEntries, "evaluator-cache-default-max-entries", utils.EnvVar("EVALUATOR_CACHE_DEFAULT_MAX_ENTRIES", 0), "Default maximum number of entries of an Authorino evaluator cache whose maxEntries is omitted in the AuthConfig - 0 for unlimited")
+	cmd.PersistentFlags().IntVar(&opts.evaluatorCacheMaxTTL, "evaluator-cache-max-ttl", utils.EnvVar("EVALUATOR_CACHE_MAX_TTL", 0), "Upper bound of the TTL of the entries of any Authorino evaluator cache, regardless of the AuthConfig - in seconds; 0 for no upper bound")
+	cmd.PersistentFlags().IntVar(&opts.evaluatorCacheMaxEntries, "evaluator-cache-max-entries", utils.EnvVar("EVALUATOR_CACHE_MAX_ENTRIES", 0), "Upper bound of the number of entries of any Authorino evaluator cache, regardless of the AuthConfig - 0 for no upper bound")
 	cmd.PersistentFlags().BoolVar(&opts.deepMetricsEnabled, "deep-metrics-enabled", utils.EnvVar("DEEP_METRICS_ENABLED", false), "Enable deep metrics at the level of each evaluator when requested in the AuthConfig, exported by the metrics server")
 	cmd.PersistentFlags().IntVar(&opts.webhookServicePort, "webhook-service-port", 9443, "Port number of the webhook server")
 	cmd.PersistentFlags().BoolVar(&opts.enableLeaderElection, "enable-leader-election", false, "Enable leader election for status updater - ensures only one instance of Authorino tries to update the status of reconciled resources")
 	cmd.PersistentFlags().Int64Var(&opts.maxHttpRequestBodySize, "max-http-request-body-size", utils.EnvVar("MAX_HTTP_REQUEST_BODY_SIZE", int64(8192)), "Maximum size of the body of requests accepted in the raw HTTP interface of the authorization server - in bytes")
+	cmd.PersistentFlags().Int64Var(&opts.maxRequestBodyCaptureSize, "max-request-body-capture-size", utils.EnvVar("MAX_REQUEST_BODY_CAPTURE_SIZE", int64(0)), "Maximum size of the body of the request captured in the auth pipeline and shared across the evaluators, when needed by any of them - in bytes; 0 for no limit besides the one of the proxy")
+	cmd.PersistentFlags().StringVar(&opts.conditionOutcomesHeader, "condition-outcomes-header", utils.EnvVar("CONDITION_OUTCOMES_HEADER", ""), "Name of the HTTP header to add to the response with the outcomes of the conditions evaluated for the request (for debugging); empty for not adding the header")
+	cmd.PersistentFlags().StringVar(&opts.jsonCodec, "json-codec", utils.EnvVar("JSON_CODEC", json.StandardCodecName), "JSON library used to parse the Authorization JSON on the hot path of the auth pipeline - one of: standard, jsoniter")
+	cmd.PersistentFlags().StringVar(&opts.unknownHostDecision, "unknown-host-decision", utils.EnvVar("UNKNOWN_HOST_DECISION", service.UnknownHostNotFound), "Decision for requests to hosts with no AuthConfig - one of: not-found (fail closed, 404), deny (fail closed, 403), allow (fail open)")
+	cmd.PersistentFlags().StringVar(&opts.subjectOverridesFile, "subject-overrides-file", utils.EnvVar("SUBJECT_OVERRIDES_FILE", ""), "Path to a file in the file system (e.g. mounted from a ConfigMap) with subjects to deny in all AuthConfigs ('deny <subject>') or allow regardless of the authorization rules of an AuthConfig ('allow <namespace>/<name> <subject>'), one per line - the file is watched for changes")
+	cmd.PersistentFlags().StringVar(&opts.subjectOverridesSelector, "subject-overrides-selector", utils.EnvVar("SUBJECT_OVERRIDES_SELECTOR", service.DefaultSubjectOverridesSelector), "Selector of the subject of the request in the Authorization JSON, matched against the subject overrides")
+	cmd.PersistentFlags().BoolVar(&opts.distinguishAnonymousIdentity, "distinguish-anonymous-identity", utils.EnvVar("DISTINGUISH_ANONYMOUS_IDENTITY", false), "Report identities resolved by anonymous access with result=anonymous in the metrics, instead of as successes, and omit them from the per-request identity logs")
+	cmd.PersistentFlags().IntVar(&opts.wristbandMinRSAKeySize, "wristband-min-rsa-key-size", utils.EnvVar("WRISTBAND_MIN_RSA_KEY_SIZE", response_evaluators.MinRSASigningKeySize), "Minimum size of the RSA keys used to sign Festival Wristband tokens and outbound HTTP requests - in bits; shorter keys are rejected")
+	cmd.PersistentFlags().BoolVar(&opts.trimCredentials, "trim-credentials", utils.EnvVar("TRIM_CREDENTIALS", false), "Remove leading and trailing whitespace (including newlines) from the credentials extracted from the requests, before verifying them")
+	cmd.PersistentFlags().StringVar(&opts.outboundSigningKeyPath, "outbound-signing-key", utils.EnvVar("OUTBOUND_SIGNING_KEY", ""), "Path to the private key file in the file system used to sign outbound HTTP requests to metadata and callback endpoints with a JWT that identifies the Authorino instance")
+	cmd.PersistentFlags().StringVar(&opts.outboundSigningKeyAlgorithm, "outbound-signing-key-algorithm", utils.EnvVar("OUTBOUND_SIGNING_KEY_ALGORITHM", "ES256"), "Algorithm of the key used to sign outbound HTTP requests - one of: ES256, ES384, ES512, RS256, RS384, RS512")
+	cmd.PersistentFlags().StringVar(&opts.outboundSigningIssuer, "outbound-signing-issuer", utils.EnvVar("OUTBOUND_SIGNING_ISSUER", "authorino"), "Issuer of the JWT used to sign outbound HTTP requests")
+	cmd.PersistentFlags().StringVar(&opts.outboundSigningHeader, "outbound-signing-header", utils.EnvVar("OUTBOUND_SIGNING_HEADER", metadata_evaluators.DefaultInstanceIdentityHeader), "Name of the HTTP header of the JWT used to sign outbound HTTP requests")
+	cmd.PersistentFlags().StringVar(&opts.auditWebhookURL, "audit-webhook-url", utils.EnvVar("AUDIT_WEBHOOK_URL", ""), "Endpoint of the HTTP webhook where to deliver audit records of the authorization decisions - if omitted, audit records are disabled")
+	cmd.PersistentFlags().IntVar(&opts.auditQueueSize, "audit-queue-size", utils.EnvVar("AUDIT_QUEUE_SIZE", audit.DefaultQueueSize), "Maximum number of audit records pending delivery - records emitted when the queue is full are dropped")
+	cmd.PersistentFlags().IntVar(&opts.auditBatchSize, "audit-batch-size", utils.EnvVar("AUDIT_BATCH_SIZE", audit.DefaultBatchSize), "Maximum number of audit records delivered in a single request to the audit webhook")
+	cmd.PersistentFlags().IntVar(&opts.auditFlushInterval, "audit-flush-interval", utils.EnvVar("AUDIT_FLUSH_INTERVAL", audit.DefaultFlushInterval), "Maximum time pending audit records wait before being delivered to the audit webhook - in seconds")
+	cmd.PersistentFlags().IntVar(&opts.auditMaxRetries, "audit-max-retries", utils.EnvVar("AUDIT_MAX_RETRIES", audit.DefaultMaxRetries), "Number of retries to deliver a batch of audit records to the audit webhook before dropping the records")
+	cmd.PersistentFlags().StringVar(&opts.featureGates, "feature-gates", utils.EnvVar("FEATURE_GATES", ""), "Comma-separated list of <gate>=<true|false> pairs that enable or disable experimental evaluator types - gates: ScopesAuthorization, CombinedResponse, RateLimitDescriptorsResponse; all enabled by default")
+	cmd.PersistentFlags().IntVar(&opts.workerMaxPending, "worker-max-pending", utils.EnvVar("WORKER_MAX_PENDING", workers.DefaultMaxPending), "Maximum number of runs pending while an asynchronous worker (e.g. OIDC discovery refresh, external OPA policy refresh) is busy")
+	cmd.PersistentFlags().StringVar(&opts.workerQueuePolicy, "worker-queue-policy", utils.EnvVar("WORKER_QUEUE_POLICY", workers.QueuePolicyDrop), "What to do with the runs of a busy asynchronous worker when the queue of pending runs is full - one of: drop, block. The ticks of a worker already coalesce while it is busy, so block mostly delays the runs rather than bounding memory")
 	registerCommonServerOptions(cmd, &opts.commonServerOptions)
 
 	return cmd
@@ -228,18 +330,69 @@ func runAuthorizationServer(cmd *cobra.Command, _ []string) {
 
 	// global options
 	evaluators.EvaluatorCacheSize = opts.evaluatorCacheSize
+	service.MaxRequestBodyCaptureSize = opts.maxRequestBodyCaptureSize
+	service.ConditionOutcomesHeader = opts.conditionOutcomesHeader
+	service.DistinguishAnonymousIdentity = opts.distinguishAnonymousIdentity
+	auth.TrimCredentials = opts.trimCredentials
+	if err := workers.ValidateQueue(opts.workerMaxPending, opts.workerQueuePolicy); err != nil {
+		logger.Error(err, "invalid queue of the asynchronous workers")
+		os.Exit(1)
+	}
+	workers.MaxPending = opts.workerMaxPending
+	workers.QueuePolicy = opts.workerQueuePolicy
+	if err := service.ValidateUnknownHostDecision(opts.unknownHostDecision); err != nil {
+		logger.Error(err, "invalid decision for unknown hosts")
+		os.Exit(1)
+	}
+	service.UnknownHostDecision = opts.unknownHostDecision
+	if opts.subjectOverridesFile != "" {
+		subjectOverrides := service.NewSubjectOverrideList()
+		if _, err := service.WatchSubjectOverridesFile(context.Background(), opts.subjectOverridesFile, subjectOverridesFileReloadInterval, subjectOverrides, logger.WithName("subjectoverrides")); err != nil {
+			logger.Error(err, "failed to watch the subject overrides file")
+			os.Exit(1)
+		}
+		service.SubjectOverrides = subjectOverrides
+		service.SubjectOverridesSelector = opts.subjectOverridesSelector
+	}
+	if codec, err := json.NewCodec(opts.jsonCodec); err != nil {
+		logger.Error(err, "invalid json codec")
+		os.Exit(1)
+	} else {
+		json.SetCodec(codec)
+	}
 	metrics.DeepMetricsEnabled = opts.deepMetricsEnabled
+	response_evaluators.MinRSASigningKeySize = opts.wristbandMinRSAKeySize
+	featureGates, err := controllers.ParseFeatureGates(opts.featureGates)
+	if err != nil {
+		logger.Error(err, "invalid feature gates")
+		os.Exit(1)
+	}
+	if opts.outboundSigningKeyPath != "" {
+		instanceIdentity, err := newInstanceIdentity(*opts)
+		if err != nil {
+			logger.Error(err, "failed to load the key to sign outbound requests")
+			os.Exit(1)
+		}
+		metadata_evaluators.OutboundRequestsInstanceIdentity = instanceIdentity
+	}
 
 	// creates the index of authconfigs
 	index := index.NewIndex()
+	metrics.Register(controllers.NewServedHostsCollector(index))
+
+	// starts the delivery of audit records
+	auditor := newAuditor(*opts)
 
 	// starts authorization server
-	startExtAuthServerGRPC(index, *opts)
-	startExtAuthServerHTTP(index, *opts)
+	startExtAuthServerGRPC(index, auditor, *opts)
+	startExtAuthServerHTTP(index, auditor, *opts)
 
 	// starts the oidc discovery server
 	startOIDCServer(index, *opts)
 
+	// starts the diagnostics server
+	startDiagnosticsServer(index, *opts)
+
 	baseManagerOptions := ctrl.Options{
 		Scheme:                 scheme,
 		WebhookServer:          webhook.NewServer(webhook.Options{Port: opts.webhookServicePort}),
@@ -261,16 +414,45 @@ func runAuthorizationServer(cmd *cobra.Command, _ []string) {
 	statusReport := controllers.NewStatusReportMap()
 	controllerLogger := log.WithName("controller-runtime").WithName("manager").WithName("controller")
 
+	// label selector of the authconfigs to watch, reloadable at runtime if read from a file
+	var authConfigLabelSelector labels.Selector = controllers.ToLabelSelector(opts.watchedAuthConfigLabelSelector)
+	if opts.authConfigLabelSelectorFile != "" {
+		reloadableLabelSelector := controllers.NewReloadableLabelSelector(authConfigLabelSelector)
+		if _, err := controllers.WatchLabelSelectorFile(context.Background(), opts.authConfigLabelSelectorFile, labelSelectorFileReloadInterval, reloadableLabelSelector, logger.WithName("labelselector")); err != nil {
+			logger.Error(err, "failed to watch the label selector file")
+			os.Exit(1)
+		}
+		authConfigLabelSelector = reloadableLabelSelector
+	}
+
 	// sets up the authconfig reconciler
 	authConfigReconciler := &controllers.AuthConfigReconciler{
-		Client:                      mgr.GetClient(),
+		Client:                      controllers.NewKubeAPIClient(mgr.GetClient()),
 		Index:                       index,
 		AllowSupersedingHostSubsets: opts.allowSupersedingHostSubsets,
 		StatusReport:                statusReport,
 		Logger:                      controllerLogger.WithName("authconfig"),
 		Scheme:                      mgr.GetScheme(),
-		LabelSelector:               controllers.ToLabelSelector(opts.watchedAuthConfigLabelSelector),
+		LabelSelector:               authConfigLabelSelector,
 		Namespace:                   opts.watchNamespace,
+		DependencyReconcileDelay:    time.Duration(opts.dependencyReconcileDelay) * time.Millisecond,
+		DependencyReconcileJitter:   time.Duration(opts.dependencyReconcileJitter) * time.Millisecond,
+		EvictAfterReconcileFailures: opts.evictAfterReconcileFailures,
+		MaxAuthConfigTimeout:        timeoutMs(opts.maxAuthConfigTimeout),
+		MaxConcurrentReconciles:     opts.maxConcurrentReconciles,
+		RateLimiter: controllers.NewReconcileRateLimiter(
+			time.Duration(opts.reconcileRetryBaseDelay)*time.Millisecond,
+			time.Duration(opts.reconcileRetryMaxDelay)*time.Millisecond,
+			opts.reconcileRateLimiterQPS,
+			opts.reconcileRateLimiterBurst,
+		),
+		EvaluatorCachePolicy: controllers.EvaluatorCachePolicy{
+			DefaultTTL:        opts.evaluatorCacheDefaultTTL,
+			DefaultMaxEntries: opts.evaluatorCacheDefaultMaxEntries,
+			MaxTTL:            opts.evaluatorCacheMaxTTL,
+			MaxEntries:        opts.evaluatorCacheMaxEntries,
+		},
+		FeatureGates: featureGates,
 	}
 	if err = authConfigReconciler.SetupWithManager(mgr); err != nil {
 		logger.Error(err, "failed to setup controller", "controller", "authconfig")
@@ -307,17 +489,56 @@ func runAuthorizationServer(cmd *cobra.Command, _ []string) {
 		}
 	}()
 
-	// sets up the status update manager
-	leaderElectionId := sha256.Sum256([]byte(opts.watchedAuthConfigLabelSelector))
+	// runs the status update manager, set up again whenever the label selector is reloaded, so the leader election id
+	// reflects the label selector in use
+	selectorChanged := make(chan struct{}, 1)
+	if reloadable, ok := authConfigLabelSelector.(*controllers.ReloadableLabelSelector); ok {
+		reloadable.OnChange(func() {
+			select {
+			case selectorChanged <- struct{}{}:
+			default: // a restart is already pending
+			}
+		})
+	}
+	for {
+		ctx, cancel := context.WithCancel(signalHandler)
+		go func() {
+			select {
+			case <-selectorChanged:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		err := runStatusUpdateManager(ctx, baseManagerOptions, opts.enableLeaderElection, authConfigLabelSelector, statusReport, controllerLogger)
+		cancel()
+		if err != nil {
+			logger.Error(err, "failed to start status update manager")
+			os.Exit(1)
+		}
+		if signalHandler.Err() != nil {
+			break
+		}
+		logger.Info("label selector changed, restarting status update manager")
+	}
+
+	// flushes the pending audit records
+	auditor.Stop()
+}
+
+// runStatusUpdateManager sets up and starts the manager of the status updates of the AuthConfigs, until the context
+// is done. The leader election id is derived from the label selector, so only one instance per selection of
+// AuthConfigs updates the status.
+func runStatusUpdateManager(ctx context.Context, baseManagerOptions ctrl.Options, leaderElection bool, labelSelector labels.Selector, statusReport *controllers.StatusReportMap, controllerLogger logr.Logger) error {
+	leaderElectionId := sha256.Sum256([]byte(labelSelector.String()))
 	statusUpdaterOptions := baseManagerOptions
 	statusUpdaterOptions.Metrics.BindAddress = "0"    // disabled so it does not clash with the reconciliation manager
 	statusUpdaterOptions.HealthProbeBindAddress = "0" // disabled so it does not clash with the reconciliation manager
-	statusUpdaterOptions.LeaderElection = opts.enableLeaderElection
+	statusUpdaterOptions.LeaderElection = leaderElection
 	statusUpdaterOptions.LeaderElectionID = fmt.Sprintf("%v.%v", hex.EncodeToString(leaderElectionId[:4]), leaderElectionIDSuffix)
+	statusUpdaterOptions.LeaderElectionReleaseOnCancel = true // so the lease of the previous label selector is released on restart
 	statusUpdateManager, err := setupManager(statusUpdaterOptions)
 	if err != nil {
-		logger.Error(err, "failed to setup status update manager")
-		os.Exit(1)
+		return fmt.Errorf("failed to setup status update manager: %w", err)
 	}
 
 	// sets up the authconfig status update controller
@@ -325,17 +546,14 @@ func runAuthorizationServer(cmd *cobra.Command, _ []string) {
 		Client:        statusUpdateManager.GetClient(),
 		Logger:        controllerLogger.WithName("authconfig").WithName("statusupdater"),
 		StatusReport:  statusReport,
-		LabelSelector: controllers.ToLabelSelector(opts.watchedAuthConfigLabelSelector),
+		LabelSelector: labelSelector,
 	}).SetupWithManager(statusUpdateManager); err != nil {
 		logger.Error(err, "failed to create controller", "controller", "authconfigstatusupdate")
 	}
 
 	// starts the status update manager
-	logger.Info("starting status update manager")
-	if err := statusUpdateManager.Start(signalHandler); err != nil {
-		logger.Error(err, "failed to start status update manager")
-		os.Exit(1)
-	}
+	logger.Info("starting status update manager", "leaderElectionId", statusUpdaterOptions.LeaderElectionID)
+	return statusUpdateManager.Start(ctx)
 }
 
 func runWebhookServer(cmd *cobra.Command, _ []string) {
@@ -419,7 +637,9 @@ func setupTelemetryServices(opts telemetryOptions) {
 
 func setupManager(options ctrl.Options) (ctrl.Manager, error) {
 	if options.Metrics.BindAddress != "0" {
-		options.Metrics.ExtraHandlers = map[string]http.Handler{"/server-metrics": promhttp.Handler()}
+		// same as promhttp.Handler(), but in the OpenMetrics format if requested, so the exemplars are exposed
+		serverMetricsHandler := promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+		options.Metrics.ExtraHandlers = map[string]http.Handler{"/server-metrics": serverMetricsHandler}
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
@@ -436,7 +656,7 @@ func setupManager(options ctrl.Options) (ctrl.Manager, error) {
 	return mgr, nil
 }
 
-func startExtAuthServerGRPC(authConfigIndex index.Index, opts authServerOptions) {
+func startExtAuthServerGRPC(authConfigIndex index.Index, auditor *audit.Auditor, opts authServerOptions) {
 	lis, err := listen(opts.extAuthGRPCPort)
 
 	if err != nil {
@@ -449,8 +669,15 @@ func startExtAuthServerGRPC(authConfigIndex index.Index, opts authServerOptions)
 		return
 	}
 
+	if err := validateGRPCServerOptions(opts); err != nil {
+		logger.Error(err, "invalid grpc auth service options")
+		os.Exit(1)
+	}
+
 	grpcServerOpts := []grpc.ServerOption{
-		grpc.MaxConcurrentStreams(gRPCMaxConcurrentStreams),
+		grpc.MaxConcurrentStreams(uint32(opts.grpcMaxConcurrentStreams)),
+		grpc.KeepaliveParams(grpcKeepaliveParams(opts)),
+		grpc.KeepaliveEnforcementPolicy(grpcKeepaliveEnforcementPolicy(opts)),
 		grpc.ChainStreamInterceptor(grpc_prometheus.StreamServerInterceptor, otel_grpc.StreamServerInterceptor()),
 		grpc.ChainUnaryInterceptor(grpc_prometheus.UnaryServerInterceptor, otel_grpc.UnaryServerInterceptor()),
 	}
@@ -474,7 +701,7 @@ func startExtAuthServerGRPC(authConfigIndex index.Index, opts authServerOptions)
 	grpcServer := grpc.NewServer(grpcServerOpts...)
 	reflection.Register(grpcServer)
 
-	envoy_auth.RegisterAuthorizationServer(grpcServer, &service.AuthService{Index: authConfigIndex, Timeout: timeoutMs(opts.timeout)})
+	envoy_auth.RegisterAuthorizationServer(grpcServer, &service.AuthService{Index: authConfigIndex, Timeout: timeoutMs(opts.timeout), Auditor: auditor})
 	healthpb.RegisterHealthServer(grpcServer, &service.HealthService{})
 	grpc_prometheus.Register(grpcServer)
 	grpc_prometheus.EnableHandlingTimeHistogram()
@@ -489,16 +716,88 @@ func startExtAuthServerGRPC(authConfigIndex index.Index, opts authServerOptions)
 	}()
 }
 
-func startExtAuthServerHTTP(authConfigIndex index.Index, opts authServerOptions) {
-	startHTTPService("auth", opts.extAuthHTTPPort, service.HTTPAuthorizationBasePath, opts.tlsCertPath, opts.tlsCertKeyPath, service.NewAuthService(authConfigIndex, timeoutMs(opts.timeout), opts.maxHttpRequestBodySize))
+// validateGRPCServerOptions checks the maximum number of concurrent streams fits in the range of HTTP/2 and the
+// keepalive and connection age settings of the gRPC authorization server are not negative
+func validateGRPCServerOptions(opts authServerOptions) error {
+	if opts.grpcMaxConcurrentStreams <= 0 || int64(opts.grpcMaxConcurrentStreams) > math.MaxUint32 {
+		return fmt.Errorf("grpc max concurrent streams must be greater than 0 and at most %d: %d", uint32(math.MaxUint32), opts.grpcMaxConcurrentStreams)
+	}
+	for _, option := range []struct {
+		name  string
+		value int
+	}{
+		{"keepalive time", opts.grpcKeepaliveTime},
+		{"keepalive timeout", opts.grpcKeepaliveTimeout},
+		{"keepalive min time", opts.grpcKeepaliveMinTime},
+		{"max connection idle", opts.grpcMaxConnectionIdle},
+		{"max connection age", opts.grpcMaxConnectionAge},
+		{"max connection age grace", opts.grpcMaxConnectionAgeGrace},
+	} {
+		if option.value < 0 {
+			return fmt.Errorf("grpc %s must not be negative: %d", option.name, option.value)
+		}
+	}
+	return nil
+}
+
+// grpcKeepaliveParams returns the keepalive and connection age settings of the gRPC authorization server.
+// Zero values fall back to the defaults of gRPC.
+func grpcKeepaliveParams(opts authServerOptions) keepalive.ServerParameters {
+	return keepalive.ServerParameters{
+		MaxConnectionIdle:     time.Duration(opts.grpcMaxConnectionIdle) * time.Second,
+		MaxConnectionAge:      time.Duration(opts.grpcMaxConnectionAge) * time.Second,
+		MaxConnectionAgeGrace: time.Duration(opts.grpcMaxConnectionAgeGrace) * time.Second,
+		Time:                  time.Duration(opts.grpcKeepaliveTime) * time.Second,
+		Timeout:               time.Duration(opts.grpcKeepaliveTimeout) * time.Second,
+	}
+}
+
+// grpcKeepaliveEnforcementPolicy returns the policy the gRPC authorization server enforces on the keepalive pings of the clients.
+func grpcKeepaliveEnforcementPolicy(opts authServerOptions) keepalive.EnforcementPolicy {
+	return keepalive.EnforcementPolicy{
+		MinTime:             time.Duration(opts.grpcKeepaliveMinTime) * time.Second,
+		PermitWithoutStream: opts.grpcKeepaliveWithoutStream,
+	}
+}
+
+func startExtAuthServerHTTP(authConfigIndex index.Index, auditor *audit.Auditor, opts authServerOptions) {
+	authService := service.NewAuthService(authConfigIndex, timeoutMs(opts.timeout), opts.maxHttpRequestBodySize)
+	authService.Auditor = auditor
+	startHTTPService("auth", "", opts.extAuthHTTPPort, service.HTTPAuthorizationBasePath, opts.tlsCertPath, opts.tlsCertKeyPath, authService, http.DefaultServeMux)
 }
 
 func startOIDCServer(authConfigIndex index.Index, opts authServerOptions) {
-	startHTTPService("oidc", opts.oidcHTTPPort, service.OIDCBasePath, opts.oidcTLSCertPath, opts.oidcTLSCertKeyPath, &service.OidcService{Index: authConfigIndex})
+	startHTTPService("oidc", "", opts.oidcHTTPPort, service.OIDCBasePath, opts.oidcTLSCertPath, opts.oidcTLSCertKeyPath, &service.OidcService{Index: authConfigIndex}, http.DefaultServeMux)
+}
+
+// startDiagnosticsServer starts the admin server to evaluate synthetic requests, on a mux of its own so it is not
+// reachable on the ports of the other http services
+func startDiagnosticsServer(authConfigIndex index.Index, opts authServerOptions) {
+	startHTTPService("diagnostics", opts.diagnosticsHTTPHost, opts.diagnosticsHTTPPort, service.DiagnosticsBasePath, "", "", &service.DiagnosticsService{Index: authConfigIndex, Timeout: timeoutMs(opts.timeout)}, http.NewServeMux())
+}
+
+func newAuditor(opts authServerOptions) *audit.Auditor {
+	if opts.auditWebhookURL == "" {
+		return nil
+	}
+	sink := audit.NewWebhookSink(opts.auditWebhookURL, audit.DefaultWebhookTimeout*time.Second)
+	return audit.NewAuditor(sink, opts.auditQueueSize, opts.auditBatchSize, time.Duration(opts.auditFlushInterval)*time.Second, opts.auditMaxRetries)
+}
+
+func newInstanceIdentity(opts authServerOptions) (*metadata_evaluators.InstanceIdentity, error) {
+	keyPEM, err := os.ReadFile(opts.outboundSigningKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := response_evaluators.NewSigningKey("", opts.outboundSigningKeyAlgorithm, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return metadata_evaluators.NewInstanceIdentity(opts.outboundSigningIssuer, opts.outboundSigningHeader, metadata_evaluators.DefaultInstanceIdentityTokenDuration, *signingKey)
 }
 
-func startHTTPService(name string, port int, basePath, tlsCertPath, tlsCertKeyPath string, handler http.Handler) {
-	lis, err := listen(port)
+func startHTTPService(name, host string, port int, basePath, tlsCertPath, tlsCertKeyPath string, handler http.Handler, mux *http.ServeMux) {
+	lis, err := listenHost(host, port)
 
 	if err != nil {
 		logger.Error(err, fmt.Sprintf("failed to obtain port for the http %s service", name))
@@ -510,7 +809,7 @@ func startHTTPService(name string, port int, basePath, tlsCertPath, tlsCertKeyPa
 		return
 	}
 
-	http.Handle(basePath, otel_http.NewHandler(handler, name))
+	mux.Handle(basePath, otel_http.NewHandler(handler, name))
 
 	tlsEnabled := tlsCertPath != "" && tlsCertKeyPath != ""
 
@@ -521,6 +820,7 @@ func startHTTPService(name string, port int, basePath, tlsCertPath, tlsCertKeyPa
 
 		if tlsEnabled {
 			server := &http.Server{
+				Handler: mux,
 				TLSConfig: &tls.Config{
 					MinVersion: tls.VersionTLS12,
 					ClientAuth: tls.RequestClientCert,
@@ -528,7 +828,7 @@ func startHTTPService(name string, port int, basePath, tlsCertPath, tlsCertKeyPa
 			}
 			err = server.ServeTLS(lis, tlsCertPath, tlsCertKeyPath)
 		} else {
-			err = http.Serve(lis, nil)
+			err = http.Serve(lis, mux)
 		}
 
 		if err != nil {
@@ -539,11 +839,16 @@ func startHTTPService(name string, port int, basePath, tlsCertPath, tlsCertKeyPa
 }
 
 func listen(port int) (net.Listener, error) {
+	return listenHost("", port)
+}
+
+// listenHost listens on the given port of the network interface of the host; empty host for all interfaces
+func listenHost(host string, port int) (net.Listener, error) {
 	if port == 0 {
 		return nil, nil
 	}
 
-	if lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port)); err != nil {
+	if lis, err := net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(port))); err != nil {
 		return nil, err
 	} else {
 		return lis, nil
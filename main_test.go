@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+	"gotest.tools/assert"
+)
+
+func TestGRPCKeepaliveOptions(t *testing.T) {
+	opts := &authServerOptions{}
+	cmd := authServerCmd(opts)
+	err := cmd.ParseFlags([]string{
+		"--grpc-max-concurrent-streams=500",
+		"--grpc-keepalive-time=30",
+		"--grpc-keepalive-timeout=5",
+		"--grpc-keepalive-min-time=10",
+		"--grpc-keepalive-permit-without-stream",
+		"--grpc-max-connection-idle=300",
+		"--grpc-max-connection-age=600",
+		"--grpc-max-connection-age-grace=15",
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, validateGRPCServerOptions(*opts))
+
+	assert.Equal(t, opts.grpcMaxConcurrentStreams, 500)
+	assert.DeepEqual(t, grpcKeepaliveParams(*opts), keepalive.ServerParameters{
+		MaxConnectionIdle:     300 * time.Second,
+		MaxConnectionAge:      600 * time.Second,
+		MaxConnectionAgeGrace: 15 * time.Second,
+		Time:                  30 * time.Second,
+		Timeout:               5 * time.Second,
+	})
+	assert.DeepEqual(t, grpcKeepaliveEnforcementPolicy(*opts), keepalive.EnforcementPolicy{
+		MinTime:             10 * time.Second,
+		PermitWithoutStream: true,
+	})
+}
+
+func TestGRPCKeepaliveOptionsDefaults(t *testing.T) {
+	opts := &authServerOptions{}
+	cmd := authServerCmd(opts)
+	assert.NilError(t, cmd.ParseFlags([]string{}))
+
+	// zero values fall back to the defaults of grpc
+	assert.Equal(t, opts.grpcMaxConcurrentStreams, gRPCMaxConcurrentStreams)
+	assert.DeepEqual(t, grpcKeepaliveParams(*opts), keepalive.ServerParameters{})
+	assert.DeepEqual(t, grpcKeepaliveEnforcementPolicy(*opts), keepalive.EnforcementPolicy{})
+	assert.NilError(t, validateGRPCServerOptions(*opts))
+}
+
+func TestGRPCServerOptionsValidation(t *testing.T) {
+	parse := func(args ...string) authServerOptions {
+		opts := &authServerOptions{}
+		assert.NilError(t, authServerCmd(opts).ParseFlags(args))
+		return *opts
+	}
+
+	assert.Error(t, validateGRPCServerOptions(parse("--grpc-max-concurrent-streams=0")), "grpc max concurrent streams must be greater than 0 and at most 4294967295: 0")
+	assert.Error(t, validateGRPCServerOptions(parse("--grpc-max-concurrent-streams=4294967296")), "grpc max concurrent streams must be greater than 0 and at most 4294967295: 4294967296")
+	assert.NilError(t, validateGRPCServerOptions(parse("--grpc-max-concurrent-streams=4294967295")))
+	assert.Error(t, validateGRPCServerOptions(parse("--grpc-keepalive-time=-1")), "grpc keepalive time must not be negative: -1")
+	assert.Error(t, validateGRPCServerOptions(parse("--grpc-keepalive-min-time=-5")), "grpc keepalive min time must not be negative: -5")
+	assert.Error(t, validateGRPCServerOptions(parse("--grpc-max-connection-age-grace=-1")), "grpc max connection age grace must not be negative: -1")
+}
@@ -0,0 +1,194 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kuadrant/authorino/pkg/log"
+	"github.com/kuadrant/authorino/pkg/metrics"
+)
+
+const (
+	DefaultQueueSize     = 1000
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = 5 // in seconds
+	DefaultMaxRetries    = 3
+
+	dropReasonQueueFull      = "queue_full"
+	dropReasonStopped        = "stopped"
+	dropReasonDeliveryFailed = "delivery_failed"
+)
+
+var (
+	// RetryBackoff is the base delay between attempts to deliver a batch of audit records to the sink.
+	// The delay doubles at each retry.
+	RetryBackoff = 500 * time.Millisecond
+
+	auditRecordsDroppedMetric   = metrics.NewCounterMetric("audit_records_dropped_total", "Number of audit records dropped before delivery to the audit sink.", "reason")
+	auditRecordsDeliveredMetric = metrics.NewCounterMetric("audit_records_delivered_total", "Number of audit records delivered to the audit sink.")
+)
+
+func init() {
+	metrics.Register(
+		auditRecordsDroppedMetric,
+		auditRecordsDeliveredMetric,
+	)
+}
+
+// Record is the audit record of an authorization decision.
+type Record struct {
+	Time       time.Time `json:"time"`
+	RequestId  string    `json:"requestId,omitempty"`
+	Host       string    `json:"host,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Authorized bool      `json:"authorized"`
+	Code       string    `json:"code"`
+	Status     int32     `json:"status,omitempty"`
+	Message    string    `json:"message,omitempty"`
+}
+
+// Sink delivers batches of audit records to an audit pipeline.
+type Sink interface {
+	Send(ctx context.Context, records []Record) error
+}
+
+// NewAuditor starts an auditor that delivers the emitted records to the sink in batches of up to batchSize records.
+// Pending records are flushed at least every flushInterval.
+// Records emitted while the queue of pending records is full are dropped.
+// Batches that fail to be delivered are retried up to maxRetries times and then dropped.
+func NewAuditor(sink Sink, queueSize, batchSize int, flushInterval time.Duration, maxRetries int) *Auditor {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval * time.Second
+	}
+
+	a := &Auditor{
+		sink:          sink,
+		queue:         make(chan Record, queueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		maxRetries:    maxRetries,
+		done:          make(chan struct{}),
+		logger:        log.WithName("audit"),
+	}
+
+	go a.run()
+
+	return a
+}
+
+type Auditor struct {
+	sink          Sink
+	queue         chan Record
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+
+	stopped bool
+	done    chan struct{}
+	mu      sync.RWMutex
+
+	logger log.Logger
+}
+
+// Emit enqueues a record for delivery to the sink without blocking.
+// If the queue is full or the auditor is stopped, the record is dropped.
+func (a *Auditor) Emit(record Record) {
+	if a == nil {
+		return
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.stopped {
+		a.logger.V(1).Info("auditor stopped, dropping audit record", "request id", record.RequestId)
+		reportDroppedRecords(dropReasonStopped, 1)
+		return
+	}
+
+	select {
+	case a.queue <- record:
+	default:
+		reportDroppedRecords(dropReasonQueueFull, 1)
+	}
+}
+
+// Stop flushes the pending records to the sink and stops the auditor.
+func (a *Auditor) Stop() {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		return
+	}
+	a.stopped = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	<-a.done
+}
+
+func (a *Auditor) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, a.batchSize)
+
+	for {
+		select {
+		case record, ok := <-a.queue:
+			if !ok {
+				a.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= a.batchSize {
+				a.flush(batch)
+				batch = make([]Record, 0, a.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				a.flush(batch)
+				batch = make([]Record, 0, a.batchSize)
+			}
+		}
+	}
+}
+
+func (a *Auditor) flush(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+
+	backoff := RetryBackoff
+	var err error
+
+	for attempt := 0; attempt <= a.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = a.sink.Send(context.TODO(), batch); err == nil {
+			auditRecordsDeliveredMetric.WithLabelValues().Add(float64(len(batch)))
+			return
+		}
+		a.logger.V(1).Info("failed to deliver audit records", "attempt", attempt+1, "records", len(batch), "reason", err)
+	}
+
+	a.logger.Error(err, "failed to deliver audit records", "records", len(batch))
+	reportDroppedRecords(dropReasonDeliveryFailed, len(batch))
+}
+
+func reportDroppedRecords(reason string, count int) {
+	auditRecordsDroppedMetric.WithLabelValues(reason).Add(float64(count))
+}
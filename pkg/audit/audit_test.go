@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	gohttptest "net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+type sinkMock struct {
+	records  []Record
+	batches  int
+	calls    int
+	failures int
+	called   chan struct{}
+	release  chan struct{}
+	mu       sync.Mutex
+}
+
+func (s *sinkMock) Send(_ context.Context, records []Record) error {
+	if s.called != nil {
+		s.called <- struct{}{}
+	}
+	if s.release != nil {
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls <= s.failures {
+		return fmt.Errorf("sink unavailable")
+	}
+	s.batches++
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *sinkMock) delivered() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records
+}
+
+func TestAuditorDeliversRecordsInBatches(t *testing.T) {
+	sink := &sinkMock{}
+	auditor := NewAuditor(sink, 10, 2, time.Hour, 0)
+
+	for i := 0; i < 5; i++ {
+		auditor.Emit(Record{RequestId: fmt.Sprint(i), Authorized: true, Code: "OK"})
+	}
+	auditor.Stop()
+
+	records := sink.delivered()
+	assert.Equal(t, len(records), 5)
+	assert.Equal(t, sink.batches, 3)
+	for i, record := range records {
+		assert.Equal(t, record.RequestId, fmt.Sprint(i))
+	}
+}
+
+func TestAuditorFlushesPeriodically(t *testing.T) {
+	sink := &sinkMock{}
+	auditor := NewAuditor(sink, 10, 100, 10*time.Millisecond, 0)
+	defer auditor.Stop()
+
+	auditor.Emit(Record{RequestId: "1", Code: "PERMISSION_DENIED"})
+
+	assert.Assert(t, waitFor(func() bool { return len(sink.delivered()) == 1 }))
+}
+
+func TestAuditorRetriesDelivery(t *testing.T) {
+	RetryBackoff = time.Millisecond
+
+	sink := &sinkMock{failures: 2}
+	auditor := NewAuditor(sink, 10, 1, time.Hour, 2)
+	auditor.Emit(Record{RequestId: "1"})
+	auditor.Stop()
+
+	assert.Equal(t, sink.calls, 3)
+	assert.Equal(t, len(sink.delivered()), 1)
+}
+
+func TestAuditorDropsUndeliveredRecords(t *testing.T) {
+	RetryBackoff = time.Millisecond
+	dropped := testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonDeliveryFailed))
+
+	sink := &sinkMock{failures: 10}
+	auditor := NewAuditor(sink, 10, 2, time.Hour, 1)
+	auditor.Emit(Record{RequestId: "1"})
+	auditor.Emit(Record{RequestId: "2"})
+	auditor.Stop()
+
+	assert.Equal(t, sink.calls, 2)
+	assert.Equal(t, len(sink.delivered()), 0)
+	assert.Equal(t, testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonDeliveryFailed)), dropped+2)
+}
+
+func TestAuditorQueueOverflow(t *testing.T) {
+	dropped := testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonQueueFull))
+
+	sink := &sinkMock{called: make(chan struct{}, 10), release: make(chan struct{})}
+	auditor := NewAuditor(sink, 2, 1, time.Hour, 0)
+
+	// the first record is dequeued and blocks the delivery
+	auditor.Emit(Record{RequestId: "1"})
+	<-sink.called
+
+	auditor.Emit(Record{RequestId: "2"})
+	auditor.Emit(Record{RequestId: "3"})
+	auditor.Emit(Record{RequestId: "4"}) // queue full
+	auditor.Emit(Record{RequestId: "5"}) // queue full
+
+	assert.Equal(t, testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonQueueFull)), dropped+2)
+
+	close(sink.release)
+	auditor.Stop()
+
+	records := sink.delivered()
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, records[2].RequestId, "3")
+}
+
+func TestAuditorNil(t *testing.T) {
+	var auditor *Auditor
+	auditor.Emit(Record{RequestId: "1"}) // does not panic
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received []Record
+	server := gohttptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, req.Method, "POST")
+		assert.Equal(t, req.Header.Get("Content-Type"), "application/json")
+		_ = json.NewDecoder(req.Body).Decode(&received)
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, time.Second)
+	err := sink.Send(context.TODO(), []Record{{RequestId: "1", Authorized: true, Code: "OK"}, {RequestId: "2", Code: "UNAUTHENTICATED"}})
+
+	assert.NilError(t, err)
+	assert.Equal(t, len(received), 2)
+	assert.Equal(t, received[0].RequestId, "1")
+	assert.Assert(t, received[0].Authorized)
+	assert.Equal(t, received[1].Code, "UNAUTHENTICATED")
+}
+
+func TestWebhookSinkErrorStatus(t *testing.T) {
+	server := gohttptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, time.Second)
+	err := sink.Send(context.TODO(), []Record{{RequestId: "1"}})
+
+	assert.ErrorContains(t, err, "503")
+}
+
+func waitFor(condition func() bool) bool {
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestAuditorDropsRecordsAfterStop(t *testing.T) {
+	droppedQueueFull := testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonQueueFull))
+	droppedStopped := testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonStopped))
+
+	sink := &sinkMock{}
+	auditor := NewAuditor(sink, 10, 1, time.Hour, 0)
+	auditor.Stop()
+	auditor.Emit(Record{RequestId: "1"})
+
+	assert.Equal(t, len(sink.delivered()), 0)
+	assert.Equal(t, testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonStopped)), droppedStopped+1)
+	assert.Equal(t, testutil.ToFloat64(auditRecordsDroppedMetric.WithLabelValues(dropReasonQueueFull)), droppedQueueFull)
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const DefaultWebhookTimeout = 10 // in seconds
+
+// NewWebhookSink returns an audit sink that POSTs each batch of records as a JSON array to an HTTP endpoint.
+func NewWebhookSink(endpoint string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{
+		Endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+type WebhookSink struct {
+	Endpoint string
+
+	client *http.Client
+}
+
+func (w *WebhookSink) Send(ctx context.Context, records []Record) error {
+	payload, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook responded with status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -48,6 +48,17 @@ type ConditionalEvaluator interface {
 	GetConditions() jsonexp.Expression
 }
 
+// ShadowEvaluator is implemented by configs whose results can be recorded without being enforced
+type ShadowEvaluator interface {
+	IsShadow() bool
+}
+
+// ShortCircuitEvaluator is implemented by configs that can end their phase of the auth pipeline when they succeed,
+// skipping the configs of lower priority
+type ShortCircuitEvaluator interface {
+	ShortCircuitsOnSuccess() bool
+}
+
 type IdentityConfigEvaluator interface {
 	GetAuthCredentials() AuthCredentials
 	GetOIDC() interface{}
@@ -84,11 +95,45 @@ type AuthResult struct {
 	Message string `json:"message,omitempty"`
 	// Headers are other HTTP headers to inject in the response
 	Headers []map[string]string `json:"headers,omitempty"`
+	// AppendHeaders are the names of the Headers whose values are appended to the headers of the request, instead of
+	// overwriting them
+	AppendHeaders []string `json:"appendHeaders,omitempty"`
 	// Metadata are Envoy dynamic metadata content
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 	// Body in the response of the request
 	// auth check result
 	Body string `json:"body,omitempty"`
+	// ConditionOutcomes are the outcomes of the conditions evaluated throughout the auth pipeline, when captured for
+	// debugging
+	ConditionOutcomes []ConditionOutcome `json:"conditionOutcomes,omitempty"`
+}
+
+// ConditionOutcome records whether the conditions of the AuthConfig (top-level) or of one of its evaluators matched
+type ConditionOutcome struct {
+	// Phase of the auth pipeline the conditions belong to (e.g. 'identity', 'authorization'), or 'authconfig' for the
+	// top-level conditions
+	Phase string `json:"phase"`
+	// Name of the evaluator the conditions belong to; empty for the top-level conditions
+	Name string `json:"name,omitempty"`
+	// Matched tells whether the conditions matched
+	Matched bool `json:"matched"`
+	// Error is the error that prevented the conditions from being evaluated, if any
+	Error string `json:"error,omitempty"`
+}
+
+// EvaluatorTrace records the outcome of one of the evaluators of the auth pipeline, when captured for diagnostics.
+// The objects resolved by the evaluators are not recorded, as they may contain credentials and other sensitive data.
+type EvaluatorTrace struct {
+	// Phase of the auth pipeline the evaluator belongs to (e.g. 'identity', 'authorization')
+	Phase string `json:"phase"`
+	// Name of the evaluator
+	Name string `json:"name,omitempty"`
+	// Type of the evaluator (e.g. 'JWT', 'OPA')
+	Type string `json:"type,omitempty"`
+	// Outcome of the evaluator - one of: success, failure, skipped (unmatching conditions), cancelled
+	Outcome string `json:"outcome"`
+	// Error is the reason of the failure of the evaluator, if any
+	Error string `json:"error,omitempty"`
 }
 
 // Success tells whether the auth check result was successful and therefore access can be granted to the requested
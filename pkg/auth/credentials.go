@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,6 +29,16 @@ const (
 
 var errNotFound = fmt.Errorf(credentialNotFoundMsg)
 
+// IsCredentialNotFound tells whether the error is due to the credential missing in the request, as opposed to the
+// credential being invalid
+func IsCredentialNotFound(err error) bool {
+	return errors.Is(err, errNotFound)
+}
+
+// TrimCredentials enables removing the leading and trailing whitespace (including newlines) of the credentials extracted
+// from the requests, for clients that send them by mistake; disabled by default, so the credentials are used exactly as sent
+var TrimCredentials bool
+
 // AuthCredentials interface represents the methods needed to fetch credentials from input
 type AuthCredentials interface {
 	GetCredentialsFromReq(*envoy_auth.AttributeContext_HttpRequest) (string, error)
@@ -60,6 +71,17 @@ type AuthCredential struct {
 
 // GetCredentialsFromReq will retrieve the secrets from a given location
 func (c *AuthCredential) GetCredentialsFromReq(httpReq *envoy_auth.AttributeContext_HttpRequest) (string, error) {
+	cred, err := c.getCredentialsFromReq(httpReq)
+	if err != nil || !TrimCredentials {
+		return cred, err
+	}
+	if cred = strings.TrimSpace(cred); cred == "" {
+		return "", errNotFound
+	}
+	return cred, nil
+}
+
+func (c *AuthCredential) getCredentialsFromReq(httpReq *envoy_auth.AttributeContext_HttpRequest) (string, error) {
 	switch c.In {
 	case inCustomHeader:
 		return getCredFromCustomHeader(httpReq.GetHeaders(), c.KeySelector)
@@ -123,7 +145,7 @@ func (c *AuthCredential) BuildRequestWithCredentials(ctx context.Context, endpoi
 }
 
 func getCredFromCustomHeader(headers map[string]string, keyName string) (string, error) {
-	cred, ok := headers[strings.ToLower(keyName)]
+	cred, ok := getHeader(headers, keyName)
 	if !ok {
 		return "", errNotFound
 	}
@@ -131,7 +153,7 @@ func getCredFromCustomHeader(headers map[string]string, keyName string) (string,
 }
 
 func getCredFromAuthHeader(headers map[string]string, keyName string) (string, error) {
-	authHeader, ok := headers["authorization"]
+	authHeader, ok := getHeader(headers, "Authorization")
 
 	if !ok {
 		return "", errNotFound
@@ -144,7 +166,7 @@ func getCredFromAuthHeader(headers map[string]string, keyName string) (string, e
 }
 
 func getFromCookieHeader(headers map[string]string, keyName string) (string, error) {
-	header, ok := headers["cookie"]
+	header, ok := getHeader(headers, "Cookie")
 	if !ok {
 		return "", errNotFound
 	}
@@ -159,6 +181,20 @@ func getFromCookieHeader(headers map[string]string, keyName string) (string, err
 	return "", errNotFound
 }
 
+// getHeader looks up a header by name regardless of case.
+// Envoy sends the header names in lowercase, but other clients of the authorization service may not.
+func getHeader(headers map[string]string, name string) (string, bool) {
+	if value, ok := headers[strings.ToLower(name)]; ok {
+		return value, true
+	}
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
 func getCredFromQuery(path string, keyName string) (string, error) {
 	const credValue = "credValue"
 	regex := regexp.MustCompile("([?&]" + keyName + "=)(?P<" + credValue + ">[^&]*)")
@@ -70,6 +70,42 @@ func TestGetCredentialsFromCustomHeaderFail(t *testing.T) {
 	assert.Error(t, err, "credential not found")
 }
 
+func TestGetCredentialsFromCustomHeaderCaseInsensitive(t *testing.T) {
+	authCredentials := NewAuthCredential("X-API-Key", "custom_header")
+
+	for _, headerName := range []string{"x-api-key", "X-API-Key", "X-Api-Key", "X-API-KEY"} {
+		httpReq := envoyServiceAuthV3.AttributeContext_HttpRequest{
+			Headers: map[string]string{headerName: "DasUberApiKey"},
+		}
+		cred, err := authCredentials.GetCredentialsFromReq(&httpReq)
+
+		assert.NilError(t, err, headerName)
+		assert.Equal(t, cred, "DasUberApiKey", headerName)
+	}
+}
+
+func TestGetCredentialsFromAuthHeaderCaseInsensitive(t *testing.T) {
+	var httpReq = envoyServiceAuthV3.AttributeContext_HttpRequest{
+		Headers: map[string]string{"Authorization": "Bearer DasUberToken"},
+	}
+
+	cred, err := NewAuthCredential("", "").GetCredentialsFromReq(&httpReq)
+
+	assert.NilError(t, err)
+	assert.Equal(t, cred, "DasUberToken")
+}
+
+func TestGetCredentialsFromCookieHeaderCaseInsensitive(t *testing.T) {
+	var httpReq = envoyServiceAuthV3.AttributeContext_HttpRequest{
+		Headers: map[string]string{"Cookie": "API-KEY=HumanInstrumentality"},
+	}
+
+	cred, err := NewAuthCredential("API-KEY", "cookie").GetCredentialsFromReq(&httpReq)
+
+	assert.NilError(t, err)
+	assert.Equal(t, cred, "HumanInstrumentality")
+}
+
 func TestGetCredentialsFromAuthHeaderSuccess(t *testing.T) {
 	var httpReq = envoyServiceAuthV3.AttributeContext_HttpRequest{
 		Headers: map[string]string{"authorization": "X-API-KEY DasUberApiKey"},
@@ -85,6 +121,38 @@ func TestGetCredentialsFromAuthHeaderSuccess(t *testing.T) {
 	assert.Check(t, cred == "DasUberApiKey")
 }
 
+func TestGetCredentialsWithTrimming(t *testing.T) {
+	defer func(trim bool) { TrimCredentials = trim }(TrimCredentials)
+
+	var httpReq = envoyServiceAuthV3.AttributeContext_HttpRequest{
+		Headers: map[string]string{"authorization": "APIKEY DasUberApiKey\n", "x-api-key": " \t"},
+	}
+
+	authCredentials := AuthCredential{
+		KeySelector: "APIKEY",
+		In:          "authorization_header",
+	}
+	emptyCredentials := AuthCredential{
+		KeySelector: "X-API-KEY",
+		In:          "custom_header",
+	}
+
+	// disabled
+	TrimCredentials = false
+	cred, err := authCredentials.GetCredentialsFromReq(&httpReq)
+	assert.NilError(t, err)
+	assert.Equal(t, cred, "DasUberApiKey\n")
+
+	// enabled
+	TrimCredentials = true
+	cred, err = authCredentials.GetCredentialsFromReq(&httpReq)
+	assert.NilError(t, err)
+	assert.Equal(t, cred, "DasUberApiKey")
+
+	_, err = emptyCredentials.GetCredentialsFromReq(&httpReq)
+	assert.Error(t, err, credentialNotFoundMsg)
+}
+
 func TestGetCredentialsFromAuthHeaderFail(t *testing.T) {
 	var httpReq = envoyServiceAuthV3.AttributeContext_HttpRequest{
 		Headers: map[string]string{"authorization": "X-API-KEY DasUberApiKey"},
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"strings"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+)
+
+// RequestHost returns the host of the request used to look up the AuthConfig in the index.
+// The authority of HTTP/2 requests (':authority' pseudo-header) is preferred over the 'Host' header of HTTP/1.1 requests,
+// and both are normalized, so the lookup does not depend on the version of the protocol.
+func RequestHost(httpRequest *envoy_auth.AttributeContext_HttpRequest) string {
+	host := httpRequest.GetHeaders()[":authority"]
+	if host == "" {
+		host = httpRequest.GetHost()
+	}
+	if host == "" {
+		host = httpRequest.GetHeaders()["host"]
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
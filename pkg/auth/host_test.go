@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"gotest.tools/assert"
+)
+
+func TestRequestHost(t *testing.T) {
+	assert.Equal(t, RequestHost(&envoy_auth.AttributeContext_HttpRequest{Host: "api.example.com", Headers: map[string]string{":authority": "API.example.com:8443"}}), "api.example.com:8443")
+	assert.Equal(t, RequestHost(&envoy_auth.AttributeContext_HttpRequest{Host: "api.example.com"}), "api.example.com")
+	assert.Equal(t, RequestHost(&envoy_auth.AttributeContext_HttpRequest{Headers: map[string]string{"host": " API.example.com. "}}), "api.example.com")
+}
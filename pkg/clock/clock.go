@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"time"
+
+	k8sclock "k8s.io/utils/clock"
+)
+
+// Clock tells the time to the time-dependent evaluators (e.g. token expiry, cache TTLs, wristband timestamps) and
+// schedules the workers (e.g. refresh of the OpenID Connect configuration).
+// Tests can replace it with a fake clock (k8s.io/utils/clock/testing) to advance the time deterministically.
+type Clock = k8sclock.WithTicker
+
+// Ticker is a ticker obtained from a Clock
+type Ticker = k8sclock.Ticker
+
+// RealClock is the wall clock
+var RealClock Clock = k8sclock.RealClock{}
+
+// OrRealClock returns the given clock, or the wall clock if nil
+func OrRealClock(c Clock) Clock {
+	if c == nil {
+		return RealClock
+	}
+	return c
+}
+
+// Now returns the current time of the given clock, or of the wall clock if nil
+func Now(c Clock) time.Time {
+	return OrRealClock(c).Now()
+}
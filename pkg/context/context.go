@@ -2,18 +2,21 @@ package context
 
 import (
 	gocontext "context"
+	"sync"
 	"time"
 )
 
 const (
 	kTimeout key = iota
 	kCancelFunc
+	kMemo
+	kDryRun
 )
 
 type key int
 
 func (k key) String() string {
-	return []string{"timeout", "cancel"}[k]
+	return []string{"timeout", "cancel", "memo", "dryrun"}[k]
 }
 
 type options struct {
@@ -86,3 +89,55 @@ func Cancel(ctx gocontext.Context) {
 		cancel()
 	}
 }
+
+type memo struct {
+	mu      sync.Mutex
+	entries map[interface{}]*memoEntry
+}
+
+type memoEntry struct {
+	once  sync.Once
+	value interface{}
+	err   error
+}
+
+// WithMemo returns a copy of the parent context that memoizes the results of the functions called with Memoize, for as
+// long as the context lives (e.g. throughout a request).
+func WithMemo(parent gocontext.Context) gocontext.Context {
+	return gocontext.WithValue(parent, kMemo, &memo{entries: make(map[interface{}]*memoEntry)})
+}
+
+// Memoize calls fn only once per key within a context created with WithMemo, and returns the same result to all the
+// callers, including concurrent ones.
+// If the context does not memoize, fn is called every time.
+func Memoize(ctx gocontext.Context, key interface{}, fn func() (interface{}, error)) (interface{}, error) {
+	m, ok := ctx.Value(kMemo).(*memo)
+	if !ok {
+		return fn()
+	}
+
+	m.mu.Lock()
+	entry, exists := m.entries[key]
+	if !exists {
+		entry = &memoEntry{}
+		m.entries[key] = entry
+	}
+	m.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.value, entry.err = fn()
+	})
+	return entry.value, entry.err
+}
+
+// WithDryRun returns a copy of the parent context that tells the functions called with it to avoid side effects, such as
+// storing data in caches or sending requests to external services that may change their state.
+func WithDryRun(parent gocontext.Context) gocontext.Context {
+	return gocontext.WithValue(parent, kDryRun, true)
+}
+
+// IsDryRun tells whether the context was created with WithDryRun.
+func IsDryRun(ctx gocontext.Context) bool {
+	dryRun, _ := ctx.Value(kDryRun).(bool)
+	return dryRun
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/kuadrant/authorino/pkg/auth"
+	authorinoContext "github.com/kuadrant/authorino/pkg/context"
 	"github.com/kuadrant/authorino/pkg/evaluators/authorization"
 	"github.com/kuadrant/authorino/pkg/jsonexp"
 	"github.com/kuadrant/authorino/pkg/log"
@@ -15,6 +16,7 @@ const (
 	authorizationJSON       = "AUTHORIZATION_JSON"
 	authorizationKubernetes = "AUTHORIZATION_KUBERNETES"
 	authorizationAuthzed    = "AUTHORIZATION_AUTHZED"
+	authorizationScopes     = "AUTHORIZATION_SCOPES"
 )
 
 type AuthorizationConfig struct {
@@ -22,12 +24,16 @@ type AuthorizationConfig struct {
 	Priority   int                `yaml:"priority"`
 	Conditions jsonexp.Expression `yaml:"conditions"`
 	Metrics    bool               `yaml:"metrics"`
-	Cache      EvaluatorCache
+	Shadow     bool               `yaml:"shadow"`
+	// AllowShortCircuits makes an access granted by the config skip the authorization configs of lower priority
+	AllowShortCircuits bool `yaml:"allowShortCircuits"`
+	Cache              EvaluatorCache
 
 	OPA             *authorization.OPA                 `yaml:"opa,omitempty"`
 	JSON            *authorization.JSONPatternMatching `yaml:"json,omitempty"`
 	KubernetesAuthz *authorization.KubernetesAuthz     `yaml:"kubernetes,omitempty"`
 	Authzed         *authorization.Authzed             `yaml:"authzed,omitempty"`
+	Scopes          *authorization.Scopes              `yaml:"scopes,omitempty"`
 }
 
 func (config *AuthorizationConfig) GetAuthConfigEvaluator() auth.AuthConfigEvaluator {
@@ -40,6 +46,8 @@ func (config *AuthorizationConfig) GetAuthConfigEvaluator() auth.AuthConfigEvalu
 		return config.KubernetesAuthz
 	case authorizationAuthzed:
 		return config.Authzed
+	case authorizationScopes:
+		return config.Scopes
 	default:
 		return nil
 	}
@@ -55,9 +63,11 @@ func (config *AuthorizationConfig) Call(pipeline auth.AuthPipeline, ctx context.
 
 		cache := config.Cache
 		var cacheKey interface{}
+		var authJSON string
 
 		if cache != nil {
-			cacheKey = cache.ResolveKeyFor(pipeline.GetAuthorizationJSON())
+			authJSON = pipeline.GetAuthorizationJSON()
+			cacheKey = cache.ResolveKeyFor(authJSON)
 			if cachedObj, err := cache.Get(cacheKey); err != nil {
 				logger.V(1).Error(err, "failed to retrieve data from the cache")
 			} else if cachedObj != nil {
@@ -67,7 +77,7 @@ func (config *AuthorizationConfig) Call(pipeline auth.AuthPipeline, ctx context.
 
 		obj, err := evaluator.Call(pipeline, log.IntoContext(ctx, logger))
 
-		if err == nil && cacheKey != nil {
+		if err == nil && cacheKey != nil && !authorinoContext.IsDryRun(ctx) && cache.ShouldCache(authJSON, obj) {
 			if err := cache.Set(cacheKey, obj); err != nil {
 				logger.V(1).Info("unable to store data in the cache", "err", err)
 			}
@@ -95,6 +105,8 @@ func (config *AuthorizationConfig) GetType() string {
 		return authorizationKubernetes
 	case config.Authzed != nil:
 		return authorizationAuthzed
+	case config.Scopes != nil:
+		return authorizationScopes
 	default:
 		return ""
 	}
@@ -112,6 +124,18 @@ func (config *AuthorizationConfig) GetConditions() jsonexp.Expression {
 	return config.Conditions
 }
 
+// impl:ShadowEvaluator
+
+func (config *AuthorizationConfig) IsShadow() bool {
+	return config.Shadow
+}
+
+// impl:ShortCircuitEvaluator
+
+func (config *AuthorizationConfig) ShortCircuitsOnSuccess() bool {
+	return config.AllowShortCircuits
+}
+
 // impl:metrics.Object
 
 func (config *AuthorizationConfig) MetricsEnabled() bool {
@@ -133,6 +157,8 @@ func (config *AuthorizationConfig) getCleaner() auth.AuthConfigCleaner {
 	switch {
 	case config.OPA != nil:
 		return config.OPA
+	case config.Authzed != nil:
+		return config.Authzed
 	default:
 		return nil
 	}
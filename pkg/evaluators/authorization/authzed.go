@@ -2,18 +2,26 @@ package authorization
 
 import (
 	gocontext "context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/json"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	insecuregrpc "google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 
 	authzedpb "github.com/authzed/authzed-go/proto/authzed/api/v1"
-	authzed "github.com/authzed/authzed-go/v1"
 	"github.com/authzed/grpcutil"
 )
 
+// DefaultAuthzedTLSSessionCacheSize is the number of TLS sessions kept by default for resumption with the SpiceDB server
+const DefaultAuthzedTLSSessionCacheSize = 32
+
 type Authzed struct {
 	Endpoint     string
 	Insecure     bool
@@ -24,6 +32,19 @@ type Authzed struct {
 	Resource     json.JSONValue
 	ResourceKind json.JSONValue
 	Permission   json.JSONValue
+
+	// DisableConnectionReuse makes every check request to dial a new connection to the SpiceDB server
+	DisableConnectionReuse bool
+	// TLSSessionCacheSize is the capacity of the TLS client session cache; 0 disables TLS session resumption
+	TLSSessionCacheSize int
+	// KeepaliveTime is the interval between HTTP/2 keepalive pings sent over the connection; 0 disables the pings
+	KeepaliveTime time.Duration
+
+	rootCAs           *x509.CertPool
+	tlsSessionCache   tls.ClientSessionCache
+	tlsSessionCacheMu sync.Mutex
+	conn              *grpc.ClientConn
+	connMu            sync.Mutex
 }
 
 type permissionResponse struct {
@@ -32,19 +53,15 @@ type permissionResponse struct {
 }
 
 func (a *Authzed) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (interface{}, error) {
-	var dialOpts []grpc.DialOption
-
-	if a.Insecure {
-		dialOpts = append(dialOpts, grpcutil.WithInsecureBearerToken(a.SharedSecret), grpc.WithTransportCredentials(insecuregrpc.NewCredentials()))
-	} else {
-		systemCertsOption, _ := grpcutil.WithSystemCerts(grpcutil.VerifyCA)
-		dialOpts = append(dialOpts, grpcutil.WithBearerToken(a.SharedSecret), systemCertsOption)
-	}
-
-	client, err := authzed.NewClient(a.Endpoint, dialOpts...)
+	conn, err := a.getConnection()
 	if err != nil {
 		return nil, err
 	}
+	if a.DisableConnectionReuse {
+		defer conn.Close()
+	}
+
+	client := authzedpb.NewPermissionsServiceClient(conn)
 
 	authJSON := pipeline.GetAuthorizationJSON()
 
@@ -74,6 +91,79 @@ func (a *Authzed) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (inter
 	return obj, nil
 }
 
+// Clean closes the connection to the SpiceDB server, if any
+func (a *Authzed) Clean(_ gocontext.Context) error {
+	a.connMu.Lock()
+	defer a.connMu.Unlock()
+
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+// getConnection returns the gRPC connection to the SpiceDB server.
+// Unless connection reuse is disabled, the connection is dialed once and shared across check requests, multiplexing the
+// requests over the same HTTP/2 connection.
+func (a *Authzed) getConnection() (*grpc.ClientConn, error) {
+	if a.DisableConnectionReuse {
+		return grpc.Dial(a.Endpoint, a.dialOptions()...)
+	}
+
+	a.connMu.Lock()
+	defer a.connMu.Unlock()
+
+	if a.conn == nil {
+		conn, err := grpc.Dial(a.Endpoint, a.dialOptions()...)
+		if err != nil {
+			return nil, err
+		}
+		a.conn = conn
+	}
+	return a.conn, nil
+}
+
+func (a *Authzed) dialOptions() []grpc.DialOption {
+	var dialOpts []grpc.DialOption
+
+	if a.Insecure {
+		dialOpts = append(dialOpts, grpcutil.WithInsecureBearerToken(a.SharedSecret), grpc.WithTransportCredentials(insecuregrpc.NewCredentials()))
+	} else {
+		tlsConfig := &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    a.rootCAs, // nil means the system cert pool
+		}
+		if sessionCache := a.getTLSSessionCache(); sessionCache != nil {
+			tlsConfig.ClientSessionCache = sessionCache
+		}
+		dialOpts = append(dialOpts, grpcutil.WithBearerToken(a.SharedSecret), grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	if a.KeepaliveTime > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: a.KeepaliveTime}))
+	}
+
+	return dialOpts
+}
+
+// getTLSSessionCache returns the TLS client session cache, shared across all connections dialed by the evaluator so
+// new connections to the same endpoint can resume a previous session instead of performing a full handshake
+func (a *Authzed) getTLSSessionCache() tls.ClientSessionCache {
+	if a.TLSSessionCacheSize <= 0 {
+		return nil
+	}
+
+	a.tlsSessionCacheMu.Lock()
+	defer a.tlsSessionCacheMu.Unlock()
+
+	if a.tlsSessionCache == nil {
+		a.tlsSessionCache = tls.NewLRUClientSessionCache(a.TLSSessionCacheSize)
+	}
+	return a.tlsSessionCache
+}
+
 func authzedObjectFor(name, kind json.JSONValue, authJSON string) *authzedpb.ObjectReference {
 	return &authzedpb.ObjectReference{
 		ObjectId:   fmt.Sprintf("%s", name.ResolveFor(authJSON)),
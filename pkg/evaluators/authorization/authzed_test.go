@@ -2,8 +2,18 @@ package authorization
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	gojson "encoding/json"
+	"math/big"
+	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
 	"github.com/kuadrant/authorino/pkg/httptest"
@@ -13,6 +23,7 @@ import (
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/golang/mock/gomock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"gotest.tools/assert"
 )
 
@@ -104,6 +115,187 @@ func TestAuthzedCallForbidden(t *testing.T) {
 	assert.Check(t, obj == nil)
 }
 
+func TestAuthzedReusesConnection(t *testing.T) {
+	server := newTestAuthzedTLSServer(t)
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(testAuthzedAuthDataMock()).AnyTimes()
+
+	authzed := server.newAuthzed()
+	authzed.TLSSessionCacheSize = DefaultAuthzedTLSSessionCacheSize
+	defer authzed.Clean(context.TODO())
+
+	for i := 0; i < 5; i++ {
+		_, err := authzed.Call(pipelineMock, context.TODO())
+		assert.NilError(t, err)
+	}
+
+	assert.Equal(t, server.connections.Load(), int32(1))
+	assert.Equal(t, server.handshakes.Load(), int32(1))
+}
+
+func TestAuthzedWithoutConnectionReuse(t *testing.T) {
+	server := newTestAuthzedTLSServer(t)
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(testAuthzedAuthDataMock()).AnyTimes()
+
+	authzed := server.newAuthzed()
+	authzed.DisableConnectionReuse = true
+	authzed.TLSSessionCacheSize = DefaultAuthzedTLSSessionCacheSize
+
+	for i := 0; i < 3; i++ {
+		_, err := authzed.Call(pipelineMock, context.TODO())
+		assert.NilError(t, err)
+	}
+
+	assert.Equal(t, server.connections.Load(), int32(3))
+	assert.Equal(t, server.handshakes.Load(), int32(3))
+	assert.Equal(t, server.resumedHandshakes.Load(), int32(2)) // all but the first handshake resumed the TLS session
+}
+
+func TestAuthzedWithoutTLSSessionCache(t *testing.T) {
+	server := newTestAuthzedTLSServer(t)
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(testAuthzedAuthDataMock()).AnyTimes()
+
+	authzed := server.newAuthzed()
+	authzed.DisableConnectionReuse = true
+	authzed.TLSSessionCacheSize = 0
+
+	for i := 0; i < 3; i++ {
+		_, err := authzed.Call(pipelineMock, context.TODO())
+		assert.NilError(t, err)
+	}
+
+	assert.Equal(t, server.handshakes.Load(), int32(3))
+	assert.Equal(t, server.resumedHandshakes.Load(), int32(0))
+}
+
+func TestAuthzedCleanClosesConnection(t *testing.T) {
+	server := newTestAuthzedTLSServer(t)
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(testAuthzedAuthDataMock()).AnyTimes()
+
+	authzed := server.newAuthzed()
+	authzed.TLSSessionCacheSize = DefaultAuthzedTLSSessionCacheSize
+
+	_, err := authzed.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.NilError(t, authzed.Clean(context.TODO()))
+	assert.Check(t, authzed.conn == nil)
+
+	_, err = authzed.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.NilError(t, authzed.Clean(context.TODO()))
+
+	assert.Equal(t, server.connections.Load(), int32(2))
+	assert.Equal(t, server.resumedHandshakes.Load(), int32(1))
+}
+
+type testAuthzedTLSServer struct {
+	*grpc.Server
+	listener          net.Listener
+	rootCAs           *x509.CertPool
+	connections       atomic.Int32
+	handshakes        atomic.Int32
+	resumedHandshakes atomic.Int32
+}
+
+func newTestAuthzedTLSServer(t *testing.T) *testAuthzedTLSServer {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "spicedb"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	cert, _ := x509.ParseCertificate(certDER)
+
+	s := &testAuthzedTLSServer{rootCAs: x509.NewCertPool()}
+	s.rootCAs.AddCert(cert)
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+		VerifyConnection: func(state tls.ConnectionState) error {
+			s.handshakes.Add(1)
+			if state.DidResume {
+				s.resumedHandshakes.Add(1)
+			}
+			return nil
+		},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	s.listener = &testCountingListener{Listener: listener, count: &s.connections}
+
+	s.Server = grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	authzedpb.RegisterPermissionsServiceServer(s.Server, &testAuthzedPermissionService{
+		checkPermissionHandler: func() *authzedpb.CheckPermissionResponse {
+			return &authzedpb.CheckPermissionResponse{Permissionship: authzedpb.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION}
+		},
+	})
+	go s.Serve(s.listener)
+
+	return s
+}
+
+func (s *testAuthzedTLSServer) Close() {
+	s.Stop()
+}
+
+func (s *testAuthzedTLSServer) newAuthzed() *Authzed {
+	return &Authzed{
+		Endpoint:     s.listener.Addr().String(),
+		SharedSecret: "secret",
+		Subject:      json.JSONValue{Static: "1"},
+		SubjectKind:  json.JSONValue{Static: "user"},
+		Resource:     json.JSONValue{Static: "123"},
+		ResourceKind: json.JSONValue{Static: "post"},
+		Permission:   json.JSONValue{Static: "read"},
+		rootCAs:      s.rootCAs,
+	}
+}
+
+type testCountingListener struct {
+	net.Listener
+	count *atomic.Int32
+}
+
+func (l *testCountingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.count.Add(1)
+	}
+	return conn, err
+}
+
 func testAuthzedAuthDataMock() string {
 	type mockIdentityObject struct {
 		User string `json:"user"`
@@ -8,20 +8,58 @@ import (
 	"github.com/kuadrant/authorino/pkg/jsonexp"
 )
 
+type JSONPatternMatchingRuleEffect bool
+
+const (
+	JSONPatternMatchingRuleAllow JSONPatternMatchingRuleEffect = true
+	JSONPatternMatchingRuleDeny  JSONPatternMatchingRuleEffect = false
+)
+
+// JSONPatternMatchingRule is an entry of an ordered allow/deny list of rules
+type JSONPatternMatchingRule struct {
+	Effect     JSONPatternMatchingRuleEffect
+	Conditions jsonexp.Expression
+}
+
 type JSONPatternMatching struct {
+	// Rules must all match for the request to be authorized
 	Rules jsonexp.Expression
+	// OrderedRules are evaluated in order; the first matching rule decides the authorization, falling through to deny
+	OrderedRules []JSONPatternMatchingRule
 }
 
 func (j *JSONPatternMatching) Call(pipeline auth.AuthPipeline, ctx context.Context) (interface{}, error) {
-	if j.Rules == nil {
-		return true, nil
+	authJSON := pipeline.GetAuthorizationJSON()
+
+	if j.Rules != nil {
+		authorized, err := j.Rules.Matches(authJSON)
+		if err != nil {
+			return false, err
+		}
+		if !authorized {
+			return false, fmt.Errorf(unauthorizedErrorMsg)
+		}
 	}
-	authorized, err := j.Rules.Matches(pipeline.GetAuthorizationJSON())
-	if err != nil {
-		return false, err
+
+	if j.OrderedRules == nil {
+		return true, nil
 	}
-	if !authorized {
-		return false, fmt.Errorf(unauthorizedErrorMsg)
+
+	for _, rule := range j.OrderedRules {
+		if rule.Conditions != nil {
+			match, err := rule.Conditions.Matches(authJSON)
+			if err != nil {
+				return false, err
+			}
+			if !match {
+				continue
+			}
+		}
+		if rule.Effect == JSONPatternMatchingRuleAllow {
+			return true, nil
+		}
+		break
 	}
-	return true, nil
+
+	return false, fmt.Errorf(unauthorizedErrorMsg)
 }
@@ -301,3 +301,108 @@ func BenchmarkJSONPatternMatchingAuthz(b *testing.B) {
 	b.StopTimer()
 	assert.NilError(b, err)
 }
+
+func TestCallWithOrderedRules(t *testing.T) {
+	ctrl := NewController(t)
+	defer ctrl.Finish()
+
+	type authorizationJSON struct {
+		Context *envoy_auth.AttributeContext `json:"context"`
+	}
+
+	requestJSON := func(method, path string) string {
+		authJSON, _ := gojson.Marshal(&authorizationJSON{
+			Context: &envoy_auth.AttributeContext{
+				Request: &envoy_auth.AttributeContext_Request{
+					Http: &envoy_auth.AttributeContext_HttpRequest{Method: method, Path: path},
+				},
+			},
+		})
+		return string(authJSON)
+	}
+
+	jsonAuth := &JSONPatternMatching{
+		OrderedRules: []JSONPatternMatchingRule{
+			{ // deny deleting anything under /admin
+				Effect: JSONPatternMatchingRuleDeny,
+				Conditions: jsonexp.All(
+					jsonexp.Pattern{Selector: "context.request.http.path", Operator: jsonexp.RegexOperator, Value: "^/admin"},
+					jsonexp.Pattern{Selector: "context.request.http.method", Operator: jsonexp.EqualOperator, Value: "DELETE"},
+				),
+			},
+			{ // allow everything else under /admin
+				Effect:     JSONPatternMatchingRuleAllow,
+				Conditions: jsonexp.All(jsonexp.Pattern{Selector: "context.request.http.path", Operator: jsonexp.RegexOperator, Value: "^/admin"}),
+			},
+			{ // deny everything under /internal, including GETs allowed by the following rule
+				Effect:     JSONPatternMatchingRuleDeny,
+				Conditions: jsonexp.All(jsonexp.Pattern{Selector: "context.request.http.path", Operator: jsonexp.RegexOperator, Value: "^/internal"}),
+			},
+			{ // allow all GETs
+				Effect:     JSONPatternMatchingRuleAllow,
+				Conditions: jsonexp.All(jsonexp.Pattern{Selector: "context.request.http.method", Operator: jsonexp.EqualOperator, Value: "GET"}),
+			},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		authorized bool
+	}{
+		{name: "first-match deny", method: "DELETE", path: "/admin/users", authorized: false},
+		{name: "first-match deny (prefix)", method: "DELETE", path: "/admin", authorized: false},
+		{name: "first-match allow", method: "POST", path: "/admin/users", authorized: true},
+		{name: "first-match deny shadows later allow", method: "GET", path: "/internal/metrics", authorized: false},
+		{name: "later allow", method: "GET", path: "/products", authorized: true},
+		{name: "default deny fallthrough", method: "POST", path: "/products", authorized: false},
+	}
+
+	for _, tc := range testCases {
+		pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+		pipelineMock.EXPECT().GetAuthorizationJSON().Return(requestJSON(tc.method, tc.path))
+
+		authorized, err := jsonAuth.Call(pipelineMock, nil)
+		assert.Equal(t, authorized.(bool), tc.authorized, tc.name)
+		if tc.authorized {
+			assert.NilError(t, err, tc.name)
+		} else {
+			assert.Error(t, err, "Unauthorized", tc.name)
+		}
+	}
+}
+
+func TestCallWithOrderedRulesAndPatterns(t *testing.T) {
+	ctrl := NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"identity":{"group":"admin","active":false}}}`).AnyTimes()
+
+	jsonAuth := &JSONPatternMatching{
+		Rules: jsonexp.All(jsonexp.Pattern{Selector: "auth.identity.active", Operator: jsonexp.EqualOperator, Value: "true"}),
+		OrderedRules: []JSONPatternMatchingRule{
+			{Effect: JSONPatternMatchingRuleAllow, Conditions: jsonexp.All(jsonexp.Pattern{Selector: "auth.identity.group", Operator: jsonexp.EqualOperator, Value: "admin"})},
+		},
+	}
+	authorized, err := jsonAuth.Call(pipelineMock, nil)
+	assert.Check(t, !authorized.(bool))
+	assert.Error(t, err, "Unauthorized")
+
+	// rule without conditions matches all requests
+	jsonAuth = &JSONPatternMatching{
+		OrderedRules: []JSONPatternMatchingRule{{Effect: JSONPatternMatchingRuleAllow}},
+	}
+	authorized, err = jsonAuth.Call(pipelineMock, nil)
+	assert.Check(t, authorized.(bool))
+	assert.NilError(t, err)
+
+	// no rules
+	jsonAuth = &JSONPatternMatching{
+		OrderedRules: []JSONPatternMatchingRule{},
+	}
+	authorized, err = jsonAuth.Call(pipelineMock, nil)
+	assert.Check(t, !authorized.(bool))
+	assert.Error(t, err, "Unauthorized")
+}
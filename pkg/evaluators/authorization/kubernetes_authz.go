@@ -4,6 +4,7 @@ import (
 	gocontext "context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/context"
@@ -15,18 +16,113 @@ import (
 	"k8s.io/client-go/kubernetes"
 	kubeAuthzClient "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/transport"
+)
+
+const (
+	// MaxBatchSize is the maximum number of resources checked in a batch of SubjectAccessReviews
+	MaxBatchSize = 100
+	// maxConcurrentBatchReviews is the maximum number of SubjectAccessReviews of a batch issued concurrently
+	maxConcurrentBatchReviews = 5
 )
 
 type kubernetesSubjectAccessReviewer interface {
 	SubjectAccessReviews() kubeAuthzClient.SubjectAccessReviewInterface
 }
 
+type kubernetesSelfSubjectAccessReviewer interface {
+	// CreateImpersonated issues the SelfSubjectAccessReview as the impersonated user and groups
+	CreateImpersonated(ctx gocontext.Context, user string, groups []string, review *kubeAuthz.SelfSubjectAccessReview) (*kubeAuthz.SelfSubjectAccessReview, error)
+}
+
+// impersonatingReviewer issues SelfSubjectAccessReviews with impersonation headers, so the review reflects the
+// actual access of the impersonated user. It requires Authorino to be granted the 'impersonate' verb on the users and
+// groups.
+type impersonatingReviewer struct {
+	client rest.Interface
+}
+
+func (r *impersonatingReviewer) CreateImpersonated(ctx gocontext.Context, user string, groups []string, review *kubeAuthz.SelfSubjectAccessReview) (*kubeAuthz.SelfSubjectAccessReview, error) {
+	req := r.client.Post().Resource("selfsubjectaccessreviews").SetHeader(transport.ImpersonateUserHeader, user)
+	if len(groups) > 0 {
+		req = req.SetHeader(transport.ImpersonateGroupHeader, groups...)
+	}
+	result := &kubeAuthz.SelfSubjectAccessReview{}
+	if err := req.Body(review).Do(ctx).Into(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func NewKubernetesAuthz(user json.JSONValue, groups []string, resourceAttributes *KubernetesAuthzResourceAttributes) (*KubernetesAuthz, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	return newKubernetesAuthzForConfig(config, user, groups, resourceAttributes)
+}
+
+// NewKubernetesAuthzWithKubeconfig creates a KubernetesAuthz that issues the SubjectAccessReviews to the API server of
+// the cluster set in the kubeconfig (e.g. a remote cluster), instead of the cluster where Authorino is running
+func NewKubernetesAuthzWithKubeconfig(kubeconfig []byte, user json.JSONValue, groups []string, resourceAttributes *KubernetesAuthzResourceAttributes) (*KubernetesAuthz, error) {
+	config, err := restConfigFromInlineKubeconfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	return newKubernetesAuthzForConfig(config, user, groups, resourceAttributes)
+}
+
+// restConfigFromInlineKubeconfig builds the rest config for the current context of a kubeconfig, out of the server,
+// the CA data, the token and the client certificate and key data only. Kubeconfigs are supplied by users in secrets,
+// thus any setting that reads files or runs commands from the filesystem of Authorino is rejected.
+func restConfigFromInlineKubeconfig(kubeconfig []byte) (*rest.Config, error) {
+	kubeconfigObj, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeContext, ok := kubeconfigObj.Contexts[kubeconfigObj.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found", kubeconfigObj.CurrentContext)
+	}
+	cluster, ok := kubeconfigObj.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found", kubeContext.Cluster)
+	}
+	authInfo, ok := kubeconfigObj.AuthInfos[kubeContext.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("user %q not found", kubeContext.AuthInfo)
+	}
+
+	if cluster.Server == "" {
+		return nil, fmt.Errorf("cluster %q has no server", kubeContext.Cluster)
+	}
+	if cluster.CertificateAuthority != "" {
+		return nil, fmt.Errorf("cluster %q: certificate-authority files are not supported, use certificate-authority-data", kubeContext.Cluster)
+	}
+	if authInfo.Exec != nil || authInfo.AuthProvider != nil {
+		return nil, fmt.Errorf("user %q: exec and auth-provider plugins are not supported", kubeContext.AuthInfo)
+	}
+	if authInfo.TokenFile != "" || authInfo.ClientCertificate != "" || authInfo.ClientKey != "" {
+		return nil, fmt.Errorf("user %q: tokenFile, client-certificate and client-key files are not supported, use token, client-certificate-data and client-key-data", kubeContext.AuthInfo)
+	}
+
+	return &rest.Config{
+		Host:        cluster.Server,
+		BearerToken: authInfo.Token,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData:     cluster.CertificateAuthorityData,
+			CertData:   authInfo.ClientCertificateData,
+			KeyData:    authInfo.ClientKeyData,
+			ServerName: cluster.TLSServerName,
+		},
+	}, nil
+}
+
+func newKubernetesAuthzForConfig(config *rest.Config, user json.JSONValue, groups []string, resourceAttributes *KubernetesAuthzResourceAttributes) (*KubernetesAuthz, error) {
 	k8sClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, err
@@ -37,6 +133,7 @@ func NewKubernetesAuthz(user json.JSONValue, groups []string, resourceAttributes
 		Groups:             groups,
 		ResourceAttributes: resourceAttributes,
 		authorizer:         k8sClient.AuthorizationV1(),
+		selfAuthorizer:     &impersonatingReviewer{client: k8sClient.AuthorizationV1().RESTClient()},
 	}, nil
 }
 
@@ -47,14 +144,20 @@ type KubernetesAuthzResourceAttributes struct {
 	Name        json.JSONValue
 	SubResource json.JSONValue
 	Verb        json.JSONValue
+	// Batch expands the attributes that resolve to arrays into multiple resources, checked together
+	Batch bool
 }
 
 type KubernetesAuthz struct {
 	User               json.JSONValue
 	Groups             []string
 	ResourceAttributes *KubernetesAuthzResourceAttributes
+	// Impersonate issues SelfSubjectAccessReviews as the user and groups (impersonation), instead of
+	// SubjectAccessReviews that state the user and groups explicitly
+	Impersonate bool
 
-	authorizer kubernetesSubjectAccessReviewer
+	authorizer     kubernetesSubjectAccessReviewer
+	selfAuthorizer kubernetesSelfSubjectAccessReviewer
 }
 
 func (k *KubernetesAuthz) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (interface{}, error) {
@@ -67,10 +170,21 @@ func (k *KubernetesAuthz) Call(pipeline auth.AuthPipeline, ctx gocontext.Context
 		return fmt.Sprintf("%s", value.ResolveFor(authJSON))
 	}
 
-	subjectAccessReview := kubeAuthz.SubjectAccessReview{
-		Spec: kubeAuthz.SubjectAccessReviewSpec{
-			User: jsonValueToStr(k.User),
-		},
+	subjectAccessReview := kubeAuthz.SubjectAccessReview{}
+	if user := k.User.ResolveFor(authJSON); user != nil {
+		subjectAccessReview.Spec.User = fmt.Sprintf("%s", user)
+	}
+
+	if len(k.Groups) > 0 {
+		subjectAccessReview.Spec.Groups = k.Groups
+	}
+
+	if k.ResourceAttributes != nil && k.ResourceAttributes.Batch {
+		batch, err := k.batchResourceAttributes(authJSON)
+		if err != nil {
+			return false, err
+		}
+		return k.reviewBatch(ctx, subjectAccessReview, batch)
 	}
 
 	if k.ResourceAttributes != nil {
@@ -93,8 +207,12 @@ func (k *KubernetesAuthz) Call(pipeline auth.AuthPipeline, ctx gocontext.Context
 		}
 	}
 
-	if len(k.Groups) > 0 {
-		subjectAccessReview.Spec.Groups = k.Groups
+	return k.review(ctx, subjectAccessReview)
+}
+
+func (k *KubernetesAuthz) review(ctx gocontext.Context, subjectAccessReview kubeAuthz.SubjectAccessReview) (bool, error) {
+	if k.Impersonate {
+		return k.reviewImpersonated(ctx, subjectAccessReview)
 	}
 
 	log.FromContext(ctx).WithName("kubernetesauthz").V(1).Info("calling kubernetes subject access review api", "subjectaccessreview", subjectAccessReview)
@@ -106,6 +224,141 @@ func (k *KubernetesAuthz) Call(pipeline auth.AuthPipeline, ctx gocontext.Context
 	}
 }
 
+// reviewImpersonated checks the attributes of the review as the user and groups of the review, by impersonation
+func (k *KubernetesAuthz) reviewImpersonated(ctx gocontext.Context, subjectAccessReview kubeAuthz.SubjectAccessReview) (bool, error) {
+	user := subjectAccessReview.Spec.User
+	if user == "" {
+		return false, fmt.Errorf("not authorized: impersonation requires a user")
+	}
+
+	selfSubjectAccessReview := kubeAuthz.SelfSubjectAccessReview{
+		Spec: kubeAuthz.SelfSubjectAccessReviewSpec{
+			ResourceAttributes:    subjectAccessReview.Spec.ResourceAttributes,
+			NonResourceAttributes: subjectAccessReview.Spec.NonResourceAttributes,
+		},
+	}
+
+	log.FromContext(ctx).WithName("kubernetesauthz").V(1).Info("calling kubernetes self subject access review api", "user", user, "groups", subjectAccessReview.Spec.Groups, "selfsubjectaccessreview", selfSubjectAccessReview)
+
+	result, err := k.selfAuthorizer.CreateImpersonated(ctx, user, subjectAccessReview.Spec.Groups, &selfSubjectAccessReview)
+	if err != nil {
+		return false, err
+	}
+	return parseSubjectAccessReviewResult(&kubeAuthz.SubjectAccessReview{Status: result.Status})
+}
+
+// reviewBatch checks the resources of the batch concurrently, with up to maxConcurrentBatchReviews SubjectAccessReviews
+// in flight at a time, and authorizes only if all of them are allowed.
+// Once one of the resources is denied, the resources not yet checked are skipped.
+func (k *KubernetesAuthz) reviewBatch(ctx gocontext.Context, subjectAccessReview kubeAuthz.SubjectAccessReview, batch []*kubeAuthz.ResourceAttributes) (bool, error) {
+	if len(batch) == 0 {
+		return false, fmt.Errorf("not authorized: no resources to check")
+	}
+
+	ctx, cancel := gocontext.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failure error
+	)
+	indexes := make(chan int)
+	for w := 0; w < maxConcurrentBatchReviews && w < len(batch); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				review := *subjectAccessReview.DeepCopy()
+				review.Spec.ResourceAttributes = batch[i]
+				if _, err := k.review(ctx, review); err != nil {
+					mu.Lock()
+					if failure == nil {
+						r := batch[i]
+						failure = fmt.Errorf("%w (resource: %s %s/%s/%s in namespace %s)", err, r.Verb, r.Group, r.Resource, r.Name, r.Namespace)
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range batch {
+		if ctx.Err() != nil {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if failure != nil {
+		return false, failure
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// batchResourceAttributes zips the resource attributes that resolve to arrays into a list of resources.
+// Attributes that do not resolve to arrays apply to all the resources of the batch.
+func (k *KubernetesAuthz) batchResourceAttributes(authJSON string) ([]*kubeAuthz.ResourceAttributes, error) {
+	size := -1
+	resolve := func(value json.JSONValue) (values []string, single string) {
+		resolved := value.ResolveFor(authJSON)
+		items, isArray := resolved.([]interface{})
+		if !isArray {
+			return nil, fmt.Sprintf("%s", resolved)
+		}
+		values = make([]string, len(items))
+		for i, item := range items {
+			values[i] = fmt.Sprintf("%s", item)
+		}
+		return values, ""
+	}
+
+	attrs := k.ResourceAttributes
+	resolved := make([][]string, 6)
+	singles := make([]string, 6)
+	for i, value := range []json.JSONValue{attrs.Namespace, attrs.Group, attrs.Resource, attrs.Name, attrs.SubResource, attrs.Verb} {
+		resolved[i], singles[i] = resolve(value)
+		if resolved[i] == nil {
+			continue
+		}
+		if size >= 0 && len(resolved[i]) != size {
+			return nil, fmt.Errorf("failed to batch resource attributes: arrays of different lengths")
+		}
+		size = len(resolved[i])
+	}
+	if size < 0 {
+		size = 1 // no array attribute, a batch of one
+	}
+	if size > MaxBatchSize {
+		return nil, fmt.Errorf("failed to batch resource attributes: too many resources (max: %d)", MaxBatchSize)
+	}
+
+	at := func(attr, index int) string {
+		if resolved[attr] == nil {
+			return singles[attr]
+		}
+		return resolved[attr][index]
+	}
+
+	batch := make([]*kubeAuthz.ResourceAttributes, size)
+	for i := range batch {
+		batch[i] = &kubeAuthz.ResourceAttributes{
+			Namespace:   at(0, i),
+			Group:       at(1, i),
+			Resource:    at(2, i),
+			Name:        at(3, i),
+			Subresource: at(4, i),
+			Verb:        at(5, i),
+		}
+	}
+	return batch, nil
+}
+
 func parseSubjectAccessReviewResult(subjectAccessReview *kubeAuthz.SubjectAccessReview) (bool, error) {
 	status := subjectAccessReview.Status
 	if status.Allowed {
@@ -2,7 +2,14 @@ package authorization
 
 import (
 	"context"
+	gojson "encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
 	"github.com/kuadrant/authorino/pkg/json"
@@ -172,3 +179,424 @@ func TestKubernetesAuthzResource_Denied(t *testing.T) {
 	assert.Equal(t, requestData.User, "john")
 	assert.Equal(t, requestData.ResourceAttributes.Namespace, "default")
 }
+
+// batchSubjectAccessReviews denies the resources whose names are listed as denied, and records the requested resources
+type batchSubjectAccessReviews struct {
+	kubeAuthzClient.SubjectAccessReviewInterface
+	client *k8sBatchAuthorizationClientMock
+}
+
+func (t *batchSubjectAccessReviews) Create(ctx context.Context, subjectAccessReview *kubeAuthz.SubjectAccessReview, opts metav1.CreateOptions) (*kubeAuthz.SubjectAccessReview, error) {
+	t.client.mu.Lock()
+	t.client.requests = append(t.client.requests, *subjectAccessReview.Spec.DeepCopy())
+	t.client.inFlight++
+	if t.client.inFlight > t.client.maxInFlight {
+		t.client.maxInFlight = t.client.inFlight
+	}
+	t.client.mu.Unlock()
+
+	time.Sleep(t.client.latency)
+
+	t.client.mu.Lock()
+	defer t.client.mu.Unlock()
+	t.client.inFlight--
+
+	name := subjectAccessReview.Spec.ResourceAttributes.Name
+	for _, denied := range t.client.denied {
+		if name == denied {
+			return &kubeAuthz.SubjectAccessReview{Status: kubeAuthz.SubjectAccessReviewStatus{Allowed: false, Reason: "forbidden " + name}}, nil
+		}
+	}
+	return &kubeAuthz.SubjectAccessReview{Status: kubeAuthz.SubjectAccessReviewStatus{Allowed: true}}, nil
+}
+
+type k8sBatchAuthorizationClientMock struct {
+	denied      []string
+	latency     time.Duration
+	requests    []kubeAuthz.SubjectAccessReviewSpec
+	inFlight    int
+	maxInFlight int
+	mu          sync.Mutex
+}
+
+func (client *k8sBatchAuthorizationClientMock) SubjectAccessReviews() kubeAuthzClient.SubjectAccessReviewInterface {
+	return &batchSubjectAccessReviews{client: client}
+}
+
+func TestKubernetesAuthzResourceBatch_Allowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{"request":{"http":{"method":"GET","path":"/hello","body":"{\"resources\":[{\"ns\":\"ns-1\",\"name\":\"foo\"},{\"ns\":\"ns-2\",\"name\":\"bar\"}]}"}}},"auth":{"identity":{"username":"john"}}}`)
+
+	authorizer := &k8sBatchAuthorizationClientMock{}
+	kubernetesAuth := &KubernetesAuthz{
+		User:   json.JSONValue{Pattern: "auth.identity.username"},
+		Groups: []string{},
+		ResourceAttributes: &KubernetesAuthzResourceAttributes{
+			Namespace: json.JSONValue{Pattern: "context.request.http.body.@fromstr.resources.#.ns"},
+			Resource:  json.JSONValue{Static: "configmaps"},
+			Name:      json.JSONValue{Pattern: "context.request.http.body.@fromstr.resources.#.name"},
+			Verb:      json.JSONValue{Static: "get"},
+			Batch:     true,
+		},
+		authorizer: authorizer,
+	}
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+
+	assert.Check(t, authorized.(bool))
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(authorizer.requests), 2)
+	reviewed := map[string]string{}
+	for _, request := range authorizer.requests {
+		assert.Equal(t, request.User, "john")
+		assert.Equal(t, request.ResourceAttributes.Resource, "configmaps")
+		assert.Equal(t, request.ResourceAttributes.Verb, "get")
+		reviewed[request.ResourceAttributes.Name] = request.ResourceAttributes.Namespace
+	}
+	assert.Equal(t, reviewed["foo"], "ns-1")
+	assert.Equal(t, reviewed["bar"], "ns-2")
+}
+
+func TestKubernetesAuthzResourceBatch_OneDenied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{"request":{"http":{"method":"GET","path":"/hello"}}},"auth":{"identity":{"username":"john","configmaps":["foo","bar","baz"]}}}`)
+
+	authorizer := &k8sBatchAuthorizationClientMock{denied: []string{"bar"}}
+	kubernetesAuth := &KubernetesAuthz{
+		User:   json.JSONValue{Pattern: "auth.identity.username"},
+		Groups: []string{},
+		ResourceAttributes: &KubernetesAuthzResourceAttributes{
+			Namespace: json.JSONValue{Static: "default"},
+			Resource:  json.JSONValue{Static: "configmaps"},
+			Name:      json.JSONValue{Pattern: "auth.identity.configmaps"},
+			Verb:      json.JSONValue{Static: "get"},
+			Batch:     true,
+		},
+		authorizer: authorizer,
+	}
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+
+	assert.Check(t, !authorized.(bool))
+	assert.ErrorContains(t, err, "not authorized: forbidden bar")
+	assert.ErrorContains(t, err, "/configmaps/bar in namespace default")
+	assert.Check(t, len(authorizer.requests) >= 2) // the resources not yet checked when one is denied are skipped
+}
+
+func TestKubernetesAuthzResourceBatch_BoundedConcurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	names := make([]string, 20)
+	for i := range names {
+		names[i] = fmt.Sprintf(`"cm-%d"`, i)
+	}
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"identity":{"username":"john","configmaps":[` + strings.Join(names, ",") + `]}}}`)
+
+	authorizer := &k8sBatchAuthorizationClientMock{latency: 10 * time.Millisecond}
+	kubernetesAuth := &KubernetesAuthz{
+		User: json.JSONValue{Pattern: "auth.identity.username"},
+		ResourceAttributes: &KubernetesAuthzResourceAttributes{
+			Namespace: json.JSONValue{Static: "default"},
+			Resource:  json.JSONValue{Static: "configmaps"},
+			Name:      json.JSONValue{Pattern: "auth.identity.configmaps"},
+			Verb:      json.JSONValue{Static: "get"},
+			Batch:     true,
+		},
+		authorizer: authorizer,
+	}
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+
+	assert.Check(t, authorized.(bool))
+	assert.NilError(t, err)
+	assert.Equal(t, len(authorizer.requests), 20)
+	assert.Check(t, authorizer.maxInFlight <= maxConcurrentBatchReviews)
+}
+
+func TestKubernetesAuthzResourceBatch_TooManyResources(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	names := make([]string, MaxBatchSize+1)
+	for i := range names {
+		names[i] = fmt.Sprintf(`"cm-%d"`, i)
+	}
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"identity":{"username":"john","configmaps":[` + strings.Join(names, ",") + `]}}}`)
+
+	authorizer := &k8sBatchAuthorizationClientMock{}
+	kubernetesAuth := &KubernetesAuthz{
+		User: json.JSONValue{Pattern: "auth.identity.username"},
+		ResourceAttributes: &KubernetesAuthzResourceAttributes{
+			Name:  json.JSONValue{Pattern: "auth.identity.configmaps"},
+			Batch: true,
+		},
+		authorizer: authorizer,
+	}
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+
+	assert.Check(t, !authorized.(bool))
+	assert.ErrorContains(t, err, "too many resources")
+	assert.Equal(t, len(authorizer.requests), 0)
+}
+
+func TestKubernetesAuthzResourceBatch_ArraysOfDifferentLengths(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"identity":{"username":"john","namespaces":["ns-1","ns-2"],"configmaps":["foo","bar","baz"]}}}`)
+
+	authorizer := &k8sBatchAuthorizationClientMock{}
+	kubernetesAuth := &KubernetesAuthz{
+		User: json.JSONValue{Pattern: "auth.identity.username"},
+		ResourceAttributes: &KubernetesAuthzResourceAttributes{
+			Namespace: json.JSONValue{Pattern: "auth.identity.namespaces"},
+			Name:      json.JSONValue{Pattern: "auth.identity.configmaps"},
+			Batch:     true,
+		},
+		authorizer: authorizer,
+	}
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+
+	assert.Check(t, !authorized.(bool))
+	assert.ErrorContains(t, err, "arrays of different lengths")
+	assert.Equal(t, len(authorizer.requests), 0)
+}
+
+func newRemoteKubernetesAPIServer(t *testing.T, allowed bool, reviews *[]kubeAuthz.SubjectAccessReviewSpec) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/apis/authorization.k8s.io/v1/subjectaccessreviews" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		review := kubeAuthz.SubjectAccessReview{}
+		if err := gojson.NewDecoder(r.Body).Decode(&review); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*reviews = append(*reviews, review.Spec)
+		review.Status = kubeAuthz.SubjectAccessReviewStatus{Allowed: allowed}
+		w.Header().Set("Content-Type", "application/json")
+		_ = gojson.NewEncoder(w).Encode(review)
+	}))
+}
+
+func remoteKubeconfig(server string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: %s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: authorino
+current-context: remote
+users:
+- name: authorino
+  user:
+    token: remote-token
+`, server))
+}
+
+func TestKubernetesAuthzWithKubeconfig_Allowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var reviews []kubeAuthz.SubjectAccessReviewSpec
+	remote := newRemoteKubernetesAPIServer(t, true, &reviews)
+	defer remote.Close()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{"request":{"http":{"method":"GET","path":"/hello"}}},"auth":{"identity":{"username":"john"}}}`)
+
+	kubernetesAuth, err := NewKubernetesAuthzWithKubeconfig(
+		remoteKubeconfig(remote.URL),
+		json.JSONValue{Pattern: "auth.identity.username"},
+		[]string{},
+		&KubernetesAuthzResourceAttributes{Namespace: json.JSONValue{Static: "default"}, Resource: json.JSONValue{Static: "pods"}, Verb: json.JSONValue{Static: "get"}},
+	)
+	assert.NilError(t, err)
+
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.Check(t, authorized.(bool))
+
+	assert.Equal(t, len(reviews), 1)
+	assert.Equal(t, reviews[0].User, "john")
+	assert.Equal(t, reviews[0].ResourceAttributes.Namespace, "default")
+	assert.Equal(t, reviews[0].ResourceAttributes.Resource, "pods")
+}
+
+func TestKubernetesAuthzWithKubeconfig_Denied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var reviews []kubeAuthz.SubjectAccessReviewSpec
+	remote := newRemoteKubernetesAPIServer(t, false, &reviews)
+	defer remote.Close()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{"request":{"http":{"method":"GET","path":"/hello"}}},"auth":{"identity":{"username":"john"}}}`)
+
+	pipelineMock.EXPECT().GetHttp().Return(&envoy_auth.AttributeContext_HttpRequest{Method: "GET", Path: "/hello"})
+
+	kubernetesAuth, err := NewKubernetesAuthzWithKubeconfig(remoteKubeconfig(remote.URL), json.JSONValue{Pattern: "auth.identity.username"}, []string{}, nil)
+	assert.NilError(t, err)
+
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+	assert.Check(t, !authorized.(bool))
+	assert.ErrorContains(t, err, "not authorized")
+
+	assert.Equal(t, len(reviews), 1)
+	assert.Equal(t, reviews[0].NonResourceAttributes.Path, "/hello")
+}
+
+func TestKubernetesAuthzWithInvalidKubeconfig(t *testing.T) {
+	_, err := NewKubernetesAuthzWithKubeconfig([]byte("not a kubeconfig"), json.JSONValue{Static: "john"}, nil, nil)
+	assert.ErrorContains(t, err, "invalid kubeconfig")
+}
+
+func TestKubernetesAuthzWithKubeconfigReadingFromTheFilesystem(t *testing.T) {
+	kubeconfig := func(cluster, user string) []byte {
+		return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.cluster:6443
+%s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: authorino
+current-context: remote
+users:
+- name: authorino
+  user:
+%s
+`, cluster, user))
+	}
+
+	for name, config := range map[string][]byte{
+		"certificate-authority": kubeconfig("    certificate-authority: /etc/ssl/ca.crt", "    token: remote-token"),
+		"tokenFile":             kubeconfig("", "    tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token"),
+		"client-certificate":    kubeconfig("", "    client-certificate: /etc/ssl/tls.crt\n    client-key: /etc/ssl/tls.key"),
+		"exec":                  kubeconfig("", "    exec:\n      apiVersion: client.authentication.k8s.io/v1\n      command: /bin/sh"),
+		"auth-provider":         kubeconfig("", "    auth-provider:\n      name: oidc"),
+	} {
+		_, err := NewKubernetesAuthzWithKubeconfig(config, json.JSONValue{Static: "john"}, nil, nil)
+		assert.ErrorContains(t, err, "invalid kubeconfig", name)
+		assert.ErrorContains(t, err, "not supported", name)
+	}
+}
+
+type impersonatedReview struct {
+	user   string
+	groups []string
+	spec   kubeAuthz.SelfSubjectAccessReviewSpec
+}
+
+// newImpersonationKubernetesAPIServer mocks an API server where only the members of the 'admins' group can access pods
+func newImpersonationKubernetesAPIServer(t *testing.T, reviews *[]impersonatedReview) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		review := kubeAuthz.SelfSubjectAccessReview{}
+		if err := gojson.NewDecoder(r.Body).Decode(&review); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		groups := r.Header.Values("Impersonate-Group")
+		*reviews = append(*reviews, impersonatedReview{user: r.Header.Get("Impersonate-User"), groups: groups, spec: review.Spec})
+		for _, group := range groups {
+			if group == "admins" && review.Spec.ResourceAttributes != nil && review.Spec.ResourceAttributes.Resource == "pods" {
+				review.Status.Allowed = true
+			}
+		}
+		if !review.Status.Allowed {
+			review.Status.Reason = "no rbac policy matched"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = gojson.NewEncoder(w).Encode(review)
+	}))
+}
+
+func TestKubernetesAuthzImpersonation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var reviews []impersonatedReview
+	server := newImpersonationKubernetesAPIServer(t, &reviews)
+	defer server.Close()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{"request":{"http":{"method":"GET","path":"/hello"}}},"auth":{"identity":{"username":"john"}}}`).AnyTimes()
+
+	resourceAttributes := &KubernetesAuthzResourceAttributes{Namespace: json.JSONValue{Static: "default"}, Resource: json.JSONValue{Static: "pods"}, Verb: json.JSONValue{Static: "get"}}
+
+	// member of the admins group
+	kubernetesAuth, err := NewKubernetesAuthzWithKubeconfig(remoteKubeconfig(server.URL), json.JSONValue{Pattern: "auth.identity.username"}, []string{"developers", "admins"}, resourceAttributes)
+	assert.NilError(t, err)
+	kubernetesAuth.Impersonate = true
+
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.Check(t, authorized.(bool))
+
+	assert.Equal(t, len(reviews), 1)
+	assert.Equal(t, reviews[0].user, "john")
+	assert.DeepEqual(t, reviews[0].groups, []string{"developers", "admins"})
+	assert.Equal(t, reviews[0].spec.ResourceAttributes.Namespace, "default")
+	assert.Equal(t, reviews[0].spec.ResourceAttributes.Resource, "pods")
+	assert.Equal(t, reviews[0].spec.ResourceAttributes.Verb, "get")
+
+	// not a member of the admins group
+	kubernetesAuth, err = NewKubernetesAuthzWithKubeconfig(remoteKubeconfig(server.URL), json.JSONValue{Pattern: "auth.identity.username"}, []string{"developers"}, resourceAttributes)
+	assert.NilError(t, err)
+	kubernetesAuth.Impersonate = true
+
+	authorized, err = kubernetesAuth.Call(pipelineMock, context.TODO())
+	assert.Check(t, !authorized.(bool))
+	assert.Error(t, err, "not authorized: no rbac policy matched")
+
+	assert.Equal(t, len(reviews), 2)
+	assert.Equal(t, reviews[1].user, "john")
+	assert.DeepEqual(t, reviews[1].groups, []string{"developers"})
+}
+
+func TestKubernetesAuthzImpersonationWithoutUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var reviews []impersonatedReview
+	server := newImpersonationKubernetesAPIServer(t, &reviews)
+	defer server.Close()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{"request":{"http":{"method":"GET","path":"/hello"}}},"auth":{"identity":{}}}`)
+	pipelineMock.EXPECT().GetHttp().Return(&envoy_auth.AttributeContext_HttpRequest{Method: "GET", Path: "/hello"})
+
+	kubernetesAuth, err := NewKubernetesAuthzWithKubeconfig(remoteKubeconfig(server.URL), json.JSONValue{}, []string{"admins"}, nil)
+	assert.NilError(t, err)
+	kubernetesAuth.Impersonate = true
+
+	authorized, err := kubernetesAuth.Call(pipelineMock, context.TODO())
+	assert.Check(t, !authorized.(bool))
+	assert.Error(t, err, "not authorized: impersonation requires a user")
+	assert.Equal(t, len(reviews), 0)
+}
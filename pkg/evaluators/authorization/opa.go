@@ -3,16 +3,22 @@ package authorization
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/kuadrant/authorino/pkg/auth"
+	authorinoJSON "github.com/kuadrant/authorino/pkg/json"
 	"github.com/kuadrant/authorino/pkg/log"
+	"github.com/kuadrant/authorino/pkg/metrics"
 	"github.com/kuadrant/authorino/pkg/workers"
 
 	opaParser "github.com/open-policy-agent/opa/ast"
@@ -36,9 +42,28 @@ default allow = false
 	msg_opaPolicyRefreshFromRegistrySkipped  = "external policy unchanged"
 	msg_opaPolicyRefreshFromRegistrySuccess  = "policy updated from external registry"
 	msg_opaPolicyRefreshFromRegistryDisabled = "auto-refresh of external policy disabled"
+	msg_opaPolicyEvaluationTimeoutError      = "policy evaluation timed out after %s"
 )
 
+var (
+	opaEvaluationTimeoutsMetric = metrics.NewCounterMetric("opa_evaluation_timeouts_total", "Number of OPA policy evaluations cancelled for exceeding the maximum evaluation time.", "policy")
+)
+
+func init() {
+	metrics.Register(opaEvaluationTimeoutsMetric)
+}
+
 func NewOPAAuthorization(policyName string, rego string, externalSource *OPAExternalSource, allValues bool, nonce int, ctx context.Context) (*OPA, error) {
+	return newOPAAuthorization(policyName, rego, externalSource, allValues, nil, nonce, ctx)
+}
+
+// NewOPAAuthorizationWithOutputs builds an OPA policy that exposes, besides `allow`, only the values of the named rules
+// (outputs) to the subsequent phases of the auth pipeline
+func NewOPAAuthorizationWithOutputs(policyName string, rego string, externalSource *OPAExternalSource, outputs []string, nonce int, ctx context.Context) (*OPA, error) {
+	return newOPAAuthorization(policyName, rego, externalSource, false, outputs, nonce, ctx)
+}
+
+func newOPAAuthorization(policyName string, rego string, externalSource *OPAExternalSource, allValues bool, outputs []string, nonce int, ctx context.Context) (*OPA, error) {
 	logger := log.FromContext(ctx).WithName("opa")
 
 	pullFromRegistry := rego == "" && externalSource != nil && externalSource.Endpoint != ""
@@ -55,6 +80,7 @@ func NewOPAAuthorization(policyName string, rego string, externalSource *OPAExte
 	o := &OPA{
 		ExternalSource: externalSource,
 		AllValues:      allValues,
+		Outputs:        outputs,
 		policyName:     policyName,
 		policyUID:      generatePolicyUID(policyName, rego, nonce),
 		opaContext:     context.TODO(),
@@ -73,7 +99,23 @@ func NewOPAAuthorization(policyName string, rego string, externalSource *OPAExte
 type OPA struct {
 	Rego           string `yaml:"rego"`
 	ExternalSource *OPAExternalSource
-	AllValues      bool
+	// AllValues exposes the values of all the rules of the policy, including intermediate ones, in the authorization result.
+	// When false, only `allow` and the rules listed in Outputs are exposed.
+	AllValues bool
+	Outputs   []string
+
+	// RouteSelector selects from the Authorization JSON the key of the policy in Routes to evaluate.
+	// When the selected value matches none of the keys, the policy falls back to its own Rego.
+	RouteSelector *authorinoJSON.JSONValue
+	Routes        map[string]*OPA
+
+	// EvaluationTimeout is the maximum time the evaluation of the policy can take for a request.
+	// Evaluations that exceed it are cancelled and the request is denied. Zero means no limit.
+	EvaluationTimeout time.Duration
+
+	// WasmEntrypoint is the entrypoint of the policy precompiled to WebAssembly, evaluated instead of Rego; empty for
+	// policies interpreted from Rego
+	WasmEntrypoint string
 
 	opaContext context.Context
 	policy     *rego.PreparedEvalQuery
@@ -84,30 +126,67 @@ type OPA struct {
 }
 
 func (opa *OPA) Call(pipeline auth.AuthPipeline, ctx context.Context) (interface{}, error) {
+	if route := opa.route(pipeline); route != nil {
+		return route.Call(pipeline, ctx)
+	}
+
 	opa.mu.RLock()
 	defer opa.mu.RUnlock()
 
 	var authJSON interface{}
-	if err := json.Unmarshal([]byte(pipeline.GetAuthorizationJSON()), &authJSON); err != nil {
+	if err := authorinoJSON.Unmarshal([]byte(pipeline.GetAuthorizationJSON()), &authJSON); err != nil {
 		return false, err
 	} else {
+		evalCtx := opa.opaContext
+		if opa.EvaluationTimeout > 0 {
+			var cancel context.CancelFunc
+			evalCtx, cancel = context.WithTimeout(evalCtx, opa.EvaluationTimeout)
+			defer cancel()
+		}
+
 		options := rego.EvalInput(authJSON)
-		results, err := opa.policy.Eval(opa.opaContext, options)
+		results, err := opa.policy.Eval(evalCtx, options)
 
 		if err != nil {
+			if errors.Is(evalCtx.Err(), context.DeadlineExceeded) {
+				metrics.ReportMetric(opaEvaluationTimeoutsMetric, opa.policyName)
+				return nil, fmt.Errorf(msg_opaPolicyEvaluationTimeoutError, opa.EvaluationTimeout)
+			}
 			return nil, err
 		} else if len(results) == 0 {
 			return nil, fmt.Errorf(msg_opaPolicyInvalidResponseError)
-		} else if allowed, ok := results[0].Bindings[allowQuery].(bool); !ok || !allowed {
+		}
+
+		bindings := results[0].Bindings
+		if opa.WasmEntrypoint != "" {
+			bindings = opa.wasmBindings(results[0])
+		}
+
+		if allowed, ok := bindings[allowQuery].(bool); !ok || !allowed {
 			return nil, fmt.Errorf(unauthorizedErrorMsg)
-		} else {
-			return results[0].Bindings, nil
 		}
+		return bindings, nil
+	}
+}
+
+// route returns the policy selected for the request among the routes, or nil if none matches
+func (opa *OPA) route(pipeline auth.AuthPipeline) *OPA {
+	if opa.RouteSelector == nil || len(opa.Routes) == 0 {
+		return nil
 	}
+
+	selected, _ := authorinoJSON.StringifyJSON(opa.RouteSelector.ResolveFor(pipeline.GetAuthorizationJSON()))
+	return opa.Routes[selected]
 }
 
 // Clean ensures the goroutine started by ExternalSource.setupRefresher is cleaned up
-func (opa *OPA) Clean(_ context.Context) error {
+func (opa *OPA) Clean(ctx context.Context) error {
+	for _, route := range opa.Routes {
+		if err := route.Clean(ctx); err != nil {
+			return err
+		}
+	}
+
 	if opa.ExternalSource == nil {
 		return nil
 	}
@@ -128,7 +207,7 @@ func (opa *OPA) updateRego(rego string, ctx context.Context, force bool) (bool,
 
 	opa.Rego = newRego
 
-	if policy, err := precompilePolicy(opa.opaContext, opa.policyUID, opa.Rego, opa.AllValues); err != nil {
+	if policy, err := precompilePolicy(opa.opaContext, opa.policyUID, opa.Rego, opa.AllValues, opa.Outputs); err != nil {
 		opa.Rego = currentRego
 		log.FromContext(ctx).Error(err, msg_OpaPolicyPrecompileError, "policy", opa.policyName)
 		return false, err
@@ -138,7 +217,7 @@ func (opa *OPA) updateRego(rego string, ctx context.Context, force bool) (bool,
 	}
 }
 
-func precompilePolicy(ctx context.Context, policyUID, policyRego string, allValues bool) (*rego.PreparedEvalQuery, error) {
+func precompilePolicy(ctx context.Context, policyUID, policyRego string, allValues bool, outputs []string) (*rego.PreparedEvalQuery, error) {
 	policyName := fmt.Sprintf(`authorino.authz["%s"]`, policyUID)
 	policyContent := fmt.Sprintf(policyTemplate, policyName, policyRego)
 	policyFileName := policyUID + ".rego"
@@ -161,6 +240,21 @@ func precompilePolicy(ctx context.Context, policyUID, policyRego string, allValu
 				rules[name] = nil
 			}
 		}
+	} else if len(outputs) > 0 {
+		rules := map[string]interface{}{}
+		for _, rule := range module.Rules {
+			rules[string(rule.Head.Name)] = nil
+		}
+		projected := map[string]interface{}{allowQuery: nil}
+		for _, name := range outputs {
+			if _, found := rules[name]; !found {
+				return nil, fmt.Errorf("unknown rule in policy outputs: %s", name)
+			}
+			if _, found := projected[name]; !found {
+				queries = append(queries, fmt.Sprintf(queryTemplate, name, name))
+				projected[name] = nil
+			}
+		}
 	}
 
 	r := rego.New(
@@ -201,8 +295,50 @@ type OPAExternalSource struct {
 	Endpoint     string
 	SharedSecret string
 	auth.AuthCredentials
-	TTL       int
-	refresher workers.Worker
+	TTL int
+	// TLSConfig of the connections to the external registry, e.g. with a client certificate for mutual TLS; nil for the defaults
+	TLSConfig  *tls.Config
+	refresher  workers.Worker
+	client     *http.Client
+	clientOnce sync.Once
+}
+
+// NewOPAExternalSourceTLSConfig builds the TLS config to fetch policies from an external registry that requires mutual
+// TLS, from a PEM-encoded client certificate and key, and optionally the PEM-encoded CA certificates to verify the
+// certificate of the registry (otherwise, the system roots)
+func NewOPAExternalSourceTLSConfig(certPEM, keyPEM, caPEM []byte) (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %v", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if len(caPEM) > 0 {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("invalid ca certificate")
+		}
+		config.RootCAs = rootCAs
+	}
+
+	return config, nil
+}
+
+func (ext *OPAExternalSource) httpClient() *http.Client {
+	ext.clientOnce.Do(func() {
+		if ext.TLSConfig == nil {
+			ext.client = http.DefaultClient
+			return
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = ext.TLSConfig
+		ext.client = &http.Client{Transport: transport}
+	})
+	return ext.client
 }
 
 func (ext *OPAExternalSource) downloadRegoDataFromUrl() (string, error) {
@@ -213,7 +349,7 @@ func (ext *OPAExternalSource) downloadRegoDataFromUrl() (string, error) {
 
 	otel.GetTextMapPropagator().Inject(req.Context(), otel_propagation.HeaderCarrier(req.Header))
 
-	if resp, err := http.DefaultClient.Do(req); err != nil {
+	if resp, err := ext.httpClient().Do(req); err != nil {
 		return "", fmt.Errorf("failed to fetch Rego config: %v", err)
 	} else {
 		defer resp.Body.Close()
@@ -245,7 +381,7 @@ func (ext *OPAExternalSource) setupRefresher(ctx context.Context, opa *OPA) {
 
 	var startErr error
 
-	ext.refresher, startErr = workers.StartWorker(ctx, ext.TTL, func() {
+	ext.refresher, startErr = workers.StartWorker(ctx, "opa_external_policy", ext.TTL, func() {
 		if downloadedRego, err := ext.downloadRegoDataFromUrl(); err == nil {
 			if updated, err := opa.updateRego(downloadedRego, ctx, false); updated {
 				logger.Info(msg_opaPolicyRefreshFromRegistrySuccess)
@@ -2,8 +2,19 @@ package authorization
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	nethttptest "net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -11,11 +22,13 @@ import (
 	"github.com/kuadrant/authorino/pkg/auth"
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
 	"github.com/kuadrant/authorino/pkg/httptest"
+	authorinoJSON "github.com/kuadrant/authorino/pkg/json"
 	mock_workers "github.com/kuadrant/authorino/pkg/workers/mocks"
 
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/golang/mock/gomock"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"gotest.tools/assert"
 )
 
@@ -53,6 +66,106 @@ func TestOPAExternalUrl(t *testing.T) {
 	assertOPAAuthorization(t, opa)
 }
 
+func TestOPAExternalUrlWithClientCert(t *testing.T) {
+	ca := newTestOPACertificate(t, "ca", nil)
+	serverCert := newTestOPACertificate(t, "registry", ca)
+	clientCert := newTestOPACertificate(t, "authorino", ca)
+
+	server := nethttptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(opaInlineRegoDataMock))
+	}))
+	serverTLSCert, _ := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(ca.certPEM)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverTLSCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	tlsConfig, err := NewOPAExternalSourceTLSConfig(clientCert.certPEM, clientCert.keyPEM, ca.certPEM)
+	assert.NilError(t, err)
+
+	externalSource := &OPAExternalSource{
+		Endpoint:        server.URL + "/rego",
+		AuthCredentials: auth.NewAuthCredential("", ""),
+		TLSConfig:       tlsConfig,
+	}
+	opa, err := NewOPAAuthorization("test-opa", "", externalSource, false, 0, context.TODO())
+	assert.NilError(t, err)
+	assertOPAAuthorization(t, opa)
+
+	// without the client certificate
+	tlsConfig, err = NewOPAExternalSourceTLSConfig(clientCert.certPEM, clientCert.keyPEM, ca.certPEM)
+	assert.NilError(t, err)
+	tlsConfig.Certificates = nil
+	externalSource = &OPAExternalSource{
+		Endpoint:        server.URL + "/rego",
+		AuthCredentials: auth.NewAuthCredential("", ""),
+		TLSConfig:       tlsConfig,
+	}
+	_, err = NewOPAAuthorization("test-opa", "", externalSource, false, 0, context.TODO())
+	assert.Check(t, err != nil)
+}
+
+func TestNewOPAExternalSourceTLSConfig(t *testing.T) {
+	ca := newTestOPACertificate(t, "ca", nil)
+	clientCert := newTestOPACertificate(t, "authorino", ca)
+
+	tlsConfig, err := NewOPAExternalSourceTLSConfig(clientCert.certPEM, clientCert.keyPEM, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(tlsConfig.Certificates), 1)
+	assert.Check(t, tlsConfig.RootCAs == nil)
+
+	_, err = NewOPAExternalSourceTLSConfig(clientCert.certPEM, nil, nil)
+	assert.ErrorContains(t, err, "invalid client certificate")
+
+	_, err = NewOPAExternalSourceTLSConfig(clientCert.certPEM, clientCert.keyPEM, []byte("not a certificate"))
+	assert.Error(t, err, "invalid ca certificate")
+}
+
+type testOPACertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestOPACertificate generates a certificate signed by the given ca, or a self-signed ca certificate if nil
+func newTestOPACertificate(t *testing.T, commonName string, ca *testOPACertificate) *testOPACertificate {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	parent, parentKey := template, key
+	if ca == nil {
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.BasicConstraintsValid = true
+		template.IsCA = true
+	} else {
+		parent, parentKey = ca.cert, ca.key
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NilError(t, err)
+	cert, _ := x509.ParseCertificate(certDER)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	return &testOPACertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
 func TestOPAInlineRegoAndExternalUrl(t *testing.T) {
 	extHttpMetadataServer := httptest.NewHttpServerMock(opaExtHttpServerMockAddr, map[string]httptest.HttpServerMockResponseFunc{
 		"/rego": func() httptest.HttpServerMockResponse {
@@ -197,6 +310,52 @@ func TestOPAAllValues(t *testing.T) {
 	assert.Assert(t, !undefinedFound)
 }
 
+func TestOPAOutputs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock("/allow", "GET")).Times(1)
+
+	opa, err := NewOPAAuthorizationWithOutputs("test-opa", opaInlineRegoDataMock, &OPAExternalSource{}, []string{"method"}, 0, context.TODO())
+	assert.NilError(t, err)
+
+	results, err := opa.Call(pipelineMock, nil)
+	resultSet, _ := results.(rego.Vars)
+	authorized, _ := resultSet["allow"].(bool)
+	method, _ := resultSet["method"].(string)
+	_, pathFound := resultSet["path"]
+
+	assert.NilError(t, err)
+	assert.Assert(t, authorized)
+	assert.Equal(t, method, "GET")
+	assert.Assert(t, !pathFound)
+	assert.Equal(t, len(resultSet), 2)
+}
+
+func TestOPAWithoutAllValues(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock("/allow", "GET")).Times(1)
+
+	opa, _ := NewOPAAuthorization("test-opa", opaInlineRegoDataMock, &OPAExternalSource{}, false, 0, context.TODO())
+
+	results, err := opa.Call(pipelineMock, nil)
+	resultSet, _ := results.(rego.Vars)
+	authorized, _ := resultSet["allow"].(bool)
+
+	assert.NilError(t, err)
+	assert.Assert(t, authorized)
+	assert.Equal(t, len(resultSet), 1)
+}
+
+func TestOPAUnknownOutput(t *testing.T) {
+	_, err := NewOPAAuthorizationWithOutputs("test-opa", opaInlineRegoDataMock, &OPAExternalSource{}, []string{"undefined"}, 0, context.TODO())
+	assert.ErrorContains(t, err, "unknown rule in policy outputs: undefined")
+}
+
 func TestOPANonBooleanAllowed(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -214,6 +373,105 @@ func TestOPANonBooleanAllowed(t *testing.T) {
 	assert.ErrorContains(t, err, "Unauthorized")
 }
 
+func TestOPARouting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opa, err := NewOPAAuthorization("test-opa", `allow { input.context.request.http.method == "GET" }`, nil, false, 0, context.TODO())
+	assert.NilError(t, err)
+
+	v1, err := NewOPAAuthorization("test-opa/v1", `allow { input.context.request.http.method == "POST" }`, nil, false, 0, context.TODO())
+	assert.NilError(t, err)
+	v2, err := NewOPAAuthorization("test-opa/v2", `allow { input.context.request.http.method == "DELETE" }`, nil, false, 0, context.TODO())
+	assert.NilError(t, err)
+
+	opa.RouteSelector = &authorinoJSON.JSONValue{Pattern: `context.request.http.path.@extract:{"sep":"/","pos":1}`}
+	opa.Routes = map[string]*OPA{"v1": v1, "v2": v2}
+
+	testCases := []struct {
+		path       string
+		method     string
+		authorized bool
+	}{
+		{"/v1/pets", "POST", true},
+		{"/v1/pets", "DELETE", false},
+		{"/v1/pets", "GET", false},
+		{"/v2/pets", "DELETE", true},
+		{"/v2/pets", "POST", false},
+		{"/v2/pets", "GET", false},
+		{"/v3/pets", "GET", true}, // fallback
+		{"/v3/pets", "POST", false},
+	}
+
+	for _, tc := range testCases {
+		pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+		pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock(tc.path, tc.method)).MinTimes(1)
+
+		_, err := opa.Call(pipelineMock, nil)
+		if tc.authorized {
+			assert.NilError(t, err, "%s %s", tc.method, tc.path)
+		} else {
+			assert.Error(t, err, unauthorizedErrorMsg, "%s %s", tc.method, tc.path)
+		}
+	}
+}
+
+func TestOPARoutingWithoutFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	opa, err := NewOPAAuthorization("test-opa", "", nil, false, 0, context.TODO())
+	assert.NilError(t, err)
+	v1, err := NewOPAAuthorization("test-opa/v1", `allow = true`, nil, false, 0, context.TODO())
+	assert.NilError(t, err)
+
+	opa.RouteSelector = &authorinoJSON.JSONValue{Pattern: `context.request.http.path.@extract:{"sep":"/","pos":1}`}
+	opa.Routes = map[string]*OPA{"v1": v1}
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock("/v1/pets", "GET")).MinTimes(1)
+	_, err = opa.Call(pipelineMock, nil)
+	assert.NilError(t, err)
+
+	pipelineMock = mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock("/v2/pets", "GET")).MinTimes(1)
+	_, err = opa.Call(pipelineMock, nil)
+	assert.Error(t, err, unauthorizedErrorMsg)
+}
+
+func TestOPAEvaluationTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// never finds a match, so it iterates over every pair of numbers in the range
+	slowPolicy := `allow {
+  r := numbers.range(1, 100000)
+  some i, j
+  r[i] + r[j] < 0
+}`
+	opa, err := NewOPAAuthorization("test-opa-slow", slowPolicy, nil, false, 0, context.TODO())
+	assert.NilError(t, err)
+	opa.EvaluationTimeout = 50 * time.Millisecond
+
+	timeoutsBefore := testutil.ToFloat64(opaEvaluationTimeoutsMetric.WithLabelValues("test-opa-slow"))
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock("/allow", "GET"))
+
+	start := time.Now()
+	_, err = opa.Call(pipelineMock, nil)
+	assert.Error(t, err, "policy evaluation timed out after 50ms")
+	assert.Assert(t, time.Since(start) < 5*time.Second)
+	assert.Equal(t, testutil.ToFloat64(opaEvaluationTimeoutsMetric.WithLabelValues("test-opa-slow")), timeoutsBefore+1)
+}
+
+func TestOPAEvaluationTimeoutNotExceeded(t *testing.T) {
+	opa, err := NewOPAAuthorization("test-opa", opaInlineRegoDataMock, nil, false, 0, context.TODO())
+	assert.NilError(t, err)
+	opa.EvaluationTimeout = 5 * time.Second
+	assertOPAAuthorization(t, opa)
+}
+
 func assertOPAAuthorization(t *testing.T, opa *OPA) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -0,0 +1,6 @@
+//go:build cgo
+
+package authorization
+
+// registers the OPA WASM runtime (wasmtime), only available in builds with cgo
+import _ "github.com/open-policy-agent/opa/features/wasm"
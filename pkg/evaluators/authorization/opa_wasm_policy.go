@@ -0,0 +1,140 @@
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/kuadrant/authorino/pkg/log"
+
+	opaParser "github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+
+	"go.opentelemetry.io/otel"
+	otel_propagation "go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	wasmModulePath = "/policy.wasm"
+
+	msg_opaWasmModuleMissingError = "missing wasm module"
+)
+
+// NewOPAWasmAuthorization builds an OPA policy precompiled to WebAssembly (e.g. with `opa build -t wasm -e authz`),
+// evaluated with the OPA WASM runtime instead of interpreting Rego. If the module is empty, it is downloaded from the url.
+// The value of the entrypoint is the virtual document of the policy, where `allow` decides the authorization. Of the
+// other rules, only the ones listed in outputs are exposed to the subsequent phases of the auth pipeline, unless allValues
+// is true.
+// The OPA WASM runtime requires Authorino to be built with cgo.
+func NewOPAWasmAuthorization(policyName string, module []byte, url string, entrypoint string, allValues bool, outputs []string, ctx context.Context) (*OPA, error) {
+	logger := log.FromContext(ctx).WithName("opa")
+
+	if len(module) == 0 && url != "" {
+		var err error
+		if module, err = downloadWasmModule(ctx, url); err != nil {
+			logger.Error(err, msg_opaPolicyDownloadError, "policy", policyName, "endpoint", url)
+			return nil, err
+		}
+	}
+
+	if len(module) == 0 {
+		return nil, fmt.Errorf(msg_opaWasmModuleMissingError)
+	}
+
+	o := &OPA{
+		AllValues:      allValues,
+		Outputs:        outputs,
+		WasmEntrypoint: entrypoint,
+		policyName:     policyName,
+		policyUID:      generatePolicyUID(policyName, string(module), 0),
+		opaContext:     context.TODO(),
+	}
+
+	policy, err := precompileWasmPolicy(o.opaContext, o.policyUID, module, entrypoint)
+	if err != nil {
+		logger.Error(err, msg_OpaPolicyPrecompileError, "policy", policyName)
+		return nil, err
+	}
+	o.policy = policy
+
+	return o, nil
+}
+
+func precompileWasmPolicy(ctx context.Context, policyUID string, module []byte, entrypoint string) (*rego.PreparedEvalQuery, error) {
+	entrypoint = strings.Trim(entrypoint, "/")
+	query, err := opaParser.ParseRef("data." + strings.ReplaceAll(entrypoint, "/", "."))
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm entrypoint %s: %v", entrypoint, err)
+	}
+
+	b := &bundle.Bundle{
+		Manifest: bundle.Manifest{
+			Revision:      policyUID,
+			WasmResolvers: []bundle.WasmResolver{{Entrypoint: entrypoint, Module: wasmModulePath}},
+		},
+		WasmModules: []bundle.WasmModuleFile{{URL: wasmModulePath, Path: wasmModulePath, Raw: module}},
+		Data:        map[string]interface{}{}, // the root document of the store must be an object, even if empty
+	}
+	b.Manifest.Init()
+
+	r := rego.New(
+		rego.Query(query.String()),
+		rego.ParsedBundle(policyUID, b),
+	)
+
+	if regoPolicy, err := r.PrepareForEval(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load wasm module: %v", err)
+	} else {
+		return &regoPolicy, nil
+	}
+}
+
+// wasmBindings maps the virtual document of a policy precompiled to WebAssembly to the values of the rules, the same as
+// the ones resolved for a policy interpreted from Rego
+func (opa *OPA) wasmBindings(result rego.Result) rego.Vars {
+	var document map[string]interface{}
+	if len(result.Expressions) > 0 {
+		document, _ = result.Expressions[0].Value.(map[string]interface{})
+	}
+
+	bindings := rego.Vars{allowQuery: document[allowQuery]}
+	if opa.AllValues {
+		for name, value := range document {
+			bindings[name] = value
+		}
+	} else {
+		for _, name := range opa.Outputs {
+			bindings[name] = document[name]
+		}
+	}
+	return bindings
+}
+
+func downloadWasmModule(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	otel.GetTextMapPropagator().Inject(req.Context(), otel_propagation.HeaderCarrier(req.Header))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch wasm module: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	return body, nil
+}
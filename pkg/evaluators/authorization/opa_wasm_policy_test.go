@@ -0,0 +1,111 @@
+//go:build cgo
+
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
+	"github.com/kuadrant/authorino/pkg/httptest"
+
+	"github.com/golang/mock/gomock"
+	opaParser "github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/compile"
+	"github.com/open-policy-agent/opa/rego"
+	"gotest.tools/assert"
+)
+
+const opaWasmExtHttpServerMockAddr string = "127.0.0.1:9008"
+
+// compileWasmPolicy compiles the Rego policy used in the tests to WebAssembly, same as `opa build -t wasm -e authz`
+func compileWasmPolicy(t *testing.T) []byte {
+	t.Helper()
+
+	policy := "package authz\ndefault allow = false\n" + opaInlineRegoDataMock
+	module, err := opaParser.ParseModule("/policy.rego", policy)
+	assert.NilError(t, err)
+
+	compiler := compile.New().
+		WithTarget(compile.TargetWasm).
+		WithEntrypoints("authz").
+		WithBundle(&bundle.Bundle{Modules: []bundle.ModuleFile{{URL: "/policy.rego", Path: "/policy.rego", Raw: []byte(policy), Parsed: module}}})
+	assert.NilError(t, compiler.Build(context.TODO()))
+
+	wasmModules := compiler.Bundle().WasmModules
+	assert.Equal(t, len(wasmModules), 1)
+	return wasmModules[0].Raw
+}
+
+func TestOPAWasm(t *testing.T) {
+	opa, err := NewOPAWasmAuthorization("test-opa", compileWasmPolicy(t), "", "authz", false, nil, context.TODO())
+
+	assert.NilError(t, err)
+	assertOPAAuthorization(t, opa)
+}
+
+func TestOPAWasmSameAsRego(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	module := compileWasmPolicy(t)
+
+	for _, allValues := range []bool{false, true} {
+		regoPolicy, err := NewOPAAuthorization("test-opa", opaInlineRegoDataMock, &OPAExternalSource{}, allValues, 0, context.TODO())
+		assert.NilError(t, err)
+		wasmPolicy, err := NewOPAWasmAuthorization("test-opa", module, "", "authz", allValues, nil, context.TODO())
+		assert.NilError(t, err)
+
+		for _, request := range [][]string{{"/allow", "GET"}, {"/allow", "POST"}, {"/deny", "GET"}} {
+			pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+			pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock(request[0], request[1])).Times(2)
+
+			regoResults, regoErr := regoPolicy.Call(pipelineMock, nil)
+			wasmResults, wasmErr := wasmPolicy.Call(pipelineMock, nil)
+
+			if regoErr != nil {
+				assert.Error(t, wasmErr, regoErr.Error())
+				continue
+			}
+			assert.NilError(t, wasmErr)
+			assert.DeepEqual(t, wasmResults.(rego.Vars), regoResults.(rego.Vars))
+		}
+	}
+}
+
+func TestOPAWasmOutputs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(opaAuthDataMock("/allow", "GET")).Times(1)
+
+	opa, err := NewOPAWasmAuthorization("test-opa", compileWasmPolicy(t), "", "authz", false, []string{"method"}, context.TODO())
+	assert.NilError(t, err)
+
+	results, err := opa.Call(pipelineMock, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, results.(rego.Vars), rego.Vars{"allow": true, "method": "GET"})
+}
+
+func TestOPAWasmExternalUrl(t *testing.T) {
+	module := compileWasmPolicy(t)
+
+	extHttpServer := httptest.NewHttpServerMock(opaWasmExtHttpServerMockAddr, map[string]httptest.HttpServerMockResponseFunc{
+		"/policy.wasm": func() httptest.HttpServerMockResponse {
+			return httptest.HttpServerMockResponse{Status: 200, Body: string(module)}
+		},
+	})
+	defer extHttpServer.Close()
+
+	opa, err := NewOPAWasmAuthorization("test-opa", nil, "http://"+opaWasmExtHttpServerMockAddr+"/policy.wasm", "authz", false, nil, context.TODO())
+
+	assert.NilError(t, err)
+	assertOPAAuthorization(t, opa)
+}
+
+func TestOPAWasmMissingModule(t *testing.T) {
+	_, err := NewOPAWasmAuthorization("test-opa", nil, "", "authz", false, nil, context.TODO())
+	assert.Error(t, err, msg_opaWasmModuleMissingError)
+}
@@ -0,0 +1,71 @@
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/json"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	scopeClaimSelector = "auth.identity.scope"
+	scpClaimSelector   = "auth.identity.scp"
+)
+
+// Scopes authorizes the request if the resolved identity (e.g. an access token) carries the scope required for the
+// request, in either the `scope` claim (space-delimited string) or the `scp` claim (string or array of strings)
+type Scopes struct {
+	// Required resolves the scope required for the request, usually from a template (e.g. "{context.request.http.method}")
+	Required json.JSONValue
+	// Mapping translates the value resolved by Required into the actual scope required.
+	// When set, requests whose resolved value is not mapped are denied.
+	Mapping map[string]string
+}
+
+func (s *Scopes) Call(pipeline auth.AuthPipeline, _ context.Context) (interface{}, error) {
+	authJSON := pipeline.GetAuthorizationJSON()
+
+	required, err := json.StringifyJSON(s.Required.ResolveFor(authJSON))
+	if err != nil {
+		return false, err
+	}
+
+	if s.Mapping != nil {
+		scope, mapped := s.Mapping[required]
+		if !mapped {
+			return false, fmt.Errorf("no scope mapped for %s", required)
+		}
+		required = scope
+	}
+
+	if required == "" {
+		return false, fmt.Errorf("unable to resolve the required scope")
+	}
+
+	for _, scope := range grantedScopes(authJSON) {
+		if scope == required {
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("missing required scope: %s", required)
+}
+
+func grantedScopes(authJSON string) []string {
+	scopes := strings.Fields(gjson.Get(authJSON, scopeClaimSelector).String())
+
+	scp := gjson.Get(authJSON, scpClaimSelector)
+	if scp.IsArray() {
+		for _, scope := range scp.Array() {
+			scopes = append(scopes, scope.String())
+		}
+	} else if scp.Exists() {
+		scopes = append(scopes, strings.Fields(scp.String())...)
+	}
+
+	return scopes
+}
@@ -0,0 +1,69 @@
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
+	"github.com/kuadrant/authorino/pkg/json"
+
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func scopesAuthDataMock(method, identity string) string {
+	return `{"context":{"request":{"http":{"method":"` + method + `","path":"/pets"}}},"auth":{"identity":` + identity + `}}`
+}
+
+func TestScopesWithMapping(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scopes := &Scopes{
+		Required: json.JSONValue{Pattern: "context.request.http.method"},
+		Mapping:  map[string]string{"GET": "read", "POST": "write"},
+	}
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+
+	// token with only `read` scope
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(scopesAuthDataMock("GET", `{"scope":"openid read"}`))
+	authorized, err := scopes.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.Assert(t, authorized.(bool))
+
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(scopesAuthDataMock("POST", `{"scope":"openid read"}`))
+	authorized, err = scopes.Call(pipelineMock, context.TODO())
+	assert.Error(t, err, "missing required scope: write")
+	assert.Assert(t, !authorized.(bool))
+
+	// unmapped value
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(scopesAuthDataMock("DELETE", `{"scope":"read write"}`))
+	_, err = scopes.Call(pipelineMock, context.TODO())
+	assert.Error(t, err, "no scope mapped for DELETE")
+}
+
+func TestScopesWithTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	scopes := &Scopes{Required: json.JSONValue{Pattern: "pets:{context.request.http.method.@case:lower}"}}
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+
+	// `scp` claim as array
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(scopesAuthDataMock("POST", `{"scp":["pets:get","pets:post"]}`))
+	authorized, err := scopes.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.Assert(t, authorized.(bool))
+
+	// `scp` claim as string
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(scopesAuthDataMock("POST", `{"scp":"pets:get"}`))
+	_, err = scopes.Call(pipelineMock, context.TODO())
+	assert.Error(t, err, "missing required scope: pets:post")
+
+	// no scopes at all
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(scopesAuthDataMock("GET", `{"sub":"john"}`))
+	_, err = scopes.Call(pipelineMock, context.TODO())
+	assert.Error(t, err, "missing required scope: pets:get")
+}
@@ -2,9 +2,13 @@ package evaluators
 
 import (
 	gojson "encoding/json"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/kuadrant/authorino/pkg/clock"
 	"github.com/kuadrant/authorino/pkg/json"
+	"github.com/kuadrant/authorino/pkg/jsonexp"
 
 	"github.com/coocood/freecache"
 	gocache "github.com/eko/gocache/cache"
@@ -17,24 +21,64 @@ type EvaluatorCache interface {
 	Get(key interface{}) (interface{}, error)
 	Set(key, value interface{}) error
 	ResolveKeyFor(authJSON string) interface{}
+	ShouldCache(authJSON string, value interface{}) bool
 	Shutdown() error
 }
 
-func NewEvaluatorCache(keyTemplate json.JSONValue, ttl int) EvaluatorCache {
+// NewEvaluatorCache creates a cache whose entries expire after ttl seconds.
+// If maxEntries is greater than zero, new entries are not stored while the cache holds maxEntries entries not yet expired.
+func NewEvaluatorCache(keyTemplate json.JSONValue, ttl, maxEntries int, conditions jsonexp.Expression) EvaluatorCache {
+	return NewEvaluatorCacheWithClock(keyTemplate, ttl, maxEntries, conditions, clock.RealClock)
+}
+
+// NewEvaluatorCacheWithClock creates a cache whose entries expire after ttl seconds, as measured by the given clock
+func NewEvaluatorCacheWithClock(keyTemplate json.JSONValue, ttl, maxEntries int, conditions jsonexp.Expression, cacheClock clock.Clock) EvaluatorCache {
 	duration := time.Duration(ttl) * time.Second
-	cacheClient := freecache.NewCache(EvaluatorCacheSize * 1024 * 1024)
+	cacheClock = clock.OrRealClock(cacheClock)
+	cacheClient := freecache.NewCacheCustomTimer(EvaluatorCacheSize*1024*1024, freecacheTimer{cacheClock})
 	cacheStore := cache_store.NewFreecache(cacheClient, &cache_store.Options{Expiration: duration})
 	c := &evaluatorCache{
 		keyTemplate: keyTemplate,
+		conditions:  conditions,
+		ttl:         duration,
+		maxEntries:  maxEntries,
+		clock:       cacheClock,
+		client:      cacheClient,
 		store:       gocache.New(cacheStore),
+		tracked:     make(map[string]struct{}),
 	}
 	return c
 }
 
+// freecacheTimer tells the time to the cache client, in seconds
+type freecacheTimer struct {
+	clock clock.Clock
+}
+
+func (t freecacheTimer) Now() uint32 {
+	return uint32(t.clock.Now().Unix())
+}
+
 // evaluatorCache caches JSON values (objects, arrays, strings, etc)
 type evaluatorCache struct {
 	keyTemplate json.JSONValue
+	conditions  jsonexp.Expression
+	ttl         time.Duration
+	maxEntries  int
+	clock       clock.Clock
+	client      *freecache.Cache
 	store       *gocache.Cache
+
+	// expirations of the entries added to the cache, in order, to count the entries not yet expired when the number of
+	// entries is limited
+	expirations []cacheEntryExpiration
+	tracked     map[string]struct{}
+	mu          sync.Mutex
+}
+
+type cacheEntryExpiration struct {
+	key       interface{}
+	expiresAt time.Time
 }
 
 func (c *evaluatorCache) Get(key interface{}) (interface{}, error) {
@@ -51,17 +95,88 @@ func (c *evaluatorCache) Get(key interface{}) (interface{}, error) {
 }
 
 func (c *evaluatorCache) Set(key, value interface{}) error {
-	if valueAsBytes, err := gojson.Marshal(value); err != nil {
+	valueAsBytes, err := gojson.Marshal(value)
+	if err != nil {
 		return err
-	} else {
+	}
+
+	if c.maxEntries <= 0 {
 		return c.store.Set(key, valueAsBytes, nil)
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ttl, _ := c.store.GetWithTTL(key)
+	exists := ttl > 0
+	if !exists && c.liveEntries() >= c.maxEntries {
+		return nil // only existing entries can be updated in a full cache
+	}
+
+	if err := c.store.Set(key, valueAsBytes, nil); err != nil {
+		return err
+	}
+	if trackedKey := fmt.Sprint(key); !exists {
+		if _, tracked := c.tracked[trackedKey]; !tracked {
+			c.tracked[trackedKey] = struct{}{}
+			c.expirations = append(c.expirations, cacheEntryExpiration{key: key, expiresAt: c.clock.Now().Add(c.ttl)})
+		}
+	}
+	return nil
+}
+
+// liveEntries returns the number of entries not yet expired.
+// The entries due to expire are checked against the store, as they may have been updated (i.e. live longer) or evicted.
+// It must be called with the lock held.
+func (c *evaluatorCache) liveEntries() int {
+	if c.ttl > 0 {
+		now := c.clock.Now()
+		for len(c.expirations) > 0 && !c.expirations[0].expiresAt.After(now) {
+			key := c.expirations[0].key
+			c.expirations = c.expirations[1:]
+			if _, ttl, _ := c.store.GetWithTTL(key); ttl > 0 {
+				c.expirations = append(c.expirations, cacheEntryExpiration{key: key, expiresAt: now.Add(c.ttl)})
+			} else {
+				delete(c.tracked, fmt.Sprint(key))
+			}
+		}
+	}
+
+	// entries evicted by the client to free up memory are not yet accounted for
+	if count := int(c.client.EntryCount()); count < len(c.expirations) {
+		return count
+	}
+	return len(c.expirations)
 }
 
 func (c *evaluatorCache) ResolveKeyFor(authJSON string) interface{} {
 	return c.keyTemplate.ResolveFor(authJSON)
 }
 
+// ShouldCache tells whether a value satisfies the conditions to be stored in the cache.
+// The conditions are matched against the Authorization JSON with the value added as the `result` property.
+func (c *evaluatorCache) ShouldCache(authJSON string, value interface{}) bool {
+	if c.conditions == nil {
+		return true
+	}
+
+	data := map[string]interface{}{}
+	_ = json.Unmarshal([]byte(authJSON), &data)
+	data["result"] = value
+
+	dataJSON, err := gojson.Marshal(data)
+	if err != nil {
+		return false
+	}
+
+	matches, err := c.conditions.Matches(string(dataJSON))
+	return err == nil && matches
+}
+
 func (c *evaluatorCache) Shutdown() error {
+	c.mu.Lock()
+	c.expirations = nil
+	c.tracked = make(map[string]struct{})
+	c.mu.Unlock()
 	return c.store.Clear()
 }
@@ -0,0 +1,118 @@
+package evaluators
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kuadrant/authorino/pkg/json"
+	"github.com/kuadrant/authorino/pkg/jsonexp"
+
+	"gotest.tools/assert"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestEvaluatorCacheShouldCache(t *testing.T) {
+	authJSON := `{"context":{"request":{"http":{"method":"GET"}}}}`
+
+	// unconditional
+	cache := NewEvaluatorCache(json.JSONValue{Static: "x"}, 60, 0, nil)
+	defer cache.Shutdown()
+	assert.Check(t, cache.ShouldCache(authJSON, map[string]interface{}{"active": false}))
+
+	// condition on the result
+	cache = NewEvaluatorCache(json.JSONValue{Static: "x"}, 60, 0, jsonexp.All(
+		jsonexp.Pattern{Selector: "result.active", Operator: jsonexp.EqualOperator, Value: "true"},
+	))
+	defer cache.Shutdown()
+	assert.Check(t, cache.ShouldCache(authJSON, map[string]interface{}{"active": true}))
+	assert.Check(t, !cache.ShouldCache(authJSON, map[string]interface{}{"active": false}))
+	assert.Check(t, !cache.ShouldCache(authJSON, nil))
+
+	// condition on the authorization json
+	cache = NewEvaluatorCache(json.JSONValue{Static: "x"}, 60, 0, jsonexp.All(
+		jsonexp.Pattern{Selector: "context.request.http.method", Operator: jsonexp.EqualOperator, Value: "GET"},
+	))
+	defer cache.Shutdown()
+	assert.Check(t, cache.ShouldCache(authJSON, "some-result"))
+	assert.Check(t, !cache.ShouldCache(`{"context":{"request":{"http":{"method":"POST"}}}}`, "some-result"))
+}
+
+func TestEvaluatorCacheMaxEntries(t *testing.T) {
+	cache := NewEvaluatorCache(json.JSONValue{Static: "x"}, 60, 2, nil)
+	defer cache.Shutdown()
+
+	assert.NilError(t, cache.Set("a", "value-a"))
+	assert.NilError(t, cache.Set("b", "value-b"))
+	assert.NilError(t, cache.Set("c", "value-c")) // full
+
+	value, _ := cache.Get("c")
+	assert.Check(t, value == nil)
+
+	// existing entries can still be updated
+	assert.NilError(t, cache.Set("a", "new-value-a"))
+	value, _ = cache.Get("a")
+	assert.Equal(t, value, "new-value-a")
+	value, _ = cache.Get("b")
+	assert.Equal(t, value, "value-b")
+}
+
+func TestEvaluatorCacheUnlimitedEntries(t *testing.T) {
+	cache := NewEvaluatorCache(json.JSONValue{Static: "x"}, 60, 0, nil)
+	defer cache.Shutdown()
+
+	for _, key := range []string{"a", "b", "c"} {
+		assert.NilError(t, cache.Set(key, "value-"+key))
+	}
+	value, _ := cache.Get("c")
+	assert.Equal(t, value, "value-c")
+}
+
+func TestEvaluatorCacheTTL(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	cache := NewEvaluatorCacheWithClock(json.JSONValue{Static: "x"}, 60, 0, nil, fakeClock)
+	defer cache.Shutdown()
+
+	assert.NilError(t, cache.Set("a", "value-a"))
+
+	fakeClock.Step(59 * time.Second)
+	value, _ := cache.Get("a")
+	assert.Equal(t, value, "value-a")
+
+	fakeClock.Step(time.Second) // expired
+	value, _ = cache.Get("a")
+	assert.Check(t, value == nil)
+}
+
+func TestEvaluatorCacheMaxEntriesWithExpiredEntries(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	cache := NewEvaluatorCacheWithClock(json.JSONValue{Static: "x"}, 60, 1, nil, fakeClock)
+	defer cache.Shutdown()
+
+	assert.NilError(t, cache.Set("a", "value-a"))
+	assert.NilError(t, cache.Set("b", "value-b")) // full
+	value, _ := cache.Get("b")
+	assert.Check(t, value == nil)
+
+	fakeClock.Step(time.Minute) // the expired entry no longer counts
+
+	assert.NilError(t, cache.Set("b", "value-b"))
+	value, _ = cache.Get("b")
+	assert.Equal(t, value, "value-b")
+}
+
+func TestEvaluatorCacheMaxEntriesWithUpdatedEntries(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	cache := NewEvaluatorCacheWithClock(json.JSONValue{Static: "x"}, 60, 1, nil, fakeClock)
+	defer cache.Shutdown()
+
+	assert.NilError(t, cache.Set("a", "value-a"))
+	fakeClock.Step(30 * time.Second)
+	assert.NilError(t, cache.Set("a", "new-value-a")) // extends the life of the entry
+	fakeClock.Step(30 * time.Second)
+
+	assert.NilError(t, cache.Set("b", "value-b")) // still full
+	value, _ := cache.Get("b")
+	assert.Check(t, value == nil)
+	value, _ = cache.Get("a")
+	assert.Equal(t, value, "new-value-a")
+}
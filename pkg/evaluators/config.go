@@ -2,8 +2,12 @@ package evaluators
 
 import (
 	"context"
+	gojson "encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/json"
@@ -23,6 +27,20 @@ type AuthConfig struct {
 	ResponseConfigs      []auth.AuthConfigEvaluator `yaml:"response,omitempty"`
 	CallbackConfigs      []auth.AuthConfigEvaluator `yaml:"callbacks,omitempty"`
 
+	// OmitRequestBody tells that no evaluator needs the body of the request, so it is not captured in the auth pipeline
+	OmitRequestBody bool
+	// Timeout overrides the global timeout of the auth pipeline; zero for the global timeout
+	Timeout time.Duration
+	// StrictIdentity denies the requests whose credentials resolve to more than one identity, instead of accepting the
+	// first identity resolved
+	StrictIdentity bool
+	// MaintenanceBypass lets the requests that carry a valid signed maintenance header skip the auth pipeline
+	MaintenanceBypass *MaintenanceBypass
+	// Evicted tells that the config stands in for the last valid config of an AuthConfig evicted after consecutive
+	// reconcile failures, so the requests to its hosts are denied (fail closed), regardless of the decision for unknown
+	// hosts
+	Evicted bool
+
 	DenyWith
 }
 
@@ -40,14 +58,31 @@ func (config *AuthConfig) GetChallengeHeaders() []map[string]string {
 }
 
 func (config *AuthConfig) Clean(ctx context.Context) error {
+	return CleanAll(ctx, config)
+}
+
+// CleanAll cleans the evaluators of multiple AuthConfigs, e.g. the ones indexed for all the hosts of a resource.
+// Evaluators shared by more than one of the AuthConfigs (i.e. the same pointer) are cleaned only once.
+func CleanAll(ctx context.Context, configs ...*AuthConfig) error {
 	evaluators := []auth.AuthConfigEvaluator{}
-	evaluators = append(evaluators, config.IdentityConfigs...)
-	evaluators = append(evaluators, config.MetadataConfigs...)
-	evaluators = append(evaluators, config.AuthorizationConfigs...)
-	evaluators = append(evaluators, config.ResponseConfigs...)
-	evaluators = append(evaluators, config.CallbackConfigs...)
+	seen := make(map[auth.AuthConfigEvaluator]struct{})
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		for _, phase := range [][]auth.AuthConfigEvaluator{config.IdentityConfigs, config.MetadataConfigs, config.AuthorizationConfigs, config.ResponseConfigs, config.CallbackConfigs} {
+			for _, evaluator := range phase {
+				if _, ok := seen[evaluator]; ok {
+					continue
+				}
+				seen[evaluator] = struct{}{}
+				evaluators = append(evaluators, evaluator)
+			}
+		}
+	}
 
 	var errors error
+	var mutex sync.Mutex
 	var wait sync.WaitGroup
 	wait.Add(len(evaluators))
 
@@ -56,7 +91,9 @@ func (config *AuthConfig) Clean(ctx context.Context) error {
 			defer wait.Done()
 			if cleaner, ok := e.(auth.AuthConfigCleaner); ok {
 				if err := cleaner.Clean(ctx); err != nil {
+					mutex.Lock()
 					errors = multierror.Append(errors, err)
+					mutex.Unlock()
 				}
 			}
 		}(evaluator)
@@ -67,6 +104,32 @@ func (config *AuthConfig) Clean(ctx context.Context) error {
 	return errors
 }
 
+// SortByPriority orders the evaluators of a phase by priority and, among the ones with the same priority, by name.
+// The evaluators are declared in the AuthConfig by name (keys of a map), so there is no declaration order to keep; the
+// name is the stable tie-break, for the order to be the same across reconciles.
+func SortByPriority(configs []auth.AuthConfigEvaluator) {
+	sort.SliceStable(configs, func(i, j int) bool {
+		if pi, pj := priorityOf(configs[i]), priorityOf(configs[j]); pi != pj {
+			return pi < pj
+		}
+		return nameOf(configs[i]) < nameOf(configs[j])
+	})
+}
+
+func priorityOf(config auth.AuthConfigEvaluator) int {
+	if prioritizable, ok := config.(auth.Prioritizable); ok {
+		return prioritizable.GetPriority()
+	}
+	return 0
+}
+
+func nameOf(config auth.AuthConfigEvaluator) string {
+	if named, ok := config.(auth.NamedEvaluator); ok {
+		return named.GetName()
+	}
+	return ""
+}
+
 type DenyWith struct {
 	Unauthenticated *DenyWithValues
 	Unauthorized    *DenyWithValues
@@ -76,5 +139,67 @@ type DenyWithValues struct {
 	Code    int32
 	Message *json.JSONValue
 	Headers []json.JSONProperty
-	Body    *json.JSONValue
+	// HeaderConditions are the conditions for including the headers in the denial, by name of the header; headers
+	// without conditions are always included
+	HeaderConditions map[string]jsonexp.Expression
+	Body             *json.JSONValue
+	Problem          *ProblemDetails
+	// DynamicMetadata are properties emitted as Envoy dynamic metadata along with the denial, not sent to the client
+	DynamicMetadata []json.JSONProperty
+}
+
+const (
+	ProblemDetailsContentType = "application/problem+json"
+	ProblemDetailsDefaultType = "about:blank"
+)
+
+// ProblemDetails holds the members of a problem details document (RFC 7807), except for the status
+type ProblemDetails struct {
+	Type     *json.JSONValue
+	Title    *json.JSONValue
+	Detail   *json.JSONValue
+	Instance *json.JSONValue
+}
+
+type problemDetailsDocument struct {
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Status   int32  `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// Build serializes the problem details document for the denial status and message, resolving the dynamic members
+// from the Authorization JSON
+func (p *ProblemDetails) Build(status int32, message, authJSON string) (string, error) {
+	resolve := func(value *json.JSONValue) string {
+		if value == nil {
+			return ""
+		}
+		resolved, _ := json.StringifyJSON(value.ResolveFor(authJSON))
+		return resolved
+	}
+
+	document := problemDetailsDocument{
+		Type:     resolve(p.Type),
+		Title:    resolve(p.Title),
+		Status:   status,
+		Detail:   resolve(p.Detail),
+		Instance: resolve(p.Instance),
+	}
+	if document.Type == "" {
+		document.Type = ProblemDetailsDefaultType
+	}
+	if document.Title == "" {
+		document.Title = message
+	}
+	if document.Title == "" {
+		document.Title = http.StatusText(int(status))
+	}
+
+	encoded, err := gojson.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
 }
@@ -2,10 +2,14 @@ package evaluators
 
 import (
 	"context"
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/kuadrant/authorino/pkg/auth"
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
+	"github.com/kuadrant/authorino/pkg/workers"
 
 	"github.com/golang/mock/gomock"
 	"gotest.tools/assert"
@@ -51,3 +55,72 @@ func TestCleanConfig(t *testing.T) {
 		assert.Check(t, ev.cleaned)
 	}
 }
+
+// authConfigEvaluatorWorkerMock is an evaluator that runs an async worker until cleaned
+type authConfigEvaluatorWorkerMock struct {
+	worker  workers.Worker
+	cleaned int32
+}
+
+func newAuthConfigEvaluatorWorkerMock(t *testing.T) *authConfigEvaluatorWorkerMock {
+	worker, err := workers.StartWorker(context.Background(), "test", 60, func() {})
+	assert.NilError(t, err)
+	return &authConfigEvaluatorWorkerMock{worker: worker}
+}
+
+func (a *authConfigEvaluatorWorkerMock) Call(_ auth.AuthPipeline, _ context.Context) (interface{}, error) {
+	return nil, nil
+}
+
+func (a *authConfigEvaluatorWorkerMock) Clean(_ context.Context) error {
+	atomic.AddInt32(&a.cleaned, 1)
+	return a.worker.Stop()
+}
+
+func TestCleanAllConfigs(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	shared := newAuthConfigEvaluatorWorkerMock(t)
+	ev1 := newAuthConfigEvaluatorWorkerMock(t)
+	ev2 := newAuthConfigEvaluatorWorkerMock(t)
+
+	config1 := &AuthConfig{IdentityConfigs: []auth.AuthConfigEvaluator{shared}, MetadataConfigs: []auth.AuthConfigEvaluator{ev1}}
+	config2 := &AuthConfig{IdentityConfigs: []auth.AuthConfigEvaluator{shared}, AuthorizationConfigs: []auth.AuthConfigEvaluator{ev2}}
+	config1Copy := *config1 // e.g. indexed for another host
+
+	assert.Check(t, runtime.NumGoroutine() > goroutines)
+
+	err := CleanAll(context.Background(), config1, &config1Copy, config2, nil)
+	assert.NilError(t, err)
+
+	for _, ev := range []*authConfigEvaluatorWorkerMock{shared, ev1, ev2} {
+		assert.Equal(t, atomic.LoadInt32(&ev.cleaned), int32(1))
+	}
+
+	// the workers' goroutines are gone
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("leaked goroutines: %d, expected at most %d", runtime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	configs := []auth.AuthConfigEvaluator{
+		&AuthorizationConfig{Name: "c", Priority: 1},
+		&AuthorizationConfig{Name: "b", Priority: 0},
+		&AuthorizationConfig{Name: "d", Priority: 0},
+		&AuthorizationConfig{Name: "a", Priority: 1},
+		&AuthorizationConfig{Name: "e", Priority: 0},
+	}
+
+	SortByPriority(configs)
+
+	var names []string
+	for _, config := range configs {
+		names = append(names, config.(*AuthorizationConfig).Name)
+	}
+	assert.DeepEqual(t, names, []string{"b", "d", "e", "a", "c"})
+}
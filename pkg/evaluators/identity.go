@@ -6,7 +6,9 @@ import (
 	"fmt"
 
 	"github.com/kuadrant/authorino/pkg/auth"
+	authorinoContext "github.com/kuadrant/authorino/pkg/context"
 	"github.com/kuadrant/authorino/pkg/evaluators/identity"
+	"github.com/kuadrant/authorino/pkg/json"
 	"github.com/kuadrant/authorino/pkg/jsonexp"
 	"github.com/kuadrant/authorino/pkg/log"
 
@@ -23,7 +25,11 @@ const (
 	identityAPIKey     = "IDENTITY_APIKEY"
 	identityKubernetes = "IDENTITY_KUBERNETES"
 	identityPlain      = "IDENTITY_PLAIN"
+	identityLDAP       = "IDENTITY_LDAP"
 	identityNoop       = "IDENTITY_NOOP"
+
+	msg_identityMissingSubject = "missing subject claim"
+	msg_identityRevoked        = "token revoked"
 )
 
 type IdentityConfig struct {
@@ -40,9 +46,16 @@ type IdentityConfig struct {
 	APIKey         *identity.APIKey         `yaml:"apiKey,omitempty"`
 	KubernetesAuth *identity.KubernetesAuth `yaml:"kubernetes,omitempty"`
 	Plain          *identity.Plain          `yaml:"plain,omitempty"`
+	LDAP           *identity.LDAP           `yaml:"ldap,omitempty"`
 	Noop           *identity.Noop           `yaml:"noop,omitempty"`
 
+	ClaimMappings      []json.JSONProperty `yaml:"claimMappings"`
 	ExtendedProperties []IdentityExtension `yaml:"extendedProperties"`
+	// RequiredSubjectClaim is the selector of a claim that must be present and not empty in the resolved identity object
+	// for the identity to count as authenticated
+	RequiredSubjectClaim string `yaml:"requiredSubjectClaim,omitempty"`
+	// RevocationList rejects the identities of revoked tokens, including the ones resolved from the cache
+	RevocationList *identity.RevocationList `yaml:"revocationList,omitempty"`
 }
 
 func (config *IdentityConfig) GetAuthConfigEvaluator() auth.AuthConfigEvaluator {
@@ -61,6 +74,8 @@ func (config *IdentityConfig) GetAuthConfigEvaluator() auth.AuthConfigEvaluator
 		return config.KubernetesAuth
 	case identityPlain:
 		return config.Plain
+	case identityLDAP:
+		return config.LDAP
 	case identityNoop:
 		return config.Noop
 	default:
@@ -82,19 +97,32 @@ func (config *IdentityConfig) Call(pipeline auth.AuthPipeline, ctx context.Conte
 
 		cache := config.Cache
 		var cacheKey interface{}
+		var authJSON string
 
 		if cache != nil {
-			cacheKey = cache.ResolveKeyFor(pipeline.GetAuthorizationJSON())
+			authJSON = pipeline.GetAuthorizationJSON()
+			cacheKey = cache.ResolveKeyFor(authJSON)
 			if cachedObj, err := cache.Get(cacheKey); err != nil {
 				logger.V(1).Error(err, "failed to retrieve data from the cache")
 			} else if cachedObj != nil {
+				if err := config.checkRevocation(cachedObj); err != nil {
+					return nil, err
+				}
 				return cachedObj, nil
 			}
 		}
 
 		obj, err := evaluator.Call(pipeline, log.IntoContext(ctx, logger))
 
-		if err == nil && cacheKey != nil {
+		if err == nil {
+			if err = config.checkRequiredSubject(obj); err != nil {
+				obj = nil
+			} else if err = config.checkRevocation(obj); err != nil {
+				obj = nil
+			}
+		}
+
+		if err == nil && cacheKey != nil && !authorinoContext.IsDryRun(ctx) && cache.ShouldCache(authJSON, obj) {
 			if err := cache.Set(cacheKey, obj); err != nil {
 				logger.V(1).Info("unable to store data in the cache", "err", err)
 			}
@@ -104,6 +132,27 @@ func (config *IdentityConfig) Call(pipeline auth.AuthPipeline, ctx context.Conte
 	}
 }
 
+// checkRequiredSubject checks that the resolved identity object has the required subject claim, if any, not empty
+func (config *IdentityConfig) checkRequiredSubject(obj interface{}) error {
+	if config.RequiredSubjectClaim == "" {
+		return nil
+	}
+	identityObjAsJSON, _ := gojson.Marshal(obj)
+	subject := (&json.JSONValue{Pattern: config.RequiredSubjectClaim}).ResolveFor(string(identityObjAsJSON))
+	if subject == nil || subject == "" {
+		return fmt.Errorf("%s: %s", msg_identityMissingSubject, config.RequiredSubjectClaim)
+	}
+	return nil
+}
+
+// checkRevocation checks that the token of the resolved identity object is not in the revocation list, if any
+func (config *IdentityConfig) checkRevocation(obj interface{}) error {
+	if config.RevocationList != nil && config.RevocationList.IsRevoked(obj) {
+		return fmt.Errorf(msg_identityRevoked)
+	}
+	return nil
+}
+
 // impl:NamedEvaluator
 
 func (config *IdentityConfig) GetName() string {
@@ -128,6 +177,8 @@ func (config *IdentityConfig) GetType() string {
 		return identityKubernetes
 	case config.Plain != nil:
 		return identityPlain
+	case config.LDAP != nil:
+		return identityLDAP
 	case config.Noop != nil:
 		return identityNoop
 	default:
@@ -150,8 +201,13 @@ func (config *IdentityConfig) GetConditions() jsonexp.Expression {
 // impl:AuthConfigCleaner
 
 func (config *IdentityConfig) Clean(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("identity")
+	if config.RevocationList != nil {
+		if err := config.RevocationList.Clean(log.IntoContext(ctx, logger)); err != nil {
+			return err
+		}
+	}
 	if cleaner := config.getCleaner(); cleaner != nil {
-		logger := log.FromContext(ctx).WithName("identity")
 		return cleaner.Clean(log.IntoContext(ctx, logger))
 	}
 	// it is ok for there to be no clean method as not all config types need it
@@ -183,7 +239,7 @@ func (config *IdentityConfig) ResolveExtendedProperties(pipeline auth.AuthPipeli
 	_, resolvedIdentityObj := pipeline.GetResolvedIdentity()
 
 	// return the original object if there is no extension property to resolve (to save the unnecessary json marshaling/unmarshaling overhead)
-	if len(config.ExtendedProperties) == 0 {
+	if len(config.ClaimMappings) == 0 && len(config.ExtendedProperties) == 0 {
 		return resolvedIdentityObj, nil
 	}
 
@@ -194,6 +250,13 @@ func (config *IdentityConfig) ResolveExtendedProperties(pipeline auth.AuthPipeli
 		return nil, err
 	}
 
+	// project the claims of the resolved identity object into renamed properties
+	for _, claimMapping := range config.ClaimMappings {
+		if value := claimMapping.Value.ResolveFor(string(identityObjAsJSON)); value != nil {
+			extendedIdentityObject[claimMapping.Name] = value
+		}
+	}
+
 	authJSON := pipeline.GetAuthorizationJSON()
 
 	for _, extendedProperty := range config.ExtendedProperties {
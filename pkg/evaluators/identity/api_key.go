@@ -26,48 +26,98 @@ type APIKey struct {
 	Name           string              `yaml:"name"`
 	LabelSelectors k8s_labels.Selector `yaml:"labelSelectors"`
 	Namespace      string              `yaml:"namespace"`
+	// Namespaces is a list of namespaces where to look for the secrets; if set, it takes precedence over Namespace
+	Namespaces []string `yaml:"namespaces,omitempty"`
 
-	secrets   map[string]k8s.Secret
+	secrets map[string]k8s.Secret
+	// keys indexes the API key values by the secrets that hold them, so single secrets are added, updated and revoked
+	// without scanning the whole cache
+	keys      map[k8s_types.NamespacedName]string
 	mutex     sync.RWMutex
 	k8sClient k8s_client.Reader
 }
 
-func NewApiKeyIdentity(name string, labelSelectors k8s_labels.Selector, namespace string, authCred auth.AuthCredentials, k8sClient k8s_client.Reader, ctx context.Context) *APIKey {
+// NewApiKeyIdentity builds an API key identity evaluator and preloads the matching k8s secrets.
+// It fails if the secrets cannot be preloaded, so the evaluator is never used with an incomplete set of trusted API keys.
+func NewApiKeyIdentity(name string, labelSelectors k8s_labels.Selector, namespace string, authCred auth.AuthCredentials, k8sClient k8s_client.Reader, ctx context.Context) (*APIKey, error) {
 	apiKey := &APIKey{
 		AuthCredentials: authCred,
 		Name:            name,
 		LabelSelectors:  labelSelectors,
 		Namespace:       namespace,
 		secrets:         make(map[string]k8s.Secret),
+		keys:            make(map[k8s_types.NamespacedName]string),
 		k8sClient:       k8sClient,
 	}
 	if err := apiKey.loadSecrets(context.TODO()); err != nil {
 		log.FromContext(ctx).WithName("apikey").Error(err, credentialsFetchingErrorMsg)
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
 	}
-	return apiKey
+	return apiKey, nil
+}
+
+// NewApiKeyIdentityInNamespaces builds an API key identity evaluator that looks for the k8s secrets in a given list of
+// namespaces only, aggregating the matching secrets across them.
+func NewApiKeyIdentityInNamespaces(name string, labelSelectors k8s_labels.Selector, namespaces []string, authCred auth.AuthCredentials, k8sClient k8s_client.Reader, ctx context.Context) (*APIKey, error) {
+	apiKey := &APIKey{
+		AuthCredentials: authCred,
+		Name:            name,
+		LabelSelectors:  labelSelectors,
+		Namespaces:      namespaces,
+		secrets:         make(map[string]k8s.Secret),
+		keys:            make(map[k8s_types.NamespacedName]string),
+		k8sClient:       k8sClient,
+	}
+	if err := apiKey.loadSecrets(context.TODO()); err != nil {
+		log.FromContext(ctx).WithName("apikey").Error(err, credentialsFetchingErrorMsg)
+		return nil, fmt.Errorf("failed to load api keys: %w", err)
+	}
+	return apiKey, nil
 }
 
 // loadSecrets will load the matching k8s secrets from the cluster to the cache of trusted API keys
 func (a *APIKey) loadSecrets(ctx context.Context) error {
-	opts := []k8s_client.ListOption{k8s_client.MatchingLabelsSelector{Selector: a.LabelSelectors}}
-	if namespace := a.Namespace; namespace != "" {
-		opts = append(opts, k8s_client.InNamespace(namespace))
-	}
-	var secretList = &k8s.SecretList{}
-	if err := a.k8sClient.List(ctx, secretList, opts...); err != nil {
-		return err
+	var secrets []k8s.Secret
+
+	if len(a.Namespaces) > 0 {
+		for _, namespace := range a.Namespaces {
+			items, err := a.listSecrets(ctx, namespace)
+			if err != nil {
+				return err
+			}
+			secrets = append(secrets, items...)
+		}
+	} else {
+		items, err := a.listSecrets(ctx, a.Namespace)
+		if err != nil {
+			return err
+		}
+		secrets = items
 	}
 
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	for _, secret := range secretList.Items {
+	for _, secret := range secrets {
 		a.appendK8sSecretBasedIdentity(secret)
 	}
 
 	return nil
 }
 
+// listSecrets lists the k8s secrets matching the label selectors in a namespace, or in all namespaces if empty
+func (a *APIKey) listSecrets(ctx context.Context, namespace string) ([]k8s.Secret, error) {
+	opts := []k8s_client.ListOption{k8s_client.MatchingLabelsSelector{Selector: a.LabelSelectors}}
+	if namespace != "" {
+		opts = append(opts, k8s_client.InNamespace(namespace))
+	}
+	var secretList = &k8s.SecretList{}
+	if err := a.k8sClient.List(ctx, secretList, opts...); err != nil {
+		return nil, err
+	}
+	return secretList.Items, nil
+}
+
 // Call will evaluate the credentials within the request against the authorized ones
 func (a *APIKey) Call(pipeline auth.AuthPipeline, _ context.Context) (interface{}, error) {
 	if reqKey, err := a.GetCredentialsFromReq(pipeline.GetHttp()); err != nil {
@@ -76,10 +126,8 @@ func (a *APIKey) Call(pipeline auth.AuthPipeline, _ context.Context) (interface{
 		a.mutex.RLock()
 		defer a.mutex.RUnlock()
 
-		for key, secret := range a.secrets {
-			if key == reqKey {
-				return secret, nil
-			}
+		if secret, found := a.secrets[reqKey]; found {
+			return secret, nil
 		}
 	}
 	err := fmt.Errorf(invalidApiKeyMsg)
@@ -103,18 +151,17 @@ func (a *APIKey) AddK8sSecretBasedIdentity(ctx context.Context, new k8s.Secret)
 	logger := log.FromContext(ctx).WithName("apikey")
 
 	// updating existing
+	name := k8s_types.NamespacedName{Namespace: new.GetNamespace(), Name: new.GetName()}
 	newAPIKeyValue := string(new.Data[apiKeySelector])
-	for oldAPIKeyValue, current := range a.secrets {
-		if current.GetNamespace() == new.GetNamespace() && current.GetName() == new.GetName() {
-			if oldAPIKeyValue != newAPIKeyValue {
-				a.appendK8sSecretBasedIdentity(new)
-				delete(a.secrets, oldAPIKeyValue)
-				logger.V(1).Info("api key updated")
-			} else {
-				logger.V(1).Info("api key unchanged")
-			}
-			return
+	if oldAPIKeyValue, exists := a.keys[name]; exists {
+		if oldAPIKeyValue != newAPIKeyValue {
+			a.deleteK8sSecretBasedIdentity(name)
+			a.appendK8sSecretBasedIdentity(new)
+			logger.V(1).Info("api key updated")
+		} else {
+			logger.V(1).Info("api key unchanged")
 		}
+		return
 	}
 
 	if a.appendK8sSecretBasedIdentity(new) {
@@ -130,16 +177,20 @@ func (a *APIKey) RevokeK8sSecretBasedIdentity(ctx context.Context, deleted k8s_t
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	for key, secret := range a.secrets {
-		if secret.GetNamespace() == deleted.Namespace && secret.GetName() == deleted.Name {
-			delete(a.secrets, key)
-			log.FromContext(ctx).WithName("apikey").V(1).Info("api key deleted")
-			return
-		}
+	if a.deleteK8sSecretBasedIdentity(deleted) {
+		log.FromContext(ctx).WithName("apikey").V(1).Info("api key deleted")
 	}
 }
 
 func (a *APIKey) withinScope(namespace string) bool {
+	if len(a.Namespaces) > 0 {
+		for _, ns := range a.Namespaces {
+			if ns == namespace {
+				return true
+			}
+		}
+		return false
+	}
 	return a.Namespace == "" || a.Namespace == namespace
 }
 
@@ -149,7 +200,23 @@ func (a *APIKey) appendK8sSecretBasedIdentity(secret k8s.Secret) bool {
 	value, isAPIKeySecret := secret.Data[apiKeySelector]
 	if isAPIKeySecret && len(value) > 0 {
 		a.secrets[string(value)] = secret
+		a.keys[k8s_types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}] = string(value)
 		return true
 	}
 	return false
 }
+
+// Deletes the K8s Secret from the cache of API keys
+// The API key value is kept if it was taken over by another secret.
+// Caution! This function is not thread-safe. Make sure to acquire a lock before calling it.
+func (a *APIKey) deleteK8sSecretBasedIdentity(name k8s_types.NamespacedName) bool {
+	value, exists := a.keys[name]
+	if !exists {
+		return false
+	}
+	delete(a.keys, name)
+	if secret, found := a.secrets[value]; found && secret.GetNamespace() == name.Namespace && secret.GetName() == name.Name {
+		delete(a.secrets, value)
+	}
+	return true
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/kuadrant/authorino/pkg/auth"
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
 
 	k8s "k8s.io/api/core/v1"
 	k8s_meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8s_labels "k8s.io/apimachinery/pkg/labels"
+	k8s_types "k8s.io/apimachinery/pkg/types"
 
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	gomock "github.com/golang/mock/gomock"
 	"gotest.tools/assert"
 )
@@ -32,7 +35,7 @@ func TestNewApiKeyIdentityAllNamespaces(t *testing.T) {
 	defer ctrl.Finish()
 
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("jedi", selector, "", mock_auth.NewMockAuthCredentials(ctrl), testAPIKeyK8sClient, context.TODO())
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "", mock_auth.NewMockAuthCredentials(ctrl), testAPIKeyK8sClient, context.TODO())
 
 	assert.Equal(t, apiKey.Name, "jedi")
 	assert.Equal(t, apiKey.LabelSelectors.String(), "planet=coruscant")
@@ -51,7 +54,7 @@ func TestNewApiKeyIdentitySingleNamespace(t *testing.T) {
 	defer ctrl.Finish()
 
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("jedi", selector, "ns1", mock_auth.NewMockAuthCredentials(ctrl), testAPIKeyK8sClient, context.TODO())
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "ns1", mock_auth.NewMockAuthCredentials(ctrl), testAPIKeyK8sClient, context.TODO())
 
 	assert.Equal(t, apiKey.Name, "jedi")
 	assert.Equal(t, apiKey.LabelSelectors.String(), "planet=coruscant")
@@ -65,6 +68,39 @@ func TestNewApiKeyIdentitySingleNamespace(t *testing.T) {
 	assert.Check(t, !exists)
 }
 
+func TestNewApiKeyIdentityInNamespaces(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testAPIKeyK8sSecret4 := &k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "mace", Namespace: "ns3", Labels: map[string]string{"planet": "coruscant"}}, Data: map[string][]byte{"api_key": []byte("MaceWinduLightSaber")}}
+	k8sClient := mockK8sClient(testAPIKeyK8sSecret1, testAPIKeyK8sSecret2, testAPIKeyK8sSecret3, testAPIKeyK8sSecret4)
+
+	selector, _ := k8s_labels.Parse("planet=coruscant")
+	apiKey, err := NewApiKeyIdentityInNamespaces("jedi", selector, []string{"ns1", "ns2"}, mock_auth.NewMockAuthCredentials(ctrl), k8sClient, context.TODO())
+
+	assert.NilError(t, err)
+	assert.Equal(t, apiKey.Namespace, "")
+	assert.DeepEqual(t, apiKey.Namespaces, []string{"ns1", "ns2"})
+	assert.Equal(t, len(apiKey.secrets), 2)
+	_, exists := apiKey.secrets["ObiWanKenobiLightSaber"]
+	assert.Check(t, exists)
+	_, exists = apiKey.secrets["MasterYodaLightSaber"]
+	assert.Check(t, exists)
+	_, exists = apiKey.secrets["AnakinSkywalkerLightSaber"]
+	assert.Check(t, !exists)
+	_, exists = apiKey.secrets["MaceWinduLightSaber"]
+	assert.Check(t, !exists)
+
+	// secrets added to other namespaces are ignored
+	apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "plo", Namespace: "ns3", Labels: map[string]string{"planet": "coruscant"}}, Data: map[string][]byte{"api_key": []byte("PloKoonLightSaber")}})
+	apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "ahsoka", Namespace: "ns2", Labels: map[string]string{"planet": "coruscant"}}, Data: map[string][]byte{"api_key": []byte("AhsokaTanoLightSaber")}})
+	assert.Equal(t, len(apiKey.secrets), 3)
+	_, exists = apiKey.secrets["PloKoonLightSaber"]
+	assert.Check(t, !exists)
+	_, exists = apiKey.secrets["AhsokaTanoLightSaber"]
+	assert.Check(t, exists)
+}
+
 func TestCallSuccess(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -74,13 +110,36 @@ func TestCallSuccess(t *testing.T) {
 	authCredMock.EXPECT().GetCredentialsFromReq(gomock.Any()).Return("ObiWanKenobiLightSaber", nil)
 
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
 	auth, err := apiKey.Call(pipelineMock, context.TODO())
 
 	assert.NilError(t, err)
 	assert.Equal(t, string(auth.(k8s.Secret).Data["api_key"]), "ObiWanKenobiLightSaber")
 }
 
+func TestCallWithTrimmedApiKey(t *testing.T) {
+	defer func(trim bool) { auth.TrimCredentials = trim }(auth.TrimCredentials)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetHttp().Return(&envoy_auth.AttributeContext_HttpRequest{
+		Headers: map[string]string{"authorization": "APIKEY ObiWanKenobiLightSaber\n"},
+	}).AnyTimes()
+
+	selector, _ := k8s_labels.Parse("planet=coruscant")
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "", auth.NewAuthCredential("APIKEY", "authorization_header"), testAPIKeyK8sClient, context.TODO())
+
+	auth.TrimCredentials = false
+	_, err := apiKey.Call(pipelineMock, context.TODO())
+	assert.Error(t, err, "the API Key provided is invalid")
+
+	auth.TrimCredentials = true
+	obj, err := apiKey.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.Equal(t, obj.(k8s.Secret).Name, "obi-wan")
+}
+
 func TestCallNoApiKeyFail(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -90,7 +149,7 @@ func TestCallNoApiKeyFail(t *testing.T) {
 	authCredMock.EXPECT().GetCredentialsFromReq(gomock.Any()).Return("", fmt.Errorf("something went wrong getting the API Key"))
 
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
 
 	_, err := apiKey.Call(pipelineMock, context.TODO())
 
@@ -106,7 +165,7 @@ func TestCallInvalidApiKeyFail(t *testing.T) {
 	authCredMock.EXPECT().GetCredentialsFromReq(gomock.Any()).Return("ASithLightSaber", nil)
 
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
 	_, err := apiKey.Call(pipelineMock, context.TODO())
 
 	assert.Error(t, err, "the API Key provided is invalid")
@@ -114,7 +173,7 @@ func TestCallInvalidApiKeyFail(t *testing.T) {
 
 func TestLoadSecretsSuccess(t *testing.T) {
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("X-API-KEY", selector, "", nil, testAPIKeyK8sClient, nil)
+	apiKey, _ := NewApiKeyIdentity("X-API-KEY", selector, "", nil, testAPIKeyK8sClient, nil)
 
 	err := apiKey.loadSecrets(context.TODO())
 	assert.NilError(t, err)
@@ -131,12 +190,78 @@ func TestLoadSecretsSuccess(t *testing.T) {
 
 func TestLoadSecretsFail(t *testing.T) {
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("X-API-KEY", selector, "", nil, &flawedAPIkeyK8sClient{}, context.TODO())
+	apiKey := &APIKey{Name: "X-API-KEY", LabelSelectors: selector, secrets: make(map[string]k8s.Secret), keys: make(map[k8s_types.NamespacedName]string), k8sClient: &flawedAPIkeyK8sClient{}}
 
 	err := apiKey.loadSecrets(context.TODO())
 	assert.Error(t, err, "something terribly wrong happened")
 }
 
+func TestNewApiKeyIdentityFailsToLoadSecrets(t *testing.T) {
+	selector, _ := k8s_labels.Parse("planet=coruscant")
+	apiKey, err := NewApiKeyIdentity("X-API-KEY", selector, "", nil, &flawedAPIkeyK8sClient{}, context.TODO())
+
+	assert.Check(t, apiKey == nil)
+	assert.Error(t, err, "failed to load api keys: something terribly wrong happened")
+}
+
+func TestAPIKeyIncrementalUpdates(t *testing.T) {
+	selector, _ := k8s_labels.Parse("planet=coruscant")
+	apiKey, err := NewApiKeyIdentity("jedi", selector, "", nil, testAPIKeyK8sClient, context.TODO())
+	assert.NilError(t, err)
+
+	assertKeys := func(expected map[string]string) {
+		t.Helper()
+		assert.Equal(t, len(apiKey.secrets), len(expected))
+		assert.Equal(t, len(apiKey.keys), len(expected))
+		for value, name := range expected {
+			secret, exists := apiKey.secrets[value]
+			assert.Check(t, exists, value)
+			assert.Equal(t, secret.GetName(), name)
+			assert.Equal(t, apiKey.keys[k8s_types.NamespacedName{Namespace: secret.GetNamespace(), Name: name}], value)
+		}
+	}
+	assertKeys(map[string]string{"ObiWanKenobiLightSaber": "obi-wan", "MasterYodaLightSaber": "yoda"})
+
+	// added
+	apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "mace", Namespace: "ns1"}, Data: map[string][]byte{"api_key": []byte("MaceWinduLightSaber")}})
+	assertKeys(map[string]string{"ObiWanKenobiLightSaber": "obi-wan", "MasterYodaLightSaber": "yoda", "MaceWinduLightSaber": "mace"})
+
+	// updated
+	apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "mace", Namespace: "ns1"}, Data: map[string][]byte{"api_key": []byte("MaceWinduPurpleLightSaber")}})
+	assertKeys(map[string]string{"ObiWanKenobiLightSaber": "obi-wan", "MasterYodaLightSaber": "yoda", "MaceWinduPurpleLightSaber": "mace"})
+
+	// updated without the api key
+	apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "mace", Namespace: "ns1"}})
+	assertKeys(map[string]string{"ObiWanKenobiLightSaber": "obi-wan", "MasterYodaLightSaber": "yoda"})
+
+	// revoked
+	apiKey.RevokeK8sSecretBasedIdentity(context.TODO(), k8s_types.NamespacedName{Namespace: "ns2", Name: "yoda"})
+	assertKeys(map[string]string{"ObiWanKenobiLightSaber": "obi-wan"})
+
+	// unknown secret revoked
+	apiKey.RevokeK8sSecretBasedIdentity(context.TODO(), k8s_types.NamespacedName{Namespace: "ns2", Name: "anakin"})
+	assertKeys(map[string]string{"ObiWanKenobiLightSaber": "obi-wan"})
+}
+
+func TestAPIKeyRevokeSecretWithSharedValue(t *testing.T) {
+	selector, _ := k8s_labels.Parse("planet=coruscant")
+	apiKey, err := NewApiKeyIdentity("jedi", selector, "", nil, testAPIKeyK8sClient, context.TODO())
+	assert.NilError(t, err)
+
+	// another secret takes over the same api key value
+	apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "obi-wan-copy", Namespace: "ns1"}, Data: map[string][]byte{"api_key": []byte("ObiWanKenobiLightSaber")}})
+
+	// revoking the original secret keeps the api key of the one that took over
+	apiKey.RevokeK8sSecretBasedIdentity(context.TODO(), k8s_types.NamespacedName{Namespace: "ns1", Name: "obi-wan"})
+	secret, exists := apiKey.secrets["ObiWanKenobiLightSaber"]
+	assert.Check(t, exists)
+	assert.Equal(t, secret.GetName(), "obi-wan-copy")
+
+	apiKey.RevokeK8sSecretBasedIdentity(context.TODO(), k8s_types.NamespacedName{Namespace: "ns1", Name: "obi-wan-copy"})
+	_, exists = apiKey.secrets["ObiWanKenobiLightSaber"]
+	assert.Check(t, !exists)
+}
+
 func BenchmarkAPIKeyAuthn(b *testing.B) {
 	ctrl := gomock.NewController(b)
 	defer ctrl.Finish()
@@ -145,7 +270,7 @@ func BenchmarkAPIKeyAuthn(b *testing.B) {
 	authCredMock := mock_auth.NewMockAuthCredentials(ctrl)
 	authCredMock.EXPECT().GetCredentialsFromReq(gomock.Any()).Return("ObiWanKenobiLightSaber", nil).MinTimes(1)
 	selector, _ := k8s_labels.Parse("planet=coruscant")
-	apiKey := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "", authCredMock, testAPIKeyK8sClient, context.TODO())
 
 	var err error
 	b.ResetTimer()
@@ -155,3 +280,21 @@ func BenchmarkAPIKeyAuthn(b *testing.B) {
 	b.StopTimer()
 	assert.NilError(b, err)
 }
+
+func BenchmarkAPIKeyUpdateSecret(b *testing.B) {
+	selector, _ := k8s_labels.Parse("planet=coruscant")
+	apiKey, _ := NewApiKeyIdentity("jedi", selector, "", nil, mockK8sClient(), context.TODO())
+	for i := 0; i < 50000; i++ {
+		apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: fmt.Sprintf("clone-%d", i), Namespace: "ns1"}, Data: map[string][]byte{"api_key": []byte(fmt.Sprintf("CloneTrooper%dBlaster", i))}})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// each change touches a single secret, regardless of the number of secrets in the cache
+		apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "clone-25000", Namespace: "ns1"}, Data: map[string][]byte{"api_key": []byte(fmt.Sprintf("CloneTrooper25000Blaster%d", i))}})
+		apiKey.RevokeK8sSecretBasedIdentity(context.TODO(), k8s_types.NamespacedName{Namespace: "ns1", Name: "clone-49999"})
+		apiKey.AddK8sSecretBasedIdentity(context.TODO(), k8s.Secret{ObjectMeta: k8s_meta.ObjectMeta{Name: "clone-49999", Namespace: "ns1"}, Data: map[string][]byte{"api_key": []byte("CloneTrooper49999Blaster")}})
+	}
+	b.StopTimer()
+	assert.Equal(b, len(apiKey.secrets), 50000)
+}
@@ -0,0 +1,184 @@
+package identity
+
+import (
+	gocontext "context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/context"
+	"github.com/kuadrant/authorino/pkg/log"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	ldapUsernamePlaceholder     = "{username}"
+	ldapDefaultSearchFilter     = "(uid={username})"
+	ldapTimeout                 = 10 * time.Second
+	msg_ldapInvalidCredentials  = "invalid username or password"
+	msg_ldapMalformedCredential = "malformed credentials, expected base64-encoded username:password"
+)
+
+type ldapClient interface {
+	Bind(username, password string) error
+	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close()
+}
+
+type ldapDialer func(ctx gocontext.Context, url string) (ldapClient, error)
+
+// LDAP verifies username and password credentials (as in HTTP Basic authentication) by binding to an LDAP server
+type LDAP struct {
+	auth.AuthCredentials
+
+	Url string `yaml:"url"`
+	// Template of the DN (or any other bind name, e.g. "{username}@example.com" for Active Directory) to bind with,
+	// where the placeholder {username} is replaced with the escaped username
+	BindDnTemplate string `yaml:"bindDnTemplate"`
+	// Attributes of the entry of the user to retrieve after the bind
+	Attributes []string `yaml:"attributes,omitempty"`
+	// Base DN of the subtree where to look up the entry of the user; empty for the entry at the bind DN
+	SearchBaseDn string `yaml:"searchBaseDn,omitempty"`
+	// Filter to look up the entry of the user under the search base DN
+	SearchFilter string `yaml:"searchFilter,omitempty"`
+
+	dial ldapDialer
+}
+
+func NewLDAPIdentity(url, bindDnTemplate string, attributes []string, searchBaseDn, searchFilter string, authCred auth.AuthCredentials) (*LDAP, error) {
+	if !strings.HasPrefix(url, "ldap://") && !strings.HasPrefix(url, "ldaps://") {
+		return nil, fmt.Errorf("invalid ldap url %s: scheme must be ldap or ldaps", url)
+	}
+	if !strings.Contains(bindDnTemplate, ldapUsernamePlaceholder) {
+		return nil, fmt.Errorf("invalid bind dn template %s: missing %s placeholder", bindDnTemplate, ldapUsernamePlaceholder)
+	}
+	if searchFilter == "" {
+		searchFilter = ldapDefaultSearchFilter
+	}
+	return &LDAP{
+		AuthCredentials: authCred,
+		Url:             url,
+		BindDnTemplate:  bindDnTemplate,
+		Attributes:      attributes,
+		SearchBaseDn:    searchBaseDn,
+		SearchFilter:    searchFilter,
+		dial:            dialLDAP,
+	}, nil
+}
+
+func (l *LDAP) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (interface{}, error) {
+	if err := context.CheckContext(ctx); err != nil {
+		return nil, err
+	}
+
+	credential, err := l.GetCredentialsFromReq(pipeline.GetHttp())
+	if err != nil {
+		return nil, err
+	}
+	username, password, err := parseBasicCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := l.dial(ctx, l.Url)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// aborts the pending operations when the request is cancelled
+	stop := gocontext.AfterFunc(ctx, conn.Close)
+	defer stop()
+
+	bindDn := strings.ReplaceAll(l.BindDnTemplate, ldapUsernamePlaceholder, ldap.EscapeDN(username))
+
+	log.FromContext(ctx).WithName("ldap").V(1).Info("binding to the ldap server", "url", l.Url, "dn", bindDn)
+
+	if err := conn.Bind(bindDn, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf(msg_ldapInvalidCredentials)
+		}
+		return nil, err
+	}
+
+	identity := map[string]interface{}{
+		"username": username,
+		"dn":       bindDn,
+	}
+
+	if len(l.Attributes) == 0 {
+		return identity, nil
+	}
+
+	entry, err := l.lookupEntry(conn, bindDn, username)
+	if err != nil {
+		return nil, err
+	}
+	identity["dn"] = entry.DN
+	attributes := make(map[string]interface{}, len(l.Attributes))
+	for _, attribute := range entry.Attributes {
+		attributes[attribute.Name] = attribute.Values
+	}
+	identity["attributes"] = attributes
+
+	return identity, nil
+}
+
+// lookupEntry retrieves the attributes of the entry of the user, as the bound user
+func (l *LDAP) lookupEntry(conn ldapClient, bindDn, username string) (*ldap.Entry, error) {
+	request := ldap.NewSearchRequest(bindDn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false, "(objectClass=*)", l.Attributes, nil)
+	if l.SearchBaseDn != "" {
+		filter := strings.ReplaceAll(l.SearchFilter, ldapUsernamePlaceholder, ldap.EscapeFilter(username))
+		request = ldap.NewSearchRequest(l.SearchBaseDn, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false, filter, l.Attributes, nil)
+	}
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("ldap entry of the user not found or not unique")
+	}
+	return result.Entries[0], nil
+}
+
+// parseBasicCredential decodes a base64-encoded username:password credential.
+// Empty passwords are rejected, as LDAP servers treat a bind with an empty password as an unauthenticated bind, that
+// usually succeeds.
+func parseBasicCredential(credential string) (username, password string, err error) {
+	decoded, err := base64.StdEncoding.DecodeString(credential)
+	if err != nil {
+		return "", "", fmt.Errorf(msg_ldapMalformedCredential)
+	}
+	username, password, found := strings.Cut(string(decoded), ":")
+	if !found || username == "" || password == "" {
+		return "", "", fmt.Errorf(msg_ldapMalformedCredential)
+	}
+	return username, password, nil
+}
+
+type ldapConn struct {
+	*ldap.Conn
+}
+
+func (c *ldapConn) Close() {
+	c.Conn.Close()
+}
+
+// dialLDAP connects to the LDAP server, with a timeout for the connection and each operation that does not exceed the
+// deadline of the request
+func dialLDAP(ctx gocontext.Context, url string) (ldapClient, error) {
+	timeout := ldapTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	conn, err := ldap.DialURL(url, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	return &ldapConn{conn}, nil
+}
@@ -0,0 +1,160 @@
+package identity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/kuadrant/authorino/pkg/auth"
+	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+// ldapServerMock binds the users whose password matches the one of the entry at the bind dn
+type ldapServerMock struct {
+	passwords map[string]string
+	entries   []*ldap.Entry
+
+	binds    []string
+	searches []*ldap.SearchRequest
+	closed   bool
+}
+
+func (s *ldapServerMock) Bind(username, password string) error {
+	s.binds = append(s.binds, username)
+	if p, ok := s.passwords[username]; !ok || p != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, nil)
+	}
+	return nil
+}
+
+func (s *ldapServerMock) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	s.searches = append(s.searches, searchRequest)
+	return &ldap.SearchResult{Entries: s.entries}, nil
+}
+
+func (s *ldapServerMock) Close() {
+	s.closed = true
+}
+
+func newLDAPMock(t *testing.T, server *ldapServerMock, bindDn string, attributes []string, searchBaseDn, searchFilter string) *LDAP {
+	l, err := NewLDAPIdentity("ldap://ldap.example.com", bindDn, attributes, searchBaseDn, searchFilter, auth.NewAuthCredential("Basic", "authorization_header"))
+	assert.NilError(t, err)
+	l.dial = func(_ context.Context, _ string) (ldapClient, error) { return server, nil }
+	return l
+}
+
+func newLDAPPipelineMock(ctrl *gomock.Controller, username, password string) *mock_auth.MockAuthPipeline {
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	credential := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	pipelineMock.EXPECT().GetHttp().Return(&envoy_auth.AttributeContext_HttpRequest{
+		Headers: map[string]string{"authorization": "Basic " + credential},
+	})
+	return pipelineMock
+}
+
+func TestLDAPBind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := &ldapServerMock{passwords: map[string]string{"uid=john,ou=people,dc=example,dc=org": "secret"}}
+	l := newLDAPMock(t, server, "uid={username},ou=people,dc=example,dc=org", nil, "", "")
+
+	obj, err := l.Call(newLDAPPipelineMock(ctrl, "john", "secret"), context.TODO())
+	assert.NilError(t, err)
+	j, _ := json.Marshal(obj)
+	assert.Equal(t, string(j), `{"dn":"uid=john,ou=people,dc=example,dc=org","username":"john"}`)
+	assert.Equal(t, len(server.searches), 0)
+	assert.Check(t, server.closed)
+}
+
+func TestLDAPBindWithInvalidPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := &ldapServerMock{passwords: map[string]string{"uid=john,ou=people,dc=example,dc=org": "secret"}}
+	l := newLDAPMock(t, server, "uid={username},ou=people,dc=example,dc=org", nil, "", "")
+
+	obj, err := l.Call(newLDAPPipelineMock(ctrl, "john", "wrong"), context.TODO())
+	assert.Error(t, err, msg_ldapInvalidCredentials)
+	assert.Check(t, obj == nil)
+}
+
+func TestLDAPBindWithEmptyPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := &ldapServerMock{passwords: map[string]string{"uid=john,ou=people,dc=example,dc=org": ""}}
+	l := newLDAPMock(t, server, "uid={username},ou=people,dc=example,dc=org", nil, "", "")
+
+	_, err := l.Call(newLDAPPipelineMock(ctrl, "john", ""), context.TODO())
+	assert.Error(t, err, msg_ldapMalformedCredential)
+	assert.Equal(t, len(server.binds), 0) // an unauthenticated bind would succeed
+}
+
+func TestLDAPBindEscapesUsername(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := &ldapServerMock{}
+	l := newLDAPMock(t, server, "uid={username},ou=people,dc=example,dc=org", nil, "", "")
+
+	_, err := l.Call(newLDAPPipelineMock(ctrl, "john,x", "secret"), context.TODO())
+	assert.Error(t, err, msg_ldapInvalidCredentials)
+	assert.DeepEqual(t, server.binds, []string{`uid=john\,x,ou=people,dc=example,dc=org`})
+}
+
+func TestLDAPAttributes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := &ldapServerMock{
+		passwords: map[string]string{"uid=john,ou=people,dc=example,dc=org": "secret"},
+		entries: []*ldap.Entry{ldap.NewEntry("uid=john,ou=people,dc=example,dc=org", map[string][]string{
+			"mail":     {"john@example.org"},
+			"memberOf": {"cn=admins,ou=groups,dc=example,dc=org", "cn=devs,ou=groups,dc=example,dc=org"},
+		})},
+	}
+	l := newLDAPMock(t, server, "uid={username},ou=people,dc=example,dc=org", []string{"mail", "memberOf"}, "", "")
+
+	obj, err := l.Call(newLDAPPipelineMock(ctrl, "john", "secret"), context.TODO())
+	assert.NilError(t, err)
+	j, _ := json.Marshal(obj)
+	assert.Equal(t, string(j), `{"attributes":{"mail":["john@example.org"],"memberOf":["cn=admins,ou=groups,dc=example,dc=org","cn=devs,ou=groups,dc=example,dc=org"]},"dn":"uid=john,ou=people,dc=example,dc=org","username":"john"}`)
+	assert.Equal(t, len(server.searches), 1)
+	assert.Equal(t, server.searches[0].BaseDN, "uid=john,ou=people,dc=example,dc=org")
+	assert.Equal(t, server.searches[0].Scope, ldap.ScopeBaseObject)
+}
+
+func TestLDAPAttributesBySearch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := &ldapServerMock{
+		passwords: map[string]string{"john@example.org": "secret"},
+		entries: []*ldap.Entry{ldap.NewEntry("cn=John,ou=users,dc=example,dc=org", map[string][]string{
+			"mail": {"john@example.org"},
+		})},
+	}
+	l := newLDAPMock(t, server, "{username}@example.org", []string{"mail"}, "ou=users,dc=example,dc=org", "(sAMAccountName={username})")
+
+	obj, err := l.Call(newLDAPPipelineMock(ctrl, "john", "secret"), context.TODO())
+	assert.NilError(t, err)
+	j, _ := json.Marshal(obj)
+	assert.Equal(t, string(j), `{"attributes":{"mail":["john@example.org"]},"dn":"cn=John,ou=users,dc=example,dc=org","username":"john"}`)
+	assert.Equal(t, server.searches[0].BaseDN, "ou=users,dc=example,dc=org")
+	assert.Equal(t, server.searches[0].Filter, "(sAMAccountName=john)")
+}
+
+func TestNewLDAPIdentityWithInvalidSettings(t *testing.T) {
+	_, err := NewLDAPIdentity("http://ldap.example.com", "uid={username}", nil, "", "", nil)
+	assert.Error(t, err, "invalid ldap url http://ldap.example.com: scheme must be ldap or ldaps")
+
+	_, err = NewLDAPIdentity("ldaps://ldap.example.com", "uid=john", nil, "", "", nil)
+	assert.Error(t, err, "invalid bind dn template uid=john: missing {username} placeholder")
+}
@@ -16,6 +16,8 @@ import (
 	otel_propagation "go.opentelemetry.io/otel/propagation"
 )
 
+const msg_oauth2JWTNotIntrospected = "token in jwt format not introspected"
+
 type OAuth2 struct {
 	auth.AuthCredentials
 
@@ -23,6 +25,8 @@ type OAuth2 struct {
 	TokenTypeHint         string `yaml:"tokenTypeHint,omitempty"`
 	ClientID              string `yaml:"clientId"`
 	ClientSecret          string `yaml:"clientSecret"`
+	// OpaqueTokensOnly rejects the tokens in JWT format without introspecting them
+	OpaqueTokensOnly bool `yaml:"opaqueTokensOnly,omitempty"`
 }
 
 func NewOAuth2Identity(tokenIntrospectionUrl string, tokenTypeHint string, clientID string, clientSecret string, creds auth.AuthCredentials) *OAuth2 {
@@ -34,11 +38,11 @@ func NewOAuth2Identity(tokenIntrospectionUrl string, tokenTypeHint string, clien
 	}
 
 	return &OAuth2{
-		creds,
-		tokenIntrospectionUrl,
-		tokenHint,
-		clientID,
-		clientSecret,
+		AuthCredentials:       creds,
+		TokenIntrospectionUrl: tokenIntrospectionUrl,
+		TokenTypeHint:         tokenHint,
+		ClientID:              clientID,
+		ClientSecret:          clientSecret,
 	}
 }
 
@@ -53,6 +57,10 @@ func (oauth *OAuth2) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (in
 		return nil, err
 	}
 
+	if oauth.OpaqueTokensOnly && isJWT(accessToken) {
+		return nil, fmt.Errorf(msg_oauth2JWTNotIntrospected)
+	}
+
 	// introspect token
 	tokenIntrospectionURL, _ := url.Parse(oauth.TokenIntrospectionUrl)
 	tokenIntrospectionURL.User = url.UserPassword(oauth.ClientID, oauth.ClientSecret)
@@ -1,16 +1,26 @@
 package identity
 
 import (
+	"bytes"
 	gocontext "context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"net"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/clock"
 	"github.com/kuadrant/authorino/pkg/context"
 	"github.com/kuadrant/authorino/pkg/log"
 	"github.com/kuadrant/authorino/pkg/workers"
 
 	goidc "github.com/coreos/go-oidc"
+	"github.com/go-jose/go-jose/v4"
 )
 
 const (
@@ -18,13 +28,57 @@ const (
 	msg_oidcProviderConfigRefreshSuccess  = "openid connect configuration updated"
 	msg_oidcProviderConfigRefreshError    = "failed to discovery openid connect configuration"
 	msg_oidcProviderConfigRefreshDisabled = "auto-refresh of openid connect configuration disabled"
+	msg_oidcInvalidAudience               = "invalid audience"
+	msg_oidcInvalidHostAudience           = "audience does not match the host"
+	msg_oidcNotJWT                        = "token not in jwt format"
+	msg_oidcIssuerNotMatched              = "token issued by another issuer"
+
+	hostAudiencePlaceholder = "{host}"
+
+	// JWTHeaderProperty is the property of the resolved identity object that holds the decoded header of the JWT (e.g. 'kid', 'alg')
+	JWTHeaderProperty = "jwt_header"
+	// ExpiredInGraceProperty is the property of the resolved identity object that flags a JWT accepted within the expiry grace window
+	ExpiredInGraceProperty = "expired_in_grace"
 )
 
 type OIDC struct {
 	auth.AuthCredentials
-	Endpoint  string `yaml:"endpoint"`
+	Endpoint string `yaml:"endpoint"`
+	// Audiences accepted in the "aud" claim of the token; the token must have at least one of them
+	Audiences []string `yaml:"audiences,omitempty"`
+	// HostAudience is the template of an audience derived from the host of the request, that the "aud" claim of the token must contain
+	HostAudience string `yaml:"hostAudience,omitempty"`
+	// SkipIssuerCheck accepts tokens whose "iss" claim does not match the issuer of the OpenID Connect configuration
+	SkipIssuerCheck bool `yaml:"skipIssuerCheck,omitempty"`
+	// PreMatchIssuer rejects without verification the tokens not in JWT format or whose unverified "iss" claim does not
+	// match the issuer, so tokens meant for other identity sources sharing the same credentials are not verified
+	PreMatchIssuer bool `yaml:"preMatchIssuer,omitempty"`
+	// ExpiryGrace is how long after the expiration ("exp" claim) the token is still accepted, flagged as expired in grace
+	ExpiryGrace time.Duration `yaml:"expiryGrace,omitempty"`
+	// ClockSkew is how long after the expiration ("exp" claim) the token is still accepted as not expired, to tolerate
+	// the drift between the clock of the issuer and the one of Authorino
+	ClockSkew time.Duration `yaml:"clockSkew,omitempty"`
 	provider  *goidc.Provider
 	refresher workers.Worker
+	// keySet is a static JSON Web Key Set to verify the tokens offline, without OpenID Connect Discovery
+	keySet goidc.KeySet
+	// Clock tells the time to verify the expiration of the tokens; defaults to the wall clock
+	Clock clock.Clock `yaml:"-"`
+	// RetryableWhenProviderUnavailable tells the auth pipeline to deny the requests whose token cannot be verified for
+	// the OpenID Connect configuration being unavailable as temporarily unavailable, instead of unauthenticated
+	RetryableWhenProviderUnavailable bool `yaml:"retryableWhenProviderUnavailable,omitempty"`
+}
+
+// ProviderUnavailableError is the error of verifying a token while the OpenID Connect configuration of the issuer is
+// not available (e.g. the discovery failed and has not recovered yet), as opposed to the token being invalid
+type ProviderUnavailableError struct {
+	Endpoint string
+	// Retryable tells whether the request shall be denied as temporarily unavailable, so the client can retry
+	Retryable bool
+}
+
+func (e *ProviderUnavailableError) Error() string {
+	return msg_oidcProviderConfigMissingError
 }
 
 func NewOIDC(endpoint string, creds auth.AuthCredentials, ttl int, ctx gocontext.Context) *OIDC {
@@ -38,6 +92,19 @@ func NewOIDC(endpoint string, creds auth.AuthCredentials, ttl int, ctx gocontext
 	return oidc
 }
 
+// NewOIDCWithStaticJWKS creates an OIDC evaluator that verifies the tokens against a static JSON Web Key Set, with no
+// requests to the issuer
+func NewOIDCWithStaticJWKS(rawJWKS []byte, creds auth.AuthCredentials) (*OIDC, error) {
+	keySet, err := newStaticKeySet(rawJWKS)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDC{
+		AuthCredentials: creds,
+		keySet:          keySet,
+	}, nil
+}
+
 func (oidc *OIDC) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (interface{}, error) {
 	// retrieve access token
 	accessToken, err := oidc.GetCredentialsFromReq(pipeline.GetRequest().GetAttributes().GetRequest().GetHttp())
@@ -45,16 +112,106 @@ func (oidc *OIDC) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (inter
 		return nil, err
 	}
 
+	if oidc.PreMatchIssuer {
+		if err := oidc.preMatchIssuer(accessToken); err != nil {
+			return nil, err
+		}
+	}
+
 	// verify jwt and extract claims
 	var claims interface{}
-	if _, err := oidc.decodeAndVerifyToken(accessToken, log.IntoContext(ctx, log.FromContext(ctx).WithName("oidc")), &claims); err != nil {
+	idToken, err := oidc.decodeAndVerifyToken(accessToken, log.IntoContext(ctx, log.FromContext(ctx).WithName("oidc")), &claims)
+	if err != nil {
 		return nil, err
-	} else {
-		return claims, nil
 	}
+
+	// verify the audience
+	var host string
+	if oidc.HostAudience != "" {
+		host = auth.RequestHost(pipeline.GetHttp())
+	}
+	if err := oidc.verifyAudience(idToken.Audience, host); err != nil {
+		return nil, err
+	}
+
+	claimsObj, _ := claims.(map[string]interface{})
+
+	// flag the token accepted within the expiry grace window
+	if claimsObj != nil && idToken.Expiry.Before(clock.Now(oidc.Clock).Add(-oidc.ClockSkew)) {
+		claimsObj[ExpiredInGraceProperty] = true
+	}
+
+	// expose the header of the verified jwt, unless a claim with the same name exists
+	if claimsObj != nil {
+		if _, exists := claimsObj[JWTHeaderProperty]; !exists {
+			if header, err := decodeJWTHeader(accessToken); err == nil {
+				claimsObj[JWTHeaderProperty] = header
+			}
+		}
+	}
+
+	return claims, nil
+}
+
+func decodeJWTHeader(token string) (map[string]interface{}, error) {
+	encodedHeader, _, found := strings.Cut(token, ".")
+	if !found {
+		return nil, fmt.Errorf("malformed jwt")
+	}
+	decodedHeader, err := base64.RawURLEncoding.DecodeString(encodedHeader)
+	if err != nil {
+		return nil, err
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(decodedHeader, &header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// isJWT tells whether the token is in JWT format, i.e. three dot-separated segments whose first one decodes to a JSON
+// header, regardless of the token being valid
+func isJWT(token string) bool {
+	if strings.Count(token, ".") != 2 {
+		return false
+	}
+	_, err := decodeJWTHeader(token)
+	return err == nil
+}
+
+// preMatchIssuer checks that the token is a JWT whose "iss" claim, not verified yet, matches the issuer
+func (oidc *OIDC) preMatchIssuer(token string) error {
+	if !isJWT(token) {
+		return fmt.Errorf(msg_oidcNotJWT)
+	}
+
+	if oidc.keySet != nil || oidc.SkipIssuerCheck {
+		return nil // no issuer to match
+	}
+
+	encodedPayload := strings.Split(token, ".")[1]
+	decodedPayload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return fmt.Errorf(msg_oidcNotJWT)
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(decodedPayload, &claims); err != nil {
+		return fmt.Errorf(msg_oidcNotJWT)
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(oidc.Endpoint, "/") {
+		return fmt.Errorf(msg_oidcIssuerNotMatched)
+	}
+	return nil
 }
 
 func (oidc *OIDC) getProvider(ctx gocontext.Context, force bool) *goidc.Provider {
+	if oidc.keySet != nil {
+		return nil // discovery disabled
+	}
+
 	if oidc.provider == nil || force {
 		endpoint := oidc.Endpoint
 		if provider, err := goidc.NewProvider(gocontext.TODO(), endpoint); err != nil {
@@ -87,24 +244,84 @@ func (oidc *OIDC) decodeAndVerifyToken(accessToken string, ctx gocontext.Context
 	return idToken, nil
 }
 
-func (oidc *OIDC) verifyToken(accessToken string, ctx gocontext.Context) (*goidc.IDToken, error) {
-	provider := oidc.getProvider(ctx, false)
+// verifiedTokenKey identifies a token verified by an OIDC evaluator in the memo of the request
+type verifiedTokenKey struct {
+	oidc  *OIDC
+	token string
+}
 
-	if provider == nil {
-		return nil, fmt.Errorf(msg_oidcProviderConfigMissingError)
+// verifyToken verifies the token at most once per request, so the evaluators that need the same token verified again
+// throughout the auth pipeline reuse the result of the first verification
+func (oidc *OIDC) verifyToken(accessToken string, ctx gocontext.Context) (*goidc.IDToken, error) {
+	idToken, err := context.Memoize(ctx, verifiedTokenKey{oidc, accessToken}, func() (interface{}, error) {
+		return oidc.verifyTokenOnce(accessToken, ctx)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return idToken.(*goidc.IDToken), nil
+}
 
-	tokenVerifierConfig := &goidc.Config{SkipClientIDCheck: true, SkipIssuerCheck: true}
-	if idToken, err := provider.Verifier(tokenVerifierConfig).Verify(ctx, accessToken); err != nil {
+func (oidc *OIDC) verifyTokenOnce(accessToken string, ctx gocontext.Context) (*goidc.IDToken, error) {
+	now := clock.OrRealClock(oidc.Clock).Now
+	tokenVerifierConfig := &goidc.Config{SkipClientIDCheck: true, SkipIssuerCheck: oidc.SkipIssuerCheck, Now: now}
+	verifier, err := oidc.verifier(ctx, tokenVerifierConfig)
+	if err != nil {
 		return nil, err
-	} else {
+	}
+
+	idToken, err := verifier.Verify(ctx, accessToken)
+	if err == nil || oidc.ClockSkew+oidc.ExpiryGrace <= 0 {
+		return idToken, err
+	}
+
+	// retry as if verifying the token earlier in time, so just-expired tokens are accepted within the clock skew
+	// allowance and the grace window
+	tokenVerifierConfig.Now = func() time.Time { return now().Add(-oidc.ClockSkew - oidc.ExpiryGrace) }
+	if idToken, graceErr := verifier.Verify(ctx, accessToken); graceErr == nil {
 		return idToken, nil
 	}
+	return nil, err
+}
+
+func (oidc *OIDC) verifier(ctx gocontext.Context, config *goidc.Config) (*goidc.IDTokenVerifier, error) {
+	if oidc.keySet != nil {
+		config.SupportedSigningAlgs = staticKeySetSigningAlgorithms
+		config.SkipIssuerCheck = true // no issuer to check against
+		return goidc.NewVerifier("", oidc.keySet, config), nil
+	}
+
+	provider := oidc.getProvider(ctx, false)
+	if provider == nil {
+		return nil, &ProviderUnavailableError{Endpoint: oidc.Endpoint, Retryable: oidc.RetryableWhenProviderUnavailable}
+	}
+	return provider.Verifier(config), nil
+}
+
+// verifyAudience checks the audiences of the token against the static audiences and the audience derived from the host of the request
+func (oidc *OIDC) verifyAudience(tokenAudiences []string, host string) error {
+	if len(oidc.Audiences) > 0 && !containsAnyAudience(tokenAudiences, oidc.Audiences, false) {
+		return fmt.Errorf(msg_oidcInvalidAudience)
+	}
+
+	if oidc.HostAudience != "" {
+		expectedAudience := strings.ReplaceAll(oidc.HostAudience, hostAudiencePlaceholder, normalizeHost(host))
+		if !containsAnyAudience(tokenAudiences, []string{expectedAudience}, true) {
+			return fmt.Errorf(msg_oidcInvalidHostAudience)
+		}
+	}
+
+	return nil
 }
 
 func (oidc *OIDC) GetURL(name string, ctx gocontext.Context) (*url.URL, error) {
+	provider := oidc.getProvider(ctx, false)
+	if provider == nil {
+		return nil, fmt.Errorf(msg_oidcProviderConfigMissingError)
+	}
+
 	var providerClaims map[string]interface{}
-	_ = oidc.getProvider(ctx, false).Claims(&providerClaims)
+	_ = provider.Claims(&providerClaims)
 
 	if endpoint, err := url.Parse(providerClaims[name].(string)); err != nil {
 		return nil, err
@@ -113,22 +330,134 @@ func (oidc *OIDC) GetURL(name string, ctx gocontext.Context) (*url.URL, error) {
 	}
 }
 
+// configureProviderRefresh starts a worker to refresh the OpenID Connect configuration every ttl seconds.
+// A ttl of zero or less disables the auto-refresh, i.e. no worker is started.
 func (oidc *OIDC) configureProviderRefresh(ttl int, ctx gocontext.Context) {
+	if ttl <= 0 {
+		log.FromContext(ctx).V(1).Info(msg_oidcProviderConfigRefreshDisabled, "reason", "ttl not set")
+		return
+	}
+
 	var err error
 
-	oidc.refresher, err = workers.StartWorker(ctx, ttl, func() {
+	oidc.refresher, err = workers.StartWorkerWithClock(ctx, "oidc", ttl, func() {
 		oidc.getProvider(ctx, true)
-	})
+	}, oidc.Clock)
 
 	if err != nil {
 		log.FromContext(ctx).V(1).Info(msg_oidcProviderConfigRefreshDisabled, "reason", err)
 	}
 }
 
-// Clean ensures the goroutine started by configureProviderRefresh is cleaned up
+func containsAnyAudience(tokenAudiences, expectedAudiences []string, normalize bool) bool {
+	for _, expected := range expectedAudiences {
+		for _, aud := range tokenAudiences {
+			if aud == expected || (normalize && normalizeAudience(aud) == normalizeAudience(expected)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalizeAudience(aud string) string {
+	return strings.TrimSuffix(strings.ToLower(aud), "/")
+}
+
+// normalizeHost strips the port and the trailing dot of the host and lowercases it
+func normalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// Clean ensures the goroutine started by configureProviderRefresh is cleaned up.
+// It is a no-op if the auto-refresh is disabled.
 func (oidc *OIDC) Clean(ctx gocontext.Context) error {
 	if oidc.refresher == nil {
 		return nil
 	}
 	return oidc.refresher.Stop()
 }
+
+var staticKeySetSigningAlgorithms = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// staticKeySet verifies the signature of the tokens against a JSON Web Key Set provided out-of-band
+type staticKeySet struct {
+	keys jose.JSONWebKeySet
+}
+
+// newStaticKeySet parses a JSON Web Key Set or, alternatively, one or more PEM-encoded public keys or certificates
+func newStaticKeySet(rawJWKS []byte) (*staticKeySet, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(rawJWKS), []byte("-----BEGIN")) {
+		return newStaticKeySetFromPEM(rawJWKS)
+	}
+	keySet := &staticKeySet{}
+	if err := json.Unmarshal(rawJWKS, &keySet.keys); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+	if len(keySet.keys.Keys) == 0 {
+		return nil, fmt.Errorf("invalid jwks: no keys")
+	}
+	return keySet, nil
+}
+
+func newStaticKeySetFromPEM(rawPEM []byte) (*staticKeySet, error) {
+	keySet := &staticKeySet{}
+	for block, rest := pem.Decode(rawPEM); block != nil; block, rest = pem.Decode(rest) {
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			err = fmt.Errorf("unsupported pem block type %s", block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		keySet.keys.Keys = append(keySet.keys.Keys, jose.JSONWebKey{Key: key})
+	}
+	if len(keySet.keys.Keys) == 0 {
+		return nil, fmt.Errorf("invalid public key: no pem block")
+	}
+	return keySet, nil
+}
+
+func (s *staticKeySet) VerifySignature(_ gocontext.Context, token string) ([]byte, error) {
+	algorithms := make([]jose.SignatureAlgorithm, len(staticKeySetSigningAlgorithms))
+	for i, alg := range staticKeySetSigningAlgorithms {
+		algorithms[i] = jose.SignatureAlgorithm(alg)
+	}
+
+	jws, err := jose.ParseSigned(token, algorithms)
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt: %w", err)
+	}
+
+	keyID := jws.Signatures[0].Header.KeyID
+	for _, key := range s.keys.Keys {
+		// keys without id (e.g. pem-encoded) are tried for any token
+		if keyID != "" && key.KeyID != "" && key.KeyID != keyID {
+			continue
+		}
+		if payload, err := jws.Verify(key); err == nil {
+			return payload, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to verify signature: no matching key in the jwks")
+}
@@ -0,0 +1,136 @@
+package identity
+
+import (
+	gocontext "context"
+	gojson "encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/kuadrant/authorino/pkg/json"
+	"github.com/kuadrant/authorino/pkg/log"
+	"github.com/kuadrant/authorino/pkg/workers"
+
+	"go.opentelemetry.io/otel"
+	otel_propagation "go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	msg_revocationListRefreshSuccess  = "revocation list updated"
+	msg_revocationListRefreshError    = "failed to fetch the revocation list"
+	msg_revocationListRefreshDisabled = "auto-refresh of the revocation list disabled"
+)
+
+// RevocationList is a list of revoked tokens, fetched from an endpoint that responds with a JSON array of the
+// identifiers of the revoked tokens, and refreshed periodically
+type RevocationList struct {
+	Endpoint string `yaml:"endpoint"`
+	// Selector of the identifier of the token in the resolved identity object (e.g. "jti")
+	Selector string `yaml:"selector"`
+
+	revoked   map[string]struct{}
+	mutex     sync.RWMutex
+	refresher workers.Worker
+}
+
+// NewRevocationList fetches the revocation list from the endpoint and refreshes it every ttl seconds, if greater than zero.
+// It fails if the list cannot be fetched, so revoked tokens are never accepted for an incomplete list.
+func NewRevocationList(endpoint, selector string, ttl int, ctx gocontext.Context) (*RevocationList, error) {
+	list := &RevocationList{
+		Endpoint: endpoint,
+		Selector: selector,
+		revoked:  make(map[string]struct{}),
+	}
+
+	logger := log.FromContext(ctx).WithName("revocationlist")
+	if err := list.Refresh(ctx); err != nil {
+		logger.Error(err, msg_revocationListRefreshError, "endpoint", endpoint)
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		logger.V(1).Info(msg_revocationListRefreshDisabled, "reason", "ttl not set")
+		return list, nil
+	}
+
+	var err error
+	list.refresher, err = workers.StartWorker(ctx, "revocation_list", ttl, func() {
+		if err := list.Refresh(ctx); err != nil {
+			// keeps the last list fetched
+			logger.Error(err, msg_revocationListRefreshError, "endpoint", endpoint)
+		}
+	})
+	if err != nil {
+		logger.V(1).Info(msg_revocationListRefreshDisabled, "reason", err)
+	}
+
+	return list, nil
+}
+
+// Refresh fetches the revocation list from the endpoint, replacing the current one
+func (l *RevocationList) Refresh(ctx gocontext.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.Endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	otel.GetTextMapPropagator().Inject(ctx, otel_propagation.HeaderCarrier(req.Header))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	if err := gojson.Unmarshal(body, &ids); err != nil {
+		return fmt.Errorf("invalid revocation list: %v", err)
+	}
+
+	revoked := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		revoked[id] = struct{}{}
+	}
+
+	l.mutex.Lock()
+	l.revoked = revoked
+	l.mutex.Unlock()
+
+	log.FromContext(ctx).WithName("revocationlist").V(1).Info(msg_revocationListRefreshSuccess, "endpoint", l.Endpoint, "size", len(revoked))
+
+	return nil
+}
+
+// IsRevoked tells whether the token of the resolved identity object is in the revocation list
+func (l *RevocationList) IsRevoked(identityObj interface{}) bool {
+	identityObjAsJSON, _ := gojson.Marshal(identityObj)
+	id, _ := (&json.JSONValue{Pattern: l.Selector}).ResolveFor(string(identityObjAsJSON)).(string)
+	if id == "" {
+		return false
+	}
+
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	_, revoked := l.revoked[id]
+	return revoked
+}
+
+// Clean stops refreshing the revocation list
+func (l *RevocationList) Clean(_ gocontext.Context) error {
+	if l.refresher == nil {
+		return nil
+	}
+	return l.refresher.Stop()
+}
@@ -1,17 +1,21 @@
 package evaluators
 
 import (
+	"context"
 	gojson "encoding/json"
 	"testing"
 
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
 	"github.com/kuadrant/authorino/pkg/evaluators/identity"
+	"github.com/kuadrant/authorino/pkg/httptest"
 	"github.com/kuadrant/authorino/pkg/json"
 
 	"github.com/golang/mock/gomock"
 	"gotest.tools/assert"
 )
 
+const revocationListHttpServerMockAddr string = "127.0.0.1:9012"
+
 func TestIdentityConfig_ResolveExtendedProperties(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -54,3 +58,133 @@ func TestIdentityConfig_ResolveExtendedProperties(t *testing.T) {
 	extendedIdentityObjectJSON, _ := gojson.Marshal(extendedIdentityObject)
 	assert.Equal(t, string(extendedIdentityObjectJSON), `{"exp":1629884250,"prop1":"value1","prop2":"foo","sub":"foo"}`)
 }
+
+func TestIdentityConfig_ResolveClaimMappings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+
+	var identityObject interface{}
+	_ = gojson.Unmarshal([]byte(`{"sub":"foo","email_address":"foo@example.com","realm_access":{"roles":["admin","user"]},"preferred_username":"foo"}`), &identityObject)
+
+	identityConfig := IdentityConfig{
+		Name:           "test",
+		KubernetesAuth: &identity.KubernetesAuth{},
+		ClaimMappings: []json.JSONProperty{
+			{Name: "email", Value: json.JSONValue{Pattern: "email_address"}},
+			{Name: "roles", Value: json.JSONValue{Pattern: "realm_access.roles"}},
+			{Name: "first_role", Value: json.JSONValue{Pattern: "realm_access.roles.0"}},
+			{Name: "username", Value: json.JSONValue{Pattern: "preferred_username"}},
+			{Name: "missing", Value: json.JSONValue{Pattern: "not_a_claim"}},
+		},
+		ExtendedProperties: []IdentityExtension{
+			NewIdentityExtension("username", json.JSONValue{Static: "bar"}, false), // default ignored, the property is mapped
+		},
+	}
+
+	pipelineMock.EXPECT().GetResolvedIdentity().Return(nil, identityObject)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{},"auth":{"identity":{}}}`)
+
+	extendedIdentityObject, err := identityConfig.ResolveExtendedProperties(pipelineMock)
+	assert.NilError(t, err)
+
+	extendedIdentityObjectJSON, _ := gojson.Marshal(extendedIdentityObject)
+	assert.Equal(t, string(extendedIdentityObjectJSON), `{"email":"foo@example.com","email_address":"foo@example.com","first_role":"admin","preferred_username":"foo","realm_access":{"roles":["admin","user"]},"roles":["admin","user"],"sub":"foo","username":"foo"}`)
+}
+
+func TestIdentityConfig_RequiredSubjectClaim(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	identityConfig := IdentityConfig{
+		Name:                 "test",
+		Plain:                &identity.Plain{Pattern: "context.request.http.headers.x-user"},
+		RequiredSubjectClaim: "sub",
+	}
+
+	call := func(authJSON string) (interface{}, error) {
+		pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+		pipelineMock.EXPECT().GetAuthorizationJSON().Return(authJSON)
+		return identityConfig.Call(pipelineMock, context.TODO())
+	}
+
+	// with the subject claim
+	obj, err := call(`{"context":{"request":{"http":{"headers":{"x-user":{"sub":"john","name":"John"}}}}}}`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, obj, map[string]interface{}{"sub": "john", "name": "John"})
+
+	// without the subject claim
+	obj, err = call(`{"context":{"request":{"http":{"headers":{"x-user":{"name":"John"}}}}}}`)
+	assert.Check(t, obj == nil)
+	assert.Error(t, err, "missing subject claim: sub")
+
+	// with an empty subject claim
+	obj, err = call(`{"context":{"request":{"http":{"headers":{"x-user":{"sub":"","name":"John"}}}}}}`)
+	assert.Check(t, obj == nil)
+	assert.Error(t, err, "missing subject claim: sub")
+
+	// no subject claim required
+	identityConfig.RequiredSubjectClaim = ""
+	obj, err = call(`{"context":{"request":{"http":{"headers":{"x-user":{"name":"John"}}}}}}`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, obj, map[string]interface{}{"name": "John"})
+}
+
+func TestIdentityConfig_RevocationList(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	revoked := `[]`
+	revocationListServer := httptest.NewHttpServerMock(revocationListHttpServerMockAddr, map[string]httptest.HttpServerMockResponseFunc{
+		"/revoked": func() httptest.HttpServerMockResponse {
+			return httptest.HttpServerMockResponse{Status: 200, Body: revoked}
+		},
+	})
+	defer revocationListServer.Close()
+
+	revocationList, err := identity.NewRevocationList("http://"+revocationListHttpServerMockAddr+"/revoked", "jti", 0, context.TODO())
+	assert.NilError(t, err)
+
+	identityConfig := IdentityConfig{
+		Name:           "test",
+		Plain:          &identity.Plain{Pattern: "context.request.http.headers.x-user"},
+		Cache:          NewEvaluatorCache(json.JSONValue{Pattern: "context.request.http.headers.authorization"}, 60, 0, nil),
+		RevocationList: revocationList,
+	}
+	defer identityConfig.Clean(context.TODO())
+
+	call := func(authJSON string) (interface{}, error) {
+		pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+		pipelineMock.EXPECT().GetAuthorizationJSON().Return(authJSON).AnyTimes()
+		return identityConfig.Call(pipelineMock, context.TODO())
+	}
+
+	// resolved and cached
+	obj, err := call(`{"context":{"request":{"http":{"headers":{"authorization":"Bearer t1","x-user":{"jti":"t1","sub":"john"}}}}}}`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, obj, map[string]interface{}{"jti": "t1", "sub": "john"})
+
+	// from the cache
+	obj, err = call(`{"context":{"request":{"http":{"headers":{"authorization":"Bearer t1"}}}}}`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, obj, map[string]interface{}{"jti": "t1", "sub": "john"})
+
+	// revoked after cached
+	revoked = `["t1"]`
+	assert.NilError(t, revocationList.Refresh(context.TODO()))
+
+	obj, err = call(`{"context":{"request":{"http":{"headers":{"authorization":"Bearer t1"}}}}}`)
+	assert.Check(t, obj == nil)
+	assert.Error(t, err, "token revoked")
+
+	// revoked before cached
+	obj, err = call(`{"context":{"request":{"http":{"headers":{"authorization":"Bearer t1","x-user":{"jti":"t1","sub":"john"}}}}}}`)
+	assert.Check(t, obj == nil)
+	assert.Error(t, err, "token revoked")
+
+	// not revoked
+	obj, err = call(`{"context":{"request":{"http":{"headers":{"authorization":"Bearer t2","x-user":{"jti":"t2","sub":"jane"}}}}}}`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, obj, map[string]interface{}{"jti": "t2", "sub": "jane"})
+}
@@ -0,0 +1,82 @@
+package evaluators
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kuadrant/authorino/pkg/clock"
+)
+
+// DefaultMaintenanceBypassMaxTTL is the maximum lifetime of a maintenance header when none is configured
+const DefaultMaintenanceBypassMaxTTL = time.Hour
+
+// MaintenanceBypass lets requests that carry a valid signed maintenance header skip the auth pipeline (break-glass).
+//
+// The value of the header is "<expires-at>.<signature>", where <expires-at> is a Unix timestamp (in seconds) and
+// <signature> is the hex-encoded HMAC-SHA256 of "<host>.<expires-at>" with the shared secret, <host> being the host of
+// the request, in lowercase and without the port number.
+// Headers that expire later than MaxTTL from now are rejected, so a leaked header cannot be used indefinitely.
+type MaintenanceBypass struct {
+	Header string
+	Secret []byte
+	MaxTTL time.Duration
+	Clock  clock.Clock
+}
+
+// Now returns the current time of the clock of the maintenance bypass
+func (b *MaintenanceBypass) Now() time.Time {
+	return clock.Now(b.Clock)
+}
+
+// Verify tells whether the request carries a valid maintenance header.
+// It returns false and no error if the header is absent, and false and the reason if the header is invalid.
+func (b *MaintenanceBypass) Verify(host string, headers map[string]string, now time.Time) (bool, error) {
+	value, ok := headers[strings.ToLower(b.Header)]
+	if !ok || value == "" {
+		return false, nil
+	}
+
+	expiresAt, signature, found := strings.Cut(value, ".")
+	if !found {
+		return false, fmt.Errorf("malformed maintenance header")
+	}
+
+	exp, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("malformed maintenance header")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(b.Sign(host, exp))) {
+		return false, fmt.Errorf("invalid maintenance header signature")
+	}
+
+	if now.Unix() > exp {
+		return false, fmt.Errorf("expired maintenance header")
+	}
+
+	maxTTL := b.MaxTTL
+	if maxTTL <= 0 {
+		maxTTL = DefaultMaintenanceBypassMaxTTL
+	}
+	if exp-now.Unix() > int64(maxTTL/time.Second) {
+		return false, fmt.Errorf("maintenance header expires too far in the future")
+	}
+
+	return true, nil
+}
+
+// Sign returns the signature of a maintenance header for the host, valid until expiresAt (Unix timestamp, in seconds)
+func (b *MaintenanceBypass) Sign(host string, expiresAt int64) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	mac := hmac.New(sha256.New, b.Secret)
+	mac.Write([]byte(fmt.Sprintf("%s.%d", strings.ToLower(host), expiresAt)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
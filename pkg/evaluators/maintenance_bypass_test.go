@@ -0,0 +1,67 @@
+package evaluators
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestMaintenanceBypassVerify(t *testing.T) {
+	bypass := &MaintenanceBypass{Header: "X-Maintenance", Secret: []byte("s3cr3t")}
+	now := time.Unix(1700000000, 0)
+	exp := now.Add(time.Hour).Unix()
+	validValue := fmt.Sprintf("%d.%s", exp, bypass.Sign("talker-api", exp))
+
+	// valid
+	ok, err := bypass.Verify("talker-api", map[string]string{"x-maintenance": validValue}, now)
+	assert.NilError(t, err)
+	assert.Check(t, ok)
+
+	// valid regardless of the port number and case of the host
+	ok, err = bypass.Verify("Talker-API:8000", map[string]string{"x-maintenance": validValue}, now)
+	assert.NilError(t, err)
+	assert.Check(t, ok)
+
+	// absent
+	ok, err = bypass.Verify("talker-api", map[string]string{}, now)
+	assert.NilError(t, err)
+	assert.Check(t, !ok)
+
+	// invalid signature
+	ok, err = bypass.Verify("talker-api", map[string]string{"x-maintenance": fmt.Sprintf("%d.%s", exp, (&MaintenanceBypass{Secret: []byte("other")}).Sign("talker-api", exp))}, now)
+	assert.Error(t, err, "invalid maintenance header signature")
+	assert.Check(t, !ok)
+
+	// signed for another host
+	ok, err = bypass.Verify("other-api", map[string]string{"x-maintenance": validValue}, now)
+	assert.Error(t, err, "invalid maintenance header signature")
+	assert.Check(t, !ok)
+
+	// tampered expiration
+	ok, err = bypass.Verify("talker-api", map[string]string{"x-maintenance": fmt.Sprintf("%d.%s", exp+3600, bypass.Sign("talker-api", exp))}, now)
+	assert.Error(t, err, "invalid maintenance header signature")
+	assert.Check(t, !ok)
+
+	// expired
+	ok, err = bypass.Verify("talker-api", map[string]string{"x-maintenance": validValue}, now.Add(2*time.Hour))
+	assert.Error(t, err, "expired maintenance header")
+	assert.Check(t, !ok)
+
+	// lifetime longer than the maximum
+	farExp := now.Add(2 * time.Hour).Unix()
+	ok, err = bypass.Verify("talker-api", map[string]string{"x-maintenance": fmt.Sprintf("%d.%s", farExp, bypass.Sign("talker-api", farExp))}, now)
+	assert.Error(t, err, "maintenance header expires too far in the future")
+	assert.Check(t, !ok)
+
+	longerBypass := &MaintenanceBypass{Header: "X-Maintenance", Secret: []byte("s3cr3t"), MaxTTL: 3 * time.Hour}
+	ok, err = longerBypass.Verify("talker-api", map[string]string{"x-maintenance": fmt.Sprintf("%d.%s", farExp, bypass.Sign("talker-api", farExp))}, now)
+	assert.NilError(t, err)
+	assert.Check(t, ok)
+
+	// malformed
+	ok, err = bypass.Verify("talker-api", map[string]string{"x-maintenance": "not-signed"}, now)
+	assert.Error(t, err, "malformed maintenance header")
+	assert.Check(t, !ok)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/kuadrant/authorino/pkg/auth"
+	authorinoContext "github.com/kuadrant/authorino/pkg/context"
 	"github.com/kuadrant/authorino/pkg/evaluators/metadata"
 	"github.com/kuadrant/authorino/pkg/jsonexp"
 	"github.com/kuadrant/authorino/pkg/log"
@@ -51,9 +52,11 @@ func (config *MetadataConfig) Call(pipeline auth.AuthPipeline, ctx context.Conte
 
 		cache := config.Cache
 		var cacheKey interface{}
+		var authJSON string
 
 		if cache != nil {
-			cacheKey = cache.ResolveKeyFor(pipeline.GetAuthorizationJSON())
+			authJSON = pipeline.GetAuthorizationJSON()
+			cacheKey = cache.ResolveKeyFor(authJSON)
 			if cachedObj, err := cache.Get(cacheKey); err != nil {
 				logger.V(1).Error(err, "failed to retrieve data from the cache")
 			} else if cachedObj != nil {
@@ -63,7 +66,7 @@ func (config *MetadataConfig) Call(pipeline auth.AuthPipeline, ctx context.Conte
 
 		obj, err := evaluator.Call(pipeline, log.IntoContext(ctx, logger))
 
-		if err == nil && cacheKey != nil {
+		if err == nil && cacheKey != nil && !authorinoContext.IsDryRun(ctx) && cache.ShouldCache(authJSON, obj) {
 			if err := cache.Set(cacheKey, obj); err != nil {
 				logger.V(1).Info("unable to store data in the cache", "err", err)
 			}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/context"
@@ -16,8 +17,10 @@ import (
 	"github.com/kuadrant/authorino/pkg/log"
 	"github.com/kuadrant/authorino/pkg/oauth2"
 
+	"github.com/tidwall/gjson"
 	"go.opentelemetry.io/otel"
 	otel_propagation "go.opentelemetry.io/otel/propagation"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 type GenericHttp struct {
@@ -25,12 +28,41 @@ type GenericHttp struct {
 	Method                string
 	Body                  *json.JSONValue
 	Parameters            []json.JSONProperty
+	OmitEmptyParameters   bool
 	Headers               []json.JSONProperty
+	RepeatedHeaders       []string
+	QueryParameters       []json.JSONProperty
+	QueryArraySeparator   string
 	ContentType           string
 	SharedSecret          string
 	OAuth2                *oauth2.ClientCredentials
 	OAuth2TokenForceFetch bool
+	// Retries is the number of times to retry the request after a failure to connect or a 5xx response
+	Retries int
+	// RetryNonIdempotent retries the requests of non-idempotent methods (e.g. POST) as well
+	RetryNonIdempotent bool
 	auth.AuthCredentials
+
+	// SharedSecretRef and SharedSecretKey locate the shared secret in a Kubernetes Secret, so it can be reloaded when the
+	// Secret changes; nil if the shared secret is not reloaded
+	SharedSecretRef *types.NamespacedName
+	SharedSecretKey string
+
+	sharedSecretMutex sync.RWMutex
+}
+
+// SetSharedSecret replaces the shared secret sent in the subsequent requests to the HTTP service.
+// It is safe to call while the evaluator is in use.
+func (h *GenericHttp) SetSharedSecret(sharedSecret string) {
+	h.sharedSecretMutex.Lock()
+	defer h.sharedSecretMutex.Unlock()
+	h.SharedSecret = sharedSecret
+}
+
+func (h *GenericHttp) getSharedSecret() string {
+	h.sharedSecretMutex.RLock()
+	defer h.sharedSecretMutex.RUnlock()
+	return h.SharedSecret
 }
 
 func (h *GenericHttp) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (interface{}, error) {
@@ -39,19 +71,19 @@ func (h *GenericHttp) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (i
 	}
 
 	authJSON := pipeline.GetAuthorizationJSON()
-	endpoint := json.ReplaceJSONPlaceholders(h.Endpoint, authJSON)
-
-	req, err := h.buildRequest(ctx, endpoint, authJSON)
+	endpoint, err := h.buildEndpoint(authJSON)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	req, resp, err := h.send(ctx, endpoint, authJSON)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	log.FromContext(ctx).WithName("http").V(1).Info("received response", "method", req.Method, "url", endpoint, "status", resp.StatusCode)
+
 	// parse the response as json
 	if strings.Contains(strings.Join(resp.Header["Content-Type"], ";"), "application/json") {
 		decoder := gojson.NewDecoder(resp.Body)
@@ -87,6 +119,85 @@ func (h *GenericHttp) Call(pipeline auth.AuthPipeline, ctx gocontext.Context) (i
 	return string(str), nil
 }
 
+// idempotentMethods are the HTTP methods whose requests can be retried safely (RFC 9110, section 9.2.2)
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// send sends the request to the HTTP service, retrying it after failures to connect and 5xx responses, if the method
+// is idempotent or the retry of non-idempotent methods is enabled
+func (h *GenericHttp) send(ctx gocontext.Context, endpoint, authJSON string) (*http.Request, *http.Response, error) {
+	attempts := 1
+	if idempotentMethods[h.Method] || h.RetryNonIdempotent {
+		attempts += h.Retries
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := h.buildRequest(ctx, endpoint, authJSON)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if (err == nil && resp.StatusCode < http.StatusInternalServerError) || attempt >= attempts || context.CheckContext(ctx) != nil {
+			return req, resp, err
+		}
+
+		reason := err
+		if resp != nil {
+			reason = fmt.Errorf("%s", resp.Status)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		log.FromContext(ctx).WithName("http").V(1).Info("retrying request", "method", req.Method, "url", endpoint, "attempt", attempt, "reason", reason)
+	}
+}
+
+// buildEndpoint resolves the placeholders of the endpoint and appends the query string parameters.
+// Parameters whose values resolve to an array are set once per item, or joined with the separator, if any.
+func (h *GenericHttp) buildEndpoint(authJSON string) (string, error) {
+	endpoint := json.ReplaceJSONPlaceholders(h.Endpoint, authJSON)
+	if len(h.QueryParameters) == 0 {
+		return endpoint, nil
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := endpointURL.Query()
+	for _, param := range h.QueryParameters {
+		encoded, err := gojson.Marshal(param.Value.ResolveFor(authJSON))
+		if err != nil {
+			return "", fmt.Errorf("failed to encode query string parameter %s", param.Name)
+		}
+		value := gjson.ParseBytes(encoded)
+		items := []gjson.Result{value}
+		if value.IsArray() {
+			items = value.Array()
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, item.String())
+		}
+		if value.IsArray() && h.QueryArraySeparator != "" && len(values) > 0 {
+			values = []string{strings.Join(values, h.QueryArraySeparator)}
+		}
+		for _, v := range values {
+			query.Add(param.Name, v)
+		}
+	}
+	endpointURL.RawQuery = query.Encode()
+
+	return endpointURL.String(), nil
+}
+
 func (h *GenericHttp) buildRequest(ctx gocontext.Context, endpoint, authJSON string) (*http.Request, error) {
 	var requestBody io.Reader
 	var contentType string
@@ -109,8 +220,9 @@ func (h *GenericHttp) buildRequest(ctx gocontext.Context, endpoint, authJSON str
 
 	var req *http.Request
 	var err error
+	var creds string
 	if h.AuthCredentials != nil {
-		creds := h.SharedSecret
+		creds = h.getSharedSecret()
 		if h.OAuth2 != nil {
 			token, err := h.OAuth2.ClientCredentialsToken(ctx, h.OAuth2TokenForceFetch)
 			if err != nil {
@@ -126,28 +238,72 @@ func (h *GenericHttp) buildRequest(ctx gocontext.Context, endpoint, authJSON str
 		return nil, err
 	}
 
+	// default content type, unless explicitly set in the custom headers
+	req.Header.Set("Content-Type", contentType)
+
 	for _, header := range h.Headers {
-		req.Header.Set(header.Name, fmt.Sprintf("%s", header.Value.ResolveFor(authJSON)))
+		value := header.Value.ResolveFor(authJSON)
+		if !h.isRepeatedHeader(header.Name) {
+			req.Header.Set(header.Name, fmt.Sprintf("%s", value))
+			continue
+		}
+		// repeated headers are sent as one header line per value
+		if values, ok := value.([]interface{}); ok {
+			for _, v := range values {
+				req.Header.Add(header.Name, fmt.Sprintf("%s", v))
+			}
+		} else {
+			req.Header.Add(header.Name, fmt.Sprintf("%s", value))
+		}
 	}
 
-	req.Header.Set("Content-Type", contentType)
 	otel.GetTextMapPropagator().Inject(ctx, otel_propagation.HeaderCarrier(req.Header))
 
+	if instanceIdentity := OutboundRequestsInstanceIdentity; instanceIdentity != nil {
+		if err := instanceIdentity.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+
 	if logger := log.FromContext(ctx).WithName("http").V(1); logger.Enabled() {
-		logData := []interface{}{
-			"method", method,
-			"url", endpoint,
-			"headers", req.Header,
-		}
-		if requestBody != nil {
-			if b, ok := requestBody.(*bytes.Buffer); ok {
-				logData = append(logData, "body", b.String())
+		logger.Info("sending request", "method", method, "url", endpoint, "headers", redactHeaders(req.Header, creds), "bodySize", req.ContentLength)
+	}
+
+	return req, nil
+}
+
+// redactedValue replaces the values of sensitive headers in the logs
+const redactedValue = "[REDACTED]"
+
+// sensitiveHeaders are the headers whose values are never logged, regardless of carrying the credentials of the request or not
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// redactHeaders returns a copy of the headers suitable for logging, with the values of the sensitive headers and any
+// occurrence of the credential (shared secret or OAuth2 token) redacted
+func redactHeaders(headers http.Header, credential string) http.Header {
+	redacted := headers.Clone()
+	if credential != "" {
+		for _, values := range redacted {
+			for i := range values {
+				values[i] = strings.ReplaceAll(values[i], credential, redactedValue)
 			}
 		}
-		logger.Info("sending request", logData...)
 	}
+	for _, name := range sensitiveHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, redactedValue)
+		}
+	}
+	return redacted
+}
 
-	return req, nil
+func (h *GenericHttp) isRepeatedHeader(name string) bool {
+	for _, repeated := range h.RepeatedHeaders {
+		if strings.EqualFold(repeated, name) {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *GenericHttp) buildRequestBody(authData string) (io.Reader, error) {
@@ -161,7 +317,11 @@ func (h *GenericHttp) buildRequestBody(authData string) (io.Reader, error) {
 
 	data := make(map[string]interface{})
 	for _, param := range h.Parameters {
-		data[param.Name] = param.Value.ResolveFor(authData)
+		value := param.Value.ResolveFor(authData)
+		if h.OmitEmptyParameters && isEmptyValue(value) {
+			continue
+		}
+		data[param.Name] = value
 	}
 
 	switch h.ContentType {
@@ -187,3 +347,18 @@ func (h *GenericHttp) buildRequestBody(authData string) (io.Reader, error) {
 		return nil, fmt.Errorf("unsupported content-type")
 	}
 }
+
+// isEmptyValue tells whether a resolved value is null, an empty string, an empty array or an empty object, once
+// encoded as JSON (static values are raw JSON)
+func isEmptyValue(value interface{}) bool {
+	encoded, err := gojson.Marshal(value)
+	if err != nil {
+		return false
+	}
+	switch strings.TrimSpace(string(encoded)) {
+	case "null", `""`, "[]", "{}":
+		return true
+	default:
+		return false
+	}
+}
@@ -5,17 +5,25 @@ import (
 	"context"
 	gojson "encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/kuadrant/authorino/pkg/auth"
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
 	"github.com/kuadrant/authorino/pkg/httptest"
 	"github.com/kuadrant/authorino/pkg/json"
+	"github.com/kuadrant/authorino/pkg/log"
 	"github.com/kuadrant/authorino/pkg/oauth2"
 
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/go-logr/logr/funcr"
 	"github.com/golang/mock/gomock"
 	"gotest.tools/assert"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 )
 
 const (
@@ -227,7 +235,7 @@ func TestGenericHttpCallWithCustomHeaders(t *testing.T) {
 		Method:   "GET",
 		Headers: []json.JSONProperty{
 			{Name: "X-Requested-By", Value: json.JSONValue{Static: "authorino"}},
-			{Name: "Content-Type", Value: json.JSONValue{Static: "to-be-overwritten"}},
+			{Name: "Content-Type", Value: json.JSONValue{Static: "application/vnd.api+json"}},
 		},
 		AuthCredentials: sharedCredsMock,
 	}
@@ -236,7 +244,193 @@ func TestGenericHttpCallWithCustomHeaders(t *testing.T) {
 
 	assert.NilError(t, err)
 	assert.Equal(t, httpRequestMock.Header.Get("X-Requested-By"), "authorino")
-	assert.Equal(t, httpRequestMock.Header.Get("Content-Type"), "text/plain")
+	assert.Equal(t, httpRequestMock.Header.Get("Content-Type"), "application/vnd.api+json")
+}
+
+func TestGenericHttpCallWithPOSTDefaultContentType(t *testing.T) {
+	extHttpMetadataServer := httptest.NewHttpServerMock(testHttpMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": httptest.NewHttpServerMockResponseFuncJSON(`{"foo":"bar"}`),
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint := "http://" + testHttpMetadataServerHost + "/metadata"
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(genericHttpAuthDataMock())
+
+	sharedCredsMock := mock_auth.NewMockAuthCredentials(ctrl)
+	requestBody := bytes.NewBuffer([]byte(`{"user":"mock"}`))
+	httpRequestMock, _ := http.NewRequest("POST", endpoint, requestBody)
+	sharedCredsMock.EXPECT().BuildRequestWithCredentials(ctx, endpoint, "POST", "", requestBody).Return(httpRequestMock, nil)
+
+	metadata := &GenericHttp{
+		Endpoint:        endpoint,
+		Method:          "POST",
+		Parameters:      []json.JSONProperty{{Name: "user", Value: json.JSONValue{Pattern: "auth.identity.user"}}},
+		ContentType:     "application/json",
+		Headers:         []json.JSONProperty{{Name: "X-Requested-By", Value: json.JSONValue{Static: "authorino"}}},
+		AuthCredentials: sharedCredsMock,
+	}
+
+	_, err := metadata.Call(pipelineMock, ctx)
+
+	assert.NilError(t, err)
+	assert.Equal(t, httpRequestMock.Header.Get("Content-Type"), "application/json")
+}
+
+func TestGenericHttpCallWithPOSTExplicitContentTypeHeader(t *testing.T) {
+	extHttpMetadataServer := httptest.NewHttpServerMock(testHttpMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": httptest.NewHttpServerMockResponseFuncJSON(`{"foo":"bar"}`),
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint := "http://" + testHttpMetadataServerHost + "/metadata"
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(genericHttpAuthDataMock())
+
+	sharedCredsMock := mock_auth.NewMockAuthCredentials(ctrl)
+	requestBody := bytes.NewBuffer([]byte(`{"user":"mock"}`))
+	httpRequestMock, _ := http.NewRequest("POST", endpoint, requestBody)
+	sharedCredsMock.EXPECT().BuildRequestWithCredentials(ctx, endpoint, "POST", "", requestBody).Return(httpRequestMock, nil)
+
+	metadata := &GenericHttp{
+		Endpoint:        endpoint,
+		Method:          "POST",
+		Parameters:      []json.JSONProperty{{Name: "user", Value: json.JSONValue{Pattern: "auth.identity.user"}}},
+		ContentType:     "application/json",
+		Headers:         []json.JSONProperty{{Name: "Content-Type", Value: json.JSONValue{Static: "application/json; charset=utf-8"}}},
+		AuthCredentials: sharedCredsMock,
+	}
+
+	_, err := metadata.Call(pipelineMock, ctx)
+
+	assert.NilError(t, err)
+	assert.Equal(t, httpRequestMock.Header.Get("Content-Type"), "application/json; charset=utf-8")
+}
+
+func TestGenericHttpCallWithRepeatedHeaders(t *testing.T) {
+	extHttpMetadataServer := httptest.NewHttpServerMock(testHttpMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": httptest.NewHttpServerMockResponseFuncJSON(`{"foo":"bar"}`),
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint := "http://" + testHttpMetadataServerHost + "/metadata"
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"identity":{"groups":["admin","dev"]}}}`)
+
+	sharedCredsMock := mock_auth.NewMockAuthCredentials(ctrl)
+	httpRequestMock, _ := http.NewRequest("GET", endpoint, nil)
+	sharedCredsMock.EXPECT().BuildRequestWithCredentials(ctx, endpoint, "GET", "", nil).Return(httpRequestMock, nil)
+
+	metadata := &GenericHttp{
+		Endpoint: endpoint,
+		Method:   "GET",
+		Headers: []json.JSONProperty{
+			{Name: "X-Group", Value: json.JSONValue{Pattern: "auth.identity.groups"}},
+			{Name: "X-Requested-By", Value: json.JSONValue{Static: "authorino"}},
+		},
+		RepeatedHeaders: []string{"x-group", "x-requested-by"},
+		AuthCredentials: sharedCredsMock,
+	}
+
+	_, err := metadata.Call(pipelineMock, ctx)
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, httpRequestMock.Header.Values("X-Group"), []string{"admin", "dev"})
+	assert.DeepEqual(t, httpRequestMock.Header.Values("X-Requested-By"), []string{"authorino"})
+}
+
+func TestGenericHttpCallWithoutRepeatedHeaders(t *testing.T) {
+	extHttpMetadataServer := httptest.NewHttpServerMock(testHttpMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": httptest.NewHttpServerMockResponseFuncJSON(`{"foo":"bar"}`),
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint := "http://" + testHttpMetadataServerHost + "/metadata"
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"identity":{"groups":["admin","dev"]}}}`)
+
+	sharedCredsMock := mock_auth.NewMockAuthCredentials(ctrl)
+	httpRequestMock, _ := http.NewRequest("GET", endpoint, nil)
+	sharedCredsMock.EXPECT().BuildRequestWithCredentials(ctx, endpoint, "GET", "", nil).Return(httpRequestMock, nil)
+
+	metadata := &GenericHttp{
+		Endpoint: endpoint,
+		Method:   "GET",
+		Headers: []json.JSONProperty{
+			{Name: "X-Group", Value: json.JSONValue{Pattern: "auth.identity.groups"}},
+		},
+		AuthCredentials: sharedCredsMock,
+	}
+
+	_, err := metadata.Call(pipelineMock, ctx)
+
+	assert.NilError(t, err)
+	assert.Equal(t, len(httpRequestMock.Header.Values("X-Group")), 1)
+}
+
+func TestGenericHttpRetries(t *testing.T) {
+	var requests int32
+	extHttpMetadataServer := httptest.NewHttpServerMock(testHttpMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": func() httptest.HttpServerMockResponse {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				return httptest.HttpServerMockResponse{Status: http.StatusServiceUnavailable}
+			}
+			return httptest.HttpServerMockResponse{Status: http.StatusOK, Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"foo":"bar"}`}
+		},
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	call := func(metadata *GenericHttp) (interface{}, error) {
+		atomic.StoreInt32(&requests, 0)
+		pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+		pipelineMock.EXPECT().GetAuthorizationJSON().Return(genericHttpAuthDataMock())
+		return metadata.Call(pipelineMock, context.TODO())
+	}
+
+	endpoint := "http://" + testHttpMetadataServerHost + "/metadata"
+
+	// idempotent method, retried
+	obj, err := call(&GenericHttp{Endpoint: endpoint, Method: "GET", Retries: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(2))
+	assert.Equal(t, obj.(map[string]interface{})["foo"], "bar")
+
+	// non-idempotent method, not retried by default
+	_, _ = call(&GenericHttp{Endpoint: endpoint, Method: "POST", ContentType: "application/json", Retries: 2})
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(1))
+
+	// non-idempotent method, retried when opted in
+	obj, err = call(&GenericHttp{Endpoint: endpoint, Method: "POST", ContentType: "application/json", Retries: 2, RetryNonIdempotent: true})
+	assert.NilError(t, err)
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(2))
+	assert.Equal(t, obj.(map[string]interface{})["foo"], "bar")
+
+	// no retries
+	_, _ = call(&GenericHttp{Endpoint: endpoint, Method: "GET"})
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(1))
 }
 
 func TestGenericHttpWithInvalidJSONResponse(t *testing.T) {
@@ -429,6 +623,194 @@ func TestWithOAuth2AuthenticationWithoutTokenCache(t *testing.T) {
 	assert.Equal(t, objJSON["foo"], "bar")
 }
 
+func TestGenericHttpSetSharedSecret(t *testing.T) {
+	extHttpMetadataServer := httptest.NewHttpServerMock(testHttpMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": httptest.NewHttpServerMockResponseFuncJSON(`{"foo":"bar"}`),
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	endpoint := "http://" + testHttpMetadataServerHost + "/metadata"
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(genericHttpAuthDataMock()).AnyTimes()
+
+	sharedCredsMock := mock_auth.NewMockAuthCredentials(ctrl)
+	newRequest := func(_ context.Context, endpoint, method, _ string, _ io.Reader) (*http.Request, error) {
+		return http.NewRequest(method, endpoint, nil)
+	}
+
+	metadata := &GenericHttp{
+		Endpoint:        endpoint,
+		Method:          "GET",
+		SharedSecret:    "secret",
+		AuthCredentials: sharedCredsMock,
+	}
+
+	sharedCredsMock.EXPECT().BuildRequestWithCredentials(ctx, endpoint, "GET", "secret", nil).DoAndReturn(newRequest)
+	_, err := metadata.Call(pipelineMock, ctx)
+	assert.NilError(t, err)
+
+	metadata.SetSharedSecret("rotated")
+
+	sharedCredsMock.EXPECT().BuildRequestWithCredentials(ctx, endpoint, "GET", "rotated", nil).DoAndReturn(newRequest)
+	_, err = metadata.Call(pipelineMock, ctx)
+	assert.NilError(t, err)
+
+	// rotated while in use
+	sharedCredsMock.EXPECT().BuildRequestWithCredentials(ctx, endpoint, "GET", gomock.Any(), nil).DoAndReturn(newRequest).Times(10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := metadata.Call(pipelineMock, ctx)
+			assert.Check(t, err == nil)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			metadata.SetSharedSecret(fmt.Sprintf("rotated-%d", i))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestGenericHttpQueryParameters(t *testing.T) {
+	authJSON := `{"auth":{"identity":{"sub":"john","scopes":["read","write"],"groups":[]}}}`
+	queryParameters := []json.JSONProperty{
+		{Name: "user", Value: json.JSONValue{Pattern: "auth.identity.sub"}},
+		{Name: "scope", Value: json.JSONValue{Pattern: "auth.identity.scopes"}},
+		{Name: "group", Value: json.JSONValue{Pattern: "auth.identity.groups"}},
+		{Name: "aud", Value: json.JSONValue{Static: k8sruntime.RawExtension{Raw: []byte(`["a","b"]`)}}},
+	}
+
+	// repeated query string parameters
+	metadata := &GenericHttp{
+		Endpoint:        "http://" + testHttpMetadataServerHost + "/metadata?source={auth.identity.sub}",
+		QueryParameters: queryParameters,
+	}
+	endpoint, err := metadata.buildEndpoint(authJSON)
+	assert.NilError(t, err)
+	assert.Equal(t, endpoint, "http://"+testHttpMetadataServerHost+"/metadata?aud=a&aud=b&scope=read&scope=write&source=john&user=john")
+
+	// joined with a separator
+	metadata.QueryArraySeparator = ","
+	endpoint, err = metadata.buildEndpoint(authJSON)
+	assert.NilError(t, err)
+	assert.Equal(t, endpoint, "http://"+testHttpMetadataServerHost+"/metadata?aud=a%2Cb&scope=read%2Cwrite&source=john&user=john")
+
+	// no query string parameters
+	metadata.QueryParameters = nil
+	endpoint, err = metadata.buildEndpoint(authJSON)
+	assert.NilError(t, err)
+	assert.Equal(t, endpoint, "http://"+testHttpMetadataServerHost+"/metadata?source=john")
+}
+
+func TestGenericHttpOmitEmptyParameters(t *testing.T) {
+	params := []json.JSONProperty{
+		{Name: "user", Value: json.JSONValue{Pattern: "auth.identity.user"}},
+		{Name: "group", Value: json.JSONValue{Pattern: "auth.identity.group"}}, // missing
+		{Name: "tenant", Value: json.JSONValue{Static: k8sruntime.RawExtension{Raw: []byte(`""`)}}},
+		{Name: "roles", Value: json.JSONValue{Static: []interface{}{}}},
+	}
+
+	buildBody := func(omitEmpty bool, contentType string) string {
+		metadata := &GenericHttp{
+			Method:              "POST",
+			Parameters:          params,
+			OmitEmptyParameters: omitEmpty,
+			ContentType:         contentType,
+		}
+		body, err := metadata.buildRequestBody(genericHttpAuthDataMock())
+		assert.NilError(t, err)
+		buf := new(bytes.Buffer)
+		_, _ = buf.ReadFrom(body)
+		return buf.String()
+	}
+
+	var data map[string]interface{}
+	assert.NilError(t, gojson.Unmarshal([]byte(buildBody(true, "application/json")), &data))
+	assert.DeepEqual(t, data, map[string]interface{}{"user": "mock"})
+
+	assert.Equal(t, buildBody(true, "application/x-www-form-urlencoded"), "user=mock")
+
+	data = nil
+	assert.NilError(t, gojson.Unmarshal([]byte(buildBody(false, "application/json")), &data))
+	assert.Equal(t, len(data), 4)
+}
+
+func TestIsEmptyValue(t *testing.T) {
+	assert.Check(t, isEmptyValue(nil))
+	assert.Check(t, isEmptyValue(""))
+	assert.Check(t, isEmptyValue([]interface{}{}))
+	assert.Check(t, isEmptyValue(map[string]interface{}{}))
+	assert.Check(t, isEmptyValue(k8sruntime.RawExtension{}))
+	assert.Check(t, isEmptyValue(k8sruntime.RawExtension{Raw: []byte(`""`)}))
+	assert.Check(t, !isEmptyValue("mock"))
+	assert.Check(t, !isEmptyValue(0))
+	assert.Check(t, !isEmptyValue(false))
+	assert.Check(t, !isEmptyValue(k8sruntime.RawExtension{Raw: []byte(`"mock"`)}))
+}
+
+func TestGenericHttpDebugLog(t *testing.T) {
+	extHttpMetadataServer := httptest.NewHttpServerMock(testHttpMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": httptest.NewHttpServerMockResponseFuncJSON(`{"foo":"bar"}`),
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(genericHttpAuthDataMock()).AnyTimes()
+
+	endpoint := "http://" + testHttpMetadataServerHost + "/metadata"
+	call := func(metadata *GenericHttp, verbosity int) string {
+		var logs []string
+		logger := funcr.New(func(prefix, args string) { logs = append(logs, prefix+" "+args) }, funcr.Options{Verbosity: verbosity})
+		_, err := metadata.Call(pipelineMock, log.IntoContext(context.TODO(), logger))
+		assert.NilError(t, err)
+		return strings.Join(logs, "\n")
+	}
+
+	// shared secret in the authorization header
+	logs := call(&GenericHttp{
+		Endpoint:        endpoint,
+		Method:          "POST",
+		ContentType:     "application/json",
+		Parameters:      []json.JSONProperty{{Name: "user", Value: json.JSONValue{Pattern: "auth.identity.user"}}},
+		SharedSecret:    "super-secret",
+		AuthCredentials: auth.NewAuthCredential("", ""),
+	}, 1)
+	assert.Check(t, strings.Contains(logs, `"msg"="sending request" "method"="POST" "url"="`+endpoint+`"`))
+	assert.Check(t, strings.Contains(logs, `"Authorization"=["[REDACTED]"]`))
+	assert.Check(t, strings.Contains(logs, `"bodySize"=15`))
+	assert.Check(t, strings.Contains(logs, `"msg"="received response" "method"="POST" "url"="`+endpoint+`" "status"=200`))
+	assert.Check(t, !strings.Contains(logs, "super-secret"))
+
+	// shared secret in a custom header
+	logs = call(&GenericHttp{
+		Endpoint:        endpoint,
+		Method:          "GET",
+		SharedSecret:    "super-secret",
+		AuthCredentials: auth.NewAuthCredential("X-API-Key", "custom_header"),
+	}, 1)
+	assert.Check(t, strings.Contains(logs, `"X-Api-Key"=["[REDACTED]"]`))
+	assert.Check(t, !strings.Contains(logs, "super-secret"))
+
+	// not logged unless at debug level
+	logs = call(&GenericHttp{
+		Endpoint:        endpoint,
+		Method:          "GET",
+		SharedSecret:    "super-secret",
+		AuthCredentials: auth.NewAuthCredential("", ""),
+	}, 0)
+	assert.Equal(t, logs, "")
+}
+
 func genericHttpAuthDataMock() string {
 	type mockIdentityObject struct {
 		User string `json:"user"`
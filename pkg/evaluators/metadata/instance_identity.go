@@ -0,0 +1,94 @@
+package metadata
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/kuadrant/authorino/pkg/clock"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	DefaultInstanceIdentityHeader        = "X-Authorino-Identity"
+	DefaultInstanceIdentityTokenDuration = int64(60)
+)
+
+// OutboundRequestsInstanceIdentity is the credential of the Authorino instance used to sign the outbound HTTP requests
+// to metadata and callback endpoints. Nil disables signing.
+var OutboundRequestsInstanceIdentity *InstanceIdentity
+
+func NewInstanceIdentity(issuer, header string, tokenDuration int64, signingKey jose.JSONWebKey) (*InstanceIdentity, error) {
+	if signingKey.Key == nil {
+		return nil, fmt.Errorf("missing signing key")
+	}
+
+	if signingKey.KeyID == "" {
+		publicKey := signingKey.Public()
+		thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		signingKey.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	}
+
+	if header == "" {
+		header = DefaultInstanceIdentityHeader
+	}
+
+	if tokenDuration <= 0 {
+		tokenDuration = DefaultInstanceIdentityTokenDuration
+	}
+
+	return &InstanceIdentity{
+		Issuer:        issuer,
+		Header:        header,
+		TokenDuration: tokenDuration,
+		SigningKey:    signingKey,
+	}, nil
+}
+
+// InstanceIdentity signs outbound HTTP requests with short-lived JWTs that identify the Authorino instance, so the
+// receiving services can verify the provenance of the requests with the public key.
+// Besides the standard claims, the tokens are bound to the HTTP method (`htm`) and URL (`htu`) of the request.
+type InstanceIdentity struct {
+	Issuer        string
+	Header        string
+	TokenDuration int64
+	SigningKey    jose.JSONWebKey
+	// Clock tells the time to set the timestamps of the tokens; defaults to the wall clock
+	Clock clock.Clock
+}
+
+type instanceIdentityClaims map[string]interface{}
+
+func (c instanceIdentityClaims) Valid() error {
+	return nil
+}
+
+func (i *InstanceIdentity) Sign(req *http.Request) error {
+	iat := clock.Now(i.Clock).Unix()
+
+	claims := instanceIdentityClaims{
+		"iss": i.Issuer,
+		"aud": fmt.Sprintf("%s://%s", req.URL.Scheme, req.URL.Host),
+		"iat": iat,
+		"exp": iat + i.TokenDuration,
+		"htm": req.Method,
+		"htu": fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, req.URL.Path),
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(i.SigningKey.Algorithm), claims)
+	token.Header["kid"] = i.SigningKey.KeyID
+
+	signed, err := token.SignedString(i.SigningKey.Key)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(i.Header, signed)
+	return nil
+}
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	gohttptest "net/http/httptest"
+	"testing"
+
+	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt"
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func TestNewInstanceIdentity(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	instanceIdentity, err := NewInstanceIdentity("authorino", "", 0, jose.JSONWebKey{Key: key, Algorithm: "ES256"})
+	assert.NilError(t, err)
+	assert.Equal(t, instanceIdentity.Header, DefaultInstanceIdentityHeader)
+	assert.Equal(t, instanceIdentity.TokenDuration, DefaultInstanceIdentityTokenDuration)
+	assert.Check(t, instanceIdentity.SigningKey.KeyID != "")
+
+	_, err = NewInstanceIdentity("authorino", "", 0, jose.JSONWebKey{Algorithm: "ES256"})
+	assert.Error(t, err, "missing signing key")
+}
+
+func TestInstanceIdentitySign(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	instanceIdentity, _ := NewInstanceIdentity("authorino", "X-Signature", 60, jose.JSONWebKey{Key: key, Algorithm: "ES256", KeyID: "my-key"})
+
+	req, _ := http.NewRequest("POST", "http://metadata.io:8080/resource?id=123", nil)
+	assert.NilError(t, instanceIdentity.Sign(req))
+
+	token, err := jwt.Parse(req.Header.Get("X-Signature"), func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	assert.NilError(t, err)
+	assert.Check(t, token.Valid)
+	assert.Equal(t, token.Header["kid"], "my-key")
+	assert.Equal(t, token.Header["alg"], "ES256")
+
+	claims := token.Claims.(jwt.MapClaims)
+	assert.Equal(t, claims["iss"], "authorino")
+	assert.Equal(t, claims["aud"], "http://metadata.io:8080")
+	assert.Equal(t, claims["htm"], "POST")
+	assert.Equal(t, claims["htu"], "http://metadata.io:8080/resource")
+	assert.Equal(t, claims["exp"].(float64)-claims["iat"].(float64), float64(60))
+}
+
+func TestGenericHttpCallSignedWithInstanceIdentity(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	instanceIdentity, _ := NewInstanceIdentity("authorino", "", 0, jose.JSONWebKey{Key: key, Algorithm: "ES256"})
+
+	OutboundRequestsInstanceIdentity = instanceIdentity
+	defer func() { OutboundRequestsInstanceIdentity = nil }()
+
+	var signature string
+	server := gohttptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(DefaultInstanceIdentityHeader)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"foo":"bar"}`))
+	}))
+	defer server.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(genericHttpAuthDataMock())
+
+	metadata := &GenericHttp{
+		Endpoint: server.URL + "/metadata",
+		Method:   "GET",
+	}
+
+	_, err := metadata.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+
+	token, err := jwt.Parse(signature, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	assert.NilError(t, err)
+	claims := token.Claims.(jwt.MapClaims)
+	assert.Equal(t, claims["aud"], server.URL)
+	assert.Equal(t, claims["htm"], "GET")
+	assert.Equal(t, claims["htu"], server.URL+"/metadata")
+}
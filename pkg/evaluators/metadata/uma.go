@@ -3,6 +3,7 @@ package metadata
 import (
 	"bytes"
 	gocontext "context"
+	gojson "encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -14,6 +15,7 @@ import (
 	"github.com/kuadrant/authorino/pkg/json"
 	"github.com/kuadrant/authorino/pkg/log"
 
+	"github.com/tidwall/gjson"
 	"go.opentelemetry.io/otel"
 	otel_propagation "go.opentelemetry.io/otel/propagation"
 )
@@ -37,25 +39,28 @@ func (provider *Provider) GetTokenURL() string {
 	return provider.tokenURL
 }
 
-func (provider *Provider) GetResourcesByURI(uri string, pat PAT, ctx gocontext.Context) ([]interface{}, error) {
+func (provider *Provider) GetResourcesByURI(uri string, scopes []string, pat PAT, ctx gocontext.Context) ([]interface{}, error) {
 	if err := context.CheckContext(ctx); err != nil {
 		return nil, err
 	}
 
-	resourceIDs, err := provider.queryResourcesByURI(uri, pat, ctx)
+	resourceIDs, err := provider.queryResourcesByURI(uri, scopes, pat, ctx)
 	if err != nil {
 		return nil, err
 	}
 	return provider.getResourcesByIDs(resourceIDs, pat, ctx)
 }
 
-func (provider *Provider) queryResourcesByURI(uri string, pat PAT, ctx gocontext.Context) ([]string, error) {
+func (provider *Provider) queryResourcesByURI(uri string, scopes []string, pat PAT, ctx gocontext.Context) ([]string, error) {
 	if err := context.CheckContext(ctx); err != nil {
 		return nil, err
 	}
 
 	queryResourcesURL, _ := url.Parse(provider.resourceRegistrationURL)
 	queryResourcesURL.RawQuery = "uri=" + uri
+	for _, scope := range scopes {
+		queryResourcesURL.RawQuery += "&scope=" + url.QueryEscape(scope)
+	}
 
 	log.FromContext(ctx).V(1).Info("querying resources by uri", "url", queryResourcesURL.String())
 
@@ -163,6 +168,11 @@ type UMA struct {
 	Endpoint     string `yaml:"endpoint,omitempty"`
 	ClientID     string `yaml:"client_id"`
 	ClientSecret string `yaml:"client_secret"`
+	// URI resolves the uri of the resources to query the resource registry for. If nil, the path of the request is used.
+	URI *json.JSONValue
+	// Scopes resolves the scopes of the resources to query the resource registry for, either as an array or as a
+	// space-delimited string
+	Scopes *json.JSONValue
 
 	provider *Provider
 }
@@ -209,8 +219,11 @@ func (uma *UMA) Call(pipeline auth.AuthPipeline, parentCtx gocontext.Context) (i
 	}
 
 	// get resource data
-	uri := pipeline.GetHttp().GetPath()
-	resourceData, err := uma.provider.GetResourcesByURI(uri, pat, ctx)
+	uri, scopes, err := uma.resourceQuery(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	resourceData, err := uma.provider.GetResourcesByURI(uri, scopes, pat, ctx)
 
 	if err != nil {
 		return nil, err
@@ -219,6 +232,41 @@ func (uma *UMA) Call(pipeline auth.AuthPipeline, parentCtx gocontext.Context) (i
 	return resourceData, nil
 }
 
+// resourceQuery resolves the uri and the scopes of the resources to query the resource registry for
+func (uma *UMA) resourceQuery(pipeline auth.AuthPipeline) (uri string, scopes []string, err error) {
+	if uma.URI == nil && uma.Scopes == nil {
+		return pipeline.GetHttp().GetPath(), nil, nil
+	}
+
+	authJSON := pipeline.GetAuthorizationJSON()
+
+	if uma.URI == nil {
+		uri = pipeline.GetHttp().GetPath()
+	} else if uri, err = json.StringifyJSON(uma.URI.ResolveFor(authJSON)); err != nil {
+		return "", nil, err
+	} else if uri == "" {
+		return "", nil, fmt.Errorf("unable to resolve the uri of the resource")
+	}
+
+	if uma.Scopes != nil {
+		resolvedAsJSON, err := gojson.Marshal(uma.Scopes.ResolveFor(authJSON))
+		if err != nil {
+			return "", nil, err
+		}
+		if resolved := gjson.ParseBytes(resolvedAsJSON); resolved.IsArray() {
+			for _, scope := range resolved.Array() {
+				if scope := scope.String(); scope != "" {
+					scopes = append(scopes, scope)
+				}
+			}
+		} else {
+			scopes = strings.Fields(resolved.String())
+		}
+	}
+
+	return uri, scopes, nil
+}
+
 func (uma *UMA) clientAuthenticatedURL(rawurl string) (*url.URL, error) {
 	parsedURL, err := url.Parse(rawurl)
 	if err != nil {
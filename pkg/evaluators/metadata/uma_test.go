@@ -8,10 +8,12 @@ import (
 
 	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
 	"github.com/kuadrant/authorino/pkg/httptest"
+	authorinoJSON "github.com/kuadrant/authorino/pkg/json"
 
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/golang/mock/gomock"
 	"gotest.tools/assert"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 )
 
 type UMATest struct{}
@@ -86,3 +88,69 @@ func TestUMACall(t *testing.T) {
 	assert.Equal(t, "["+resourceData+"]", string(data))
 	assert.NilError(t, err)
 }
+
+func TestUMACallWithResourceQuery(t *testing.T) {
+	jsonResponse := func(body string) httptest.HttpServerMockResponseFunc {
+		return func() httptest.HttpServerMockResponse {
+			return httptest.HttpServerMockResponse{Status: 200, Headers: map[string]string{"Context-Type": "application/json"}, Body: body}
+		}
+	}
+
+	resourceData := `{"_id":"8d4b1d5e-5b1a-4b8e-9bc6-2cd0cbbd5e41","name":"order-123","resource_scopes":[{"name":"read"},{"name":"write"}],"uris":["/orders/123"]}`
+	httpServer := httptest.NewHttpServerMock(umaServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/uma/.well-known/uma2-configuration": jsonResponse(umaWellKnownConfig),
+		"/uma/pat":                            jsonResponse(`{"some-pat-claim": "some-value"}`),
+		"/uma/resource_set?uri=/orders/123&scope=read&scope=write": jsonResponse(`["8d4b1d5e-5b1a-4b8e-9bc6-2cd0cbbd5e41"]`),
+		"/uma/resource_set/8d4b1d5e-5b1a-4b8e-9bc6-2cd0cbbd5e41":   jsonResponse(resourceData),
+	})
+	defer httpServer.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	authJSON := `{"context":{"request":{"http":{"path":"/api/v1/orders/123"}}},"auth":{"identity":{"scope":"read write","scp":["read","write"]}}}`
+	uri := &authorinoJSON.JSONValue{Pattern: `context.request.http.path.@replace:{"old":"/api/v1","new":""}`}
+
+	for _, scopes := range []*authorinoJSON.JSONValue{
+		{Pattern: "auth.identity.scope"},                                   // space-delimited string
+		{Pattern: "auth.identity.scp"},                                     // array
+		{Static: k8sruntime.RawExtension{Raw: []byte(`["read","write"]`)}}, // static array
+	} {
+		pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+		pipelineMock.EXPECT().GetAuthorizationJSON().Return(authJSON)
+
+		uma, _ := NewUMAMetadata(umaIssuer, "client-id", "client-secret")
+		uma.URI = uri
+		uma.Scopes = scopes
+
+		obj, err := uma.Call(pipelineMock, context.TODO())
+
+		assert.NilError(t, err)
+		data, _ := json.Marshal(obj)
+		assert.Equal(t, "["+resourceData+"]", string(data))
+	}
+}
+
+func TestUMACallWithUnresolvedResourceURI(t *testing.T) {
+	httpServer := httptest.NewHttpServerMock(umaServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/uma/.well-known/uma2-configuration": func() httptest.HttpServerMockResponse {
+			return httptest.HttpServerMockResponse{Status: 200, Body: umaWellKnownConfig}
+		},
+		"/uma/pat": func() httptest.HttpServerMockResponse {
+			return httptest.HttpServerMockResponse{Status: 200, Body: `{"some-pat-claim": "some-value"}`}
+		},
+	})
+	defer httpServer.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"context":{"request":{"http":{"path":"/orders/123"}}}}`)
+
+	uma, _ := NewUMAMetadata(umaIssuer, "client-id", "client-secret")
+	uma.URI = &authorinoJSON.JSONValue{Pattern: "auth.metadata.resource.uri"}
+
+	_, err := uma.Call(pipelineMock, context.TODO())
+	assert.Error(t, err, "unable to resolve the uri of the resource")
+}
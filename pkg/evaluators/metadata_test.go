@@ -11,6 +11,7 @@ import (
 	"github.com/kuadrant/authorino/pkg/evaluators/metadata"
 	"github.com/kuadrant/authorino/pkg/httptest"
 	"github.com/kuadrant/authorino/pkg/json"
+	"github.com/kuadrant/authorino/pkg/jsonexp"
 
 	"github.com/golang/mock/gomock"
 	"gotest.tools/assert"
@@ -59,7 +60,7 @@ func TestMetadataCaching(t *testing.T) {
 	assert.NilError(t, err)
 
 	// With caching of metadata
-	cache := NewEvaluatorCache(json.JSONValue{Static: "x"}, 2) // 2 seconds ttl
+	cache := NewEvaluatorCache(json.JSONValue{Static: "x"}, 2, 0, nil) // 2 seconds ttl
 	metadataConfig.Cache = cache
 	defer metadataConfig.Clean(context.TODO())
 
@@ -84,3 +85,51 @@ func TestMetadataCaching(t *testing.T) {
 	assert.Equal(t, metadataObjectJSON["foo"], "bar")
 	assert.NilError(t, err)
 }
+
+func TestMetadataConditionalCaching(t *testing.T) {
+	var active string
+	var requests int
+	extHttpMetadataServer := httptest.NewHttpServerMock(testMetadataServerHost, map[string]httptest.HttpServerMockResponseFunc{
+		"/metadata": func() httptest.HttpServerMockResponse {
+			requests++
+			return httptest.NewHttpServerMockResponseFuncJSON(fmt.Sprintf(`{"active":%s}`, active))()
+		},
+	})
+	defer extHttpMetadataServer.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{}`).AnyTimes()
+
+	metadataConfig := MetadataConfig{
+		Name: "test",
+		GenericHTTP: &metadata.GenericHttp{
+			Endpoint:        fmt.Sprintf("http://%s/metadata", testMetadataServerHost),
+			Method:          "GET",
+			AuthCredentials: auth.NewAuthCredential("", "authorization_header"),
+		},
+		Cache: NewEvaluatorCache(json.JSONValue{Static: "x"}, 60, 0, jsonexp.All(
+			jsonexp.Pattern{Selector: "result.active", Operator: jsonexp.EqualOperator, Value: "true"},
+		)),
+	}
+	defer metadataConfig.Clean(context.TODO())
+
+	// condition does not hold – result is not cached
+	active = "false"
+	_, err := metadataConfig.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	_, err = metadataConfig.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.Equal(t, requests, 2)
+
+	// condition holds – result is cached
+	active = "true"
+	_, err = metadataConfig.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	obj, err := metadataConfig.Call(pipelineMock, context.TODO())
+	assert.NilError(t, err)
+	assert.Equal(t, requests, 3)
+	assert.Equal(t, obj.(map[string]interface{})["active"], true)
+}
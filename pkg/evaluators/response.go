@@ -3,8 +3,10 @@ package evaluators
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/kuadrant/authorino/pkg/auth"
+	authorinoContext "github.com/kuadrant/authorino/pkg/context"
 	"github.com/kuadrant/authorino/pkg/evaluators/response"
 	"github.com/kuadrant/authorino/pkg/json"
 	"github.com/kuadrant/authorino/pkg/jsonexp"
@@ -15,6 +17,8 @@ const (
 	responseWristband = "RESPONSE_WRISTBAND"
 	responseJSON      = "RESPONSE_JSON"
 	responsePlain     = "RESPONSE_PLAIN"
+	responseCombined  = "RESPONSE_COMBINED"
+	responseRateLimit = "RESPONSE_RATE_LIMIT_DESCRIPTORS"
 
 	HTTP_HEADER_WRAPPER            = "httpHeader"
 	ENVOY_DYNAMIC_METADATA_WRAPPER = "envoyDynamicMetadata"
@@ -51,10 +55,16 @@ type ResponseConfig struct {
 	WrapperKey string             `yaml:"wrapperKey"`
 	Metrics    bool               `yaml:"metrics"`
 	Cache      EvaluatorCache
+	// AppendHeader tells that the HTTP header is appended to the header of same name set in the request, instead of
+	// overwriting it
+	AppendHeader bool `yaml:"appendHeader,omitempty"`
 
 	Wristband   auth.WristbandIssuer  `yaml:"wristband,omitempty"`
 	DynamicJSON *response.DynamicJSON `yaml:"json,omitempty"`
 	Plain       *response.Plain       `yaml:"plain,omitempty"`
+	Combined    *response.Combined    `yaml:"combined,omitempty"`
+
+	RateLimitDescriptors *response.RateLimitDescriptors `yaml:"rateLimitDescriptors,omitempty"`
 }
 
 func (config *ResponseConfig) GetAuthConfigEvaluator() auth.AuthConfigEvaluator {
@@ -65,6 +75,10 @@ func (config *ResponseConfig) GetAuthConfigEvaluator() auth.AuthConfigEvaluator
 		return config.DynamicJSON
 	case responsePlain:
 		return config.Plain
+	case responseCombined:
+		return config.Combined
+	case responseRateLimit:
+		return config.RateLimitDescriptors
 	default:
 		return nil
 	}
@@ -80,9 +94,11 @@ func (config *ResponseConfig) Call(pipeline auth.AuthPipeline, ctx context.Conte
 
 		cache := config.Cache
 		var cacheKey interface{}
+		var authJSON string
 
 		if cache != nil {
-			cacheKey = cache.ResolveKeyFor(pipeline.GetAuthorizationJSON())
+			authJSON = pipeline.GetAuthorizationJSON()
+			cacheKey = cache.ResolveKeyFor(authJSON)
 			if cachedObj, err := cache.Get(cacheKey); err != nil {
 				logger.V(1).Error(err, "failed to retrieve data from the cache")
 			} else if cachedObj != nil {
@@ -92,7 +108,7 @@ func (config *ResponseConfig) Call(pipeline auth.AuthPipeline, ctx context.Conte
 
 		obj, err := evaluator.Call(pipeline, log.IntoContext(ctx, logger))
 
-		if err == nil && cacheKey != nil {
+		if err == nil && cacheKey != nil && !authorinoContext.IsDryRun(ctx) && cache.ShouldCache(authJSON, obj) {
 			if err := cache.Set(cacheKey, obj); err != nil {
 				logger.V(1).Info("unable to store data in the cache", "err", err)
 			}
@@ -118,11 +134,21 @@ func (config *ResponseConfig) GetType() string {
 		return responseJSON
 	case config.Plain != nil:
 		return responsePlain
+	case config.Combined != nil:
+		return responseCombined
+	case config.RateLimitDescriptors != nil:
+		return responseRateLimit
 	default:
 		return ""
 	}
 }
 
+// IsCombined tells whether the response item joins the outputs of other response items.
+// Combined response items are built after all the other response items.
+func (config *ResponseConfig) IsCombined() bool {
+	return config.Combined != nil
+}
+
 // impl:Prioritizable
 
 func (config *ResponseConfig) GetPriority() int {
@@ -172,3 +198,16 @@ func WrapResponses(responses map[*ResponseConfig]interface{}) (responseHeaders m
 
 	return responseHeaders, responseMetadata
 }
+
+// AppendedResponseHeaders returns the names of the HTTP headers built out of the responses that are appended to the
+// headers of the request, instead of overwriting them, sorted.
+func AppendedResponseHeaders(responses map[*ResponseConfig]interface{}) []string {
+	var headers []string
+	for responseConfig := range responses {
+		if responseConfig.Wrapper == HTTP_HEADER_WRAPPER && responseConfig.AppendHeader {
+			headers = append(headers, responseConfig.WrapperKey)
+		}
+	}
+	sort.Strings(headers)
+	return headers
+}
@@ -0,0 +1,42 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kuadrant/authorino/pkg/auth"
+
+	"github.com/tidwall/gjson"
+)
+
+const DefaultCombinedResponseSeparator = ","
+
+// Combined joins the outputs of other response items, referred by name, into a single value.
+// Response items that were not built are skipped.
+type Combined struct {
+	Responses []string
+	Separator string
+}
+
+func (c *Combined) Call(pipeline auth.AuthPipeline, _ context.Context) (interface{}, error) {
+	responses := gjson.Get(pipeline.GetAuthorizationJSON(), "auth.response").Map()
+
+	values := make([]string, 0, len(c.Responses))
+	for _, name := range c.Responses {
+		if value, ok := responses[name]; ok && value.Exists() {
+			values = append(values, value.String())
+		}
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("none of the responses to combine was built")
+	}
+
+	separator := c.Separator
+	if separator == "" {
+		separator = DefaultCombinedResponseSeparator
+	}
+
+	return strings.Join(values, separator), nil
+}
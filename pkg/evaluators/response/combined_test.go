@@ -0,0 +1,55 @@
+package response
+
+import (
+	"context"
+	"testing"
+
+	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
+
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func TestCombinedCall(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ev := Combined{Responses: []string{"token-a", "token-b"}, Separator: "; "}
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"response":{"token-a":"abc","token-b":"xyz","other":"123"}}}`)
+
+	obj, err := ev.Call(pipelineMock, context.TODO())
+
+	assert.NilError(t, err)
+	assert.Equal(t, obj, "abc; xyz")
+}
+
+func TestCombinedCallWithDefaultSeparator(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ev := Combined{Responses: []string{"token-a", "missing", "json"}}
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"response":{"token-a":"abc","json":{"x":1}}}}`)
+
+	obj, err := ev.Call(pipelineMock, context.TODO())
+
+	assert.NilError(t, err)
+	assert.Equal(t, obj, `abc,{"x":1}`)
+}
+
+func TestCombinedCallWithoutResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ev := Combined{Responses: []string{"token-a"}}
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"response":{}}}`)
+
+	_, err := ev.Call(pipelineMock, context.TODO())
+
+	assert.Error(t, err, "none of the responses to combine was built")
+}
@@ -0,0 +1,34 @@
+package response
+
+import (
+	"context"
+
+	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/json"
+)
+
+// RateLimitDescriptors builds entries of rate limit descriptors out of the authorization JSON, in the shape read by the
+// metadata actions of the Envoy rate limit filter, i.e. an object whose properties are the descriptor keys and whose
+// values are the descriptor values, as strings.
+// Entries that resolve to empty values are left out.
+type RateLimitDescriptors struct {
+	Entries []json.JSONProperty
+}
+
+func (d *RateLimitDescriptors) Call(pipeline auth.AuthPipeline, _ context.Context) (interface{}, error) {
+	authJSON := pipeline.GetAuthorizationJSON()
+
+	descriptors := make(map[string]interface{}, len(d.Entries))
+	for _, entry := range d.Entries {
+		value, err := json.StringifyJSON(entry.Value.ResolveFor(authJSON))
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			continue
+		}
+		descriptors[entry.Name] = value
+	}
+
+	return descriptors, nil
+}
@@ -0,0 +1,40 @@
+package response
+
+import (
+	"context"
+	"testing"
+
+	mock_auth "github.com/kuadrant/authorino/pkg/auth/mocks"
+	"github.com/kuadrant/authorino/pkg/json"
+
+	"github.com/golang/mock/gomock"
+	"gotest.tools/assert"
+)
+
+func TestRateLimitDescriptorsCall(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ev := RateLimitDescriptors{
+		Entries: []json.JSONProperty{
+			{Name: "tenant", Value: json.JSONValue{Pattern: "auth.identity.tenant"}},
+			{Name: "plan", Value: json.JSONValue{Pattern: "auth.authorization.plans.plan"}},
+			{Name: "tier", Value: json.JSONValue{Pattern: "auth.identity.tier"}},
+			{Name: "source", Value: json.JSONValue{Static: "authorino"}},
+			{Name: "missing", Value: json.JSONValue{Pattern: "auth.identity.missing"}},
+		},
+	}
+
+	pipelineMock := mock_auth.NewMockAuthPipeline(ctrl)
+	pipelineMock.EXPECT().GetAuthorizationJSON().Return(`{"auth":{"identity":{"tenant":"acme","tier":2},"authorization":{"plans":{"plan":"gold"}}}}`)
+
+	obj, err := ev.Call(pipelineMock, context.TODO())
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, obj, map[string]interface{}{
+		"tenant": "acme",
+		"plan":   "gold",
+		"tier":   "2",
+		"source": "authorino",
+	})
+}
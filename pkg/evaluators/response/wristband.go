@@ -2,16 +2,20 @@ package response
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/sha256"
 	gojson "encoding/json"
 	"encoding/pem"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/clock"
 	"github.com/kuadrant/authorino/pkg/evaluators/identity"
 	"github.com/kuadrant/authorino/pkg/json"
+	"github.com/kuadrant/authorino/pkg/jsonexp"
+	"github.com/kuadrant/authorino/pkg/log"
 
 	jose "github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt"
@@ -19,6 +23,12 @@ import (
 
 const DEFAULT_WRISTBAND_DURATION = int64(300)
 
+// wristbandRequiredClaims are the claims that are never left out of the wristband, for it to be verifiable
+var wristbandRequiredClaims = []string{"iss", "exp"}
+
+// MinRSASigningKeySize is the minimum size of the RSA signing keys, in bits; RSA keys shorter than that are rejected
+var MinRSASigningKeySize = 2048
+
 func NewSigningKey(name string, algorithm string, singingKey []byte) (*jose.JSONWebKey, error) {
 	signingKey := &jose.JSONWebKey{
 		KeyID:     name,
@@ -51,9 +61,44 @@ func NewSigningKey(name string, algorithm string, singingKey []byte) (*jose.JSON
 		return nil, fmt.Errorf("invalid signing key algorithm")
 	}
 
+	if err := checkSigningKeyAlgorithm(signingKey.Key, algorithm); err != nil {
+		return nil, err
+	}
+
+	if err := checkSigningKeyStrength(signingKey.Key); err != nil {
+		return nil, err
+	}
+
 	return signingKey, nil
 }
 
+// checkSigningKeyAlgorithm verifies that the private key can be used to sign with the algorithm
+func checkSigningKeyAlgorithm(key interface{}, algorithm string) error {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		curves := map[string]string{"ES256": "P-256", "ES384": "P-384", "ES512": "P-521"}
+		if curve, ok := curves[algorithm]; !ok || k.Curve.Params().Name != curve {
+			return fmt.Errorf("signing key algorithm mismatch: %s key cannot be used with %s", k.Curve.Params().Name, algorithm)
+		}
+	case *rsa.PrivateKey:
+		if !strings.HasPrefix(algorithm, "RS") {
+			return fmt.Errorf("signing key algorithm mismatch: RSA key cannot be used with %s", algorithm)
+		}
+	}
+	return nil
+}
+
+// checkSigningKeyStrength verifies that the private key is not shorter than the minimum size.
+// Elliptic curve keys are only accepted on the curves of the ES* algorithms, all considered strong enough.
+func checkSigningKeyStrength(key interface{}) error {
+	if k, ok := key.(*rsa.PrivateKey); ok {
+		if size := k.N.BitLen(); size < MinRSASigningKeySize {
+			return fmt.Errorf("weak signing key: RSA key of %d bits (min: %d)", size, MinRSASigningKeySize)
+		}
+	}
+	return nil
+}
+
 type Claims map[string]interface{}
 
 func (c *Claims) Valid() error {
@@ -74,6 +119,14 @@ func NewWristbandConfig(issuer string, claims []json.JSONProperty, tokenDuration
 		return nil, fmt.Errorf("missing at least one signing key")
 	}
 
+	keyIDs := make(map[string]bool, len(signingKeys))
+	for _, signingKey := range signingKeys {
+		if keyIDs[signingKey.KeyID] {
+			return nil, fmt.Errorf("duplicate signing key: %s", signingKey.KeyID)
+		}
+		keyIDs[signingKey.KeyID] = true
+	}
+
 	return &Wristband{
 		Issuer:        issuer,
 		CustomClaims:  claims,
@@ -83,10 +136,21 @@ func NewWristbandConfig(issuer string, claims []json.JSONProperty, tokenDuration
 }
 
 type Wristband struct {
-	Issuer        string
-	CustomClaims  []json.JSONProperty
-	TokenDuration int64
-	SigningKeys   []jose.JSONWebKey
+	Issuer       string
+	CustomClaims []json.JSONProperty
+	// CustomClaimConditions are the conditions for the custom claims to be added to the wristband, by name of the claim.
+	// Claims without conditions are always added.
+	CustomClaimConditions map[string]jsonexp.Expression
+	TokenDuration         int64
+	// SigningKeys are published in the JWKS to verify the wristbands. Only the first one (active key) signs new
+	// wristbands; the others are verification-only, to keep the wristbands issued before a key rotation valid.
+	SigningKeys []jose.JSONWebKey
+	// IncludeClaims restricts the claims of the wristband to the ones listed, besides the required ones (iss, exp)
+	IncludeClaims []string
+	// ExcludeClaims leaves the listed claims out of the wristband, except the required ones (iss, exp)
+	ExcludeClaims []string
+	// Clock tells the time to set the timestamps of the wristband; defaults to the wall clock
+	Clock clock.Clock
 }
 
 func (w *Wristband) Call(pipeline auth.AuthPipeline, ctx context.Context) (interface{}, error) {
@@ -104,7 +168,7 @@ func (w *Wristband) Call(pipeline auth.AuthPipeline, ctx context.Context) (inter
 	sub := fmt.Sprintf("%x", hash.Sum(nil))
 
 	// timestamps
-	iat := time.Now().Unix()
+	iat := clock.Now(w.Clock).Unix()
 	exp := iat + int64(w.TokenDuration)
 
 	// claims
@@ -119,13 +183,21 @@ func (w *Wristband) Call(pipeline auth.AuthPipeline, ctx context.Context) (inter
 		authJSON := pipeline.GetAuthorizationJSON()
 
 		for _, claim := range w.CustomClaims {
+			if conditions, ok := w.CustomClaimConditions[claim.Name]; ok {
+				if match, err := conditions.Matches(authJSON); err != nil || !match {
+					log.FromContext(ctx).V(1).Info("custom claim left out of the wristband", "claim", claim.Name, "reason", "unmatching conditions", "err", err)
+					continue
+				}
+			}
 			value := claim.Value
 			claims[claim.Name] = value.ResolveFor(authJSON)
 		}
 	}
 
+	w.minimizeClaims(claims)
+
 	// signing key
-	signingKey := w.SigningKeys[0]
+	signingKey := w.ActiveSigningKey()
 
 	token := jwt.NewWithClaims(jwt.GetSigningMethod(signingKey.Algorithm), &claims)
 	token.Header["kid"] = signingKey.KeyID
@@ -137,12 +209,51 @@ func (w *Wristband) Call(pipeline auth.AuthPipeline, ctx context.Context) (inter
 	}
 }
 
+// minimizeClaims removes from the claims the ones not included or explicitly excluded, to shrink the wristband
+func (w *Wristband) minimizeClaims(claims Claims) {
+	if len(w.IncludeClaims) == 0 && len(w.ExcludeClaims) == 0 {
+		return
+	}
+
+	required := func(name string) bool {
+		for _, claim := range wristbandRequiredClaims {
+			if claim == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(w.IncludeClaims) > 0 {
+		included := make(map[string]bool, len(w.IncludeClaims))
+		for _, name := range w.IncludeClaims {
+			included[name] = true
+		}
+		for name := range claims {
+			if !included[name] && !required(name) {
+				delete(claims, name)
+			}
+		}
+	}
+
+	for _, name := range w.ExcludeClaims {
+		if !required(name) {
+			delete(claims, name)
+		}
+	}
+}
+
 type oidcConfig struct {
 	Issuer               string   `json:"issuer"`
 	JWKSURI              string   `json:"jwks_uri"`
 	SupportedSigningAlgs []string `json:"id_token_signing_alg_values_supported"`
 }
 
+// ActiveSigningKey returns the key that signs new wristbands
+func (w *Wristband) ActiveSigningKey() jose.JSONWebKey {
+	return w.SigningKeys[0]
+}
+
 func (w *Wristband) GetIssuer() string {
 	return w.Issuer
 }
@@ -162,6 +273,7 @@ func (w *Wristband) OpenIDConfig() (string, error) {
 	}
 }
 
+// JWKS returns the public keys of all the signing keys, i.e. the active key and the verification-only ones
 func (w *Wristband) JWKS() (string, error) {
 	publicKeys := make([]jose.JSONWebKey, 0)
 
@@ -30,3 +30,19 @@ func TestWrapResponseObjectAsHeader(t *testing.T) {
 	responseConfig.Plain = &response.Plain{}
 	assert.Equal(t, responseConfig.WrapObjectAsHeaderValue("my-value"), "my-value")
 }
+
+func TestAppendedResponseHeaders(t *testing.T) {
+	appended := NewResponseConfig("appended", 0, nil, HTTP_HEADER_WRAPPER, "x-appended", false)
+	appended.AppendHeader = true
+	overwritten := NewResponseConfig("overwritten", 0, nil, HTTP_HEADER_WRAPPER, "x-overwritten", false)
+	metadata := NewResponseConfig("metadata", 0, nil, ENVOY_DYNAMIC_METADATA_WRAPPER, "metadata", false)
+	metadata.AppendHeader = true
+
+	responses := map[*ResponseConfig]interface{}{
+		appended:    "a",
+		overwritten: "b",
+		metadata:    "c",
+	}
+
+	assert.DeepEqual(t, AppendedResponseHeaders(responses), []string{"x-appended"})
+}
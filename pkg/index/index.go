@@ -2,6 +2,7 @@ package index
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 
@@ -23,6 +24,8 @@ type Index interface {
 
 	FindId(key string) (id string, found bool)
 	FindKeys(id string) []string
+	// Keys returns the keys of the index, each mapped to the id of the resource indexed for the key
+	Keys() map[string]string
 }
 
 func NewIndex() Index {
@@ -73,7 +76,7 @@ func (c *authConfigTree) Set(id, key string, config evaluators.AuthConfig, overr
 		AuthConfig: config,
 	}
 	err := c.root.set(revertKey(key), entry, override)
-	if err == nil {
+	if err == nil && !slices.Contains(c.keys[id], key) {
 		c.keys[id] = append(c.keys[id], key)
 	}
 	return err
@@ -87,6 +90,7 @@ func (c *authConfigTree) Delete(id string) {
 		for _, key := range keys {
 			c.deleteKey(id, key)
 		}
+		delete(c.keys, id)
 	}
 }
 
@@ -95,6 +99,10 @@ func (c *authConfigTree) DeleteKey(id, key string) {
 	defer c.mu.Unlock()
 
 	c.deleteKey(id, key)
+	c.keys[id] = slices.DeleteFunc(c.keys[id], func(k string) bool { return k == key })
+	if len(c.keys[id]) == 0 {
+		delete(c.keys, id)
+	}
 }
 
 func (c *authConfigTree) List() []*evaluators.AuthConfig {
@@ -129,6 +137,21 @@ func (c *authConfigTree) FindKeys(id string) []string {
 	return c.keys[id]
 }
 
+func (c *authConfigTree) Keys() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make(map[string]string)
+	for id, idKeys := range c.keys {
+		for _, key := range idKeys {
+			if node, tail := c.root.longestCommonLabel(revertKey(key)); tail == "" && node.entry != nil && node.entry.Id == id {
+				keys[key] = id
+			}
+		}
+	}
+	return keys
+}
+
 func (c *authConfigTree) deleteKey(id, key string) {
 	if node, _ := c.root.longestCommonLabel(revertKey(key)); node != nil && node.entry != nil && node.entry.Id == id {
 		node.entry = nil
@@ -145,6 +145,30 @@ func (f *bogusIdentity) Call(_ auth.AuthPipeline, _ context.Context) (interface{
 	return true, nil
 }
 
+func TestAuthConfigTreeKeys(t *testing.T) {
+	c := newAuthConfigTree()
+	authConfig := buildTestAuthConfig()
+
+	_ = c.Set("auth-1", "talker-api.io", authConfig, false)
+	_ = c.Set("auth-1", "echo-api.io", authConfig, false)
+	_ = c.Set("auth-1", "talker-api.io", authConfig, true) // updated, not repeated
+	assert.DeepEqual(t, c.FindKeys("auth-1"), []string{"talker-api.io", "echo-api.io"})
+
+	_ = c.Set("auth-2", "*.io", authConfig, false)
+	assert.DeepEqual(t, c.Keys(), map[string]string{"talker-api.io": "auth-1", "echo-api.io": "auth-1", "*.io": "auth-2"})
+
+	c.DeleteKey("auth-1", "talker-api.io")
+	assert.DeepEqual(t, c.FindKeys("auth-1"), []string{"echo-api.io"})
+
+	c.DeleteKey("auth-1", "echo-api.io")
+	assert.Equal(t, len(c.FindKeys("auth-1")), 0)
+	assert.DeepEqual(t, c.Keys(), map[string]string{"*.io": "auth-2"})
+
+	c.Delete("auth-2")
+	assert.Equal(t, len(c.FindKeys("auth-2")), 0)
+	assert.Equal(t, len(c.Keys()), 0)
+}
+
 func buildTestAuthConfig() evaluators.AuthConfig {
 	return evaluators.AuthConfig{
 		IdentityConfigs:      []auth.AuthConfigEvaluator{&bogusIdentity{}},
@@ -115,6 +115,20 @@ func (mr *MockIndexMockRecorder) Get(key interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockIndex)(nil).Get), key)
 }
 
+// Keys mocks base method.
+func (m *MockIndex) Keys() map[string]string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Keys")
+	ret0, _ := ret[0].(map[string]string)
+	return ret0
+}
+
+// Keys indicates an expected call of Keys.
+func (mr *MockIndexMockRecorder) Keys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockIndex)(nil).Keys))
+}
+
 // List mocks base method.
 func (m *MockIndex) List() []*evaluators.AuthConfig {
 	m.ctrl.T.Helper()
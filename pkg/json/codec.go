@@ -0,0 +1,70 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	StandardCodecName = "standard"
+	JsoniterCodecName = "jsoniter"
+)
+
+// Codec encodes and decodes JSON documents on the hot path of the auth pipeline, e.g. the Authorization JSON
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type standardCodec struct{}
+
+func (standardCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (standardCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// jsoniterCodec decodes with jsoniter, configured to behave as encoding/json, which is about twice as fast as
+// encoding/json to parse the Authorization JSON. Encoding is left to encoding/json, which is faster than jsoniter in
+// its compatible mode to encode the maps that compose the Authorization JSON (see the benchmarks).
+type jsoniterCodec struct {
+	standardCodec
+}
+
+func (jsoniterCodec) Unmarshal(data []byte, v any) error {
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, v)
+}
+
+var codecs = map[string]Codec{
+	StandardCodecName: standardCodec{},
+	JsoniterCodecName: jsoniterCodec{},
+}
+
+var codec Codec = codecs[StandardCodecName]
+
+// NewCodec returns the JSON codec registered with the given name
+func NewCodec(name string) (Codec, error) {
+	if c, ok := codecs[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown json codec: %s", name)
+}
+
+// SetCodec sets the JSON codec used on the hot path of the auth pipeline; defaults to encoding/json
+func SetCodec(c Codec) {
+	codec = c
+}
+
+// Marshal encodes v with the JSON codec of the hot path of the auth pipeline
+func Marshal(v any) ([]byte, error) {
+	return codec.Marshal(v)
+}
+
+// Unmarshal decodes data into v with the JSON codec of the hot path of the auth pipeline
+func Unmarshal(data []byte, v any) error {
+	return codec.Unmarshal(data, v)
+}
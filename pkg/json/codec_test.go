@@ -0,0 +1,96 @@
+package json
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const codecTestData = `{"context":{"request":{"http":{"id":"8f6a6b4c","method":"POST","headers":{"authorization":"Bearer n3ex87bye9238ry8","content-type":"application/json","x-forwarded-for":"10.0.0.1, 10.0.0.2"},"path":"/pets?name=<rex>&kind=dog","host":"pets.io","body":"{\"name\":\"rex\"}"}}},"auth":{"identity":{"sub":"1a0b6c6e","exp":2145865773,"iat":1659088173.5,"email_verified":false,"realm_access":{"roles":["offline_access","member"]},"address":null,"name":"Peter Whö"},"metadata":{},"authorization":{"opa":{"allow":true,"score":0.1}}}}`
+
+func TestCodecRoundTripEquivalence(t *testing.T) {
+	var expected interface{}
+	assert.NilError(t, json.Unmarshal([]byte(codecTestData), &expected))
+	expectedJSON, _ := json.Marshal(expected)
+
+	for _, name := range []string{StandardCodecName, JsoniterCodecName} {
+		c, err := NewCodec(name)
+		assert.NilError(t, err)
+
+		var decoded interface{}
+		assert.NilError(t, c.Unmarshal([]byte(codecTestData), &decoded))
+		assert.DeepEqual(t, decoded, expected)
+
+		encoded, err := c.Marshal(decoded)
+		assert.NilError(t, err)
+		assert.Equal(t, string(encoded), string(expectedJSON), name)
+	}
+}
+
+func TestCodecStructEquivalence(t *testing.T) {
+	type request struct {
+		Method  string            `json:"method"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    string            `json:"body,omitempty"`
+		Size    int64             `json:"size"`
+		Raw     json.RawMessage   `json:"raw,omitempty"`
+	}
+	data := map[string]any{
+		"request": &request{Method: "GET", Headers: map[string]string{"b": "<2>", "a": "1"}, Size: 42, Raw: json.RawMessage(`{"x":1}`)},
+		"auth":    map[string]any{"identity": "leeloo", "ratio": 0.000001},
+	}
+
+	expected, _ := json.Marshal(data)
+
+	c, _ := NewCodec(JsoniterCodecName)
+	encoded, err := c.Marshal(data)
+	assert.NilError(t, err)
+	assert.Equal(t, string(encoded), string(expected))
+}
+
+func TestNewCodec(t *testing.T) {
+	_, err := NewCodec("unknown")
+	assert.Error(t, err, "unknown json codec: unknown")
+}
+
+func TestSetCodec(t *testing.T) {
+	defer SetCodec(codecs[StandardCodecName])
+
+	jsoniterCodec, _ := NewCodec(JsoniterCodecName)
+	SetCodec(jsoniterCodec)
+
+	var decoded map[string]interface{}
+	assert.NilError(t, Unmarshal([]byte(`{"a":[1,"b"]}`), &decoded))
+	encoded, err := Marshal(decoded)
+	assert.NilError(t, err)
+	assert.Equal(t, string(encoded), `{"a":[1,"b"]}`)
+}
+
+func BenchmarkCodecUnmarshal(b *testing.B) {
+	for _, name := range []string{StandardCodecName, JsoniterCodecName} {
+		c, _ := NewCodec(name)
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var decoded interface{}
+				_ = c.Unmarshal([]byte(codecTestData), &decoded)
+			}
+		})
+	}
+}
+
+func BenchmarkCodecMarshal(b *testing.B) {
+	var data interface{}
+	_ = json.Unmarshal([]byte(codecTestData), &data)
+
+	for _, name := range []string{StandardCodecName, JsoniterCodecName} {
+		c, _ := NewCodec(name)
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = c.Marshal(data)
+			}
+		})
+	}
+}
@@ -184,6 +184,34 @@ var extractJSONStr = func(json, arg string) string {
 	return wrap(parts[pos])
 }
 
+var splitJSONStr = func(jsonStr, arg string) string {
+	var sep string = " "
+
+	if arg != "" {
+		gjson.Parse(arg).ForEach(func(key, value gjson.Result) bool {
+			if key.String() == "sep" {
+				sep = value.String()
+			}
+			return true
+		})
+	}
+
+	value := gjson.Parse(jsonStr)
+	if value.Type != gjson.String {
+		return jsonStr
+	}
+
+	parts := []string{}
+	for _, part := range strings.Split(value.String(), sep) {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	split, _ := json.Marshal(parts)
+	return string(split)
+}
+
 var replaceJSONStr = func(json, arg string) string {
 	if arg == "" {
 		return json
@@ -257,6 +285,7 @@ func escapeQuotes(s string) string {
 
 func init() {
 	gjson.AddModifier("extract", extractJSONStr)
+	gjson.AddModifier("split", splitJSONStr)
 	gjson.AddModifier("replace", replaceJSONStr)
 	gjson.AddModifier("case", caseJSONStr)
 	gjson.AddModifier("base64", base64JSONStr)
@@ -72,6 +72,26 @@ func TestJSONValueResolveFor(t *testing.T) {
 	assert.Equal(t, value.ResolveFor(jsonData), "test")
 }
 
+func TestJSONValueResolveForWithTransformations(t *testing.T) {
+	const jsonData = `{"context":{"request":{"http":{"headers":{"x-user":"am9obg==","x-email":"John.Doe@Example.COM"}}}},"auth":{"identity":{"scope":"openid profile email"}}}`
+
+	// split to array
+	value := JSONValue{Pattern: `auth.identity.scope.@split`}
+	assert.DeepEqual(t, value.ResolveFor(jsonData), []interface{}{"openid", "profile", "email"})
+
+	// base64-decode
+	value = JSONValue{Pattern: `context.request.http.headers.x-user.@base64:decode`}
+	assert.Equal(t, value.ResolveFor(jsonData), "john")
+
+	// lowercase
+	value = JSONValue{Pattern: `context.request.http.headers.x-email.@case:lower`}
+	assert.Equal(t, value.ResolveFor(jsonData), "john.doe@example.com")
+
+	// chained
+	value = JSONValue{Pattern: `context.request.http.headers.x-email|@case:lower|@split:{"sep":"@"}`}
+	assert.DeepEqual(t, value.ResolveFor(jsonData), []interface{}{"john.doe", "example.com"})
+}
+
 func TestIsTemplate(t *testing.T) {
 	var value *JSONValue
 
@@ -213,6 +233,16 @@ func TestExtractJSONStr(t *testing.T) {
 	assert.Equal(t, gjson.Get(jsonData, `auth.identity.serviceaccount.name.@extract:{"sep":":","pos":1}`).String(), "ns")
 }
 
+func TestSplitJSONStr(t *testing.T) {
+	const jsonData = `{"auth":{"identity":{"scope":"openid  profile email","roles":"admin,editor","groups":["a","b"]}}}`
+
+	assert.Equal(t, gjson.Get(jsonData, `auth.identity.scope.@split`).Raw, `["openid","profile","email"]`)
+	assert.Equal(t, gjson.Get(jsonData, `auth.identity.roles.@split:{"sep":","}`).Raw, `["admin","editor"]`)
+	assert.Equal(t, gjson.Get(jsonData, `auth.identity.roles.@split:{"sep":";"}`).Raw, `["admin,editor"]`)
+	assert.Equal(t, gjson.Get(jsonData, `auth.identity.groups.@split`).Raw, `["a","b"]`) // not a string
+	assert.Equal(t, gjson.Get(jsonData, `auth.identity.roles.@split:{"sep":","}.#`).Int(), int64(2))
+}
+
 func TestReplaceJSONStr(t *testing.T) {
 	const jsonData = `{"auth":{"identity":{"fullname":"John Doe"}}}`
 
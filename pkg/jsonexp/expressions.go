@@ -3,6 +3,7 @@ package jsonexp
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/tidwall/gjson"
 )
@@ -16,6 +17,11 @@ const (
 	IncludesOperator
 	ExcludesOperator
 	RegexOperator
+	// case-insensitive variants of the string comparison operators
+	EqualFoldOperator
+	NotEqualFoldOperator
+	IncludesFoldOperator
+	ExcludesFoldOperator
 )
 
 func (o *Operator) String() string {
@@ -30,6 +36,14 @@ func (o *Operator) String() string {
 		return "excl"
 	case RegexOperator:
 		return "matches"
+	case EqualFoldOperator:
+		return "ieq"
+	case NotEqualFoldOperator:
+		return "ineq"
+	case IncludesFoldOperator:
+		return "iincl"
+	case ExcludesFoldOperator:
+		return "iexcl"
 	}
 	return "unknown"
 }
@@ -46,6 +60,14 @@ func OperatorFromString(operator string) Operator {
 		return ExcludesOperator
 	case "matches":
 		return RegexOperator
+	case "ieq":
+		return EqualFoldOperator
+	case "ineq":
+		return NotEqualFoldOperator
+	case "iincl":
+		return IncludesFoldOperator
+	case "iexcl":
+		return ExcludesFoldOperator
 	}
 	return UnknownOperator
 }
@@ -90,6 +112,28 @@ func (p Pattern) Matches(json string) (bool, error) {
 		}
 		return re.MatchString(obtainedValue.String()), nil
 
+	case EqualFoldOperator:
+		return strings.EqualFold(expectedValue, obtainedValue.String()), nil
+
+	case NotEqualFoldOperator:
+		return !strings.EqualFold(expectedValue, obtainedValue.String()), nil
+
+	case IncludesFoldOperator:
+		for _, item := range obtainedValue.Array() {
+			if strings.EqualFold(expectedValue, item.String()) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case ExcludesFoldOperator:
+		for _, item := range obtainedValue.Array() {
+			if strings.EqualFold(expectedValue, item.String()) {
+				return false, nil
+			}
+		}
+		return true, nil
+
 	default:
 		return false, fmt.Errorf("unsupported operator for json authorization")
 	}
@@ -549,3 +549,37 @@ func TestOrAnd(t *testing.T) {
 	assert.NilError(t, err)
 	assert.Check(t, ok)
 }
+
+func TestCaseInsensitivePatterns(t *testing.T) {
+	const data = `{"method":"GET","groups":["Admins","Users"]}`
+
+	testCases := []struct {
+		pattern  Pattern
+		expected bool
+	}{
+		{Pattern{Selector: "method", Operator: EqualOperator, Value: "get"}, false},
+		{Pattern{Selector: "method", Operator: EqualFoldOperator, Value: "get"}, true},
+		{Pattern{Selector: "method", Operator: EqualFoldOperator, Value: "post"}, false},
+		{Pattern{Selector: "method", Operator: NotEqualFoldOperator, Value: "get"}, false},
+		{Pattern{Selector: "method", Operator: NotEqualFoldOperator, Value: "post"}, true},
+		{Pattern{Selector: "groups", Operator: IncludesOperator, Value: "admins"}, false},
+		{Pattern{Selector: "groups", Operator: IncludesFoldOperator, Value: "admins"}, true},
+		{Pattern{Selector: "groups", Operator: IncludesFoldOperator, Value: "guests"}, false},
+		{Pattern{Selector: "groups", Operator: ExcludesFoldOperator, Value: "ADMINS"}, false},
+		{Pattern{Selector: "groups", Operator: ExcludesFoldOperator, Value: "guests"}, true},
+	}
+
+	for _, tc := range testCases {
+		ok, err := tc.pattern.Matches(data)
+		assert.NilError(t, err)
+		assert.Equal(t, ok, tc.expected, tc.pattern.String())
+	}
+}
+
+func TestOperatorFromString(t *testing.T) {
+	for _, operator := range []string{"eq", "neq", "incl", "excl", "matches", "ieq", "ineq", "iincl", "iexcl"} {
+		o := OperatorFromString(operator)
+		assert.Equal(t, o.String(), operator)
+	}
+	assert.Equal(t, OperatorFromString("other"), UnknownOperator)
+}
@@ -1,11 +1,16 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ExemplarTraceIDLabel is the label of the exemplars that holds the ID of the trace of the observed request
+const ExemplarTraceIDLabel = "trace_id"
+
 var DeepMetricsEnabled = false
 
 type Object interface {
@@ -28,6 +33,16 @@ func NewCounterMetric(name, help string, labels ...string) *prometheus.CounterVe
 	)
 }
 
+func NewGaugeMetric(name, help string, labels ...string) *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: name,
+			Help: help,
+		},
+		labels,
+	)
+}
+
 func NewDurationMetric(name, help string, labels ...string) *prometheus.HistogramVec {
 	return prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -53,9 +68,11 @@ func ReportMetricWithObject(metric *prometheus.CounterVec, obj Object, labels ..
 	}
 }
 
-func ReportTimedMetric(metric *prometheus.HistogramVec, f func(), labels ...string) {
+// ReportTimedMetric observes the duration of f in the histogram, with an exemplar carrying the ID of the trace in the
+// context, if the trace is sampled
+func ReportTimedMetric(ctx context.Context, metric *prometheus.HistogramVec, f func(), labels ...string) {
 	timer := prometheus.NewTimer(prometheus.ObserverFunc(func(value float64) {
-		metric.WithLabelValues(labels...).Observe(value)
+		observe(ctx, metric.WithLabelValues(labels...), value)
 	}))
 
 	defer func() {
@@ -65,18 +82,41 @@ func ReportTimedMetric(metric *prometheus.HistogramVec, f func(), labels ...stri
 	f()
 }
 
-func ReportTimedMetricWithStatus(metric *prometheus.HistogramVec, f func(), status string, labels ...string) {
-	ReportTimedMetric(metric, f, extendLabelValuesWithStatus(status, labels...)...)
+func ReportTimedMetricWithStatus(ctx context.Context, metric *prometheus.HistogramVec, f func(), status string, labels ...string) {
+	ReportTimedMetric(ctx, metric, f, extendLabelValuesWithStatus(status, labels...)...)
 }
 
-func ReportTimedMetricWithObject(metric *prometheus.HistogramVec, f func(), obj Object, labels ...string) {
+func ReportTimedMetricWithObject(ctx context.Context, metric *prometheus.HistogramVec, f func(), obj Object, labels ...string) {
 	if labels, err := extendLabelValuesWithObject(obj, labels...); err == nil {
-		ReportTimedMetric(metric, f, labels...)
+		ReportTimedMetric(ctx, metric, f, labels...)
 	} else {
 		f()
 	}
 }
 
+func observe(ctx context.Context, observer prometheus.Observer, value float64) {
+	if exemplar := exemplarFromContext(ctx); exemplar != nil {
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+			exemplarObserver.ObserveWithExemplar(value, exemplar)
+			return
+		}
+	}
+	observer.Observe(value)
+}
+
+// exemplarFromContext returns the labels of the exemplar that links to the trace in the context, or nil if there is no
+// sampled trace to link to
+func exemplarFromContext(ctx context.Context) prometheus.Labels {
+	if ctx == nil {
+		return nil
+	}
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || !spanContext.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{ExemplarTraceIDLabel: spanContext.TraceID().String()}
+}
+
 func extendLabelValuesWithStatus(status string, baseLabels ...string) []string {
 	labels := make([]string, len(baseLabels))
 	copy(labels, baseLabels)
@@ -1,12 +1,16 @@
 package metrics
 
 import (
+	"context"
 	"testing"
 
 	mock_metrics "github.com/kuadrant/authorino/pkg/metrics/mocks"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
 	"gotest.tools/assert"
 )
 
@@ -52,7 +56,7 @@ func TestReportTimedMetric(t *testing.T) {
 	f := func() {
 		invoked = true
 	}
-	ReportTimedMetric(metric, f)
+	ReportTimedMetric(context.TODO(), metric, f)
 	assert.Equal(t, 1, testutil.CollectAndCount(metric))
 	assert.Check(t, invoked)
 }
@@ -63,11 +67,51 @@ func TestReportTimedMetricWithStatus(t *testing.T) {
 	f := func() {
 		invoked = true
 	}
-	ReportTimedMetricWithStatus(metric, f, "OK")
+	ReportTimedMetricWithStatus(context.TODO(), metric, f, "OK")
 	assert.Equal(t, 1, testutil.CollectAndCount(metric))
 	assert.Check(t, invoked)
 }
 
+func TestReportTimedMetricWithExemplar(t *testing.T) {
+	collectExemplars := func(metric *prometheus.HistogramVec) []*dto.Exemplar {
+		ch := make(chan prometheus.Metric, 1)
+		metric.Collect(ch)
+		var m dto.Metric
+		assert.NilError(t, (<-ch).Write(&m))
+		var exemplars []*dto.Exemplar
+		for _, bucket := range m.GetHistogram().GetBucket() {
+			if exemplar := bucket.GetExemplar(); exemplar != nil {
+				exemplars = append(exemplars, exemplar)
+			}
+		}
+		return exemplars
+	}
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+
+	// sampled trace
+	metric := NewDurationMetric("foo", "Foo metric")
+	ctx := trace.ContextWithSpanContext(context.TODO(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled}))
+	ReportTimedMetric(ctx, metric, func() {})
+	exemplars := collectExemplars(metric)
+	assert.Equal(t, len(exemplars), 1)
+	assert.Equal(t, len(exemplars[0].GetLabel()), 1)
+	assert.Equal(t, exemplars[0].GetLabel()[0].GetName(), ExemplarTraceIDLabel)
+	assert.Equal(t, exemplars[0].GetLabel()[0].GetValue(), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	// trace not sampled
+	metric = NewDurationMetric("foo", "Foo metric")
+	ctx = trace.ContextWithSpanContext(context.TODO(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+	ReportTimedMetric(ctx, metric, func() {})
+	assert.Equal(t, len(collectExemplars(metric)), 0)
+
+	// no trace
+	metric = NewDurationMetric("foo", "Foo metric")
+	ReportTimedMetric(context.TODO(), metric, func() {})
+	assert.Equal(t, len(collectExemplars(metric)), 0)
+}
+
 func TestReportTimedMetricWithObject(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -83,13 +127,13 @@ func TestReportTimedMetricWithObject(t *testing.T) {
 	object.EXPECT().GetName().Return("foo")
 
 	object.EXPECT().MetricsEnabled().Return(true)
-	ReportTimedMetricWithObject(metric, f, object)
+	ReportTimedMetricWithObject(context.TODO(), metric, f, object)
 	assert.Equal(t, 1, testutil.CollectAndCount(metric))
 	assert.Check(t, invoked)
 
 	invoked = false
 	object.EXPECT().MetricsEnabled().Return(false)
-	ReportTimedMetricWithObject(metric, f, object)
+	ReportTimedMetricWithObject(context.TODO(), metric, f, object)
 	assert.Equal(t, 1, testutil.CollectAndCount(metric))
 	assert.Check(t, invoked)
 }
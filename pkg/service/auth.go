@@ -12,12 +12,15 @@ import (
 
 	gocontext "golang.org/x/net/context"
 
+	"github.com/kuadrant/authorino/pkg/audit"
 	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/context"
+	"github.com/kuadrant/authorino/pkg/evaluators"
 	"github.com/kuadrant/authorino/pkg/index"
 	"github.com/kuadrant/authorino/pkg/log"
 	"github.com/kuadrant/authorino/pkg/metrics"
 	"github.com/kuadrant/authorino/pkg/trace"
+	"github.com/kuadrant/authorino/pkg/utils"
 
 	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
@@ -27,6 +30,7 @@ import (
 	otel_codes "go.opentelemetry.io/otel/codes"
 	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	v1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -46,8 +50,26 @@ const (
 	HTTP_MESSAGE_503 = "service unavailable"
 
 	X_LOOKUP_KEY_NAME = "host"
+
+	// Decisions for requests to hosts with no AuthConfig in the index
+	UnknownHostNotFound = "not-found" // denied as not found (404)
+	UnknownHostDeny     = "deny"      // denied as forbidden (403)
+	UnknownHostAllow    = "allow"     // allowed, leaving the decision to the upstream
 )
 
+// UnknownHostDecision is the decision for requests to hosts with no AuthConfig in the index - one of: not-found, deny, allow
+var UnknownHostDecision = UnknownHostNotFound
+
+// ValidateUnknownHostDecision tells whether a decision for requests to unknown hosts is supported
+func ValidateUnknownHostDecision(decision string) error {
+	switch decision {
+	case UnknownHostNotFound, UnknownHostDeny, UnknownHostAllow:
+		return nil
+	default:
+		return fmt.Errorf("unsupported decision for unknown hosts: %s", decision)
+	}
+}
+
 var (
 	statusCodeMapping = map[rpc.Code]envoy_type.StatusCode{
 		rpc.OK:                  envoy_type.StatusCode_OK,
@@ -56,6 +78,7 @@ var (
 		rpc.NOT_FOUND:           envoy_type.StatusCode_NotFound,
 		rpc.UNAUTHENTICATED:     envoy_type.StatusCode_Unauthorized,
 		rpc.PERMISSION_DENIED:   envoy_type.StatusCode_Forbidden,
+		rpc.UNAVAILABLE:         envoy_type.StatusCode_ServiceUnavailable,
 	}
 
 	authServerResponseStatusMetric = metrics.NewCounterMetric("auth_server_response_status", "Response status of authconfigs sent by the auth server.", "status")
@@ -76,6 +99,7 @@ type AuthService struct {
 	Index                  index.Index
 	Timeout                time.Duration
 	MaxHttpRequestBodySize int64
+	Auditor                *audit.Auditor
 }
 
 func NewAuthService(index index.Index, timeout time.Duration, maxHttpRequestBodySize int64) *AuthService {
@@ -137,7 +161,7 @@ func (a *AuthService) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	metrics.ReportTimedMetric(httpServerDuration, func() {
+	metrics.ReportTimedMetric(ctx, httpServerDuration, func() {
 		headers := make(map[string]string)
 		for key, values := range req.Header {
 			headers[strings.ToLower(key)] = strings.Join(values, " ")
@@ -218,7 +242,7 @@ func (a *AuthService) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 			respStatusCode = statusCodeMapping[code]
 			var headers []*envoy_core.HeaderValueOption
 			if code == rpc.OK {
-				headers = checkResponse.GetOkResponse().GetHeaders()
+				headers = append(checkResponse.GetOkResponse().GetHeaders(), checkResponse.GetOkResponse().GetResponseHeadersToAdd()...)
 			} else {
 				headers = checkResponse.GetDeniedResponse().GetHeaders()
 				respBody = []byte(checkResponse.GetDeniedResponse().GetBody())
@@ -262,7 +286,8 @@ func (a *AuthService) Check(parentContext gocontext.Context, req *envoy_auth.Che
 	defer span.End()
 
 	requestLogger := logger.WithValues("request id", requestId)
-	ctx = log.IntoContext(context.New(context.WithParent(ctx), context.WithTimeout(a.Timeout)), requestLogger)
+	spanCtx := ctx
+	ctx = log.IntoContext(context.New(context.WithParent(spanCtx), context.WithTimeout(a.Timeout)), requestLogger)
 
 	a.logAuthRequest(req, ctx)
 
@@ -271,26 +296,36 @@ func (a *AuthService) Check(parentContext gocontext.Context, req *envoy_auth.Che
 	if h, overridden := req.Attributes.ContextExtensions[X_LOOKUP_KEY_NAME]; overridden {
 		host = h
 	} else {
-		host = requestData.Host
+		host = auth.RequestHost(requestData)
 	}
 
-	authConfig := a.Index.Get(host)
-	// If the host is not found, but contains a port, remove the port part and retry.
-	if authConfig == nil && strings.Contains(host, ":") {
-		splitHost := strings.Split(host, ":")
-		authConfig = a.Index.Get(splitHost[0])
-	}
+	authConfig := findAuthConfig(a.Index, host)
 
-	// If we couldn't find the AuthConfig in the config, we return and deny.
-	if authConfig == nil {
-		result := auth.AuthResult{Code: rpc.NOT_FOUND, Message: RESPONSE_MESSAGE_SERVICE_NOT_FOUND}
+	// If we couldn't find the AuthConfig in the config, we return the decision for unknown hosts (by default, deny).
+	// The hosts of evicted AuthConfigs are denied as not found, regardless of the decision for unknown hosts.
+	if authConfig == nil || authConfig.Evicted {
+		result := unknownHostResult()
+		if authConfig != nil {
+			result = auth.AuthResult{Code: rpc.NOT_FOUND, Message: RESPONSE_MESSAGE_SERVICE_NOT_FOUND}
+		}
 		a.logAuthResult(result, ctx)
+		a.auditAuthResult(requestData, result)
+		if result.Success() {
+			return a.successResponse(result, ctx), nil
+		}
 		return a.deniedResponse(result), nil
 	}
 
+	// the timeout of the AuthConfig overrides the global one
+	if authConfig.Timeout > 0 && authConfig.Timeout != a.Timeout {
+		context.Cancel(ctx)
+		ctx = log.IntoContext(context.New(context.WithParent(spanCtx), context.WithTimeout(authConfig.Timeout)), requestLogger)
+	}
+
 	if err := context.CheckContext(ctx); err != nil {
 		result := auth.AuthResult{Code: rpc.UNAVAILABLE}
 		a.logAuthResult(result, ctx)
+		a.auditAuthResult(requestData, result)
 		context.Cancel(ctx)
 		span.RecordError(err)
 		span.SetStatus(otel_codes.Error, err.Error())
@@ -301,6 +336,7 @@ func (a *AuthService) Check(parentContext gocontext.Context, req *envoy_auth.Che
 	result := pipeline.Evaluate()
 
 	a.logAuthResult(result, ctx)
+	a.auditAuthResult(requestData, result)
 
 	if result.Success() {
 		return a.successResponse(result, ctx), nil
@@ -309,6 +345,28 @@ func (a *AuthService) Check(parentContext gocontext.Context, req *envoy_auth.Che
 	}
 }
 
+// findAuthConfig looks up the AuthConfig of a host in the index.
+// If the host is not found, but contains a port, the port part is removed and the lookup retried.
+func findAuthConfig(authConfigIndex index.Index, host string) *evaluators.AuthConfig {
+	authConfig := authConfigIndex.Get(host)
+	if authConfig == nil && strings.Contains(host, ":") {
+		splitHost := strings.Split(host, ":")
+		authConfig = authConfigIndex.Get(splitHost[0])
+	}
+	return authConfig
+}
+
+func unknownHostResult() auth.AuthResult {
+	switch UnknownHostDecision {
+	case UnknownHostAllow:
+		return auth.AuthResult{Code: rpc.OK}
+	case UnknownHostDeny:
+		return auth.AuthResult{Code: rpc.PERMISSION_DENIED, Message: RESPONSE_MESSAGE_SERVICE_NOT_FOUND}
+	default:
+		return auth.AuthResult{Code: rpc.NOT_FOUND, Message: RESPONSE_MESSAGE_SERVICE_NOT_FOUND}
+	}
+}
+
 func (a *AuthService) successResponse(authResult auth.AuthResult, ctx gocontext.Context) *envoy_auth.CheckResponse {
 	dynamicMetadata, err := buildEnvoyDynamicMetadata(authResult.Metadata)
 	if err != nil {
@@ -324,7 +382,8 @@ func (a *AuthService) successResponse(authResult auth.AuthResult, ctx gocontext.
 		},
 		HttpResponse: &envoy_auth.CheckResponse_OkResponse{
 			OkResponse: &envoy_auth.OkHttpResponse{
-				Headers: buildResponseHeaders(authResult.Headers),
+				Headers:              buildOkResponseHeaders(authResult.Headers, authResult.AppendHeaders),
+				ResponseHeadersToAdd: buildConditionOutcomesHeader(authResult.ConditionOutcomes),
 			},
 		},
 		DynamicMetadata: dynamicMetadata,
@@ -340,6 +399,11 @@ func (a *AuthService) deniedResponse(authResult auth.AuthResult) *envoy_auth.Che
 		httpCode = statusCodeMapping[code]
 	}
 
+	var dynamicMetadata *structpb.Struct
+	if len(authResult.Metadata) > 0 {
+		dynamicMetadata, _ = buildEnvoyDynamicMetadata(authResult.Metadata)
+	}
+
 	return &envoy_auth.CheckResponse{
 		Status: &rpcstatus.Status{
 			Code: int32(code),
@@ -349,10 +413,11 @@ func (a *AuthService) deniedResponse(authResult auth.AuthResult) *envoy_auth.Che
 				Status: &envoy_type.HttpStatus{
 					Code: httpCode,
 				},
-				Headers: buildResponseHeadersWithReason(authResult.Message, authResult.Headers),
+				Headers: append(buildResponseHeadersWithReason(authResult.Message, authResult.Headers), buildConditionOutcomesHeader(authResult.ConditionOutcomes)...),
 				Body:    authResult.Body,
 			},
 		},
+		DynamicMetadata: dynamicMetadata,
 	}
 }
 
@@ -412,11 +477,31 @@ func (a *AuthService) logAuthResult(result auth.AuthResult, ctx gocontext.Contex
 	if logger.V(1).Enabled() {
 		if !success {
 			baseLogData = append(baseLogData, "object", result)
+		} else if len(result.ConditionOutcomes) > 0 {
+			baseLogData = append(baseLogData, "conditions", result.ConditionOutcomes)
 		}
 		logger.V(1).Info("outgoing authorization response", baseLogData...) // debug
 	}
 }
 
+func (a *AuthService) auditAuthResult(httpAttrs *envoy_auth.AttributeContext_HttpRequest, result auth.AuthResult) {
+	if a.Auditor == nil {
+		return
+	}
+
+	a.Auditor.Emit(audit.Record{
+		Time:       time.Now(),
+		RequestId:  httpAttrs.Id,
+		Host:       httpAttrs.Host,
+		Method:     httpAttrs.Method,
+		Path:       strings.Split(httpAttrs.Path, "?")[0],
+		Authorized: result.Success(),
+		Code:       result.Code.String(),
+		Status:     int32(result.Status),
+		Message:    result.Message,
+	})
+}
+
 func buildResponseHeaders(headers []map[string]string) []*envoy_core.HeaderValueOption {
 	responseHeaders := make([]*envoy_core.HeaderValueOption, 0)
 
@@ -434,6 +519,29 @@ func buildResponseHeaders(headers []map[string]string) []*envoy_core.HeaderValue
 	return responseHeaders
 }
 
+// buildOkResponseHeaders builds the HTTP headers to add to the request to the upstream, telling explicitly whether
+// each header is appended to the header of same name already set in the request or overwrites it
+func buildOkResponseHeaders(headers []map[string]string, appendHeaders []string) []*envoy_core.HeaderValueOption {
+	responseHeaders := buildResponseHeaders(headers)
+	for _, header := range responseHeaders {
+		header.Append = wrapperspb.Bool(utils.SliceContains(appendHeaders, header.Header.GetKey()))
+	}
+	return responseHeaders
+}
+
+// buildConditionOutcomesHeader builds the debug HTTP header with the outcomes of the conditions evaluated in the auth
+// pipeline, if enabled
+func buildConditionOutcomesHeader(outcomes []auth.ConditionOutcome) []*envoy_core.HeaderValueOption {
+	if ConditionOutcomesHeader == "" || len(outcomes) == 0 {
+		return nil
+	}
+	value, err := json.Marshal(outcomes)
+	if err != nil {
+		return nil
+	}
+	return buildResponseHeaders([]map[string]string{{ConditionOutcomesHeader: string(value)}})
+}
+
 func buildResponseHeadersWithReason(authReason string, extraHeaders []map[string]string) []*envoy_core.HeaderValueOption {
 	var headers []map[string]string
 
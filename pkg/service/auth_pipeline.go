@@ -2,13 +2,17 @@ package service
 
 import (
 	gojson "encoding/json"
+	"errors"
 	"fmt"
+	"runtime/debug"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/context"
 	"github.com/kuadrant/authorino/pkg/evaluators"
+	"github.com/kuadrant/authorino/pkg/evaluators/identity"
 	"github.com/kuadrant/authorino/pkg/json"
 	"github.com/kuadrant/authorino/pkg/jsonexp"
 	"github.com/kuadrant/authorino/pkg/log"
@@ -17,9 +21,34 @@ import (
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/gogo/googleapis/google/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
 	gocontext "golang.org/x/net/context"
 )
 
+const (
+	shadowDecisionAllowed = "allowed"
+	shadowDecisionDenied  = "denied"
+
+	msgEvaluatorPanic      = "internal error in the evaluator"
+	msgAmbiguousIdentity   = "ambiguous credentials: more than one identity resolved"
+	msgIdentityUnavailable = "identity provider unavailable"
+	msgSubjectDenied       = "subject denied"
+
+	identityResultSuccess     = "success"
+	identityResultFailure     = "failure"
+	identityResultUnavailable = "unavailable"
+	identityResultSkipped     = "skipped"
+	identityResultAnonymous   = "anonymous"
+
+	evaluatorOutcomeSuccess   = "success"
+	evaluatorOutcomeFailure   = "failure"
+	evaluatorOutcomeSkipped   = "skipped"
+	evaluatorOutcomeCancelled = "cancelled"
+)
+
+var errDryRun = errors.New("dry run")
+
 var (
 	evaluatorMetricLabels = []string{"evaluator_type", "evaluator_name"}
 
@@ -29,10 +58,18 @@ var (
 	authServerEvaluatorIgnoredMetric   = metrics.NewAuthConfigCounterMetric("auth_server_evaluator_ignored", "Number of evaluations of individual authconfig rule ignored by the auth server.", evaluatorMetricLabels...)
 	authServerEvaluatorDeniedMetric    = metrics.NewAuthConfigCounterMetric("auth_server_evaluator_denied", "Number of denials from individual authconfig rule evaluated by the auth server.", evaluatorMetricLabels...)
 	authServerEvaluatorDurationMetric  = metrics.NewAuthConfigDurationMetric("auth_server_evaluator_duration_seconds", "Response latency of individual authconfig rule evaluated by the auth server (in seconds).", evaluatorMetricLabels...)
+	authServerEvaluatorPanicsMetric    = metrics.NewAuthConfigCounterMetric("auth_server_recovered_panics", "Number of panics recovered from individual authconfig rules evaluated by the auth server.", evaluatorMetricLabels...)
 	// authconfig metrics
 	authServerAuthConfigTotalMetric          = metrics.NewAuthConfigCounterMetric("auth_server_authconfig_total", "Total number of authconfigs enforced by the auth server, partitioned by authconfig.")
 	authServerAuthConfigResponseStatusMetric = metrics.NewAuthConfigCounterMetric("auth_server_authconfig_response_status", "Response status of authconfigs sent by the auth server, partitioned by authconfig.", "status")
 	authServerAuthConfigDurationMetric       = metrics.NewAuthConfigDurationMetric("auth_server_authconfig_duration_seconds", "Response latency of authconfig enforced by the auth server (in seconds).")
+	// shadow mode metrics
+	authServerAuthorizationShadowDecisionMetric = metrics.NewAuthConfigCounterMetric("auth_server_authorization_shadow_decision", "Decisions of authorization rules evaluated in shadow mode by the auth server, i.e. not enforced.", "authorization", "decision")
+	// maintenance bypass metrics
+	authServerMaintenanceBypassMetric = metrics.NewAuthConfigCounterMetric("auth_server_maintenance_bypass_total", "Number of requests that bypassed the auth pipeline with a valid maintenance header, partitioned by authconfig.")
+	authServerSubjectOverrideMetric   = metrics.NewAuthConfigCounterMetric("auth_server_subject_override_total", "Number of requests denied or allowed by the global subject overrides regardless of the authconfig, partitioned by authconfig.", "decision")
+	// identity metrics
+	identityResultMetric = metrics.NewAuthConfigCounterMetric("authorino_identity_result_total", "Results of the identity verification per identity source, i.e. success, failure, unavailable (identity provider not available), skipped or anonymous.", "name", "type", "result")
 )
 
 func init() {
@@ -42,9 +79,14 @@ func init() {
 		authServerEvaluatorIgnoredMetric,
 		authServerEvaluatorDeniedMetric,
 		authServerEvaluatorDurationMetric,
+		authServerEvaluatorPanicsMetric,
 		authServerAuthConfigTotalMetric,
 		authServerAuthConfigResponseStatusMetric,
 		authServerAuthConfigDurationMetric,
+		authServerAuthorizationShadowDecisionMetric,
+		authServerMaintenanceBypassMetric,
+		authServerSubjectOverrideMetric,
+		identityResultMetric,
 	)
 }
 
@@ -70,12 +112,26 @@ func newEvaluationResponse(evaluator auth.AuthConfigEvaluator, obj interface{},
 	}
 }
 
+// MaxRequestBodyCaptureSize is the maximum size of the body of the request captured in the auth pipeline and shared
+// across the evaluators - in bytes; zero for no limit besides the one of the proxy
+var MaxRequestBodyCaptureSize int64
+
+// ConditionOutcomesHeader is the name of the HTTP header to add to the response with the outcomes of the conditions
+// evaluated in the auth pipeline; empty for not adding the header
+var ConditionOutcomesHeader string
+
+// DistinguishAnonymousIdentity makes identities resolved by anonymous access to be reported with a distinct result in
+// the metrics (result=anonymous) instead of as successes, and omitted from the per-request "identity validated" logs
+var DistinguishAnonymousIdentity bool
+
 // NewAuthPipeline creates an AuthPipeline instance
 func NewAuthPipeline(parentCtx gocontext.Context, req *envoy_auth.CheckRequest, authConfig evaluators.AuthConfig) auth.AuthPipeline {
 	logger := log.FromContext(parentCtx).WithName("authpipeline")
 
+	captureRequestBody(req, !authConfig.OmitRequestBody, MaxRequestBodyCaptureSize)
+
 	return &AuthPipeline{
-		Context:       log.IntoContext(parentCtx, logger),
+		Context:       context.WithMemo(log.IntoContext(parentCtx, logger)),
 		Request:       req,
 		AuthConfig:    &authConfig,
 		Identity:      make(map[*evaluators.IdentityConfig]interface{}),
@@ -85,6 +141,34 @@ func NewAuthPipeline(parentCtx gocontext.Context, req *envoy_auth.CheckRequest,
 		Callbacks:     make(map[*evaluators.CallbackConfig]interface{}),
 		Logger:        logger,
 		mu:            sync.RWMutex{},
+
+		CaptureConditionOutcomes: ConditionOutcomesHeader != "" || logger.V(1).Enabled(),
+	}
+}
+
+// captureRequestBody captures the body of the request once for the whole auth pipeline, capped to maxSize bytes, so all
+// the evaluators read the same body from the authorization JSON.
+// If no evaluator needs the body, the body is dropped, so it is not carried along in every authorization JSON built
+// throughout the pipeline.
+func captureRequestBody(req *envoy_auth.CheckRequest, needed bool, maxSize int64) {
+	httpRequest := req.GetAttributes().GetRequest().GetHttp()
+	if httpRequest == nil {
+		return
+	}
+
+	if !needed {
+		httpRequest.Body = ""
+		httpRequest.RawBody = nil
+		return
+	}
+
+	if maxSize > 0 {
+		if int64(len(httpRequest.Body)) > maxSize {
+			httpRequest.Body = httpRequest.Body[:maxSize]
+		}
+		if int64(len(httpRequest.RawBody)) > maxSize {
+			httpRequest.RawBody = httpRequest.RawBody[:maxSize]
+		}
 	}
 }
 
@@ -104,36 +188,59 @@ type AuthPipeline struct {
 
 	Logger log.Logger
 
+	// CaptureConditionOutcomes enables recording the outcomes of the conditions evaluated throughout the pipeline
+	CaptureConditionOutcomes bool
+	conditionOutcomes        []auth.ConditionOutcome
+
+	// CaptureEvaluatorTrace enables recording the outcomes of the evaluators throughout the pipeline
+	CaptureEvaluatorTrace bool
+	evaluatorTrace        []auth.EvaluatorTrace
+
+	// identityUnavailable tells whether an identity could not be verified for the identity provider being temporarily
+	// unavailable, in which case the request is denied as such instead of unauthenticated, unless identityRejected
+	identityUnavailable bool
+	// identityRejected tells whether an identity source failed to verify a credential supplied in the request, in which
+	// case the request is denied as unauthenticated even if another identity provider is unavailable
+	identityRejected bool
+
+	// denial is the reason of the denial of the request, exposed in the Authorization JSON to the customizations of the
+	// denial; nil while the request is not denied
+	denial *DenialAttributes
+
 	mu sync.RWMutex
 }
 
 func (pipeline *AuthPipeline) evaluateAuthConfig(config auth.AuthConfigEvaluator, ctx gocontext.Context, respChannel *chan EvaluationResponse, successCallback func(), failureCallback func()) {
 	monitorable, _ := config.(metrics.Object)
-	metrics.ReportMetricWithObject(authServerEvaluatorTotalMetric, monitorable, pipeline.metricLabels()...)
+	pipeline.reportMetricWithObject(authServerEvaluatorTotalMetric, monitorable, pipeline.metricLabels()...)
 
 	if err := context.CheckContext(ctx); err != nil {
 		pipeline.Logger.V(1).Info("skipping config", "config", config, "reason", err)
-		metrics.ReportMetricWithObject(authServerEvaluatorCancelledMetric, monitorable, pipeline.metricLabels()...)
+		pipeline.reportMetricWithObject(authServerEvaluatorCancelledMetric, monitorable, pipeline.metricLabels()...)
+		pipeline.recordEvaluatorTrace(config, evaluatorOutcomeCancelled, err)
 		return
 	}
 
 	if conditionalEv, ok := config.(auth.ConditionalEvaluator); ok {
-		if err := pipeline.evaluateConditions(conditionalEv.GetConditions()); err != nil {
-			metrics.ReportMetricWithObject(authServerEvaluatorIgnoredMetric, monitorable, pipeline.metricLabels()...)
+		if err := pipeline.evaluateConditions(conditionalEv.GetConditions(), config); err != nil {
+			pipeline.reportMetricWithObject(authServerEvaluatorIgnoredMetric, monitorable, pipeline.metricLabels()...)
+			pipeline.recordEvaluatorTrace(config, evaluatorOutcomeSkipped, err)
 			return
 		}
 	}
 
 	evaluateFunc := func() {
-		if authObj, err := config.Call(pipeline, ctx); err != nil {
+		if authObj, err := pipeline.callAuthConfig(config, ctx); err != nil {
+			pipeline.recordEvaluatorTrace(config, evaluatorOutcomeFailure, err)
 			*respChannel <- newEvaluationResponse(config, nil, err)
 
-			metrics.ReportMetricWithObject(authServerEvaluatorDeniedMetric, monitorable, pipeline.metricLabels()...)
+			pipeline.reportMetricWithObject(authServerEvaluatorDeniedMetric, monitorable, pipeline.metricLabels()...)
 
 			if failureCallback != nil {
 				failureCallback()
 			}
 		} else {
+			pipeline.recordEvaluatorTrace(config, evaluatorOutcomeSuccess, nil)
 			*respChannel <- newEvaluationResponse(config, authObj, nil)
 
 			if successCallback != nil {
@@ -142,7 +249,32 @@ func (pipeline *AuthPipeline) evaluateAuthConfig(config auth.AuthConfigEvaluator
 		}
 	}
 
-	metrics.ReportTimedMetricWithObject(authServerEvaluatorDurationMetric, evaluateFunc, monitorable, pipeline.metricLabels()...)
+	pipeline.reportTimedMetricWithObject(authServerEvaluatorDurationMetric, evaluateFunc, monitorable, pipeline.metricLabels()...)
+}
+
+// callAuthConfig calls the evaluator, recovering from panics.
+// A panic is turned into an error, so the evaluator fails the same way as when it cannot evaluate the request (fail-closed),
+// instead of crashing the server.
+func (pipeline *AuthPipeline) callAuthConfig(config auth.AuthConfigEvaluator, ctx gocontext.Context) (obj interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var name, evaluatorType string
+			if namedEv, ok := config.(auth.NamedEvaluator); ok {
+				name = namedEv.GetName()
+			}
+			if typedEv, ok := config.(auth.TypedEvaluator); ok {
+				evaluatorType = typedEv.GetType()
+			}
+
+			obj = nil
+			err = errors.New(msgEvaluatorPanic)
+
+			pipeline.Logger.Error(fmt.Errorf("%v", r), "recovered from panic in the evaluator", "config", name, "type", evaluatorType, "stack", string(debug.Stack()))
+			pipeline.reportMetric(authServerEvaluatorPanicsMetric, append(pipeline.metricLabels(), evaluatorType, name)...)
+		}
+	}()
+
+	return config.Call(pipeline, ctx)
 }
 
 type authConfigEvaluationStrategy func(conf auth.AuthConfigEvaluator, ctx gocontext.Context, respChannel *chan EvaluationResponse, cancel func())
@@ -171,6 +303,10 @@ func (pipeline *AuthPipeline) evaluateOneAuthConfig(authConfigs []auth.AuthConfi
 
 func (pipeline *AuthPipeline) evaluateAllAuthConfigs(authConfigs []auth.AuthConfigEvaluator, respChannel *chan EvaluationResponse) {
 	pipeline.evaluateAuthConfigs(authConfigs, respChannel, func(conf auth.AuthConfigEvaluator, ctx gocontext.Context, respChannel *chan EvaluationResponse, cancel func()) {
+		if isShadow(conf) {
+			pipeline.evaluateAuthConfig(conf, ctx, respChannel, nil, nil) // failures in shadow mode are not enforced
+			return
+		}
 		pipeline.evaluateAuthConfig(conf, ctx, respChannel, nil, cancel) // cancels the context if at least one thread fails
 	})
 }
@@ -181,6 +317,12 @@ func (pipeline *AuthPipeline) evaluateAnyAuthConfig(authConfigs []auth.AuthConfi
 	})
 }
 
+func isShadow(conf auth.AuthConfigEvaluator) bool {
+	shadowEv, ok := conf.(auth.ShadowEvaluator)
+	return ok && shadowEv.IsShadow()
+}
+
+// groupAuthConfigsByPriority groups the configs by priority, keeping the order of the configs within each priority
 func groupAuthConfigsByPriority(authConfigs []auth.AuthConfigEvaluator) (map[int][]auth.AuthConfigEvaluator, []int) {
 	priorities := []int{}
 	authConfigsByPriority := make(map[int][]auth.AuthConfigEvaluator)
@@ -201,11 +343,20 @@ func groupAuthConfigsByPriority(authConfigs []auth.AuthConfigEvaluator) (map[int
 }
 
 func (pipeline *AuthPipeline) evaluateIdentityConfigs() EvaluationResponse {
+	if pipeline.AuthConfig.StrictIdentity {
+		return pipeline.evaluateIdentityConfigsStrictly()
+	}
+
 	logger := pipeline.Logger.WithName("identity").V(1)
 	authConfigsByPriority, priorities := groupAuthConfigsByPriority(pipeline.AuthConfig.IdentityConfigs)
 	count := len(pipeline.AuthConfig.IdentityConfigs)
 	errors := make(map[string]string)
 
+	// identity configs without a result are the ones not evaluated, e.g. due to unmatching conditions, or whose result was
+	// not needed, because another identity config succeeded first
+	results := make(map[*evaluators.IdentityConfig]string)
+	defer pipeline.reportIdentityResults(results)
+
 	for _, priority := range priorities {
 		configs := authConfigsByPriority[priority]
 		respChannel := make(chan EvaluationResponse, len(configs))
@@ -227,6 +378,7 @@ func (pipeline *AuthPipeline) evaluateIdentityConfigs() EvaluationResponse {
 
 				if extendedObj, err := conf.ResolveExtendedProperties(pipeline); err != nil {
 					resp.Error = err
+					results[conf] = identityResultFailure
 					logger.Error(err, "failed to extend identity object", "config", conf, "object", obj)
 					if count == 1 {
 						return resp
@@ -235,12 +387,14 @@ func (pipeline *AuthPipeline) evaluateIdentityConfigs() EvaluationResponse {
 					}
 				} else {
 					pipeline.setIdentityObj(conf, extendedObj)
+					results[conf] = identitySuccessResult(conf)
 
-					logger.Info("identity validated", "config", conf, "object", extendedObj)
+					logIdentityValidated(logger, conf, extendedObj)
 					return resp
 				}
 			} else {
 				err := resp.Error
+				results[conf] = pipeline.identityFailureResult(err)
 				logger.Info("cannot validate identity", "config", conf, "reason", err)
 				if count == 1 {
 					return resp
@@ -257,8 +411,136 @@ func (pipeline *AuthPipeline) evaluateIdentityConfigs() EvaluationResponse {
 	}
 }
 
+// evaluateIdentityConfigsStrictly evaluates all the identity configs, regardless of priority, and succeeds only if
+// exactly one of them resolves an identity
+func (pipeline *AuthPipeline) evaluateIdentityConfigsStrictly() EvaluationResponse {
+	logger := pipeline.Logger.WithName("identity").V(1)
+	configs := pipeline.AuthConfig.IdentityConfigs
+	errs := make(map[string]string)
+
+	results := make(map[*evaluators.IdentityConfig]string)
+	defer pipeline.reportIdentityResults(results)
+
+	respChannel := make(chan EvaluationResponse, len(configs))
+	go func() {
+		defer close(respChannel)
+		pipeline.evaluateAnyAuthConfig(configs, &respChannel)
+	}()
+
+	var resolved []EvaluationResponse
+	var lastFailure EvaluationResponse
+	for resp := range respChannel {
+		conf, _ := resp.Evaluator.(*evaluators.IdentityConfig)
+		if resp.Success() {
+			resolved = append(resolved, resp)
+			results[conf] = identitySuccessResult(conf)
+		} else {
+			results[conf] = pipeline.identityFailureResult(resp.Error)
+			errs[conf.Name] = resp.GetErrorMessage()
+			lastFailure = resp
+			logger.Info("cannot validate identity", "config", conf, "reason", resp.Error)
+		}
+	}
+
+	switch len(resolved) {
+	case 0:
+		if len(configs) == 1 {
+			return lastFailure
+		}
+		errorsJSON, _ := gojson.Marshal(errs)
+		return EvaluationResponse{
+			Error: fmt.Errorf("%s", errorsJSON),
+		}
+
+	case 1:
+		resp := resolved[0]
+		conf, _ := resp.Evaluator.(*evaluators.IdentityConfig)
+		pipeline.setIdentityObj(conf, resp.Object)
+		if extendedObj, err := conf.ResolveExtendedProperties(pipeline); err != nil {
+			results[conf] = identityResultFailure
+			logger.Error(err, "failed to extend identity object", "config", conf, "object", resp.Object)
+			resp.Error = err
+			return resp
+		} else {
+			pipeline.setIdentityObj(conf, extendedObj)
+			logIdentityValidated(logger, conf, extendedObj)
+			return resp
+		}
+
+	default:
+		names := make([]string, 0, len(resolved))
+		for _, resp := range resolved {
+			conf, _ := resp.Evaluator.(*evaluators.IdentityConfig)
+			names = append(names, conf.Name)
+		}
+		sort.Strings(names)
+		logger.Info("ambiguous identity", "configs", names)
+		return EvaluationResponse{
+			Error: errors.New(msgAmbiguousIdentity),
+		}
+	}
+}
+
+// identityFailureResult returns the result of an identity config that failed with the given error, flagging the
+// pipeline to respond as temporarily unavailable if the identity provider is not available and the config says so, or
+// as unauthenticated if the credential supplied in the request is invalid
+func (pipeline *AuthPipeline) identityFailureResult(err error) string {
+	var unavailable *identity.ProviderUnavailableError
+	if !errors.As(err, &unavailable) {
+		if !auth.IsCredentialNotFound(err) {
+			pipeline.identityRejected = true
+		}
+		return identityResultFailure
+	}
+	if unavailable.Retryable {
+		pipeline.identityUnavailable = true
+	}
+	return identityResultUnavailable
+}
+
+// isAnonymousIdentity tells whether the identity config resolves anonymous identities that shall be distinguished from
+// the authenticated ones
+func isAnonymousIdentity(conf *evaluators.IdentityConfig) bool {
+	return DistinguishAnonymousIdentity && conf.Noop != nil
+}
+
+func identitySuccessResult(conf *evaluators.IdentityConfig) string {
+	if isAnonymousIdentity(conf) {
+		return identityResultAnonymous
+	}
+	return identityResultSuccess
+}
+
+func logIdentityValidated(logger log.Logger, conf *evaluators.IdentityConfig, obj interface{}) {
+	if isAnonymousIdentity(conf) {
+		return
+	}
+	logger.Info("identity validated", "config", conf, "object", obj)
+}
+
+func (pipeline *AuthPipeline) reportIdentityResults(results map[*evaluators.IdentityConfig]string) {
+	for _, config := range pipeline.AuthConfig.IdentityConfigs {
+		conf, ok := config.(*evaluators.IdentityConfig)
+		if !ok {
+			continue
+		}
+		result, evaluated := results[conf]
+		if !evaluated {
+			result = identityResultSkipped
+		}
+		pipeline.reportMetric(identityResultMetric, append(pipeline.metricLabels(), conf.Name, conf.GetType(), result)...)
+	}
+}
+
 func (pipeline *AuthPipeline) evaluateMetadataConfigs() {
 	logger := pipeline.Logger.WithName("metadata").V(1)
+
+	// metadata sources are external services, that may change their state upon the requests
+	if pipeline.isDryRun() {
+		pipeline.skipForDryRun(pipeline.AuthConfig.MetadataConfigs, logger)
+		return
+	}
+
 	authConfigsByPriority, priorities := groupAuthConfigsByPriority(pipeline.AuthConfig.MetadataConfigs)
 
 	for _, priority := range priorities {
@@ -295,9 +577,10 @@ func (pipeline *AuthPipeline) evaluateAuthorizationConfigs() EvaluationResponse
 
 	authConfigsByPriority, priorities := groupAuthConfigsByPriority(pipeline.AuthConfig.AuthorizationConfigs)
 
-	for _, priority := range priorities {
+	for i, priority := range priorities {
 		configs := authConfigsByPriority[priority]
 		respChannel := make(chan EvaluationResponse, len(configs))
+		var shortCircuitedBy *evaluators.AuthorizationConfig
 
 		go func() {
 			defer close(respChannel)
@@ -308,22 +591,83 @@ func (pipeline *AuthPipeline) evaluateAuthorizationConfigs() EvaluationResponse
 			conf, _ := resp.Evaluator.(*evaluators.AuthorizationConfig)
 			obj := resp.Object
 
+			if isShadow(resp.Evaluator) {
+				pipeline.reportShadowDecision(conf, resp)
+				continue
+			}
+
 			if resp.Success() {
 				pipeline.setAuthorizationObj(conf, obj)
 				logger.Info("access granted", "config", conf, "object", obj)
+				if shortCircuits(resp.Evaluator) && shortCircuitedBy == nil {
+					shortCircuitedBy = conf
+				}
 			} else {
 				logger.Info("access denied", "config", conf, "reason", resp.Error)
 				return resp
 			}
 		}
+
+		// the configs of the same priority are enforced regardless, as they are evaluated concurrently
+		if shortCircuitedBy != nil {
+			pipeline.skipShortCircuited(shortCircuitedBy, priorities[i+1:], authConfigsByPriority, logger)
+			break
+		}
 	}
 
 	return EvaluationResponse{}
 }
 
+func shortCircuits(conf auth.AuthConfigEvaluator) bool {
+	shortCircuitEv, ok := conf.(auth.ShortCircuitEvaluator)
+	return ok && shortCircuitEv.ShortCircuitsOnSuccess()
+}
+
+// skipShortCircuited records the authorization configs of the remaining priorities as skipped, after the authorization
+// phase was ended by a config that short-circuits when access is granted
+func (pipeline *AuthPipeline) skipShortCircuited(conf *evaluators.AuthorizationConfig, priorities []int, authConfigsByPriority map[int][]auth.AuthConfigEvaluator, logger log.Logger) {
+	reason := fmt.Errorf("short-circuited by %s", conf.Name)
+	for _, priority := range priorities {
+		for _, skipped := range authConfigsByPriority[priority] {
+			logger.Info("skipping config", "config", skipped, "reason", reason)
+			pipeline.recordEvaluatorTrace(skipped, evaluatorOutcomeSkipped, reason)
+		}
+	}
+}
+
+func (pipeline *AuthPipeline) reportShadowDecision(conf *evaluators.AuthorizationConfig, resp EvaluationResponse) {
+	logger := pipeline.Logger.WithName("authorization").WithName("shadow")
+	decision := shadowDecisionAllowed
+
+	if resp.Success() {
+		logger.Info("shadow authorization decision", "config", conf.Name, "authorized", true)
+	} else {
+		decision = shadowDecisionDenied
+		logger.Info("shadow authorization decision", "config", conf.Name, "authorized", false, "reason", resp.Error)
+	}
+
+	pipeline.reportMetric(authServerAuthorizationShadowDecisionMetric, append(pipeline.metricLabels(), conf.Name, decision)...)
+}
+
 func (pipeline *AuthPipeline) evaluateResponseConfigs() {
+	responseConfigs := make([]auth.AuthConfigEvaluator, 0, len(pipeline.AuthConfig.ResponseConfigs))
+	combinedResponseConfigs := make([]auth.AuthConfigEvaluator, 0)
+	for _, conf := range pipeline.AuthConfig.ResponseConfigs {
+		if responseConfig, ok := conf.(*evaluators.ResponseConfig); ok && responseConfig.IsCombined() {
+			combinedResponseConfigs = append(combinedResponseConfigs, conf)
+		} else {
+			responseConfigs = append(responseConfigs, conf)
+		}
+	}
+
+	pipeline.evaluateResponseConfigsByPriority(responseConfigs)
+	// combined responses are built last, so they can refer to the output of any other response
+	pipeline.evaluateResponseConfigsByPriority(combinedResponseConfigs)
+}
+
+func (pipeline *AuthPipeline) evaluateResponseConfigsByPriority(responseConfigs []auth.AuthConfigEvaluator) {
 	logger := pipeline.Logger.WithName("response").V(1)
-	authConfigsByPriority, priorities := groupAuthConfigsByPriority(pipeline.AuthConfig.ResponseConfigs)
+	authConfigsByPriority, priorities := groupAuthConfigsByPriority(responseConfigs)
 
 	for _, priority := range priorities {
 		configs := authConfigsByPriority[priority]
@@ -350,6 +694,12 @@ func (pipeline *AuthPipeline) evaluateResponseConfigs() {
 
 func (pipeline *AuthPipeline) executeCallbacks() {
 	logger := pipeline.Logger.WithName("callbacks").V(1)
+
+	if pipeline.isDryRun() {
+		pipeline.skipForDryRun(pipeline.AuthConfig.CallbackConfigs, logger)
+		return
+	}
+
 	authConfigsByPriority, priorities := groupAuthConfigsByPriority(pipeline.AuthConfig.CallbackConfigs)
 
 	for _, priority := range priorities {
@@ -375,11 +725,15 @@ func (pipeline *AuthPipeline) executeCallbacks() {
 	}
 }
 
-func (pipeline *AuthPipeline) evaluateConditions(conditions jsonexp.Expression) error {
+// evaluateConditions evaluates the conditions of an evaluator, or the top-level conditions of the AuthConfig if the
+// evaluator is nil
+func (pipeline *AuthPipeline) evaluateConditions(conditions jsonexp.Expression, config auth.AuthConfigEvaluator) error {
 	if conditions == nil {
 		return nil
 	}
-	if match, err := conditions.Matches(pipeline.GetAuthorizationJSON()); err != nil {
+	match, err := conditions.Matches(pipeline.GetAuthorizationJSON())
+	pipeline.recordConditionOutcome(config, match, err)
+	if err != nil {
 		return err
 	} else if !match {
 		return fmt.Errorf("unmatching conditions for config")
@@ -387,6 +741,112 @@ func (pipeline *AuthPipeline) evaluateConditions(conditions jsonexp.Expression)
 	return nil
 }
 
+func (pipeline *AuthPipeline) recordConditionOutcome(config auth.AuthConfigEvaluator, match bool, err error) {
+	if !pipeline.CaptureConditionOutcomes {
+		return
+	}
+
+	outcome := auth.ConditionOutcome{Phase: evaluatorPhase(config), Matched: match && err == nil}
+	if namedEv, ok := config.(auth.NamedEvaluator); ok {
+		outcome.Name = namedEv.GetName()
+	}
+	if err != nil {
+		outcome.Error = err.Error()
+	}
+
+	pipeline.mu.Lock()
+	defer pipeline.mu.Unlock()
+	pipeline.conditionOutcomes = append(pipeline.conditionOutcomes, outcome)
+}
+
+// recordEvaluatorTrace records the outcome of an evaluator, if capturing the trace of the evaluators is enabled
+func (pipeline *AuthPipeline) recordEvaluatorTrace(config auth.AuthConfigEvaluator, outcome string, err error) {
+	if !pipeline.CaptureEvaluatorTrace {
+		return
+	}
+
+	trace := auth.EvaluatorTrace{Phase: evaluatorPhase(config), Outcome: outcome}
+	if namedEv, ok := config.(auth.NamedEvaluator); ok {
+		trace.Name = namedEv.GetName()
+	}
+	if typedEv, ok := config.(auth.TypedEvaluator); ok {
+		trace.Type = typedEv.GetType()
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	}
+
+	pipeline.mu.Lock()
+	defer pipeline.mu.Unlock()
+	pipeline.evaluatorTrace = append(pipeline.evaluatorTrace, trace)
+}
+
+var pipelinePhases = []string{"authconfig", "identity", "metadata", "authorization", "response", "callbacks"}
+
+// pipelinePhaseIndex returns the position of a phase in the order the phases of the auth pipeline are evaluated
+func pipelinePhaseIndex(phase string) int {
+	for i, p := range pipelinePhases {
+		if p == phase {
+			return i
+		}
+	}
+	return len(pipelinePhases)
+}
+
+func evaluatorPhase(config auth.AuthConfigEvaluator) string {
+	switch config.(type) {
+	case nil:
+		return "authconfig"
+	case *evaluators.IdentityConfig:
+		return "identity"
+	case *evaluators.MetadataConfig:
+		return "metadata"
+	case *evaluators.AuthorizationConfig:
+		return "authorization"
+	case *evaluators.ResponseConfig:
+		return "response"
+	case *evaluators.CallbackConfig:
+		return "callbacks"
+	default:
+		return "unknown"
+	}
+}
+
+// getConditionOutcomes returns the outcomes of the conditions recorded so far, sorted by phase of the pipeline and
+// name of the evaluator, so they read the same regardless of the order the evaluators ran concurrently
+func (pipeline *AuthPipeline) getConditionOutcomes() []auth.ConditionOutcome {
+	pipeline.mu.RLock()
+	defer pipeline.mu.RUnlock()
+	if len(pipeline.conditionOutcomes) == 0 {
+		return nil
+	}
+	outcomes := make([]auth.ConditionOutcome, len(pipeline.conditionOutcomes))
+	copy(outcomes, pipeline.conditionOutcomes)
+	sort.SliceStable(outcomes, func(i, j int) bool {
+		if pi, pj := pipelinePhaseIndex(outcomes[i].Phase), pipelinePhaseIndex(outcomes[j].Phase); pi != pj {
+			return pi < pj
+		}
+		return outcomes[i].Name < outcomes[j].Name
+	})
+	return outcomes
+}
+
+// getEvaluatorTrace returns the results of the evaluators recorded so far, sorted by phase of the pipeline and name of
+// the evaluator, so they read the same regardless of the order the evaluators ran concurrently
+func (pipeline *AuthPipeline) getEvaluatorTrace() []auth.EvaluatorTrace {
+	pipeline.mu.RLock()
+	defer pipeline.mu.RUnlock()
+	trace := make([]auth.EvaluatorTrace, len(pipeline.evaluatorTrace))
+	copy(trace, pipeline.evaluatorTrace)
+	sort.SliceStable(trace, func(i, j int) bool {
+		if pi, pj := pipelinePhaseIndex(trace[i].Phase), pipelinePhaseIndex(trace[j].Phase); pi != pj {
+			return pi < pj
+		}
+		return trace[i].Name < trace[j].Name
+	})
+	return trace
+}
+
 func getObjs[T any](m map[*T]interface{}, pipeline *AuthPipeline) map[*T]interface{} {
 	pipeline.mu.RLock()
 	defer pipeline.mu.RUnlock()
@@ -451,12 +911,19 @@ func (pipeline *AuthPipeline) setCallbackObj(conf *evaluators.CallbackConfig, ob
 func (pipeline *AuthPipeline) Evaluate() auth.AuthResult {
 	result := auth.AuthResult{Code: rpc.OK}
 
-	if err := pipeline.evaluateConditions(pipeline.AuthConfig.Conditions); err != nil {
+	if err := pipeline.evaluateConditions(pipeline.AuthConfig.Conditions, nil); err != nil {
 		pipeline.Logger.V(1).Info("skipping", "reason", err)
+		result.ConditionOutcomes = pipeline.getConditionOutcomes()
 		return result
 	}
 
-	metrics.ReportMetric(authServerAuthConfigTotalMetric, pipeline.metricLabels()...)
+	pipeline.reportMetric(authServerAuthConfigTotalMetric, pipeline.metricLabels()...)
+
+	if pipeline.bypassedForMaintenance() {
+		pipeline.reportStatusMetric(result.Code)
+		result.ConditionOutcomes = pipeline.getConditionOutcomes()
+		return result
+	}
 
 	authResult := make(chan auth.AuthResult)
 
@@ -466,24 +933,40 @@ func (pipeline *AuthPipeline) Evaluate() auth.AuthResult {
 		evaluateFunc := func() {
 			// phase 1: identity verification
 			if resp := pipeline.evaluateIdentityConfigs(); !resp.Success() {
-				result.Code = rpc.UNAUTHENTICATED
-				result.Message = resp.GetErrorMessage()
-				result.Headers = pipeline.AuthConfig.GetChallengeHeaders()
-				result = pipeline.customizeDenyWith(result, pipeline.AuthConfig.Unauthenticated)
+				if pipeline.identityUnavailable && !pipeline.identityRejected {
+					result.Code = rpc.UNAVAILABLE
+					result.Status = envoy_type.StatusCode_ServiceUnavailable
+					result.Message = msgIdentityUnavailable
+				} else {
+					result.Code = rpc.UNAUTHENTICATED
+					result.Message = resp.GetErrorMessage()
+					result.Headers = pipeline.AuthConfig.GetChallengeHeaders()
+					pipeline.setDenial(resp)
+					result = pipeline.customizeDenyWith(result, pipeline.AuthConfig.Unauthenticated)
+				}
+			} else if override := pipeline.subjectOverride(); override == SubjectOverrideDeny {
+				result.Code = rpc.PERMISSION_DENIED
+				result.Message = msgSubjectDenied
 			} else {
 				// phase 2: external metadata
 				pipeline.evaluateMetadataConfigs()
 
-				// phase 3: policy enforcement (authorization)
-				if resp := pipeline.evaluateAuthorizationConfigs(); !resp.Success() {
+				// phase 3: policy enforcement (authorization), skipped for force-allowed subjects
+				var resp EvaluationResponse
+				if override != SubjectOverrideAllow {
+					resp = pipeline.evaluateAuthorizationConfigs()
+				}
+				if !resp.Success() {
 					result.Code = rpc.PERMISSION_DENIED
 					result.Message = resp.GetErrorMessage()
+					pipeline.setDenial(resp)
 					result = pipeline.customizeDenyWith(result, pipeline.AuthConfig.Unauthorized)
 				} else {
 					// phase 4: response
 					pipeline.evaluateResponseConfigs()
 					responseHeaders, responseMetadata := evaluators.WrapResponses(pipeline.Response)
 					result.Headers = []map[string]string{responseHeaders}
+					result.AppendHeaders = evaluators.AppendedResponseHeaders(pipeline.Response)
 					result.Metadata = responseMetadata
 				}
 			}
@@ -492,17 +975,110 @@ func (pipeline *AuthPipeline) Evaluate() auth.AuthResult {
 			pipeline.executeCallbacks()
 
 			pipeline.reportStatusMetric(result.Code)
+			result.ConditionOutcomes = pipeline.getConditionOutcomes()
 			authResult <- result
 		}
 
-		metrics.ReportTimedMetric(authServerAuthConfigDurationMetric, evaluateFunc, pipeline.metricLabels()...)
+		pipeline.reportTimedMetric(authServerAuthConfigDurationMetric, evaluateFunc, pipeline.metricLabels()...)
 	}()
 
 	return <-authResult
 }
 
+// subjectOverride returns the decision of the global subject overrides for the subject of the request in the AuthConfig,
+// i.e. deny or allow, or empty if the subject is not listed
+func (pipeline *AuthPipeline) subjectOverride() string {
+	overrides := SubjectOverrides
+	if overrides == nil || overrides.Empty() {
+		return ""
+	}
+
+	subject := gjson.Get(pipeline.GetAuthorizationJSON(), SubjectOverridesSelector).String()
+	labels := pipeline.AuthConfig.Labels
+	decision := overrides.Decision(labels["namespace"]+"/"+labels["name"], subject)
+	if decision == "" {
+		return ""
+	}
+
+	pipeline.Logger.Info("SUBJECT OVERRIDE: authconfig overridden for the subject", "subject", subject, "decision", decision)
+	pipeline.reportMetric(authServerSubjectOverrideMetric, append(pipeline.metricLabels(), decision)...)
+	return decision
+}
+
+// bypassedForMaintenance tells whether the request carries a valid signed maintenance header, in which case the auth
+// pipeline is skipped and the request is allowed. Invalid maintenance headers are ignored, i.e. the request goes through
+// the auth pipeline as usual.
+func (pipeline *AuthPipeline) bypassedForMaintenance() bool {
+	bypass := pipeline.AuthConfig.MaintenanceBypass
+	if bypass == nil {
+		return false
+	}
+
+	httpRequest := pipeline.GetHttp()
+	ok, err := bypass.Verify(httpRequest.GetHost(), httpRequest.GetHeaders(), bypass.Now())
+	if err != nil {
+		pipeline.Logger.Info("maintenance bypass rejected", "reason", err.Error())
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	pipeline.Logger.Info("MAINTENANCE BYPASS: request allowed without auth", "host", httpRequest.GetHost(), "header", bypass.Header)
+	pipeline.reportMetric(authServerMaintenanceBypassMetric, pipeline.metricLabels()...)
+	return true
+}
+
 func (pipeline *AuthPipeline) reportStatusMetric(rpcStatusCode rpc.Code) {
-	metrics.ReportMetricWithStatus(authServerAuthConfigResponseStatusMetric, rpc.Code_name[int32(rpcStatusCode)], pipeline.metricLabels()...)
+	pipeline.reportMetricWithStatus(authServerAuthConfigResponseStatusMetric, rpc.Code_name[int32(rpcStatusCode)], pipeline.metricLabels()...)
+}
+
+// isDryRun tells whether the pipeline evaluates the request without side effects, i.e. without calling the metadata
+// sources nor executing the callbacks, without storing data in the caches of the evaluators and without reporting metrics
+func (pipeline *AuthPipeline) isDryRun() bool {
+	return context.IsDryRun(pipeline.Context)
+}
+
+// skipForDryRun records the configs as skipped in the trace of the evaluators, without calling them
+func (pipeline *AuthPipeline) skipForDryRun(configs []auth.AuthConfigEvaluator, logger log.Logger) {
+	for _, conf := range configs {
+		logger.Info("skipping config", "config", conf, "reason", errDryRun)
+		pipeline.recordEvaluatorTrace(conf, evaluatorOutcomeSkipped, errDryRun)
+	}
+}
+
+func (pipeline *AuthPipeline) reportMetric(metric *prometheus.CounterVec, labels ...string) {
+	if !pipeline.isDryRun() {
+		metrics.ReportMetric(metric, labels...)
+	}
+}
+
+func (pipeline *AuthPipeline) reportMetricWithStatus(metric *prometheus.CounterVec, status string, labels ...string) {
+	if !pipeline.isDryRun() {
+		metrics.ReportMetricWithStatus(metric, status, labels...)
+	}
+}
+
+func (pipeline *AuthPipeline) reportMetricWithObject(metric *prometheus.CounterVec, obj metrics.Object, labels ...string) {
+	if !pipeline.isDryRun() {
+		metrics.ReportMetricWithObject(metric, obj, labels...)
+	}
+}
+
+func (pipeline *AuthPipeline) reportTimedMetric(metric *prometheus.HistogramVec, f func(), labels ...string) {
+	if pipeline.isDryRun() {
+		f()
+		return
+	}
+	metrics.ReportTimedMetric(pipeline.Context, metric, f, labels...)
+}
+
+func (pipeline *AuthPipeline) reportTimedMetricWithObject(metric *prometheus.HistogramVec, f func(), obj metrics.Object, labels ...string) {
+	if pipeline.isDryRun() {
+		f()
+		return
+	}
+	metrics.ReportTimedMetricWithObject(pipeline.Context, metric, f, obj, labels...)
 }
 
 func (pipeline *AuthPipeline) metricLabels() []string {
@@ -543,7 +1119,11 @@ func (pipeline *AuthPipeline) GetAuthorizationJSON() string {
 	authData := make(map[string]interface{})
 
 	// identity
-	_, authData["identity"] = pipeline.GetResolvedIdentity()
+	var identitySource interface{}
+	identitySource, authData["identity"] = pipeline.GetResolvedIdentity()
+	if conf, ok := identitySource.(*evaluators.IdentityConfig); ok {
+		authData["identitySource"] = &IdentitySourceAttributes{Name: conf.Name, Type: conf.GetType()}
+	}
 
 	// metadata
 	metadata := make(map[string]interface{})
@@ -575,6 +1155,11 @@ func (pipeline *AuthPipeline) GetAuthorizationJSON() string {
 		authData["callbacks"] = callbacks
 	}
 
+	// denial
+	if pipeline.denial != nil {
+		authData["denial"] = pipeline.denial
+	}
+
 	return NewAuthorizationJSON(pipeline.GetRequest(), authData)
 }
 
@@ -597,18 +1182,66 @@ func (pipeline *AuthPipeline) customizeDenyWith(authResult auth.AuthResult, deny
 		if len(denyWith.Headers) > 0 {
 			headers := make([]map[string]string, 0)
 			for _, header := range denyWith.Headers {
+				if conditions, ok := denyWith.HeaderConditions[header.Name]; ok {
+					if match, err := conditions.Matches(authJSON); err != nil || !match {
+						continue
+					}
+				}
 				value, _ := json.StringifyJSON(header.Value.ResolveFor(authJSON))
 				headers = append(headers, map[string]string{header.Name: value})
 			}
 			authResult.Headers = headers
 		}
+
+		if len(denyWith.DynamicMetadata) > 0 {
+			metadata := make(map[string]interface{}, len(denyWith.DynamicMetadata))
+			for _, property := range denyWith.DynamicMetadata {
+				metadata[property.Name] = property.Value.ResolveFor(authJSON)
+			}
+			authResult.Metadata = metadata
+		}
+
+		if denyWith.Problem != nil {
+			status := authResult.Status
+			if status == 0 {
+				status = statusCodeMapping[authResult.Code]
+			}
+			if problem, err := denyWith.Problem.Build(int32(status), authResult.Message, authJSON); err == nil {
+				authResult.Body = problem
+				if !hasHeader(authResult.Headers, "Content-Type") {
+					authResult.Headers = append(authResult.Headers, map[string]string{"Content-Type": evaluators.ProblemDetailsContentType})
+				}
+			}
+		}
 	}
 
 	return authResult
 }
 
+// setDenial records the reason of the denial of the request from the response of the failed evaluation
+func (pipeline *AuthPipeline) setDenial(resp EvaluationResponse) {
+	denial := &DenialAttributes{Reason: resp.GetErrorMessage()}
+	if conf, ok := resp.Evaluator.(*evaluators.AuthorizationConfig); ok {
+		denial.Name = conf.Name
+		denial.Type = conf.GetType()
+	}
+	pipeline.denial = denial
+}
+
+// hasHeader tells whether a header is set in a list of headers, regardless of the case of the name
+func hasHeader(headers []map[string]string, name string) bool {
+	for _, headerMap := range headers {
+		for headerName := range headerMap {
+			if strings.EqualFold(headerName, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func NewAuthorizationJSON(request *envoy_auth.CheckRequest, authPipeline map[string]any) string {
-	authJSON, _ := gojson.Marshal(&authorizationJSON{
+	authJSON, _ := json.Marshal(&authorizationJSON{
 		Context:             request.Attributes,
 		WellKnownAttributes: NewWellKnownAttributes(request.Attributes, authPipeline),
 	})
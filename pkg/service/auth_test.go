@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	gohttptest "net/http/httptest"
 
 	"golang.org/x/net/context"
 	"gotest.tools/assert"
 
+	"github.com/kuadrant/authorino/pkg/audit"
 	"github.com/kuadrant/authorino/pkg/auth"
 	"github.com/kuadrant/authorino/pkg/evaluators"
 	"github.com/kuadrant/authorino/pkg/evaluators/authorization"
@@ -56,6 +59,26 @@ func TestSuccessResponse(t *testing.T) {
 	assert.Equal(t, getHeader(resp.GetHeaders(), "X-Custom-Header"), "some-value")
 }
 
+func TestSuccessResponseHeaderAppendModes(t *testing.T) {
+	service := AuthService{
+		Index: index.NewIndex(),
+	}
+
+	headers := []map[string]string{{"X-Appended": "a", "X-Overwritten": "b"}}
+	resp := service.successResponse(auth.AuthResult{Headers: headers, AppendHeaders: []string{"X-Appended"}}, nil).GetOkResponse()
+
+	assert.Equal(t, len(resp.GetHeaders()), 2)
+	for _, header := range resp.GetHeaders() {
+		switch header.Header.GetKey() {
+		case "X-Appended":
+			assert.Check(t, header.GetAppend().GetValue())
+		case "X-Overwritten":
+			assert.Check(t, header.GetAppend() != nil)
+			assert.Check(t, !header.GetAppend().GetValue())
+		}
+	}
+}
+
 func TestDeniedResponse(t *testing.T) {
 	service := AuthService{
 		Index: index.NewIndex(),
@@ -90,6 +113,89 @@ func TestDeniedResponse(t *testing.T) {
 	assert.Equal(t, len(resp.GetHeaders()), 2)
 }
 
+func TestDeniedResponseWithDynamicMetadata(t *testing.T) {
+	service := AuthService{
+		Index: index.NewIndex(),
+	}
+
+	checkResp := service.deniedResponse(auth.AuthResult{Code: rpc.PERMISSION_DENIED, Message: "Unauthorized", Metadata: map[string]interface{}{"denied-subject": "john"}})
+	assert.Equal(t, checkResp.GetDeniedResponse().Status.Code, envoy_type.StatusCode_Forbidden)
+	assert.Equal(t, checkResp.GetDynamicMetadata().GetFields()["denied-subject"].GetStringValue(), "john")
+	assert.Equal(t, getHeader(checkResp.GetDeniedResponse().GetHeaders(), "denied-subject"), "")
+
+	checkResp = service.deniedResponse(auth.AuthResult{Code: rpc.PERMISSION_DENIED, Message: "Unauthorized"})
+	assert.Assert(t, checkResp.GetDynamicMetadata() == nil)
+}
+
+func TestConditionOutcomesHeader(t *testing.T) {
+	service := AuthService{
+		Index: index.NewIndex(),
+	}
+	outcomes := []auth.ConditionOutcome{{Phase: "authconfig", Matched: true}, {Phase: "authorization", Name: "admin", Matched: false}}
+
+	okResp := service.successResponse(auth.AuthResult{ConditionOutcomes: outcomes}, nil).GetOkResponse()
+	assert.Equal(t, len(okResp.GetResponseHeadersToAdd()), 0) // disabled by default
+
+	defer func(header string) { ConditionOutcomesHeader = header }(ConditionOutcomesHeader)
+	ConditionOutcomesHeader = "X-Authorino-Conditions"
+	expected := `[{"phase":"authconfig","matched":true},{"phase":"authorization","name":"admin","matched":false}]`
+
+	okResp = service.successResponse(auth.AuthResult{ConditionOutcomes: outcomes}, nil).GetOkResponse()
+	assert.Equal(t, getHeader(okResp.GetResponseHeadersToAdd(), "X-Authorino-Conditions"), expected)
+	assert.Equal(t, len(okResp.GetHeaders()), 0)
+
+	deniedResp := service.deniedResponse(auth.AuthResult{Code: rpc.PERMISSION_DENIED, Message: "Unauthorized", ConditionOutcomes: outcomes}).GetDeniedResponse()
+	assert.Equal(t, getHeader(deniedResp.GetHeaders(), X_EXT_AUTH_REASON_HEADER), "Unauthorized")
+	assert.Equal(t, getHeader(deniedResp.GetHeaders(), "X-Authorino-Conditions"), expected)
+
+	deniedResp = service.deniedResponse(auth.AuthResult{Code: rpc.NOT_FOUND, Message: "Service not found"}).GetDeniedResponse()
+	assert.Equal(t, len(deniedResp.GetHeaders()), 1)
+}
+
+func TestAuthConfigTimeoutOverride(t *testing.T) {
+	// token introspection endpoint that takes 200ms to respond
+	introspectionServer := gohttptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"active":true,"sub":"john"}`))
+		case <-r.Context().Done():
+		}
+	}))
+	defer introspectionServer.Close()
+
+	newSlowAuthConfig := func(timeout time.Duration) *evaluators.AuthConfig {
+		authCred := auth.NewAuthCredential("", "")
+		identityConfig := &evaluators.IdentityConfig{Name: "introspection", OAuth2: identity.NewOAuth2Identity(introspectionServer.URL, "access_token", "client", "secret", authCred)}
+		return &evaluators.AuthConfig{IdentityConfigs: []auth.AuthConfigEvaluator{identityConfig}, Timeout: timeout}
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	i := mock_index.NewMockIndex(ctrl)
+	service := AuthService{Index: i, Timeout: 50 * time.Millisecond}
+
+	newRequest := func(host string) *envoy_auth.CheckRequest {
+		return &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+			Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Host: host, Headers: map[string]string{"authorization": "Bearer some-token"}}},
+		}}
+	}
+
+	// with override: longer budget
+	i.EXPECT().Get("legacy.com").Return(newSlowAuthConfig(5 * time.Second))
+	resp, err := service.Check(context.TODO(), newRequest("legacy.com"))
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status.Code, int32(rpc.OK))
+
+	// without override: global timeout
+	i.EXPECT().Get("fast.com").Return(newSlowAuthConfig(0))
+	start := time.Now()
+	resp, err = service.Check(context.TODO(), newRequest("fast.com"))
+	assert.NilError(t, err)
+	assert.Check(t, resp.Status.Code != int32(rpc.OK))
+	assert.Check(t, time.Since(start) < 200*time.Millisecond)
+}
+
 func TestAuthConfigLookup(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -131,6 +237,144 @@ func TestAuthConfigLookup(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestUnknownHostDecision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	i := mock_index.NewMockIndex(ctrl)
+	service := AuthService{Index: i}
+	request := &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+		Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Host: "unknown.com"}},
+	}}
+
+	defer func() { UnknownHostDecision = UnknownHostNotFound }()
+
+	// fail closed (default)
+	i.EXPECT().Get("unknown.com").Return(nil)
+	resp, err := service.Check(context.TODO(), request)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status.Code, int32(rpc.NOT_FOUND))
+	assert.Equal(t, resp.GetDeniedResponse().Status.Code, envoy_type.StatusCode_NotFound)
+
+	// fail closed as forbidden
+	UnknownHostDecision = UnknownHostDeny
+	i.EXPECT().Get("unknown.com").Return(nil)
+	resp, err = service.Check(context.TODO(), request)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status.Code, int32(rpc.PERMISSION_DENIED))
+	assert.Equal(t, resp.GetDeniedResponse().Status.Code, envoy_type.StatusCode_Forbidden)
+	assert.Equal(t, getHeader(resp.GetDeniedResponse().GetHeaders(), X_EXT_AUTH_REASON_HEADER), RESPONSE_MESSAGE_SERVICE_NOT_FOUND)
+
+	// fail open
+	UnknownHostDecision = UnknownHostAllow
+	i.EXPECT().Get("unknown.com").Return(nil)
+	resp, err = service.Check(context.TODO(), request)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status.Code, int32(rpc.OK))
+	assert.Check(t, resp.GetOkResponse() != nil)
+
+	// evicted hosts fail closed regardless
+	i.EXPECT().Get("unknown.com").Return(&evaluators.AuthConfig{Evicted: true})
+	resp, err = service.Check(context.TODO(), request)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status.Code, int32(rpc.NOT_FOUND))
+	assert.Equal(t, resp.GetDeniedResponse().Status.Code, envoy_type.StatusCode_NotFound)
+}
+
+func TestValidateUnknownHostDecision(t *testing.T) {
+	for _, decision := range []string{UnknownHostNotFound, UnknownHostDeny, UnknownHostAllow} {
+		assert.NilError(t, ValidateUnknownHostDecision(decision))
+	}
+	assert.Error(t, ValidateUnknownHostDecision("maybe"), "unsupported decision for unknown hosts: maybe")
+}
+
+func TestAuthConfigLookupByAuthority(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	i := mock_index.NewMockIndex(ctrl)
+	service := AuthService{Index: i}
+	authConfig := &evaluators.AuthConfig{}
+
+	var resp *envoy_auth.CheckResponse
+	var err error
+
+	// http/2
+	i.EXPECT().Get("host.com").Return(authConfig)
+	resp, err = service.Check(context.TODO(), &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+		Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Headers: map[string]string{":authority": "Host.com"}, Protocol: "HTTP/2"}},
+	}})
+	assert.Equal(t, int32(resp.GetDeniedResponse().Status.Code), int32(401))
+	assert.NilError(t, err)
+
+	// http/2, :authority preferred over host
+	i.EXPECT().Get("host.com").Return(authConfig)
+	resp, err = service.Check(context.TODO(), &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+		Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Host: "other.com", Headers: map[string]string{":authority": "host.com"}, Protocol: "HTTP/2"}},
+	}})
+	assert.Equal(t, int32(resp.GetDeniedResponse().Status.Code), int32(401))
+	assert.NilError(t, err)
+
+	// http/1.1
+	i.EXPECT().Get("host.com").Return(authConfig)
+	resp, err = service.Check(context.TODO(), &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+		Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Headers: map[string]string{"host": "HOST.com."}, Protocol: "HTTP/1.1"}},
+	}})
+	assert.Equal(t, int32(resp.GetDeniedResponse().Status.Code), int32(401))
+	assert.NilError(t, err)
+
+	// http/1.1 with port
+	i.EXPECT().Get("host.com:8000").Return(nil)
+	i.EXPECT().Get("host.com").Return(authConfig)
+	resp, err = service.Check(context.TODO(), &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+		Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Host: "Host.com:8000", Protocol: "HTTP/1.1"}},
+	}})
+	assert.Equal(t, int32(resp.GetDeniedResponse().Status.Code), int32(401))
+	assert.NilError(t, err)
+}
+
+type auditSinkMock struct {
+	records []audit.Record
+	mu      sync.Mutex
+}
+
+func (s *auditSinkMock) Send(_ context.Context, records []audit.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func TestAuditAuthResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	i := mock_index.NewMockIndex(ctrl)
+	sink := &auditSinkMock{}
+	auditor := audit.NewAuditor(sink, 10, 10, time.Hour, 0)
+	service := AuthService{Index: i, Auditor: auditor}
+
+	i.EXPECT().Get("unknown.com").Return(nil)
+	_, _ = service.Check(context.TODO(), &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+		Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Id: "req-1", Host: "unknown.com", Method: "GET", Path: "/foo?bar=baz"}},
+	}})
+
+	i.EXPECT().Get("myapp.io").Return(mockAnonymousAccessAuthConfig())
+	_, _ = service.Check(context.TODO(), &envoy_auth.CheckRequest{Attributes: &envoy_auth.AttributeContext{
+		Request: &envoy_auth.AttributeContext_Request{Http: &envoy_auth.AttributeContext_HttpRequest{Id: "req-2", Host: "myapp.io", Method: "POST", Path: "/foo"}},
+	}})
+
+	auditor.Stop()
+
+	assert.Equal(t, len(sink.records), 2)
+	assert.Equal(t, sink.records[0].RequestId, "req-1")
+	assert.Equal(t, sink.records[0].Path, "/foo")
+	assert.Equal(t, sink.records[0].Code, rpc.NOT_FOUND.String())
+	assert.Assert(t, !sink.records[0].Authorized)
+	assert.Equal(t, sink.records[1].RequestId, "req-2")
+	assert.Equal(t, sink.records[1].Host, "myapp.io")
+	assert.Equal(t, sink.records[1].Method, "POST")
+	assert.Equal(t, sink.records[1].Code, rpc.OK.String())
+	assert.Assert(t, sink.records[1].Authorized)
+}
+
 func TestBuildDynamicEnvoyMetadata(t *testing.T) {
 	data := map[string]interface{}{
 		"foo": runtime.RawExtension{
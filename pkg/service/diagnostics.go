@@ -0,0 +1,187 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/context"
+	"github.com/kuadrant/authorino/pkg/index"
+	"github.com/kuadrant/authorino/pkg/log"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	DiagnosticsBasePath = "/evaluate"
+
+	// DefaultMaxDiagnosticsRequestBodySize is the maximum size of the synthetic requests accepted by the diagnostics
+	// service - in bytes
+	DefaultMaxDiagnosticsRequestBodySize = 1048576
+)
+
+// DiagnosticsService implements an HTTP server for admins to evaluate synthetic requests against the AuthConfigs in
+// the index, returning the decision and the trace of the evaluators.
+// The synthetic requests go through the real auth pipeline in dry-run mode, i.e. the metadata sources and callbacks
+// are not called, the caches of the evaluators are not written, and no metrics nor audit records are emitted.
+// The response omits the objects resolved by the evaluators and the values of the headers, as they may contain
+// credentials and other sensitive data.
+type DiagnosticsService struct {
+	Index   index.Index
+	Timeout time.Duration
+}
+
+// DiagnosticsRequest is a synthetic authorization request submitted to the diagnostics service
+type DiagnosticsRequest struct {
+	// Host used to look up the AuthConfig in the index; defaults to the host of the attributes of the request
+	Host string `json:"host,omitempty"`
+	// Attributes of the request, in the format of the Envoy external authorization AttributeContext
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// DiagnosticsResponse is the decision and the trace of the evaluators for a synthetic authorization request
+type DiagnosticsResponse struct {
+	Host       string `json:"host"`
+	AuthConfig string `json:"authConfig,omitempty"`
+	Authorized bool   `json:"authorized"`
+	Code       string `json:"code"`
+	Status     int32  `json:"status,omitempty"`
+	Message    string `json:"message,omitempty"`
+	// Headers are the names of the headers of the response
+	Headers           []string                `json:"headers,omitempty"`
+	ConditionOutcomes []auth.ConditionOutcome `json:"conditionOutcomes,omitempty"`
+	Trace             []auth.EvaluatorTrace   `json:"trace"`
+}
+
+func (d *DiagnosticsService) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	requestId := ensureRequestId(req.Header.Get(ENVOY_TRACE_REQUEST_ID_HEADER))
+	logger := log.WithName("service").WithName("diagnostics").WithValues("request id", requestId)
+
+	if req.Method != http.MethodPost || strings.TrimSuffix(req.URL.Path, "/") != DiagnosticsBasePath {
+		logger.V(1).Info(HTTP_MESSAGE_404)
+		writeDiagnosticsError(writer, http.StatusNotFound, HTTP_MESSAGE_404)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(writer, req.Body, DefaultMaxDiagnosticsRequestBodySize))
+	if err != nil {
+		logger.V(1).Info(HTTP_MESSAGE_400, "reason", err.Error())
+		writeDiagnosticsError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	checkRequest, host, err := parseDiagnosticsRequest(payload)
+	if err != nil {
+		logger.V(1).Info(HTTP_MESSAGE_400, "reason", err.Error())
+		writeDiagnosticsError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+	checkRequest.Attributes.Request.Http.Id = requestId
+
+	authConfig := findAuthConfig(d.Index, host)
+	if authConfig == nil {
+		logger.V(1).Info(HTTP_MESSAGE_404, "host", host)
+		writeDiagnosticsError(writer, http.StatusNotFound, RESPONSE_MESSAGE_SERVICE_NOT_FOUND)
+		return
+	}
+
+	timeout := d.Timeout
+	if authConfig.Timeout > 0 {
+		timeout = authConfig.Timeout
+	}
+	ctx := log.IntoContext(context.WithDryRun(context.New(context.WithParent(req.Context()), context.WithTimeout(timeout))), logger)
+	defer context.Cancel(ctx)
+
+	logger.Info("evaluating synthetic authorization request", "host", host)
+
+	pipeline, _ := NewAuthPipeline(ctx, checkRequest, *authConfig).(*AuthPipeline)
+	pipeline.CaptureEvaluatorTrace = true
+	pipeline.CaptureConditionOutcomes = true
+	result := pipeline.Evaluate()
+
+	status := result.Status
+	if status == 0 {
+		status = statusCodeMapping[result.Code]
+	}
+
+	response := DiagnosticsResponse{
+		Host:              host,
+		Authorized:        result.Success(),
+		Code:              result.Code.String(),
+		Status:            int32(status),
+		Message:           result.Message,
+		Headers:           headerNames(result.Headers),
+		ConditionOutcomes: result.ConditionOutcomes,
+		Trace:             pipeline.getEvaluatorTrace(),
+	}
+	if namespace, name := authConfig.Labels["namespace"], authConfig.Labels["name"]; name != "" {
+		response.AuthConfig = fmt.Sprintf("%s/%s", namespace, name)
+	}
+
+	respBody, err := json.Marshal(response)
+	if err != nil {
+		logger.Error(err, "failed to encode diagnostics response")
+		writeDiagnosticsError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	_, _ = writer.Write(respBody)
+}
+
+// parseDiagnosticsRequest builds the authorization request out of a synthetic request submitted to the diagnostics
+// service, and returns it along with the host to look up the AuthConfig
+func parseDiagnosticsRequest(payload []byte) (*envoy_auth.CheckRequest, string, error) {
+	diagnosticsRequest := DiagnosticsRequest{}
+	if err := json.Unmarshal(payload, &diagnosticsRequest); err != nil {
+		return nil, "", fmt.Errorf("invalid synthetic request: %v", err)
+	}
+
+	attributes := &envoy_auth.AttributeContext{}
+	if len(diagnosticsRequest.Attributes) > 0 {
+		if err := protojson.Unmarshal(diagnosticsRequest.Attributes, attributes); err != nil {
+			return nil, "", fmt.Errorf("invalid attributes of the synthetic request: %v", err)
+		}
+	}
+	if attributes.GetRequest().GetHttp() == nil {
+		return nil, "", fmt.Errorf("invalid attributes of the synthetic request: missing http attributes")
+	}
+
+	host := diagnosticsRequest.Host
+	if host == "" {
+		if h, overridden := attributes.ContextExtensions[X_LOOKUP_KEY_NAME]; overridden {
+			host = h
+		} else {
+			host = auth.RequestHost(attributes.Request.Http)
+		}
+	}
+	if host == "" {
+		return nil, "", fmt.Errorf("invalid synthetic request: missing host")
+	}
+
+	return &envoy_auth.CheckRequest{Attributes: attributes}, host, nil
+}
+
+func headerNames(headers []map[string]string) []string {
+	var names []string
+	for _, headerMap := range headers {
+		for name := range headerMap {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeDiagnosticsError(writer http.ResponseWriter, statusCode int, message string) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_, _ = writer.Write(body)
+}
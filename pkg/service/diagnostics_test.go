@@ -0,0 +1,104 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	gojson "encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	gohttptest "net/http/httptest"
+
+	"github.com/kuadrant/authorino/pkg/auth"
+	"github.com/kuadrant/authorino/pkg/evaluators"
+	"github.com/kuadrant/authorino/pkg/evaluators/authorization"
+	"github.com/kuadrant/authorino/pkg/evaluators/identity"
+	"github.com/kuadrant/authorino/pkg/evaluators/metadata"
+	mock_index "github.com/kuadrant/authorino/pkg/index/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+const syntheticRequest = `{
+	"host": "myapp.io",
+	"attributes": {
+		"request": {
+			"http": {
+				"method": "GET",
+				"path": "/hello"
+			}
+		}
+	}
+}`
+
+func TestDiagnosticsServiceEvaluate(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	opaPolicy, _ := authorization.NewOPAAuthorization("deny", `allow = false`, nil, false, 0, context.TODO())
+	externalService := &metadata.GenericHttp{Endpoint: "http://external-service", Method: "POST"}
+	authConfig := &evaluators.AuthConfig{
+		Labels:               map[string]string{"namespace": "default", "name": "myapp"},
+		IdentityConfigs:      []auth.AuthConfigEvaluator{&evaluators.IdentityConfig{Name: "anonymous", Noop: &identity.Noop{}}},
+		MetadataConfigs:      []auth.AuthConfigEvaluator{&evaluators.MetadataConfig{Name: "external", GenericHTTP: externalService}},
+		AuthorizationConfigs: []auth.AuthConfigEvaluator{&evaluators.AuthorizationConfig{Name: "deny", OPA: opaPolicy}},
+		CallbackConfigs:      []auth.AuthConfigEvaluator{&evaluators.CallbackConfig{Name: "notify", HTTP: externalService}},
+	}
+	indexMock := mock_index.NewMockIndex(mockController)
+	indexMock.EXPECT().Get("myapp.io").Return(authConfig)
+
+	diagnosticsService := &DiagnosticsService{Index: indexMock}
+	request, _ := http.NewRequest("POST", "http://localhost/evaluate", bytes.NewReader([]byte(syntheticRequest)))
+	response := gohttptest.NewRecorder()
+	diagnosticsService.ServeHTTP(response, request)
+
+	assert.Equal(t, response.Code, 200)
+	assert.Equal(t, response.Header().Get("Content-Type"), "application/json")
+
+	var result DiagnosticsResponse
+	assert.NilError(t, gojson.Unmarshal(response.Body.Bytes(), &result))
+	assert.Equal(t, result.Host, "myapp.io")
+	assert.Equal(t, result.AuthConfig, "default/myapp")
+	assert.Check(t, !result.Authorized)
+	assert.Equal(t, result.Code, "PERMISSION_DENIED")
+	assert.Equal(t, result.Status, int32(403))
+	assert.DeepEqual(t, result.Trace, []auth.EvaluatorTrace{
+		{Phase: "identity", Name: "anonymous", Type: "IDENTITY_NOOP", Outcome: "success"},
+		{Phase: "metadata", Name: "external", Type: "METADATA_GENERIC_HTTP", Outcome: "skipped", Error: "dry run"},
+		{Phase: "authorization", Name: "deny", Type: "AUTHORIZATION_OPA", Outcome: "failure", Error: "Unauthorized"},
+		{Phase: "callbacks", Name: "notify", Type: "CALLBACK_HTTP", Outcome: "skipped", Error: "dry run"},
+	})
+	assert.Check(t, !strings.Contains(response.Body.String(), `"anonymous":true`)) // resolved objects are omitted
+	assert.Equal(t, testutil.ToFloat64(authServerAuthConfigTotalMetric.WithLabelValues("default", "myapp")), float64(0))
+}
+
+func TestDiagnosticsServiceUnknownHost(t *testing.T) {
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+	indexMock := mock_index.NewMockIndex(mockController)
+	indexMock.EXPECT().Get("myapp.io").Return(nil)
+
+	diagnosticsService := &DiagnosticsService{Index: indexMock}
+	request, _ := http.NewRequest("POST", "http://localhost/evaluate", bytes.NewReader([]byte(syntheticRequest)))
+	response := gohttptest.NewRecorder()
+	diagnosticsService.ServeHTTP(response, request)
+
+	assert.Equal(t, response.Code, 404)
+}
+
+func TestDiagnosticsServiceInvalidRequest(t *testing.T) {
+	diagnosticsService := &DiagnosticsService{}
+
+	request, _ := http.NewRequest("POST", "http://localhost/evaluate", bytes.NewReader([]byte(`{"host":"myapp.io"}`)))
+	response := gohttptest.NewRecorder()
+	diagnosticsService.ServeHTTP(response, request)
+	assert.Equal(t, response.Code, 400)
+
+	request, _ = http.NewRequest("GET", "http://localhost/evaluate", nil)
+	response = gohttptest.NewRecorder()
+	diagnosticsService.ServeHTTP(response, request)
+	assert.Equal(t, response.Code, 404)
+}
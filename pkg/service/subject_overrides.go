@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kuadrant/authorino/pkg/log"
+	"github.com/kuadrant/authorino/pkg/workers"
+)
+
+const (
+	SubjectOverrideDeny  = "deny"
+	SubjectOverrideAllow = "allow"
+
+	// DefaultSubjectOverridesSelector selects the subject of the request from the authorization JSON
+	DefaultSubjectOverridesSelector = "auth.identity.sub"
+)
+
+// SubjectOverrides are the global lists of subjects force-denied across all AuthConfigs or force-allowed in specific
+// AuthConfigs, checked right after the identity verification, e.g. an emergency blocklist during an incident; nil if
+// not enabled
+var SubjectOverrides *SubjectOverrideList
+
+// SubjectOverridesSelector selects the subject of the request from the authorization JSON
+var SubjectOverridesSelector = DefaultSubjectOverridesSelector
+
+// SubjectOverride is a subject listed in the overrides, along with the AuthConfig (`<namespace>/<name>`) the override
+// is scoped to; empty for the overrides that apply to all the AuthConfigs
+type SubjectOverride struct {
+	AuthConfig string
+	Subject    string
+}
+
+func NewSubjectOverrideList() *SubjectOverrideList {
+	return &SubjectOverrideList{overrides: map[SubjectOverride]string{}}
+}
+
+// SubjectOverrideList maps subjects to the decision that overrides the AuthConfig for them, i.e. deny or allow.
+// Denials apply to all the AuthConfigs, whereas allowances apply only to the AuthConfig they are scoped to, as the same
+// subject can be issued by unrelated identity sources of different AuthConfigs.
+// The list can be replaced at runtime.
+type SubjectOverrideList struct {
+	overrides map[SubjectOverride]string
+	mu        sync.RWMutex
+}
+
+// Set replaces the list and tells whether it changed
+func (l *SubjectOverrideList) Set(overrides map[SubjectOverride]string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(overrides) == len(l.overrides) {
+		changed := false
+		for override, decision := range overrides {
+			if l.overrides[override] != decision {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return false
+		}
+	}
+	l.overrides = overrides
+	return true
+}
+
+// Decision returns the decision that overrides the AuthConfig (`<namespace>/<name>`) for the subject, or empty if the
+// subject is not listed for the AuthConfig. Denials take precedence.
+func (l *SubjectOverrideList) Decision(authConfig, subject string) string {
+	if subject == "" {
+		return ""
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if decision := l.overrides[SubjectOverride{Subject: subject}]; decision != "" {
+		return decision
+	}
+	return l.overrides[SubjectOverride{AuthConfig: authConfig, Subject: subject}]
+}
+
+// Empty tells whether no subject is listed
+func (l *SubjectOverrideList) Empty() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.overrides) == 0
+}
+
+func (l *SubjectOverrideList) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]string, 0, len(l.overrides))
+	for override, decision := range l.overrides {
+		if override.AuthConfig != "" {
+			decision += " " + override.AuthConfig
+		}
+		entries = append(entries, decision+" "+override.Subject)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ", ")
+}
+
+// ParseSubjectOverrides parses a list of subject overrides, one per line, in the format `deny <subject>` (all the
+// AuthConfigs) or `allow <namespace>/<name> <subject>` (only the given AuthConfig).
+// Blank lines and lines starting with '#' are ignored.
+func ParseSubjectOverrides(content string) (map[SubjectOverride]string, error) {
+	overrides := map[SubjectOverride]string{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		decision, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch decision {
+		case SubjectOverrideDeny:
+			if rest == "" {
+				return nil, fmt.Errorf("invalid subject override at line %d: missing subject", n)
+			}
+			overrides[SubjectOverride{Subject: rest}] = SubjectOverrideDeny
+		case SubjectOverrideAllow:
+			authConfig, subject, _ := strings.Cut(rest, " ")
+			subject = strings.TrimSpace(subject)
+			if namespace, name, found := strings.Cut(authConfig, "/"); !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+				return nil, fmt.Errorf("invalid subject override at line %d: allow must be scoped to an authconfig, expected allow <namespace>/<name> <subject>", n)
+			}
+			if subject == "" {
+				return nil, fmt.Errorf("invalid subject override at line %d: missing subject", n)
+			}
+			overrides[SubjectOverride{AuthConfig: authConfig, Subject: subject}] = SubjectOverrideAllow
+		default:
+			return nil, fmt.Errorf("invalid subject override at line %d: %s is not one of: %s, %s", n, decision, SubjectOverrideDeny, SubjectOverrideAllow)
+		}
+	}
+	return overrides, scanner.Err()
+}
+
+// WatchSubjectOverridesFile reads the subject overrides from a file (e.g. a mounted ConfigMap) on a given interval (in
+// seconds) and updates the list whenever the contents of the file change.
+// If the file cannot be read or parsed, the list is kept as is.
+func WatchSubjectOverridesFile(ctx context.Context, path string, interval int, list *SubjectOverrideList, logger log.Logger) (workers.Worker, error) {
+	reload := func() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Error(err, "failed to read subject overrides file", "path", path)
+			return
+		}
+		overrides, err := ParseSubjectOverrides(string(content))
+		if err != nil {
+			logger.Error(err, "invalid subject overrides", "path", path)
+			return
+		}
+		if list.Set(overrides) {
+			logger.Info("subject overrides changed", "overrides", list.String())
+		}
+	}
+	reload()
+	return workers.StartWorker(ctx, "subject_overrides", interval, reload)
+}
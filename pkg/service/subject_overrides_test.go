@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kuadrant/authorino/pkg/log"
+
+	"gotest.tools/assert"
+)
+
+func TestParseSubjectOverrides(t *testing.T) {
+	overrides, err := ParseSubjectOverrides(`
+# incident 42
+deny   alice
+allow  default/talker-api bob
+allow default/talker-api alice
+deny system:serviceaccount:default:ci
+`)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, overrides, map[SubjectOverride]string{
+		{Subject: "alice"}: SubjectOverrideDeny,
+		{AuthConfig: "default/talker-api", Subject: "bob"}:   SubjectOverrideAllow,
+		{AuthConfig: "default/talker-api", Subject: "alice"}: SubjectOverrideAllow,
+		{Subject: "system:serviceaccount:default:ci"}:        SubjectOverrideDeny,
+	})
+
+	_, err = ParseSubjectOverrides("block alice")
+	assert.Error(t, err, "invalid subject override at line 1: block is not one of: deny, allow")
+
+	_, err = ParseSubjectOverrides("allow default/talker-api bob\ndeny")
+	assert.Error(t, err, "invalid subject override at line 2: missing subject")
+
+	_, err = ParseSubjectOverrides("allow bob")
+	assert.Error(t, err, "invalid subject override at line 1: allow must be scoped to an authconfig, expected allow <namespace>/<name> <subject>")
+
+	_, err = ParseSubjectOverrides("allow default/talker-api")
+	assert.Error(t, err, "invalid subject override at line 1: missing subject")
+}
+
+func TestSubjectOverrideList(t *testing.T) {
+	list := NewSubjectOverrideList()
+	assert.Check(t, list.Empty())
+	assert.Equal(t, list.Decision("default/talker-api", "alice"), "")
+
+	overrides := map[SubjectOverride]string{
+		{Subject: "alice"}: SubjectOverrideDeny,
+		{AuthConfig: "default/talker-api", Subject: "alice"}: SubjectOverrideAllow,
+		{AuthConfig: "default/talker-api", Subject: "bob"}:   SubjectOverrideAllow,
+	}
+	assert.Check(t, list.Set(overrides))
+	assert.Check(t, !list.Set(overrides))
+	assert.Equal(t, list.Decision("default/talker-api", "alice"), SubjectOverrideDeny) // denials take precedence
+	assert.Equal(t, list.Decision("other/api", "alice"), SubjectOverrideDeny)
+	assert.Equal(t, list.Decision("default/talker-api", "bob"), SubjectOverrideAllow)
+	assert.Equal(t, list.Decision("other/api", "bob"), "") // allowances are scoped to the authconfig
+	assert.Equal(t, list.Decision("default/talker-api", "john"), "")
+	assert.Equal(t, list.Decision("default/talker-api", ""), "")
+	assert.Equal(t, list.String(), "allow default/talker-api alice, allow default/talker-api bob, deny alice")
+}
+
+func TestWatchSubjectOverridesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides")
+	_ = os.WriteFile(path, []byte("deny alice\n"), 0644)
+
+	list := NewSubjectOverrideList()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := WatchSubjectOverridesFile(ctx, path, 1, list, log.WithName("test"))
+	assert.NilError(t, err)
+	assert.Equal(t, list.Decision("default/talker-api", "alice"), SubjectOverrideDeny) // initial read
+
+	_ = os.WriteFile(path, []byte("allow default/talker-api alice\n"), 0644)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, list.Decision("default/talker-api", "alice"), SubjectOverrideAllow)
+
+	// invalid overrides are ignored
+	_ = os.WriteFile(path, []byte("block alice\n"), 0644)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, list.Decision("default/talker-api", "alice"), SubjectOverrideAllow)
+}
@@ -115,6 +115,8 @@ type DestinationAttributes struct {
 type AuthAttributes struct {
 	// Single resolved identity object, post-identity verification
 	Identity any `json:"identity,omitempty"`
+	// Identity source (authentication config) that resolved the identity object
+	IdentitySource *IdentitySourceAttributes `json:"identity_source,omitempty"`
 	// External metadata fetched
 	Metadata map[string]any `json:"metadata,omitempty"`
 	// Authorization results resolved by each authorization rule, access granted only
@@ -123,6 +125,24 @@ type AuthAttributes struct {
 	Response map[string]any `json:"response,omitempty"`
 	// Response objects returned by the callback requests issued by the auth service
 	Callbacks map[string]any `json:"callbacks,omitempty"`
+	// Reason of the denial, for denied requests only
+	Denial *DenialAttributes `json:"denial,omitempty"`
+}
+
+type IdentitySourceAttributes struct {
+	// Name of the identity source in the AuthConfig
+	Name string `json:"name"`
+	// Type of the identity source e.g. IDENTITY_OIDC, IDENTITY_APIKEY
+	Type string `json:"type"`
+}
+
+type DenialAttributes struct {
+	// Reason of the denial, i.e. the error message of the failed evaluator
+	Reason string `json:"reason"`
+	// Name of the authorization rule in the AuthConfig that denied the request; empty for unauthenticated requests
+	Name string `json:"name,omitempty"`
+	// Type of the authorization rule that denied the request e.g. AUTHORIZATION_JSON, AUTHORIZATION_OPA
+	Type string `json:"type,omitempty"`
 }
 
 // NewWellKnownAttributes creates a new WellKnownAttributes object from an envoyauth.AttributeContext
@@ -4,13 +4,67 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/kuadrant/authorino/pkg/clock"
+	"github.com/kuadrant/authorino/pkg/metrics"
+)
+
+const (
+	// QueuePolicyDrop drops the runs of a busy worker when its queue of pending runs is full
+	QueuePolicyDrop = "drop"
+	// QueuePolicyBlock holds back the runs of a busy worker when its queue of pending runs is full, until there is room
+	// in the queue, i.e. the runs are delayed instead of dropped
+	QueuePolicyBlock = "block"
+
+	DefaultMaxPending = 1
 )
 
-// StartWorker returns a worker that executes a function repeatedly on a given interval (in seconds)
-func StartWorker(ctx context.Context, interval int, f func()) (Worker, error) {
+var (
+	// MaxPending is the maximum number of runs of a worker pending while the worker is busy
+	MaxPending = DefaultMaxPending
+	// QueuePolicy is what to do with the runs of a busy worker when its queue of pending runs is full
+	QueuePolicy = QueuePolicyDrop
+
+	workerPendingRunsMetric = metrics.NewGaugeMetric("worker_pending_runs", "Number of runs of asynchronous workers pending while the workers are busy.", "worker")
+	workerDroppedRunsMetric = metrics.NewCounterMetric("worker_dropped_runs_total", "Number of runs of asynchronous workers dropped because the queue of pending runs was full.", "worker")
+)
+
+func init() {
+	metrics.Register(
+		workerPendingRunsMetric,
+		workerDroppedRunsMetric,
+	)
+}
+
+// ValidateQueue checks the maximum number of pending runs and the queue policy of the workers
+func ValidateQueue(maxPending int, policy string) error {
+	if maxPending < 0 {
+		return fmt.Errorf("maximum number of pending runs must not be negative")
+	}
+	switch policy {
+	case QueuePolicyDrop, QueuePolicyBlock:
+		return nil
+	default:
+		return fmt.Errorf("unsupported queue policy: %s", policy)
+	}
+}
+
+// StartWorker returns a worker that executes a function repeatedly on a given interval (in seconds).
+// The name of the worker identifies the kind of work in the metrics.
+func StartWorker(ctx context.Context, name string, interval int, f func()) (Worker, error) {
+	return StartWorkerWithClock(ctx, name, interval, f, clock.RealClock)
+}
+
+// StartWorkerWithClock returns a worker that executes a function repeatedly on a given interval (in seconds), as
+// measured by the given clock
+func StartWorkerWithClock(ctx context.Context, name string, interval int, f func(), c clock.Clock) (Worker, error) {
 	w := &worker{
-		ctx: ctx,
-		f:   f,
+		ctx:        ctx,
+		name:       name,
+		f:          f,
+		clock:      clock.OrRealClock(c),
+		maxPending: MaxPending,
+		policy:     QueuePolicy,
 	}
 
 	if err := w.Start(interval); err != nil {
@@ -26,10 +80,14 @@ type Worker interface {
 }
 
 type worker struct {
-	ctx   context.Context
-	f     func()
-	timer *time.Ticker
-	done  chan bool
+	ctx        context.Context
+	name       string
+	f          func()
+	clock      clock.Clock
+	timer      clock.Ticker
+	done       chan bool
+	maxPending int
+	policy     string
 }
 
 func (w *worker) Start(interval int) error {
@@ -48,16 +106,31 @@ func (w *worker) Start(interval int) error {
 		w.timer.Stop()
 	}
 
-	w.timer = time.NewTicker(duration)
+	w.timer = w.clock.NewTicker(duration)
 
 	done := make(chan bool, 1)
+	pending := make(chan struct{}, w.maxPending)
 
+	// runs the pending work, so a busy worker does not hold back the ticks
 	go func() {
+		for range pending {
+			workerPendingRunsMetric.WithLabelValues(w.name).Dec()
+			select {
+			case <-w.ctx.Done(): // discards the runs pending when the worker stopped
+			case <-done:
+			default:
+				w.f()
+			}
+		}
+	}()
+
+	go func() {
+		defer close(pending)
 		defer w.timer.Stop()
 		for {
 			select {
-			case <-w.timer.C:
-				w.f()
+			case <-w.timer.C():
+				w.enqueue(pending, done)
 			case <-w.ctx.Done():
 				return
 			case <-done:
@@ -71,6 +144,30 @@ func (w *worker) Start(interval int) error {
 	return nil
 }
 
+// enqueue adds a run to the queue of pending work, dropping it or waiting for room in the queue if full, depending on
+// the queue policy. The run is counted as pending before it is queued, for the worker not to uncount it first, and
+// uncounted if not queued after all.
+func (w *worker) enqueue(pending chan<- struct{}, done <-chan bool) {
+	pendingRuns := workerPendingRunsMetric.WithLabelValues(w.name)
+	pendingRuns.Inc()
+	if w.policy == QueuePolicyBlock {
+		select {
+		case pending <- struct{}{}:
+		case <-w.ctx.Done():
+			pendingRuns.Dec()
+		case <-done:
+			pendingRuns.Dec()
+		}
+	} else {
+		select {
+		case pending <- struct{}{}:
+		default:
+			pendingRuns.Dec()
+			workerDroppedRunsMetric.WithLabelValues(w.name).Inc()
+		}
+	}
+}
+
 func (w *worker) Stop() error {
 	if w.done != nil {
 		close(w.done)
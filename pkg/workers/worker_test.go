@@ -2,15 +2,18 @@ package workers
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"gotest.tools/assert"
+	clocktesting "k8s.io/utils/clock/testing"
 )
 
 func TestStartWorker(t *testing.T) {
 	val := 0
-	worker, err := StartWorker(context.TODO(), 2, func() {
+	worker, err := StartWorker(context.TODO(), "test", 2, func() {
 		val += 1
 	})
 	defer worker.Stop()
@@ -25,7 +28,7 @@ func TestStartWorker(t *testing.T) {
 
 func TestStopWorker(t *testing.T) {
 	val := 0
-	worker, err := StartWorker(context.TODO(), 2, func() {
+	worker, err := StartWorker(context.TODO(), "test", 2, func() {
 		val += 1
 	})
 	assert.NilError(t, err)
@@ -39,3 +42,102 @@ func TestStopWorker(t *testing.T) {
 
 	assert.Equal(t, val, 0)
 }
+
+func TestStartWorkerWithClock(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	ticks := make(chan struct{}, 1)
+	worker, err := StartWorkerWithClock(context.TODO(), "test", 2, func() {
+		ticks <- struct{}{}
+	}, fakeClock)
+	defer worker.Stop()
+	assert.NilError(t, err)
+
+	fakeClock.Step(time.Second)
+	select {
+	case <-ticks:
+		t.Fatal("worker executed before the interval")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	fakeClock.Step(time.Second)
+	select {
+	case <-ticks:
+	case <-time.After(time.Second):
+		t.Fatal("worker not executed after the interval")
+	}
+}
+
+func TestWorkerQueueDropPolicy(t *testing.T) {
+	defer func(maxPending int, policy string) { MaxPending, QueuePolicy = maxPending, policy }(MaxPending, QueuePolicy)
+	MaxPending, QueuePolicy = 1, QueuePolicyDrop
+
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	release := make(chan struct{})
+	var runs int32
+	worker, err := StartWorkerWithClock(context.TODO(), "test-drop", 1, func() {
+		atomic.AddInt32(&runs, 1)
+		<-release
+	}, fakeClock)
+	defer worker.Stop()
+	assert.NilError(t, err)
+
+	pending := workerPendingRunsMetric.WithLabelValues("test-drop")
+	dropped := workerDroppedRunsMetric.WithLabelValues("test-drop")
+
+	fakeClock.Step(time.Second) // starts running and blocks
+	eventually(t, func() bool { return atomic.LoadInt32(&runs) == 1 })
+	fakeClock.Step(time.Second) // fills the queue
+	eventually(t, func() bool { return testutil.ToFloat64(pending) == 1 })
+	fakeClock.Step(time.Second) // dropped
+	eventually(t, func() bool { return testutil.ToFloat64(dropped) == 1 })
+
+	close(release)
+	eventually(t, func() bool { return atomic.LoadInt32(&runs) == 2 && testutil.ToFloat64(pending) == 0 })
+	assert.Equal(t, testutil.ToFloat64(dropped), float64(1))
+}
+
+func TestWorkerQueueBlockPolicy(t *testing.T) {
+	defer func(maxPending int, policy string) { MaxPending, QueuePolicy = maxPending, policy }(MaxPending, QueuePolicy)
+	MaxPending, QueuePolicy = 1, QueuePolicyBlock
+
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	release := make(chan struct{})
+	var runs int32
+	worker, err := StartWorkerWithClock(context.TODO(), "test-block", 1, func() {
+		atomic.AddInt32(&runs, 1)
+		<-release
+	}, fakeClock)
+	defer worker.Stop()
+	assert.NilError(t, err)
+
+	pending := workerPendingRunsMetric.WithLabelValues("test-block")
+	dropped := workerDroppedRunsMetric.WithLabelValues("test-block")
+
+	fakeClock.Step(time.Second) // starts running and blocks
+	eventually(t, func() bool { return atomic.LoadInt32(&runs) == 1 })
+	fakeClock.Step(time.Second) // fills the queue
+	eventually(t, func() bool { return testutil.ToFloat64(pending) == 1 })
+	fakeClock.Step(time.Second) // waits for room in the queue
+
+	close(release)
+	eventually(t, func() bool { return atomic.LoadInt32(&runs) == 3 && testutil.ToFloat64(pending) == 0 })
+	assert.Equal(t, testutil.ToFloat64(dropped), float64(0))
+}
+
+func TestValidateQueue(t *testing.T) {
+	assert.NilError(t, ValidateQueue(1, QueuePolicyDrop))
+	assert.NilError(t, ValidateQueue(0, QueuePolicyBlock))
+	assert.Error(t, ValidateQueue(-1, QueuePolicyDrop), "maximum number of pending runs must not be negative")
+	assert.Error(t, ValidateQueue(1, "fifo"), "unsupported queue policy: fifo")
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}